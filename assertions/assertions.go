@@ -0,0 +1,151 @@
+// Package assertions provides test assertions on the records of a gosette.HTTPTestServer.
+//
+// Assertions report failures with readable messages and diffs through the provided testing.T
+// (or any TestingT) and return true when they succeed, like the stretchr/testify assertions they
+// are built on. Example:
+//
+//	record := hts.PopServerRecord()
+//	assertions.AssertJSONBody(t, record, `{"name": "alice"}`)
+//	assertions.AssertHeader(t, record, "Authorization", regexp.MustCompile(`^Bearer .+`))
+//	assertions.AssertQueryParam(t, record, "page", "2")
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/gbdevw/gosette"
+	"github.com/stretchr/testify/assert"
+)
+
+// Interface implemented by *testing.T and *testing.B used to report assertion failures.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Interface implemented by *testing.T and *testing.B used to mark assertions as helpers.
+type tHelper interface {
+	Helper()
+}
+
+// Assert that the recorded request body is JSON equivalent to the expected value. The expected
+// value can be a JSON document (string or []byte) or any value which is encoded in JSON.
+// Differences are reported with a diff.
+func AssertJSONBody(t TestingT, record *gosette.ServerRecord, expected interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !assertRecord(t, record, msgAndArgs...) {
+		return false
+	}
+	return assertJSON(t, expected, record.RequestBody.Bytes(), "request", msgAndArgs...)
+}
+
+// Assert that the recorded response body is JSON equivalent to the expected value. The expected
+// value can be a JSON document (string or []byte) or any value which is encoded in JSON.
+// Differences are reported with a diff.
+func AssertResponseJSONBody(t TestingT, record *gosette.ServerRecord, expected interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !assertRecord(t, record, msgAndArgs...) {
+		return false
+	}
+	return assertJSON(t, expected, record.Response.Body.Bytes(), "response", msgAndArgs...)
+}
+
+// Assert that the recorded request has the provided header with the expected value. The expected
+// value is either a string compared with the header values or a *regexp.Regexp the header values
+// must match. The assertion succeeds if one of the header values matches.
+func AssertHeader(t TestingT, record *gosette.ServerRecord, name string, expected interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !assertRecord(t, record, msgAndArgs...) {
+		return false
+	}
+	return assertValues(t, "header "+name, record.Request.Header.Values(name), expected, msgAndArgs...)
+}
+
+// Assert that the recorded request has the provided query parameter with the expected value. The
+// expected value is either a string compared with the parameter values or a *regexp.Regexp the
+// parameter values must match. The assertion succeeds if one of the parameter values matches.
+func AssertQueryParam(t TestingT, record *gosette.ServerRecord, name string, expected interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !assertRecord(t, record, msgAndArgs...) {
+		return false
+	}
+	return assertValues(t, "query parameter "+name, record.Request.URL.Query()[name], expected, msgAndArgs...)
+}
+
+// Assert that the recorded response has the expected status code.
+func AssertStatus(t TestingT, record *gosette.ServerRecord, expected int, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !assertRecord(t, record, msgAndArgs...) {
+		return false
+	}
+	if record.Response.Code != expected {
+		return assert.Fail(t, fmt.Sprintf("expected response status %d, got %d", expected, record.Response.Code), msgAndArgs...)
+	}
+	return true
+}
+
+// Helper function which asserts that the provided record and its request are not nil.
+func assertRecord(t TestingT, record *gosette.ServerRecord, msgAndArgs ...interface{}) bool {
+	if record == nil || record.Request == nil || record.Response == nil {
+		return assert.Fail(t, "expected a server record, got none", msgAndArgs...)
+	}
+	return true
+}
+
+// Helper function which asserts that the actual body is JSON equivalent to the expected value.
+func assertJSON(t TestingT, expected interface{}, actual []byte, what string, msgAndArgs ...interface{}) bool {
+	var document []byte
+	switch v := expected.(type) {
+	case string:
+		document = []byte(v)
+	case []byte:
+		document = v
+	default:
+		var err error
+		document, err = json.Marshal(v)
+		if err != nil {
+			return assert.Fail(t, fmt.Sprintf("expected value cannot be encoded in JSON: %s", err), msgAndArgs...)
+		}
+	}
+	if !json.Valid(actual) {
+		return assert.Fail(t, fmt.Sprintf("expected %s body to be JSON, got %q", what, actual), msgAndArgs...)
+	}
+	return assert.JSONEq(t, string(document), string(actual), msgAndArgs...)
+}
+
+// Helper function which asserts that one of the provided values matches the expected string or
+// regular expression.
+func assertValues(t TestingT, what string, values []string, expected interface{}, msgAndArgs ...interface{}) bool {
+	if len(values) == 0 {
+		return assert.Fail(t, fmt.Sprintf("expected %s to be %v, got no value", what, expected), msgAndArgs...)
+	}
+	for _, value := range values {
+		switch e := expected.(type) {
+		case string:
+			if value == e {
+				return true
+			}
+		case *regexp.Regexp:
+			if e.MatchString(value) {
+				return true
+			}
+		default:
+			return assert.Fail(t, fmt.Sprintf("expected value of %s must be a string or a *regexp.Regexp, got %T", what, expected), msgAndArgs...)
+		}
+	}
+	if e, ok := expected.(*regexp.Regexp); ok {
+		return assert.Fail(t, fmt.Sprintf("expected %s to match %s, got %q", what, e, values), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("expected %s to be %q, got %q", what, expected, values), msgAndArgs...)
+}
@@ -0,0 +1,76 @@
+package assertions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gbdevw/gosette"
+	"github.com/stretchr/testify/require"
+)
+
+// A TestingT which collects reported failures.
+type recordingT struct {
+	failures []string
+}
+
+func (rt *recordingT) Errorf(format string, args ...interface{}) {
+	rt.failures = append(rt.failures, fmt.Sprintf(format, args...))
+}
+
+// Test assertions succeed and fail with readable messages.
+func TestAssertions(t *testing.T) {
+	hts := gosette.NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.When().Post("/users").RespondWith().Status(http.StatusCreated).JSONBody(map[string]int{"id": 1})
+	req, err := http.NewRequest(http.MethodPost, hts.GetBaseURL()+"/users?page=2", strings.NewReader(`{"name":"alice","age":30}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer abc")
+	resp, err := hts.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	record := hts.PopServerRecord()
+	// Succeeding assertions
+	require.True(t, AssertJSONBody(t, record, `{"age": 30, "name": "alice"}`))
+	require.True(t, AssertJSONBody(t, record, map[string]interface{}{"name": "alice", "age": 30}))
+	require.True(t, AssertResponseJSONBody(t, record, []byte(`{"id":1}`)))
+	require.True(t, AssertHeader(t, record, "Authorization", regexp.MustCompile(`^Bearer .+`)))
+	require.True(t, AssertHeader(t, record, "authorization", "Bearer abc"))
+	require.True(t, AssertQueryParam(t, record, "page", "2"))
+	require.True(t, AssertStatus(t, record, http.StatusCreated))
+	// Failing assertions
+	failing := []struct {
+		message   string
+		assertion func(rt *recordingT) bool
+	}{
+		{"Not equal", func(rt *recordingT) bool { return AssertJSONBody(rt, record, `{"name":"bob"}`) }},
+		{"cannot be encoded", func(rt *recordingT) bool { return AssertJSONBody(rt, record, func() {}) }},
+		{"expected response body to be JSON", func(rt *recordingT) bool {
+			response := httptest.NewRecorder()
+			response.WriteString("not json")
+			return AssertResponseJSONBody(rt, &gosette.ServerRecord{Request: record.Request, Response: response}, `{}`)
+		}},
+		{"to match ^Basic", func(rt *recordingT) bool {
+			return AssertHeader(rt, record, "Authorization", regexp.MustCompile(`^Basic`))
+		}},
+		{`to be "x", got ["Bearer abc"]`, func(rt *recordingT) bool { return AssertHeader(rt, record, "Authorization", "x") }},
+		{"got no value", func(rt *recordingT) bool { return AssertQueryParam(rt, record, "size", "10") }},
+		{"must be a string", func(rt *recordingT) bool { return AssertQueryParam(rt, record, "page", 2) }},
+		{"expected response status 200", func(rt *recordingT) bool { return AssertStatus(rt, record, http.StatusOK) }},
+		{"expected a server record", func(rt *recordingT) bool { return AssertStatus(rt, nil, http.StatusOK) }},
+		{"expected a server record", func(rt *recordingT) bool { return AssertJSONBody(rt, nil, `{}`) }},
+		{"expected a server record", func(rt *recordingT) bool { return AssertResponseJSONBody(rt, nil, `{}`) }},
+		{"expected a server record", func(rt *recordingT) bool { return AssertHeader(rt, nil, "a", "b") }},
+		{"expected a server record", func(rt *recordingT) bool { return AssertQueryParam(rt, nil, "a", "b") }},
+	}
+	for _, tc := range failing {
+		rt := &recordingT{}
+		require.False(t, tc.assertion(rt), tc.message)
+		require.Len(t, rt.failures, 1, tc.message)
+		require.Contains(t, rt.failures[0], tc.message)
+	}
+}
@@ -0,0 +1,69 @@
+package gosette
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// Credentials used for HTTP Basic authentication.
+type BasicCredentials struct {
+	// Expected or presented username.
+	Username string
+	// Expected or presented password.
+	Password string
+	// Realm announced in the WWW-Authenticate header of 401 responses. Defaults to gosette. Not
+	// set on recorded credentials.
+	Realm string
+}
+
+// Build a middleware which requires Basic credentials for all requests served by the test server.
+// Requests without the provided credentials are answered with a 401 response which has a
+// WWW-Authenticate header. The realm defaults to gosette when empty.
+//
+// The credentials presented by clients are recorded in the ServerRecord BasicAuth.
+func BasicAuth(username string, password string, realm string) Middleware {
+	credentials := &BasicCredentials{Username: username, Password: password, Realm: realm}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !credentials.check(r) {
+				writeHeaders(w, credentials.unauthorized())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Helper method which returns true if the provided request presents the credentials.
+func (credentials *BasicCredentials) check(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(credentials.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(credentials.Password)) == 1
+}
+
+// Helper method which returns the 401 response served when the credentials are missing or
+// incorrect.
+func (credentials *BasicCredentials) unauthorized() *PredefinedServerResponse {
+	realm := credentials.Realm
+	if realm == "" {
+		realm = "gosette"
+	}
+	return &PredefinedServerResponse{
+		Status: http.StatusUnauthorized,
+		Headers: http.Header{
+			"Www-Authenticate": {fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm)},
+		},
+	}
+}
+
+// Helper function which returns the Basic credentials presented by the provided request or nil
+// if the request has no Basic credentials.
+func presentedBasicCredentials(r *http.Request) *BasicCredentials {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	return &BasicCredentials{Username: username, Password: password}
+}
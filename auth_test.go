@@ -0,0 +1,68 @@
+package gosette
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test Basic credentials required by a predefined response. Test will ensure:
+//   - Requests without credentials or with incorrect credentials get a 401 response
+//   - Requests with the right credentials get the predefined response
+//   - Presented credentials are recorded
+func (suite *HTTPTestServerUnitTestSuite) TestRequireBasicAuth() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/secret").RespondWith().StringBody("secret").RequireBasicAuth("john", "pa$$")
+
+	testCases := []struct {
+		credentials *BasicCredentials
+		status      int
+	}{
+		{nil, http.StatusUnauthorized},
+		{&BasicCredentials{Username: "john", Password: "wrong"}, http.StatusUnauthorized},
+		{&BasicCredentials{Username: "john", Password: "pa$$"}, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/secret", nil)
+		require.NoError(suite.T(), err)
+		if tc.credentials != nil {
+			req.SetBasicAuth(tc.credentials.Username, tc.credentials.Password)
+		}
+		resp, err := client.Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), tc.status, resp.StatusCode)
+		record := suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.Equal(suite.T(), tc.credentials, record.BasicAuth)
+		if tc.status == http.StatusUnauthorized {
+			require.Equal(suite.T(), `Basic realm="gosette", charset="UTF-8"`, resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// Test the BasicAuth middleware protects the whole server.
+func TestBasicAuthMiddleware(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	srv.Use(BasicAuth("admin", "admin", "admin area"))
+	srv.Start()
+	defer srv.Close()
+
+	// Without credentials
+	resp, err := srv.Client().Get(srv.GetBaseURL() + "/any")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, `Basic realm="admin area", charset="UTF-8"`, resp.Header.Get("WWW-Authenticate"))
+
+	// With credentials
+	req, err := http.NewRequest(http.MethodGet, srv.GetBaseURL()+"/any", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "admin")
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Len(t, srv.FindRecords(ByStatus(http.StatusUnauthorized)), 1)
+}
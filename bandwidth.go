@@ -0,0 +1,77 @@
+package gosette
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Number of slices written per second by a throttled response writer.
+const throttleSlicesPerSecond = 10
+
+// Build a middleware which limits the bandwidth of all responses, including proxied responses, to
+// the provided number of bytes per second. Predefined responses which declare BytesPerSecond are
+// limited by both values.
+func ThrottleBandwidth(bytesPerSecond int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(newThrottledResponseWriter(w, r.Context(), bytesPerSecond), r)
+		})
+	}
+}
+
+// A http.ResponseWriter which limits the number of bytes written per second. Data are written in
+// slices with a flush and a pause after each of them.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	// Context which interrupts pauses when done.
+	ctx context.Context
+	// Maximum number of bytes written per second.
+	bytesPerSecond int
+}
+
+// Build a new throttled response writer which writes to the provided http.ResponseWriter.
+func newThrottledResponseWriter(w http.ResponseWriter, ctx context.Context, bytesPerSecond int) *throttledResponseWriter {
+	return &throttledResponseWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+// Write the provided data slice by slice, with a flush and a pause after each slice. An error is
+// returned if the request context is done before all data have been written.
+func (tw *throttledResponseWriter) Write(p []byte) (int, error) {
+	slice := tw.bytesPerSecond / throttleSlicesPerSecond
+	if slice < 1 {
+		slice = 1
+	}
+	written := 0
+	for written < len(p) {
+		end := written + slice
+		if end > len(p) {
+			end = len(p)
+		}
+		n, err := tw.ResponseWriter.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		flush(tw.ResponseWriter)
+		pause := time.Duration(n) * time.Second / time.Duration(tw.bytesPerSecond)
+		if err := sleep(tw.ctx, pause); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Flush the underlying http.ResponseWriter if it supports flushing.
+func (tw *throttledResponseWriter) Flush() {
+	flush(tw.ResponseWriter)
+}
+
+// Return the underlying http.ResponseWriter (see http.ResponseController).
+func (tw *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
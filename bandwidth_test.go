@@ -0,0 +1,48 @@
+package gosette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test a predefined response body is sent slowly when BytesPerSecond is set and the body is
+// recorded entirely.
+func (suite *HTTPTestServerUnitTestSuite) TestBytesPerSecond() {
+	body := bytes.Repeat([]byte("a"), 1000)
+	suite.hts.When().Get("/download").RespondWith().Body(body).BytesPerSecond(4000)
+	start := time.Now()
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/download")
+	require.NoError(suite.T(), err)
+	received, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.GreaterOrEqual(suite.T(), time.Since(start), 200*time.Millisecond)
+	require.Equal(suite.T(), body, received)
+	record := suite.hts.PopServerRecord()
+	require.Equal(suite.T(), body, record.Response.Body.Bytes())
+}
+
+// Test the ThrottleBandwidth middleware limits all responses and client timeouts interrupt slow
+// downloads.
+func TestThrottleBandwidth(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.Use(ThrottleBandwidth(100))
+	hts.When().Get("/download").RespondWith().Body(bytes.Repeat([]byte("a"), 1000))
+	client := hts.Client()
+	client.Timeout = 300 * time.Millisecond
+	resp, err := client.Get(hts.GetBaseURL() + "/download")
+	require.NoError(t, err)
+	received, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Error(t, err)
+	require.NotEmpty(t, received)
+	require.Less(t, len(received), 1000)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
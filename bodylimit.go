@@ -0,0 +1,54 @@
+package gosette
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Option which limits the size of request bodies to the provided number of bytes. Requests with a
+// larger body are answered with an empty 413 Payload Too Large response and never reach the
+// middlewares and predefined responses:
+//
+//   - Requests whose Content-Length is larger are rejected before their body is read.
+//   - Requests whose length is unknown (ex: chunked uploads) are rejected mid-stream, as soon as
+//     the limit is exceeded, and the connection is closed once the response has been written.
+//
+// The records of rejected requests have BodyTruncated set and keep the body bytes read before the
+// rejection. Scopes use the limit of the test server they have been created from.
+func WithMaxRequestBodySize(limit int64) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.maxRequestBodySize = limit
+	}
+}
+
+// Helper method which returns true if the provided request declares a body larger than the
+// configured limit.
+func (srv *HTTPTestServer) declaresTooLargeBody(r *http.Request) bool {
+	return srv.maxRequestBodySize > 0 && r.ContentLength > srv.maxRequestBodySize
+}
+
+// Helper method which limits the size of the body of the provided request if a limit is
+// configured. Reads fail with a *http.MaxBytesError once the limit is exceeded.
+func (srv *HTTPTestServer) limitRequestBody(w http.ResponseWriter, r *http.Request) {
+	if srv.maxRequestBodySize > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, srv.maxRequestBodySize)
+	}
+}
+
+// Helper function which returns true if the provided error has been caused by a request body
+// larger than the configured limit.
+func isBodyTooLarge(err error) bool {
+	var mberr *http.MaxBytesError
+	return errors.As(err, &mberr)
+}
+
+// Helper method which rejects a request whose body is larger than the configured limit with an
+// empty 413 Payload Too Large response and adds its record.
+func (srv *HTTPTestServer) rejectTooLargeBody(w http.ResponseWriter, serverRecord *ServerRecord) {
+	serverRecord.BodyTruncated = true
+	srv.addServerRecord(serverRecord)
+	writeHeaders(w, &PredefinedServerResponse{
+		Status:  http.StatusRequestEntityTooLarge,
+		Headers: http.Header{"Content-Length": {"0"}},
+	})
+}
@@ -0,0 +1,76 @@
+package gosette
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test request bodies larger than the limit are rejected with 413 responses and recorded as
+// truncated.
+func TestMaxRequestBodySize(t *testing.T) {
+	hts := NewHTTPTestServer(nil, WithMaxRequestBodySize(10))
+	hts.Start()
+	defer hts.Close()
+	hts.When().Post("/upload").RespondWith().Status(http.StatusCreated)
+	client := hts.Client()
+
+	// Bodies within the limit are served
+	resp, err := client.Post(hts.GetBaseURL()+"/upload", "text/plain", strings.NewReader("0123456789"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	record := hts.PopServerRecord()
+	require.False(t, record.BodyTruncated)
+	require.Equal(t, "0123456789", record.RequestBody.String())
+
+	// Declared lengths over the limit are rejected before the body is read
+	resp, err = client.Post(hts.GetBaseURL()+"/upload", "text/plain", strings.NewReader("0123456789A"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	record = hts.PopServerRecord()
+	require.True(t, record.BodyTruncated)
+	require.Empty(t, record.RequestBody.String())
+
+	// Form bodies are limited too
+	resp, err = client.Post(hts.GetBaseURL()+"/upload", "application/x-www-form-urlencoded", io.MultiReader(strings.NewReader("a="), strings.NewReader(strings.Repeat("b", 20))))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.True(t, hts.PopServerRecord().BodyTruncated)
+}
+
+// Test chunked uploads are rejected mid-stream and the connection is closed.
+func TestMaxRequestBodySizeMidStream(t *testing.T) {
+	hts := NewHTTPTestServer(nil, WithMaxRequestBodySize(10))
+	hts.Start()
+	defer hts.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(hts.GetBaseURL(), "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	// Send a first chunk within the limit and a second one which exceeds it
+	_, err = conn.Write([]byte("POST /upload HTTP/1.1\r\nHost: test\r\nTransfer-Encoding: chunked\r\n\r\n8\r\n01234567\r\n8\r\n89ABCDEF\r\n"))
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.True(t, resp.Close)
+	// The response has been received before the end of the upload: finish the upload and check
+	// the connection is closed
+	_, err = conn.Write([]byte("8\r\nGHIJKLMN\r\n0\r\n\r\n"))
+	require.NoError(t, err)
+	rest, _ := io.ReadAll(reader)
+	require.Empty(t, bytes.TrimSpace(rest))
+	record := hts.PopServerRecord()
+	require.True(t, record.BodyTruncated)
+	require.Equal(t, "0123456789", record.RequestBody.String())
+}
@@ -0,0 +1,640 @@
+package gosette
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+/*************************************************************************************************/
+/* REQUEST MATCHER BUILDER                                                                       */
+/*************************************************************************************************/
+
+// A fluent builder used to describe the requests a predefined response must be served for.
+//
+// The builder accumulates request matchers. All accumulated matchers must match an incoming
+// request for the predefined response to be served. Use RespondWith to register the predefined
+// response and describe it.
+//
+// Example:
+//
+//	hts.When().Get("/users").WithHeader("Accept", "application/json").
+//		RespondWith().Status(http.StatusOK).JSONBody(users)
+type RequestMatcherBuilder struct {
+	// The test server the predefined response will be registered to.
+	hts *HTTPTestServer
+	// Accumulated request matchers.
+	matchers []RequestMatcher
+	// Name of the scenario the predefined response belongs to and state the scenario must be in.
+	// Empty if the response does not belong to a scenario.
+	scenario      string
+	requiredState string
+	// The session the predefined response is added to as a step. Nil if the response is not a
+	// session step.
+	session *Session
+}
+
+// Start describing the requests a predefined response must be served for.
+func (hts *HTTPTestServer) When() *RequestMatcherBuilder {
+	return &RequestMatcherBuilder{
+		hts:      hts,
+		matchers: []RequestMatcher{},
+	}
+}
+
+// Match requests which use the provided method and target the provided path.
+func (b *RequestMatcherBuilder) Method(method string, path string) *RequestMatcherBuilder {
+	return b.Matching(MethodMatcher(method)).Path(path)
+}
+
+// Match GET requests which target the provided path.
+func (b *RequestMatcherBuilder) Get(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodGet, path)
+}
+
+// Match HEAD requests which target the provided path.
+func (b *RequestMatcherBuilder) Head(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodHead, path)
+}
+
+// Match POST requests which target the provided path.
+func (b *RequestMatcherBuilder) Post(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodPost, path)
+}
+
+// Match PUT requests which target the provided path.
+func (b *RequestMatcherBuilder) Put(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodPut, path)
+}
+
+// Match PATCH requests which target the provided path.
+func (b *RequestMatcherBuilder) Patch(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodPatch, path)
+}
+
+// Match DELETE requests which target the provided path.
+func (b *RequestMatcherBuilder) Delete(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodDelete, path)
+}
+
+// Match OPTIONS requests which target the provided path.
+func (b *RequestMatcherBuilder) Options(path string) *RequestMatcherBuilder {
+	return b.Method(http.MethodOptions, path)
+}
+
+// Match requests whose path matches the provided regular expression, whatever the method is. See
+// PathRegexMatcher.
+func (b *RequestMatcherBuilder) PathMatching(pattern *regexp.Regexp) *RequestMatcherBuilder {
+	return b.Matching(PathRegexMatcher(pattern))
+}
+
+// Match requests whose path matches the provided glob pattern, whatever the method is. See
+// PathGlobMatcher.
+func (b *RequestMatcherBuilder) PathGlob(pattern string) *RequestMatcherBuilder {
+	return b.Matching(PathGlobMatcher(pattern))
+}
+
+// Match requests whose path matches the provided URI template (ex: /users/{id}), whatever the
+// method is. See PathTemplateMatcher.
+func (b *RequestMatcherBuilder) PathTemplate(template string) *RequestMatcherBuilder {
+	return b.Matching(PathTemplateMatcher(template))
+}
+
+// Match requests which target the provided path, whatever the method is.
+func (b *RequestMatcherBuilder) Path(path string) *RequestMatcherBuilder {
+	return b.Matching(PathMatcher(path))
+}
+
+// Match requests whose Host header (without port) is equal to the provided host name.
+func (b *RequestMatcherBuilder) Host(host string) *RequestMatcherBuilder {
+	return b.Matching(HostMatcher(host))
+}
+
+// Match requests which have the provided header value.
+func (b *RequestMatcherBuilder) WithHeader(header string, value string) *RequestMatcherBuilder {
+	return b.Matching(HeaderEqualsMatcher(header, value))
+}
+
+// Match requests which have a cookie with the provided name and value.
+func (b *RequestMatcherBuilder) WithCookie(name string, value string) *RequestMatcherBuilder {
+	return b.Matching(CookieMatcher(name, value))
+}
+
+// Match requests which have the provided query parameter value. See QueryParamMatcher.
+func (b *RequestMatcherBuilder) WithQueryParam(name string, value string) *RequestMatcherBuilder {
+	return b.Matching(QueryParamMatcher(name, value))
+}
+
+// Match requests which have exactly the provided values for the provided query parameter, in any
+// order. See QueryParamValuesMatcher.
+func (b *RequestMatcherBuilder) WithQueryParamValues(name string, values ...string) *RequestMatcherBuilder {
+	return b.Matching(QueryParamValuesMatcher(name, values...))
+}
+
+// Match requests which have a value for the provided query parameter which matches the provided
+// regular expression. See QueryParamRegexMatcher.
+func (b *RequestMatcherBuilder) WithQueryParamMatching(name string, pattern *regexp.Regexp) *RequestMatcherBuilder {
+	return b.Matching(QueryParamRegexMatcher(name, pattern))
+}
+
+// Match requests whose body contains the provided substring.
+func (b *RequestMatcherBuilder) WithBodyContaining(substr string) *RequestMatcherBuilder {
+	return b.Matching(BodyContainsMatcher(substr))
+}
+
+// Match requests whose JSON body has a value selected by the provided JSONPath expression which
+// is equal to the expected value. See BodyJSONPathMatcher.
+func (b *RequestMatcherBuilder) WithJSONPath(expr string, expected interface{}) *RequestMatcherBuilder {
+	return b.Matching(BodyJSONPathMatcher(expr, expected))
+}
+
+// Match requests whose XML body has a node selected by the provided XPath expression whose string
+// value is equal to the expected value. See BodyXPathMatcher.
+func (b *RequestMatcherBuilder) WithXPath(expr string, expected string, namespaces ...XMLNamespace) *RequestMatcherBuilder {
+	return b.Matching(BodyXPathMatcher(expr, expected, namespaces...))
+}
+
+// Match GraphQL requests which execute the operation with the provided name. See
+// GraphQLOperationMatcher.
+func (b *RequestMatcherBuilder) GraphQLOperation(operationName string) *RequestMatcherBuilder {
+	return b.Matching(GraphQLOperationMatcher(operationName))
+}
+
+// Match GraphQL requests whose variables include the provided variables. See
+// GraphQLVariablesMatcher.
+func (b *RequestMatcherBuilder) WithGraphQLVariables(variables map[string]interface{}) *RequestMatcherBuilder {
+	return b.Matching(GraphQLVariablesMatcher(variables))
+}
+
+// Match SOAP requests with the provided action. See SOAPActionMatcher.
+func (b *RequestMatcherBuilder) SOAPAction(action string) *RequestMatcherBuilder {
+	return b.Matching(SOAPActionMatcher(action))
+}
+
+// Match SOAP requests which invoke the provided operation. See SOAPOperationMatcher.
+func (b *RequestMatcherBuilder) SOAPOperation(operation string) *RequestMatcherBuilder {
+	return b.Matching(SOAPOperationMatcher(operation))
+}
+
+// Serve the predefined response only while the provided scenario is in the provided state. Use
+// WillSetStateTo on the response builder to declare the transition which occurs once the response
+// has been served. See RegisterScenarioResponse.
+func (b *RequestMatcherBuilder) InScenario(scenario string, state string) *RequestMatcherBuilder {
+	b.scenario = scenario
+	b.requiredState = state
+	return b
+}
+
+// Match requests which are matched by the provided request matcher.
+func (b *RequestMatcherBuilder) Matching(matcher RequestMatcher) *RequestMatcherBuilder {
+	b.matchers = append(b.matchers, matcher)
+	return b
+}
+
+// Match returns true if the provided request is matched by all the accumulated matchers. This
+// allows the builder to be used wherever a RequestMatcher is expected.
+func (b *RequestMatcherBuilder) Match(r *http.Request) bool {
+	return MatchAll(b.matchers...).Match(r)
+}
+
+// Return the parameters extracted from the path of the provided request by the accumulated
+// matchers (see PathRegexMatcher).
+func (b *RequestMatcherBuilder) pathParams(r *http.Request) map[string]string {
+	return allMatcher(b.matchers).pathParams(r)
+}
+
+// Explain how the provided request is evaluated by each accumulated matcher. This allows the
+// builder to be used wherever an ExplainingMatcher is expected.
+func (b *RequestMatcherBuilder) Explain(r *http.Request) []CriterionResult {
+	return allMatcher(b.matchers).Explain(r)
+}
+
+// Register a predefined response for the described requests and return a builder used to
+// describe the response. The registered response is an empty 200 response until modified by
+// the returned builder.
+func (b *RequestMatcherBuilder) RespondWith() *ResponseBuilder {
+	// Build the predefined response
+	response := &PredefinedServerResponse{
+		Status:  http.StatusOK,
+		Headers: http.Header{},
+	}
+	// Register the response with a copy of the accumulated matchers
+	matchers := make([]RequestMatcher, len(b.matchers))
+	copy(matchers, b.matchers)
+	s := &stub{
+		matcher:       MatchAll(matchers...),
+		response:      response,
+		scenario:      b.scenario,
+		requiredState: b.requiredState,
+	}
+	if b.session != nil {
+		b.session.addStep(s)
+	} else {
+		b.hts.registerStub(s)
+	}
+	// Return a builder for the response
+	return &ResponseBuilder{hts: b.hts, stub: s, response: response}
+}
+
+/*************************************************************************************************/
+/* RESPONSE BUILDER                                                                              */
+/*************************************************************************************************/
+
+// A fluent builder used to describe a predefined response.
+//
+// The builder directly modifies the predefined response it has been created for. Responses
+// must be fully described before the test server receives the requests they are served for.
+type ResponseBuilder struct {
+	// The test server the predefined response has been registered to.
+	hts *HTTPTestServer
+	// The stub the predefined response has been registered with.
+	stub *stub
+	// The predefined response being built.
+	response *PredefinedServerResponse
+}
+
+// Name the response. The name identifies the response in records and logs (see ServerRecord
+// ServedBy).
+func (b *ResponseBuilder) Named(name string) *ResponseBuilder {
+	b.response.Name = name
+	return b
+}
+
+// Set the response status code.
+func (b *ResponseBuilder) Status(status int) *ResponseBuilder {
+	b.response.Status = status
+	return b
+}
+
+// Add a value for the provided response header.
+func (b *ResponseBuilder) Header(header string, value string) *ResponseBuilder {
+	b.response.Headers.Add(header, value)
+	return b
+}
+
+// Require the provided Basic credentials to get the response. See PredefinedServerResponse
+// RequireBasicAuth.
+func (b *ResponseBuilder) RequireBasicAuth(username string, password string) *ResponseBuilder {
+	b.response.RequireBasicAuth = &BasicCredentials{Username: username, Password: password}
+	return b
+}
+
+// Require the provided Digest credentials to get the response. See PredefinedServerResponse
+// RequireDigestAuth.
+func (b *ResponseBuilder) RequireDigestAuth(username string, password string) *ResponseBuilder {
+	b.response.RequireDigestAuth = &DigestCredentials{Username: username, Password: password}
+	return b
+}
+
+// Add a cookie to set with a Set-Cookie header.
+func (b *ResponseBuilder) SetCookie(cookie *http.Cookie) *ResponseBuilder {
+	b.response.SetCookies = append(b.response.SetCookies, cookie)
+	return b
+}
+
+// Add a value for the provided response trailer.
+func (b *ResponseBuilder) Trailer(trailer string, value string) *ResponseBuilder {
+	if b.response.Trailers == nil {
+		b.response.Trailers = http.Header{}
+	}
+	b.response.Trailers.Add(trailer, value)
+	return b
+}
+
+// Set the response body.
+func (b *ResponseBuilder) Body(body []byte) *ResponseBuilder {
+	b.response.Body = body
+	return b
+}
+
+// Set the response body from a string.
+func (b *ResponseBuilder) StringBody(body string) *ResponseBuilder {
+	return b.Body([]byte(body))
+}
+
+// Add a header template (see text/template) to the response. The template is executed with a
+// TemplateData built from the request each time the response is served and its output replaces
+// the static values of the header. Example:
+//
+//	RespondWith().Status(http.StatusCreated).
+//		HeaderTemplate("X-Request-ID", `{{.Header.Get "X-Request-ID"}}`).
+//		HeaderTemplate("Location", `/users/{{.PathParams.id}}`)
+func (b *ResponseBuilder) HeaderTemplate(header string, text string) *ResponseBuilder {
+	if b.response.HeaderTemplates == nil {
+		b.response.HeaderTemplates = http.Header{}
+	}
+	b.response.HeaderTemplates.Add(header, text)
+	return b
+}
+
+// Set the response body template (see text/template). The template is executed with a
+// TemplateData built from the request each time the response is served. Example:
+//
+//	RespondWith().BodyTemplate(`{"id": "{{.PathParams.id}}", "method": "{{.Method}}"}`)
+func (b *ResponseBuilder) BodyTemplate(text string) *ResponseBuilder {
+	b.response.BodyTemplate = text
+	return b
+}
+
+// Read the response body from the file at the provided path each time the response is served.
+// The Content-Type header is inferred from the file extension unless it is set.
+func (b *ResponseBuilder) BodyFile(path string) *ResponseBuilder {
+	b.response.BodyFile = path
+	return b
+}
+
+// Set the response body with the JSON encoding of the provided value and set the Content-Type
+// header to application/json.
+//
+// The method panics if the provided value cannot be encoded.
+func (b *ResponseBuilder) JSONBody(v interface{}) *ResponseBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Errorf("gosette: failed to encode JSON body: %w", err))
+	}
+	b.response.Headers.Set("Content-Type", "application/json")
+	return b.Body(body)
+}
+
+// Send a 103 Early Hints interim response with the provided Link header values before the
+// response. Call the method several times to send several interim responses. Example:
+//
+//	RespondWith().EarlyHints("</app.css>; rel=preload; as=style", "<https://cdn.example.com>; rel=preconnect")
+func (b *ResponseBuilder) EarlyHints(links ...string) *ResponseBuilder {
+	b.response.EarlyHints = append(b.response.EarlyHints, http.Header{"Link": append([]string(nil), links...)})
+	return b
+}
+
+// Push the provided resource with HTTP/2 server push before the response is written. See
+// PredefinedServerResponse Pushes.
+func (b *ResponseBuilder) Push(path string, response *PredefinedServerResponse) *ResponseBuilder {
+	b.response.Pushes = append(b.response.Pushes, &PushedResource{Path: path, Response: response})
+	return b
+}
+
+// Set the response body with a multipart body of the provided subtype (ex: mixed, related,
+// form-data, alternative) which contains the provided parts and set the Content-Type header with
+// the generated boundary. Part headers are completed from the part ContentType, FieldName and
+// FileName. Example:
+//
+//	RespondWith().Multipart("mixed",
+//		&MultipartPart{ContentType: "application/json", Content: []byte(`{"id": 1}`)},
+//		&MultipartPart{ContentType: "image/png", FileName: "avatar.png", Content: png})
+//
+// The method panics if the parts cannot be encoded.
+func (b *ResponseBuilder) Multipart(subtype string, parts ...*MultipartPart) *ResponseBuilder {
+	body, contentType, err := encodeMultipart(subtype, parts)
+	if err != nil {
+		panic(fmt.Errorf("gosette: failed to encode multipart body: %w", err))
+	}
+	b.response.Headers.Set("Content-Type", contentType)
+	return b.Body(body)
+}
+
+// Set the response body with a multipart/mixed body (ex: batch responses, MIME attachments). See
+// Multipart.
+func (b *ResponseBuilder) MultipartMixed(parts ...*MultipartPart) *ResponseBuilder {
+	return b.Multipart("mixed", parts...)
+}
+
+// Set the response body with a multipart/related body (ex: MTOM, XOP). The Content-Type header
+// declares the content type and the Content-ID of the first part as the type and the start of the
+// body. See Multipart.
+func (b *ResponseBuilder) MultipartRelated(parts ...*MultipartPart) *ResponseBuilder {
+	return b.Multipart("related", parts...)
+}
+
+// Add a representation of the body with the provided content type. The served representation is
+// selected based on the request Accept header. See PredefinedServerResponse Variants.
+func (b *ResponseBuilder) Variant(contentType string, body []byte) *ResponseBuilder {
+	b.response.Variants = append(b.response.Variants, BodyVariant{ContentType: contentType, Body: body})
+	return b
+}
+
+// Add an application/json representation of the body with the JSON encoding of the provided
+// value. See Variant.
+//
+// The method panics if the provided value cannot be encoded.
+func (b *ResponseBuilder) JSONVariant(v interface{}) *ResponseBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Errorf("gosette: failed to encode JSON body: %w", err))
+	}
+	return b.Variant("application/json", body)
+}
+
+// Add an application/xml representation of the body with the XML encoding of the provided
+// value. See Variant.
+//
+// The method panics if the provided value cannot be encoded.
+func (b *ResponseBuilder) XMLVariant(v interface{}) *ResponseBuilder {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		panic(fmt.Errorf("gosette: failed to encode XML body: %w", err))
+	}
+	return b.Variant("application/xml", body)
+}
+
+// Set the entity tag of the response. Conditional requests whose If-None-Match header lists the
+// entity tag are answered with a 304 Not Modified response. See PredefinedServerResponse ETag.
+func (b *ResponseBuilder) ETag(etag string) *ResponseBuilder {
+	b.response.ETag = etag
+	return b
+}
+
+// Set the modification time of the response. Conditional requests whose If-Modified-Since
+// header is not older are answered with a 304 Not Modified response. See PredefinedServerResponse
+// LastModified.
+func (b *ResponseBuilder) LastModified(t time.Time) *ResponseBuilder {
+	b.response.LastModified = t
+	return b
+}
+
+// Allow byte ranges of the body to be requested with the Range header. See
+// PredefinedServerResponse AcceptRanges.
+func (b *ResponseBuilder) AcceptRanges() *ResponseBuilder {
+	b.response.AcceptRanges = true
+	return b
+}
+
+// Close the client connection once the response has been written (Connection: close). See
+// ConnectionClose.
+func (b *ResponseBuilder) CloseConnection() *ResponseBuilder {
+	b.response.Connection = ConnectionClose
+	return b
+}
+
+// Explicitly keep the client connection alive once the response has been written (Connection:
+// keep-alive). See ConnectionKeepAlive.
+func (b *ResponseBuilder) KeepAlive() *ResponseBuilder {
+	b.response.Connection = ConnectionKeepAlive
+	return b
+}
+
+// Wait for the provided delay before the headers are written (slow first byte). See
+// PredefinedServerResponse Delay.
+func (b *ResponseBuilder) Delay(delay time.Duration) *ResponseBuilder {
+	b.response.Delay = delay
+	return b
+}
+
+// Send the headers and wait for the provided delay before the body is written (slow body). See
+// PredefinedServerResponse BodyDelay.
+func (b *ResponseBuilder) BodyDelay(delay time.Duration) *ResponseBuilder {
+	b.response.BodyDelay = delay
+	return b
+}
+
+// Send the response body as the provided chunks. See PredefinedServerResponse Chunks.
+func (b *ResponseBuilder) Chunks(chunks ...[]byte) *ResponseBuilder {
+	b.response.Chunks = chunks
+	return b
+}
+
+// Send the response body as the provided string chunks. See PredefinedServerResponse Chunks.
+func (b *ResponseBuilder) StringChunks(chunks ...string) *ResponseBuilder {
+	b.response.Chunks = make([][]byte, 0, len(chunks))
+	for _, chunk := range chunks {
+		b.response.Chunks = append(b.response.Chunks, []byte(chunk))
+	}
+	return b
+}
+
+// Set the delay to wait between two chunks.
+func (b *ResponseBuilder) ChunkDelay(delay time.Duration) *ResponseBuilder {
+	b.response.ChunkDelay = delay
+	return b
+}
+
+// Add a random latency drawn from the provided distribution each time the response is served.
+func (b *ResponseBuilder) Jitter(distribution LatencyDistribution) *ResponseBuilder {
+	b.response.Jitter = distribution
+	return b
+}
+
+// Add an outbound HTTP callback fired in the background once the response has been served.
+func (b *ResponseBuilder) Webhook(webhook *Webhook) *ResponseBuilder {
+	b.response.Webhooks = append(b.response.Webhooks, webhook)
+	return b
+}
+
+// Set a function called with the record of the exchange once the response has been written and
+// the exchange has been recorded. Example:
+//
+//	served := make(chan struct{})
+//	hts.When().Post("/orders").RespondWith().Status(http.StatusCreated).
+//		OnServed(func(record *gosette.ServerRecord) { close(served) })
+func (b *ResponseBuilder) OnServed(fn func(record *ServerRecord)) *ResponseBuilder {
+	b.response.OnServed = fn
+	return b
+}
+
+// Write the headers and the first n bytes of the body, then close the connection. The
+// Content-Length header advertises the full body size unless it is set. See FaultTruncatedBody.
+func (b *ResponseBuilder) AbortAfterBytes(n int) *ResponseBuilder {
+	b.response.Fault = FaultTruncatedBody
+	b.response.FaultAfterBytes = n
+	return b
+}
+
+// Write the provided bytes as is on the client connection instead of a well-formed response, then
+// close the connection. See FaultRawResponse. Example:
+//
+//	RespondWith().RawResponse([]byte("HTTP/1.1 abc Not A Status\r\n\r\n"))
+func (b *ResponseBuilder) RawResponse(raw []byte) *ResponseBuilder {
+	b.response.Fault = FaultRawResponse
+	b.response.Body = raw
+	return b
+}
+
+// Limit the number of body bytes sent per second so the body dribbles out slowly.
+func (b *ResponseBuilder) BytesPerSecond(bytesPerSecond int) *ResponseBuilder {
+	b.response.BytesPerSecond = bytesPerSecond
+	return b
+}
+
+// Set the response body with a GraphQL response which contains the provided data and set the
+// Content-Type header to application/json.
+//
+// The method panics if the provided data cannot be encoded.
+func (b *ResponseBuilder) GraphQLData(data interface{}) *ResponseBuilder {
+	return b.JSONBody(map[string]interface{}{"data": data})
+}
+
+// Set the response body with a GraphQL response which contains errors with the provided messages
+// and no data and set the Content-Type header to application/json.
+func (b *ResponseBuilder) GraphQLErrors(messages ...string) *ResponseBuilder {
+	errors := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		errors = append(errors, map[string]interface{}{"message": message})
+	}
+	return b.JSONBody(map[string]interface{}{"data": nil, "errors": errors})
+}
+
+// Set the response body with a SOAP 1.1 envelope whose body contains the provided XML content and
+// set the Content-Type header to text/xml.
+func (b *ResponseBuilder) SOAPBody(content string) *ResponseBuilder {
+	b.response.Headers.Set("Content-Type", "text/xml; charset=utf-8")
+	return b.StringBody(soapEnvelope(content))
+}
+
+// Set the response body template (see BodyTemplate) with a SOAP 1.1 envelope whose body contains
+// the provided template and set the Content-Type header to text/xml. Example:
+//
+//	RespondWith().SOAPBodyTemplate(`<GetUserResponse><id>{{.PathParams.id}}</id></GetUserResponse>`)
+func (b *ResponseBuilder) SOAPBodyTemplate(text string) *ResponseBuilder {
+	b.response.Headers.Set("Content-Type", "text/xml; charset=utf-8")
+	return b.BodyTemplate(soapEnvelope(text))
+}
+
+// Set the response with a SOAP 1.1 Fault which has the provided code (ex: Client, Server) and
+// reason, set the status to 500 and set the Content-Type header to text/xml.
+func (b *ResponseBuilder) SOAPFault(code string, reason string) *ResponseBuilder {
+	return b.Status(http.StatusInternalServerError).SOAPBody(soapFault(code, reason))
+}
+
+// Stream the provided server-sent events. See PredefinedServerResponse Events.
+func (b *ResponseBuilder) Events(events ...ServerSentEvent) *ResponseBuilder {
+	b.response.Events = events
+	return b
+}
+
+// Stream the provided values as newline-delimited JSON, one value per line. See
+// PredefinedServerResponse JSONLines.
+func (b *ResponseBuilder) NDJSON(values ...interface{}) *ResponseBuilder {
+	b.response.JSONLines = make([]JSONLine, 0, len(values))
+	for _, value := range values {
+		b.response.JSONLines = append(b.response.JSONLines, JSONLine{Value: value})
+	}
+	return b
+}
+
+// Stream the provided JSON lines as newline-delimited JSON. Use JSONLine to set per-line delays
+// and flushes. See PredefinedServerResponse JSONLines.
+func (b *ResponseBuilder) JSONLines(lines ...JSONLine) *ResponseBuilder {
+	b.response.JSONLines = lines
+	return b
+}
+
+// Compress the response body with the provided content codings (ex: gzip).
+func (b *ResponseBuilder) ContentEncoding(contentEncoding string) *ResponseBuilder {
+	b.response.ContentEncoding = contentEncoding
+	return b
+}
+
+// Move the scenario the response belongs to (see InScenario) to the provided state once the
+// response has been served.
+func (b *ResponseBuilder) WillSetStateTo(state string) *ResponseBuilder {
+	b.hts.mu.Lock()
+	defer b.hts.mu.Unlock()
+	b.stub.newState = state
+	return b
+}
+
+// Get the predefined response being built.
+func (b *ResponseBuilder) Response() *PredefinedServerResponse {
+	return b.response
+}
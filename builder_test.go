@@ -0,0 +1,113 @@
+package gosette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the fluent builder DSL. Test will ensure:
+//   - Responses described with the builder are served for matching requests only
+//   - Status, headers and body (raw, string and JSON) are set on the registered response
+//   - The builder can be used as a RequestMatcher
+func (suite *HTTPTestServerUnitTestSuite) TestFluentBuilder() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Describe responses
+	users := []map[string]interface{}{{"id": float64(1), "name": "alice"}}
+	suite.hts.When().Get("/users").WithHeader("Accept", "application/json").
+		RespondWith().Status(http.StatusOK).Header("X-Total", "1").JSONBody(users)
+	suite.hts.When().Post("/users").WithBodyContaining("bob").
+		RespondWith().Status(http.StatusCreated).StringBody("created")
+	rb := suite.hts.When().Delete("/users").RespondWith().Status(http.StatusNoContent)
+	require.Equal(suite.T(), http.StatusNoContent, rb.Response().Status)
+	rb.Body([]byte{})
+
+	// GET with the right header
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/users", nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(suite.T(), "1", resp.Header.Get("X-Total"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	decoded := []map[string]interface{}{}
+	require.NoError(suite.T(), json.Unmarshal(body, &decoded))
+	require.Equal(suite.T(), users, decoded)
+
+	// GET without the header is not matched
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/users")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	// POST with the right body
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/users", "text/plain", strings.NewReader("name=bob"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "created", string(body))
+
+	// DELETE
+	req, err = http.NewRequest(http.MethodDelete, suite.hts.GetBaseURL()+"/users", nil)
+	require.NoError(suite.T(), err)
+	resp, err = client.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNoContent, resp.StatusCode)
+
+	// Use the builder as a matcher
+	matcher := suite.hts.When().Put("/users").WithHeader("X-Version", "2")
+	req, err = http.NewRequest(http.MethodPut, "/users", nil)
+	require.NoError(suite.T(), err)
+	require.False(suite.T(), matcher.Match(req))
+	req.Header.Set("X-Version", "2")
+	require.True(suite.T(), matcher.Match(req))
+	require.True(suite.T(), suite.hts.When().Head("/").Match(httptestRequest(http.MethodHead, "/")))
+	require.True(suite.T(), suite.hts.When().Patch("/").Match(httptestRequest(http.MethodPatch, "/")))
+	require.True(suite.T(), suite.hts.When().Options("/").Match(httptestRequest(http.MethodOptions, "/")))
+}
+
+// Test JSONBody panics when the provided value cannot be encoded.
+func (suite *HTTPTestServerUnitTestSuite) TestFluentBuilderJSONBodyPanics() {
+	rb := suite.hts.When().Get("/").RespondWith()
+	require.Panics(suite.T(), func() { rb.JSONBody(make(chan int)) })
+}
+
+// Helper function which builds a request with no body for the provided method and target.
+func httptestRequest(method string, target string) *http.Request {
+	r, _ := http.NewRequest(method, target, nil)
+	return r
+}
+
+// Test the OnServed callback of predefined responses. Test will ensure:
+//   - The callback is called once per served response with the complete record
+//   - The record has been added to the record queue when the callback is called
+//   - The callback of other responses is not called
+func (suite *HTTPTestServerUnitTestSuite) TestOnServed() {
+	served := make(chan *ServerRecord, 2)
+	suite.hts.When().Post("/orders").RespondWith().Status(http.StatusCreated).StringBody("created").
+		OnServed(func(record *ServerRecord) {
+			require.Len(suite.T(), suite.hts.FindRecords(), 1)
+			served <- record
+		})
+	suite.hts.When().Get("/orders").RespondWith().Status(http.StatusOK)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/orders")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	suite.hts.ClearServerRecords()
+	resp, err = suite.hts.Client().Post(suite.hts.GetBaseURL()+"/orders", "text/plain", strings.NewReader("order"))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record := <-served
+	require.Equal(suite.T(), "order", record.RequestBody.String())
+	require.Equal(suite.T(), http.StatusCreated, record.Response.Code)
+	require.Equal(suite.T(), "created", record.Response.Body.String())
+	require.Len(suite.T(), served, 0)
+}
@@ -0,0 +1,89 @@
+package gosette
+
+import (
+	"strconv"
+	"time"
+)
+
+// Prevent the response from being stored by caches (Cache-Control: no-store).
+func (b *ResponseBuilder) NoStore() *ResponseBuilder {
+	b.response.Headers.Set("Cache-Control", "no-store")
+	return b
+}
+
+// Allow caches to reuse the response for the provided duration (Cache-Control: max-age).
+func (b *ResponseBuilder) MaxAge(maxAge time.Duration) *ResponseBuilder {
+	b.response.Headers.Set("Cache-Control", "max-age="+strconv.Itoa(seconds(maxAge)))
+	return b
+}
+
+// Allow caches to reuse the response for maxAge and then to serve it stale for up to swr while
+// they revalidate it in the background (Cache-Control: max-age, stale-while-revalidate).
+func (b *ResponseBuilder) StaleWhileRevalidate(maxAge time.Duration, swr time.Duration) *ResponseBuilder {
+	b.response.Headers.Set("Cache-Control", "max-age="+strconv.Itoa(seconds(maxAge))+", stale-while-revalidate="+strconv.Itoa(seconds(swr)))
+	return b
+}
+
+// Send an Age header which starts at the provided age and grows as time passes, as if the
+// response was served by a shared cache. Combined with MaxAge, this allows to test how clients
+// compute the freshness of responses. See PredefinedServerResponse AgeOrigin.
+func (b *ResponseBuilder) Age(initial time.Duration) *ResponseBuilder {
+	b.response.AgeOrigin = b.hts.clock.Now().Add(-initial)
+	return b
+}
+
+// Turn the predefined response into a resource whose representation can change between requests
+// to test client cache invalidation and revalidation. The response gets the entity tag "1" and
+// the current time as modification time unless they are set. Versioned must be the last call of
+// the builder chain: use the returned resource to change the representation.
+func (b *ResponseBuilder) Versioned() *VersionedResource {
+	b.hts.mu.Lock()
+	defer b.hts.mu.Unlock()
+	if b.response.ETag == "" {
+		b.response.ETag = "1"
+	}
+	if b.response.LastModified.IsZero() {
+		b.response.LastModified = b.hts.clock.Now()
+	}
+	return &VersionedResource{hts: b.hts, stub: b.stub, version: 1}
+}
+
+// A predefined response whose representation changes between requests. See ResponseBuilder
+// Versioned.
+type VersionedResource struct {
+	// The test server the predefined response has been registered to.
+	hts *HTTPTestServer
+	// The stub the predefined response has been registered with.
+	stub *stub
+	// Current version of the representation, starting from 1.
+	version int
+}
+
+// Change the representation of the resource: the next requests are served the provided body with
+// a new entity tag (the version number) and the current time as modification time. Cached
+// representations become stale and conditional requests are answered with the new
+// representation. Returns the new version.
+func (vr *VersionedResource) Update(body []byte) int {
+	vr.hts.mu.Lock()
+	defer vr.hts.mu.Unlock()
+	vr.version++
+	// Replace the response rather than modify it: it may be being served
+	updated := *vr.stub.response
+	updated.Headers = vr.stub.response.Headers.Clone()
+	updated.Body = body
+	updated.ETag = strconv.Itoa(vr.version)
+	updated.LastModified = vr.hts.clock.Now()
+	if !updated.LastModified.Truncate(time.Second).After(vr.stub.response.LastModified) {
+		// Make sure the modification time changes at the second precision of HTTP dates
+		updated.LastModified = vr.stub.response.LastModified.Truncate(time.Second).Add(time.Second)
+	}
+	vr.stub.response = &updated
+	return vr.version
+}
+
+// Return the current version of the representation, starting from 1.
+func (vr *VersionedResource) Version() int {
+	vr.hts.mu.Lock()
+	defer vr.hts.mu.Unlock()
+	return vr.version
+}
@@ -0,0 +1,73 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test caching presets set the Cache-Control and Age headers.
+func (suite *HTTPTestServerUnitTestSuite) TestCachePresets() {
+	suite.hts.When().Get("/no-store").RespondWith().NoStore()
+	suite.hts.When().Get("/max-age").RespondWith().MaxAge(time.Minute).Age(30 * time.Second)
+	suite.hts.When().Get("/swr").RespondWith().StaleWhileRevalidate(time.Minute, 90*time.Second)
+	for path, expected := range map[string][2]string{
+		"/no-store": {"no-store", ""},
+		"/max-age":  {"max-age=60", "30"},
+		"/swr":      {"max-age=60, stale-while-revalidate=90", ""},
+	} {
+		resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), expected[0], resp.Header.Get("Cache-Control"), path)
+		require.Equal(suite.T(), expected[1], resp.Header.Get("Age"), path)
+	}
+}
+
+// Test the Age header grows as time passes.
+func (suite *HTTPTestServerUnitTestSuite) TestCacheAgeProgression() {
+	suite.hts.When().Get("/aged").RespondWith().MaxAge(time.Minute).Age(0).Response().AgeOrigin = time.Now().Add(-5 * time.Second)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/aged")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "5", resp.Header.Get("Age"))
+}
+
+// Test a versioned resource changes between requests and invalidates cached representations.
+func (suite *HTTPTestServerUnitTestSuite) TestVersionedResource() {
+	resource := suite.hts.When().Get("/doc").RespondWith().MaxAge(time.Minute).StringBody("first").Versioned()
+	require.Equal(suite.T(), 1, resource.Version())
+	do := func(etag string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/doc", nil)
+		require.NoError(suite.T(), err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		return resp, string(body)
+	}
+	resp, body := do("")
+	require.Equal(suite.T(), "first", body)
+	require.Equal(suite.T(), `"1"`, resp.Header.Get("ETag"))
+	firstModified := resp.Header.Get("Last-Modified")
+	resp, _ = do(`"1"`)
+	require.Equal(suite.T(), http.StatusNotModified, resp.StatusCode)
+
+	// Change the representation
+	require.Equal(suite.T(), 2, resource.Update([]byte("second")))
+	require.Equal(suite.T(), 2, resource.Version())
+	resp, body = do(`"1"`)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "second", body)
+	require.Equal(suite.T(), `"2"`, resp.Header.Get("ETag"))
+	require.Equal(suite.T(), "max-age=60", resp.Header.Get("Cache-Control"))
+	require.NotEqual(suite.T(), firstModified, resp.Header.Get("Last-Modified"))
+	resp, _ = do(`"2"`)
+	require.Equal(suite.T(), http.StatusNotModified, resp.StatusCode)
+}
@@ -0,0 +1,120 @@
+package gosette
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Outcome of the chaos mode for a request.
+type ChaosOutcome int
+
+const (
+	// No fault has been injected: the request has been served normally.
+	ChaosNone ChaosOutcome = iota
+	// The test server has replied with a 500 response instead of serving the request.
+	ChaosServerError
+	// The test server has reset the client connection instead of serving the request.
+	ChaosConnectionReset
+	// The test server has waited for the chaos delay before serving the request normally.
+	ChaosDelay
+)
+
+// Return the name of the chaos outcome.
+func (outcome ChaosOutcome) String() string {
+	switch outcome {
+	case ChaosNone:
+		return "none"
+	case ChaosServerError:
+		return "server error"
+	case ChaosConnectionReset:
+		return "connection reset"
+	case ChaosDelay:
+		return "delay"
+	default:
+		return "unknown"
+	}
+}
+
+// Configuration of the chaos mode.
+type ChaosConfig struct {
+	// Probability, between 0 and 1, that a request gets a fault.
+	Probability float64
+	// Faults which can be injected. A fault is picked uniformly among them for each faulty
+	// request. All faults can be injected when empty.
+	Outcomes []ChaosOutcome
+	// Delay waited before requests which get a ChaosDelay fault are served. Defaults to 1 second.
+	Delay time.Duration
+	// Seed of the random source used to pick faulty requests and faults. The same seed and the
+	// same sequence of requests produce the same outcomes. The random source of the test server
+	// is used when zero (see WithRandSeed).
+	Seed int64
+}
+
+// State of the chaos mode.
+type chaos struct {
+	// Configuration of the chaos mode.
+	cfg ChaosConfig
+	// Random source used to pick faulty requests and faults.
+	rnd *rand.Rand
+}
+
+// Enable the chaos mode: a random share of the requests, defined by the configuration
+// probability, get a fault (500 response, connection reset or delay) while the other requests
+// are served normally. The outcome is recorded in the ServerRecord ChaosOutcome.
+//
+// The chaos mode is not disabled by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) EnableChaos(cfg ChaosConfig) {
+	if len(cfg.Outcomes) == 0 {
+		cfg.Outcomes = []ChaosOutcome{ChaosServerError, ChaosConnectionReset, ChaosDelay}
+	}
+	if cfg.Delay <= 0 {
+		cfg.Delay = time.Second
+	}
+	rnd := hts.rnd
+	if cfg.Seed != 0 {
+		rnd = rand.New(rand.NewSource(cfg.Seed))
+	}
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.chaos = &chaos{
+		cfg: cfg,
+		rnd: rnd,
+	}
+}
+
+// Disable the chaos mode.
+func (hts *HTTPTestServer) DisableChaos() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.chaos = nil
+}
+
+// Helper method which picks the chaos outcome of a new request. Returns ChaosNone if the chaos
+// mode is disabled. The chaos delay is returned as well.
+func (srv *HTTPTestServer) nextChaosOutcome() (ChaosOutcome, time.Duration) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.chaos == nil || srv.chaos.rnd.Float64() >= srv.chaos.cfg.Probability {
+		return ChaosNone, 0
+	}
+	outcomes := srv.chaos.cfg.Outcomes
+	return outcomes[srv.chaos.rnd.Intn(len(outcomes))], srv.chaos.cfg.Delay
+}
+
+// Helper function which builds the response served instead of the predefined response for the
+// provided chaos outcome. Returns nil if the request must be served normally.
+func chaosResponse(outcome ChaosOutcome) *PredefinedServerResponse {
+	switch outcome {
+	case ChaosServerError:
+		return &PredefinedServerResponse{
+			Status:  http.StatusInternalServerError,
+			Headers: http.Header{"Content-Type": {"text/plain"}},
+			Body:    []byte("gosette: chaos server error"),
+		}
+	case ChaosConnectionReset:
+		return &PredefinedServerResponse{Fault: FaultConnectionReset}
+	default:
+		return nil
+	}
+}
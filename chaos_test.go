@@ -0,0 +1,64 @@
+package gosette
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the chaos mode injects faults in a share of the requests, records the outcomes and can be
+// disabled.
+func TestChaos(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/items").RespondWith().Status(http.StatusOK)
+	hts.EnableChaos(ChaosConfig{Probability: 0.5, Delay: time.Millisecond, Seed: 7})
+	client := hts.Client()
+	client.Transport.(*http.Transport).DisableKeepAlives = true
+	counts := map[ChaosOutcome]int{}
+	for i := 0; i < 100; i++ {
+		resp, err := client.Get(hts.GetBaseURL() + "/items")
+		outcome := hts.PopServerRecord().ChaosOutcome
+		counts[outcome]++
+		switch outcome {
+		case ChaosConnectionReset:
+			require.Error(t, err)
+			continue
+		case ChaosServerError:
+			require.NoError(t, err)
+			require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		default:
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+	for _, outcome := range []ChaosOutcome{ChaosNone, ChaosServerError, ChaosConnectionReset, ChaosDelay} {
+		require.NotZero(t, counts[outcome], outcome.String())
+	}
+	require.Greater(t, counts[ChaosNone], 25)
+	require.Less(t, counts[ChaosNone], 75)
+	// Disable the chaos mode
+	hts.DisableChaos()
+	resp, err := client.Get(hts.GetBaseURL() + "/items")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, ChaosNone, hts.PopServerRecord().ChaosOutcome)
+}
+
+// Test only the configured outcomes are injected.
+func TestChaosOutcomes(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.EnableChaos(ChaosConfig{Probability: 1, Outcomes: []ChaosOutcome{ChaosServerError}})
+	resp, err := hts.Client().Get(hts.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, "server error", hts.PopServerRecord().ChaosOutcome.String())
+	require.Equal(t, "unknown", ChaosOutcome(42).String())
+}
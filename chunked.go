@@ -0,0 +1,32 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Helper method which writes the chunks of the provided response by using the provided
+// http.ResponseWriter. The response writer is flushed after each chunk and the response
+// ChunkDelay is waited between chunks. Writing stops if the request context is done.
+//
+// Headers and status code must have been written beforehand.
+func (srv *HTTPTestServer) writeChunks(w http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	for i, chunk := range response.Chunks {
+		// Wait between chunks
+		if i > 0 && response.ChunkDelay > 0 {
+			if sleep(r.Context(), response.ChunkDelay) != nil {
+				return
+			}
+		}
+		// Write and flush the chunk
+		if _, err := w.Write(chunk); err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to write chunk %d of the predefined response: %w", i, err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		flush(w)
+	}
+}
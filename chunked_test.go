@@ -0,0 +1,49 @@
+package gosette
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test chunked responses. Test will ensure:
+//   - Chunks are sent with Transfer-Encoding: chunked and without Content-Length
+//   - Each chunk is flushed to the client before the next one is written
+//   - The whole body is recorded
+//   - Chunks cannot be used with a content encoding
+func (suite *HTTPTestServerUnitTestSuite) TestChunkedResponse() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/stream").RespondWith().
+		StringChunks("first\n", "second\n", "third\n").
+		ChunkDelay(100 * time.Millisecond)
+
+	// Read the first chunk before the next ones are written
+	start := time.Now()
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/stream")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), []string{"chunked"}, resp.TransferEncoding)
+	require.Equal(suite.T(), int64(-1), resp.ContentLength)
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "first\n", line)
+	require.Less(suite.T(), int64(time.Since(start)), int64(100*time.Millisecond))
+	rest, err := io.ReadAll(reader)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "second\nthird\n", string(rest))
+	require.GreaterOrEqual(suite.T(), int64(time.Since(start)), int64(200*time.Millisecond))
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Equal(suite.T(), "first\nsecond\nthird\n", record.Response.Body.String())
+
+	// Chunks and content encoding
+	suite.hts.When().Get("/gzip").RespondWith().Chunks([]byte("a"), []byte("b")).ContentEncoding("gzip")
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/gzip")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+}
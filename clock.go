@@ -0,0 +1,195 @@
+package gosette
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Interface for the clock used by the time-based behaviors of the test server: response delays
+// (Delay, Jitter, chunk and event delays, bandwidth limits, latency middlewares), Date and Age
+// headers, cache modification times, outage windows, rate limit windows and Retry-After headers,
+// token expiries and webhook delays. Implementations must be safe for concurrent use.
+//
+// Use a FakeClock to test time-based behaviors without real sleeps.
+type Clock interface {
+	// Return the current time.
+	Now() time.Time
+	// Wait for the provided duration or until the provided context is done. Returns the context
+	// error if the context is done before the duration has elapsed.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Option which sets the clock used by the test server. The wall clock is used by default. Scopes
+// use the clock of the test server they have been created from.
+func WithClock(clock Clock) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.clock = clock
+	}
+}
+
+// Return the clock used by the test server.
+func (hts *HTTPTestServer) Clock() Clock {
+	return hts.clock
+}
+
+// A Clock which uses the wall clock.
+type realClock struct{}
+
+// Return the current time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Wait for the provided duration or until the provided context is done.
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// A Clock whose time only moves when it is advanced. Sleeping goroutines are woken up once the
+// clock has been advanced past the end of their sleep. Example:
+//
+//	clock := gosette.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	hts := gosette.NewHTTPTestServer(nil, gosette.WithClock(clock))
+//	hts.When().Get("/slow").RespondWith().Delay(time.Minute)
+//	// ... send the request in a goroutine ...
+//	clock.WaitForSleepers(1)
+//	clock.Advance(time.Minute)
+type FakeClock struct {
+	// Mutex used to protect the clock from concurrent access.
+	mu sync.Mutex
+	// Current time of the clock.
+	now time.Time
+	// Goroutines which are sleeping.
+	sleepers map[*fakeSleeper]struct{}
+	// Channel closed and replaced each time the set of sleepers changes.
+	changed chan struct{}
+}
+
+// A goroutine sleeping on a FakeClock.
+type fakeSleeper struct {
+	// Time the sleep ends at.
+	until time.Time
+	// Channel closed once the sleep is over.
+	done chan struct{}
+}
+
+// Create a new fake clock set to the provided time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:      now,
+		sleepers: map[*fakeSleeper]struct{}{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// Return the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Wait until the clock has been advanced by the provided duration or until the provided context
+// is done. Returns the context error if the context is done before the duration has elapsed.
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	if d <= 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	sleeper := &fakeSleeper{until: c.now.Add(d), done: make(chan struct{})}
+	c.sleepers[sleeper] = struct{}{}
+	c.notify()
+	c.mu.Unlock()
+	select {
+	case <-sleeper.done:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.sleepers[sleeper]; ok {
+			delete(c.sleepers, sleeper)
+			c.notify()
+		}
+		return ctx.Err()
+	}
+}
+
+// Advance the clock by the provided duration and wake up the goroutines whose sleep is over.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set the clock to the provided time and wake up the goroutines whose sleep is over.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(now)
+}
+
+// Return the number of goroutines which are sleeping.
+func (c *FakeClock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+// Wait until at least n goroutines are sleeping. Use it before advancing the clock to make sure
+// the request which must be delayed has started to wait.
+func (c *FakeClock) WaitForSleepers(n int) {
+	for {
+		c.mu.Lock()
+		count, changed := len(c.sleepers), c.changed
+		c.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// Helper method which sets the clock time and wakes up the goroutines whose sleep is over. The
+// caller must hold the lock.
+func (c *FakeClock) set(now time.Time) {
+	c.now = now
+	woken := false
+	for sleeper := range c.sleepers {
+		if !sleeper.until.After(now) {
+			close(sleeper.done)
+			delete(c.sleepers, sleeper)
+			woken = true
+		}
+	}
+	if woken {
+		c.notify()
+	}
+}
+
+// Helper method which wakes up the goroutines waiting for the set of sleepers to change. The
+// caller must hold the lock.
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Key of the request context value which holds the clock of the test server.
+type clockContextKey struct{}
+
+// Helper function which returns the clock stored in the provided context or the wall clock if the
+// context does not hold a clock.
+func clockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockContextKey{}).(Clock); ok {
+		return clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,99 @@
+package gosette
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the fake clock. Test will ensure:
+//   - Time only moves when the clock is advanced or set
+//   - Sleeping goroutines are woken up once the clock has been advanced past the end of their sleep
+//   - Sleeps are interrupted when their context is done
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	require.Equal(t, start, clock.Now())
+	require.NoError(t, clock.Sleep(context.Background(), 0))
+
+	// Sleep until the clock is advanced
+	done := make(chan error, 1)
+	go func() { done <- clock.Sleep(context.Background(), time.Minute) }()
+	clock.WaitForSleepers(1)
+	clock.Advance(30 * time.Second)
+	require.Equal(t, 1, clock.Sleepers())
+	clock.Advance(30 * time.Second)
+	require.NoError(t, <-done)
+	require.Equal(t, 0, clock.Sleepers())
+	require.Equal(t, start.Add(time.Minute), clock.Now())
+	clock.Set(start)
+	require.Equal(t, start, clock.Now())
+
+	// Interrupted sleep
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- clock.Sleep(ctx, time.Hour) }()
+	clock.WaitForSleepers(1)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, 0, clock.Sleepers())
+}
+
+// Test the test server uses the provided clock. Test will ensure:
+//   - Delays are waited on the clock
+//   - Date and Age headers are computed from the clock
+//   - Rate limit windows are computed from the clock
+//   - Records and log entries are timestamped with the clock
+func TestWithClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	entries := make(chan LogEntry, 10)
+	hts := NewHTTPTestServer(nil, WithClock(clock), WithLogFunc(func(entry LogEntry) { entries <- entry }))
+	hts.Start()
+	defer hts.Close()
+	require.Equal(t, clock, hts.Clock())
+	hts.RegisterResponse(PathMatcher("/slow"), &PredefinedServerResponse{Status: http.StatusOK, Delay: time.Hour})
+	hts.When().Get("/cached").RespondWith().Status(http.StatusOK).Age(time.Minute)
+	hts.When().Get("/limited").RespondWith().Status(http.StatusOK)
+
+	// Delay
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := hts.Client().Get(hts.GetBaseURL() + "/slow")
+		require.NoError(t, err)
+		resp.Body.Close()
+		done <- resp
+	}()
+	clock.WaitForSleepers(1)
+	clock.Advance(time.Hour)
+	resp := <-done
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, start.Add(time.Hour).Format(http.TimeFormat), resp.Header.Get("Date"))
+	entry := <-entries
+	require.Equal(t, start, entry.Time)
+	require.Equal(t, time.Hour, entry.Latency)
+	require.Equal(t, start, hts.PopServerRecord().ReceivedAt)
+
+	// Age
+	resp, err := hts.Client().Get(hts.GetBaseURL() + "/cached")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "3660", resp.Header.Get("Age"))
+
+	// Rate limit
+	hts.Use(RateLimit(1, time.Minute))
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		resp, err = hts.Client().Get(hts.GetBaseURL() + "/limited")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, expected, resp.StatusCode)
+	}
+	require.Equal(t, "60", resp.Header.Get("Retry-After"))
+	clock.Advance(time.Minute)
+	resp, err = hts.Client().Get(hts.GetBaseURL() + "/limited")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,83 @@
+package gosette
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Headers kept in 304 Not Modified responses.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"}
+
+// Helper function which evaluates the conditional headers of the provided request against the
+// validators (ETag and LastModified) of the provided predefined response.
+//
+// Returns a copy of the response with the ETag and Last-Modified headers set in case the request
+// has no conditional headers or its preconditions are satisfied. Returns an empty 304 Not
+// Modified response for GET and HEAD requests whose If-None-Match or If-Modified-Since
+// precondition is not satisfied and an empty 412 Precondition Failed response for other methods
+// whose If-None-Match precondition is not satisfied.
+func conditionalResponse(r *http.Request, response *PredefinedServerResponse) *PredefinedServerResponse {
+	// Set the validators
+	validated := *response
+	validated.Headers = response.Headers.Clone()
+	if validated.Headers == nil {
+		validated.Headers = http.Header{}
+	}
+	if response.ETag != "" {
+		validated.Headers.Set("ETag", quoteETag(response.ETag))
+	}
+	if !response.LastModified.IsZero() {
+		validated.Headers.Set("Last-Modified", response.LastModified.UTC().Format(http.TimeFormat))
+	}
+	// Evaluate the preconditions: If-Modified-Since is ignored when If-None-Match is present and
+	// for methods other than GET and HEAD (RFC 9110 section 13.1.3)
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = response.ETag != "" && matchETag(inm, quoteETag(response.ETag))
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !response.LastModified.IsZero() &&
+		(r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		if t, err := http.ParseTime(ims); err == nil {
+			notModified = !response.LastModified.Truncate(time.Second).After(t)
+		}
+	}
+	if !notModified {
+		return &validated
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return &PredefinedServerResponse{Status: http.StatusPreconditionFailed, Headers: http.Header{}}
+	}
+	headers := http.Header{}
+	for _, header := range notModifiedHeaders {
+		if values := validated.Headers.Values(header); len(values) > 0 {
+			headers[header] = values
+		}
+	}
+	return &PredefinedServerResponse{Status: http.StatusNotModified, Headers: headers}
+}
+
+// Helper function which returns true if one of the entity tags of the provided If-None-Match
+// header value is equal to the provided entity tag. Entity tags are compared with the weak
+// comparison function: W/"1" and "1" are equal. The * value matches any entity tag.
+func matchETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function which adds the double quotes required around the provided entity tag if they
+// are missing (ex: abc becomes "abc" and W/abc becomes W/"abc").
+func quoteETag(etag string) string {
+	prefix := ""
+	if strings.HasPrefix(etag, "W/") {
+		prefix, etag = "W/", strings.TrimPrefix(etag, "W/")
+	}
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || len(etag) < 2 {
+		etag = `"` + etag + `"`
+	}
+	return prefix + etag
+}
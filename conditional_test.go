@@ -0,0 +1,69 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test conditional requests are answered with 304 responses based on the response validators.
+func (suite *HTTPTestServerUnitTestSuite) TestConditionalRequests() {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	suite.hts.When().Path("/doc").RespondWith().
+		Header("Cache-Control", "max-age=60").
+		Header("Content-Type", "text/plain").
+		ETag("v1").
+		LastModified(modified).
+		StringBody("hello")
+	suite.hts.When().Get("/weak").RespondWith().ETag(`W/"v2"`).StringBody("weak")
+	tests := []struct {
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{http.MethodGet, "/doc", nil, http.StatusOK, "hello"},
+		{http.MethodGet, "/doc", map[string]string{"If-None-Match": `"v1"`}, http.StatusNotModified, ""},
+		{http.MethodGet, "/doc", map[string]string{"If-None-Match": `"v0", W/"v1"`}, http.StatusNotModified, ""},
+		{http.MethodGet, "/doc", map[string]string{"If-None-Match": `*`}, http.StatusNotModified, ""},
+		{http.MethodGet, "/doc", map[string]string{"If-None-Match": `"v0"`}, http.StatusOK, "hello"},
+		{http.MethodGet, "/doc", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusNotModified, ""},
+		{http.MethodGet, "/doc", map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK, "hello"},
+		{http.MethodGet, "/doc", map[string]string{"If-Modified-Since": "invalid"}, http.StatusOK, "hello"},
+		// If-Modified-Since is ignored when If-None-Match is present
+		{http.MethodGet, "/doc", map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusOK, "hello"},
+		{http.MethodPut, "/doc", map[string]string{"If-None-Match": `*`}, http.StatusPreconditionFailed, ""},
+		// If-Modified-Since is ignored for methods other than GET and HEAD
+		{http.MethodHead, "/doc", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusNotModified, ""},
+		{http.MethodPut, "/doc", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusOK, "hello"},
+		{http.MethodDelete, "/doc", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, http.StatusOK, "hello"},
+		{http.MethodGet, "/weak", map[string]string{"If-None-Match": `"v2"`}, http.StatusNotModified, ""},
+	}
+	for _, tc := range tests {
+		req, err := http.NewRequest(tc.method, suite.hts.GetBaseURL()+tc.path, nil)
+		require.NoError(suite.T(), err)
+		for header, value := range tc.headers {
+			req.Header.Set(header, value)
+		}
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), tc.status, resp.StatusCode, tc.headers)
+		require.Equal(suite.T(), tc.body, string(body), tc.headers)
+		if tc.path == "/doc" && tc.status != http.StatusPreconditionFailed {
+			require.Equal(suite.T(), `"v1"`, resp.Header.Get("ETag"))
+			require.Equal(suite.T(), "Tue, 02 Jan 2024 03:04:05 GMT", resp.Header.Get("Last-Modified"))
+			require.Equal(suite.T(), "max-age=60", resp.Header.Get("Cache-Control"))
+		}
+		if tc.status == http.StatusNotModified {
+			require.Empty(suite.T(), resp.Header.Get("Content-Type"))
+		}
+	}
+	require.Equal(suite.T(), `W/"v2"`, quoteETag(`W/v2`))
+	require.Equal(suite.T(), `""`, quoteETag(``))
+}
@@ -0,0 +1,85 @@
+package gosette
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// How the test server handles the client connection once a predefined response has been written.
+type ConnectionMode int
+
+const (
+	// The connection is handled as usual: it is kept alive unless the client asks otherwise.
+	ConnectionDefault ConnectionMode = iota
+	// The response has a Connection: close header and the connection is closed once the response
+	// has been written. Only HTTP/1.x connections are closed.
+	ConnectionClose
+	// The response has a Connection: keep-alive header and a Content-Length header so the
+	// connection can be kept alive, including with HTTP/1.0 clients which ask for it.
+	ConnectionKeepAlive
+)
+
+// A client connection accepted by the test server.
+type connection struct {
+	// Identifier of the connection, starting from 1.
+	id int64
+	// Number of requests received over the connection.
+	requests int64
+}
+
+// Key used to store the client connection in the request context.
+type connectionContextKey struct{}
+
+// Helper method which returns a http.Server ConnContext hook which stores a new connection in
+// the context of each accepted connection after calling the provided hook if any.
+func (srv *HTTPTestServer) trackConnections(next func(ctx context.Context, c net.Conn) context.Context) func(ctx context.Context, c net.Conn) context.Context {
+	return func(ctx context.Context, c net.Conn) context.Context {
+		if next != nil {
+			ctx = next(ctx, c)
+		}
+		conn := &connection{id: atomic.AddInt64(&srv.lastConnectionID, 1)}
+		return context.WithValue(ctx, connectionContextKey{}, conn)
+	}
+}
+
+// Helper function which counts a new request over the connection of the provided request and
+// records the connection identifier and whether the connection has been reused.
+func recordConnection(r *http.Request, serverRecord *ServerRecord) {
+	conn, ok := r.Context().Value(connectionContextKey{}).(*connection)
+	if !ok {
+		return
+	}
+	serverRecord.ConnectionID = int(conn.id)
+	serverRecord.ReusedConnection = atomic.AddInt64(&conn.requests, 1) > 1
+}
+
+// Build a filter which selects records of requests received over the connection with the
+// provided identifier (see ServerRecord ConnectionID).
+func ByConnection(id int) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.ConnectionID == id
+	}
+}
+
+// Helper function which returns a copy of the provided predefined response with the headers
+// required by its connection mode.
+func connectionResponse(response *PredefinedServerResponse) *PredefinedServerResponse {
+	updated := *response
+	updated.Headers = response.Headers.Clone()
+	if updated.Headers == nil {
+		updated.Headers = http.Header{}
+	}
+	switch response.Connection {
+	case ConnectionClose:
+		updated.Headers.Set("Connection", "close")
+	case ConnectionKeepAlive:
+		updated.Headers.Set("Connection", "keep-alive")
+		if len(response.Chunks) == 0 && len(response.Events) == 0 && len(response.JSONLines) == 0 && len(response.Trailers) == 0 && updated.Headers.Get("Content-Length") == "" {
+			updated.Headers.Set("Content-Length", strconv.Itoa(len(response.Body)))
+		}
+	}
+	return &updated
+}
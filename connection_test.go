@@ -0,0 +1,69 @@
+package gosette
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test connection reuse is recorded and responses can close the connection.
+func (suite *HTTPTestServerUnitTestSuite) TestConnectionControl() {
+	suite.hts.When().Get("/keep").RespondWith().StringBody("kept")
+	suite.hts.When().Get("/close").RespondWith().CloseConnection().StringBody("closed")
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+	get := func(path string) *http.Response {
+		resp, err := client.Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		return resp
+	}
+	get("/keep")
+	get("/keep")
+	resp := get("/close")
+	require.True(suite.T(), resp.Close)
+	get("/keep")
+	records := suite.hts.FindRecords()
+	require.Len(suite.T(), records, 4)
+	first := records[0].ConnectionID
+	require.NotZero(suite.T(), first)
+	require.False(suite.T(), records[0].ReusedConnection)
+	require.True(suite.T(), records[1].ReusedConnection)
+	require.True(suite.T(), records[2].ReusedConnection)
+	require.Equal(suite.T(), first, records[2].ConnectionID)
+	// The connection has been closed: a new one is used
+	require.False(suite.T(), records[3].ReusedConnection)
+	require.NotEqual(suite.T(), first, records[3].ConnectionID)
+	require.Len(suite.T(), suite.hts.FindRecords(ByConnection(first)), 3)
+}
+
+// Test responses can keep the connection of HTTP/1.0 clients alive.
+func (suite *HTTPTestServerUnitTestSuite) TestKeepAlive() {
+	suite.hts.When().Get("/keep").RespondWith().KeepAlive().StringBody("kept")
+	conn, err := net.Dial("tcp", strings.TrimPrefix(suite.hts.GetBaseURL(), "http://"))
+	require.NoError(suite.T(), err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		_, err = conn.Write([]byte("GET /keep HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"))
+		require.NoError(suite.T(), err)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(suite.T(), err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), "kept", string(body))
+		require.Equal(suite.T(), "keep-alive", resp.Header.Get("Connection"))
+		require.Equal(suite.T(), "4", resp.Header.Get("Content-Length"))
+	}
+	records := suite.hts.FindRecords()
+	require.Len(suite.T(), records, 2)
+	require.Equal(suite.T(), records[0].ConnectionID, records[1].ConnectionID)
+	require.True(suite.T(), records[1].ReusedConnection)
+}
@@ -0,0 +1,94 @@
+package gosette
+
+import (
+	"net"
+	"net/http"
+)
+
+// Option which wraps each client connection accepted by the test server with the provided
+// function, before TLS and HTTP are handled. The wrapped connection can observe or alter the raw
+// bytes exchanged with the client (ex: inject byte-level corruption, throttle or split writes,
+// force half-closes). Wrappers are applied in their registration order.
+//
+// Faults which need the TCP connection (see FaultConnectionReset) may degrade to a regular close
+// when the wrapped connection is not a *net.TCPConn.
+func WithConnWrapper(wrap func(c net.Conn) net.Conn) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.connWrappers = append(hts.connWrappers, wrap)
+		if len(hts.connWrappers) == 1 && hts.server.Listener != nil {
+			hts.server.Listener = &wrappingListener{Listener: hts.server.Listener, hts: hts}
+		}
+	}
+}
+
+// Register a hook called each time the test server accepts a client connection. Hooks are called
+// in their registration order with the accepted connection (wrapped, see WithConnWrapper).
+//
+// Connection hooks must be registered on the test server which owns the listener: they are not
+// called for scopes. Hooks are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) OnConnAccept(hook func(c net.Conn)) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.onConnAcceptHooks = append(hts.onConnAcceptHooks, hook)
+}
+
+// Register a hook called each time a client connection is closed or hijacked by the test server
+// (see FaultConnectionReset and FaultRawResponse). Hooks are called in their registration order.
+//
+// Connection hooks must be registered on the test server which owns the listener: they are not
+// called for scopes. Hooks are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) OnConnClose(hook func(c net.Conn)) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.onConnCloseHooks = append(hts.onConnCloseHooks, hook)
+}
+
+// Return the number of client connections which are currently open.
+func (hts *HTTPTestServer) OpenConnections() int {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	return hts.openConnections
+}
+
+// Helper method which returns a http.Server ConnState hook which counts open connections and
+// calls the connection hooks after calling the provided hook if any.
+func (srv *HTTPTestServer) watchConnStates(next func(c net.Conn, state http.ConnState)) func(c net.Conn, state http.ConnState) {
+	return func(c net.Conn, state http.ConnState) {
+		if next != nil {
+			next(c, state)
+		}
+		var hooks []func(c net.Conn)
+		srv.mu.Lock()
+		switch state {
+		case http.StateNew:
+			srv.openConnections++
+			hooks = srv.onConnAcceptHooks
+		case http.StateClosed, http.StateHijacked:
+			srv.openConnections--
+			hooks = srv.onConnCloseHooks
+		}
+		srv.mu.Unlock()
+		for _, hook := range hooks {
+			hook(c)
+		}
+	}
+}
+
+// A listener which wraps accepted connections with the connection wrappers of the test server.
+type wrappingListener struct {
+	net.Listener
+	// The test server which holds the connection wrappers.
+	hts *HTTPTestServer
+}
+
+// Accept waits for and returns the next connection, wrapped.
+func (wl *wrappingListener) Accept() (net.Conn, error) {
+	conn, err := wl.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	for _, wrap := range wl.hts.connWrappers {
+		conn = wrap(conn)
+	}
+	return conn, nil
+}
@@ -0,0 +1,107 @@
+package gosette
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A connection which replaces the bytes written to the client, except line breaks.
+type corruptingConn struct {
+	net.Conn
+	// Byte each written byte is replaced with.
+	with byte
+	// True once corruption is enabled.
+	enabled *int32
+}
+
+func (c *corruptingConn) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(c.enabled) == 1 {
+		corrupted := make([]byte, len(b))
+		for i := range b {
+			corrupted[i] = b[i]
+			if b[i] != '\r' && b[i] != '\n' {
+				corrupted[i] = c.with
+			}
+		}
+		return c.Conn.Write(corrupted)
+	}
+	return c.Conn.Write(b)
+}
+
+// Test the connection hooks. Test will ensure:
+//   - Accept and close hooks are called for each client connection and open connections are counted
+//   - Connection wrappers are applied to accepted connections and can corrupt the exchanged bytes
+//   - Hooks and wrappers are kept when the test server is restarted
+func TestConnectionHooks(t *testing.T) {
+	enabled := int32(0)
+	hts := NewHTTPTestServer(nil, WithConnWrapper(func(c net.Conn) net.Conn {
+		return &corruptingConn{Conn: c, with: 'x', enabled: &enabled}
+	}))
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/").RespondWith().StringBody("ok")
+	var mu sync.Mutex
+	accepted, closed := 0, 0
+	hts.OnConnAccept(func(c net.Conn) {
+		_, ok := c.(*corruptingConn)
+		require.True(t, ok)
+		mu.Lock()
+		defer mu.Unlock()
+		accepted++
+	})
+	hts.OnConnClose(func(c net.Conn) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed++
+	})
+	counts := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return accepted, closed
+	}
+
+	// Keep-alive connection
+	client := hts.Client()
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(hts.GetBaseURL())
+		require.NoError(t, err)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	a, _ := counts()
+	require.Equal(t, 1, a)
+	require.Equal(t, 1, hts.OpenConnections())
+	client.CloseIdleConnections()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, c := counts(); c < 1 && time.Now().Before(deadline); _, c = counts() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, c := counts()
+	require.Equal(t, 1, c)
+	require.Equal(t, 0, hts.OpenConnections())
+
+	// Corrupted response
+	atomic.StoreInt32(&enabled, 1)
+	_, err := client.Get(hts.GetBaseURL())
+	require.Error(t, err)
+	atomic.StoreInt32(&enabled, 0)
+
+	// Restart
+	hts.Stop()
+	require.NoError(t, hts.Restart())
+	before, _ := counts()
+	resp, err := hts.Client().Get(hts.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	after, _ := counts()
+	require.Equal(t, before+1, after)
+	atomic.StoreInt32(&enabled, 1)
+	_, err = hts.Client().Get(hts.GetBaseURL())
+	require.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Build a request matcher which matches requests which have a cookie with the provided name and
+// value.
+func CookieMatcher(name string, value string) RequestMatcher {
+	return newCriterionMatcher("cookie "+name, fmt.Sprintf("%q", value), func(r *http.Request) string {
+		values := []string{}
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == name {
+				values = append(values, cookie.Value)
+			}
+		}
+		return explainedStrings(values)
+	}, func(r *http.Request) bool {
+		return findCookie(r.Cookies(), name, value) != nil
+	})
+}
+
+// Build a filter which selects records whose request has a cookie with the provided name and
+// value.
+func ByCookie(name string, value string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return findCookie(record.Cookies, name, value) != nil
+	}
+}
+
+// Check that the recorded request has a cookie with the provided name and value.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertCookie(name string, value string) error {
+	if findCookie(record.Cookies, name, value) == nil {
+		return fmt.Errorf("expected cookie %s=%s, got %v", name, value, record.Cookies)
+	}
+	return nil
+}
+
+// Return the cookies set by the recorded response (Set-Cookie headers).
+func (record *ServerRecord) ResponseCookies() []*http.Cookie {
+	return record.Response.Result().Cookies()
+}
+
+// Helper function which returns the first cookie which has the provided name and value or nil if
+// there is no such cookie.
+func findCookie(cookies []*http.Cookie, name string, value string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name && cookie.Value == value {
+			return cookie
+		}
+	}
+	return nil
+}
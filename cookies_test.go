@@ -0,0 +1,47 @@
+package gosette
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test cookie helpers. Test will ensure:
+//   - Cookies declared on predefined responses are set with Set-Cookie headers
+//   - Request cookies are recorded and can be used to match, filter and assert
+func (suite *HTTPTestServerUnitTestSuite) TestCookies() {
+	// Use a client with a cookie jar to simulate a session
+	jar, err := cookiejar.New(nil)
+	require.NoError(suite.T(), err)
+	client := suite.hts.Client()
+	client.Jar = jar
+	defer func() { client.Jar = nil }()
+	suite.hts.When().Post("/login").RespondWith().
+		SetCookie(&http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true}).
+		SetCookie(&http.Cookie{})
+	suite.hts.When().Get("/profile").WithCookie("session", "abc").RespondWith().StringBody("john")
+
+	// Login
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/login", "text/plain", nil)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Len(suite.T(), resp.Cookies(), 1)
+	require.True(suite.T(), resp.Cookies()[0].HttpOnly)
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Empty(suite.T(), record.Cookies)
+	require.Len(suite.T(), record.ResponseCookies(), 1)
+	require.Equal(suite.T(), "session", record.ResponseCookies()[0].Name)
+
+	// Get profile with the session cookie
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/profile")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Len(suite.T(), suite.hts.FindRecords(ByCookie("session", "abc")), 1)
+	require.Empty(suite.T(), suite.hts.FindRecords(ByCookie("session", "def")))
+	record = suite.hts.PopServerRecord()
+	require.NoError(suite.T(), record.AssertCookie("session", "abc"))
+	require.Error(suite.T(), record.AssertCookie("session", "def"))
+}
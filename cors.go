@@ -0,0 +1,151 @@
+package gosette
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration of the CORS middleware built by CORS.
+type CORSConfig struct {
+	// Origins allowed to send cross-origin requests (ex: https://app.example.com). Use * to allow
+	// any origin. No origin is allowed when empty.
+	AllowedOrigins []string
+	// Methods allowed for cross-origin requests. Defaults to GET, HEAD and POST when empty.
+	AllowedMethods []string
+	// Headers allowed in cross-origin requests. Use * to allow any header. Only CORS-safelisted
+	// headers are allowed when empty. Comparison is case insensitive.
+	AllowedHeaders []string
+	// Response headers exposed to the client in the Access-Control-Expose-Headers header.
+	ExposedHeaders []string
+	// When true, credentials (cookies, authorization headers, ...) are allowed: the
+	// Access-Control-Allow-Credentials header is set and the request origin is echoed even when
+	// any origin is allowed.
+	AllowCredentials bool
+	// How long the preflight response may be cached, sent in the Access-Control-Max-Age header.
+	// The header is not set when zero.
+	MaxAge time.Duration
+}
+
+// Build a middleware which implements the CORS protocol for the provided configuration.
+//
+// Preflight requests (OPTIONS requests with the Origin and Access-Control-Request-Method headers)
+// are answered with an empty 204 response which has the Access-Control-Allow-* headers when the
+// origin, method and headers are allowed, and without them otherwise. Preflights are recorded with
+// Preflight set and never reach the predefined responses. Other requests from an allowed origin
+// are served as usual and decorated with the Access-Control-Allow-Origin,
+// Access-Control-Allow-Credentials and Access-Control-Expose-Headers headers.
+func CORS(cfg CORSConfig) Middleware {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			// Answer the preflight request
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method == http.MethodOptions && requestMethod != "" {
+				if record := recordFromContext(r.Context()); record != nil {
+					record.Preflight = true
+				}
+				requestHeaders := splitHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+				if cfg.allowsOrigin(origin) && containsFold(methods, requestMethod) && cfg.allowsHeaders(requestHeaders) {
+					cfg.writeOriginHeaders(w, origin)
+					w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+					if len(requestHeaders) > 0 {
+						w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+					}
+					if cfg.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(seconds(cfg.MaxAge)))
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// Decorate the actual request response
+			if cfg.allowsOrigin(origin) {
+				cfg.writeOriginHeaders(w, origin)
+				if len(cfg.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Build a filter which selects records of CORS preflight requests (see CORS).
+func Preflights() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Preflight
+	}
+}
+
+// Helper method which returns true if the provided origin is allowed.
+func (cfg CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper method which returns true if all the provided request headers are allowed.
+func (cfg CORSConfig) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		if !containsFold(cfg.AllowedHeaders, "*") && !containsFold(cfg.AllowedHeaders, header) && !corsSafelisted(header) {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper method which writes the Access-Control-Allow-Origin and Access-Control-Allow-Credentials
+// headers for the provided allowed origin.
+func (cfg CORSConfig) writeOriginHeaders(w http.ResponseWriter, origin string) {
+	if cfg.AllowCredentials || !containsFold(cfg.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	if cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Helper function which returns true if the provided header is a CORS-safelisted request header.
+func corsSafelisted(header string) bool {
+	return containsFold([]string{"Accept", "Accept-Language", "Content-Language", "Content-Type"}, header)
+}
+
+// Helper function which splits the provided comma separated header values.
+func splitHeaderList(values []string) []string {
+	items := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// Helper function which returns true if the provided values contain the provided value.
+// Comparison is case insensitive.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package gosette
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test preflights are answered and recorded and responses to allowed origins are decorated with
+// the Access-Control-* headers.
+func TestCORS(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.Use(CORS(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	hts.When().Path("/items").RespondWith().Status(http.StatusOK)
+	do := func(method string, headers map[string]string) *http.Response {
+		req, err := http.NewRequest(method, hts.GetBaseURL()+"/items", nil)
+		require.NoError(t, err)
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		resp, err := hts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Allowed preflight
+	resp := do(http.MethodOptions, map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  http.MethodPut,
+		"Access-Control-Request-Headers": "authorization, content-type",
+	})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, PUT", resp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "authorization, content-type", resp.Header.Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "Origin", resp.Header.Get("Vary"))
+
+	// Rejected preflights: origin, method and header
+	for _, headers := range []map[string]string{
+		{"Origin": "https://evil.example.com", "Access-Control-Request-Method": http.MethodGet},
+		{"Origin": "https://app.example.com", "Access-Control-Request-Method": http.MethodDelete},
+		{"Origin": "https://app.example.com", "Access-Control-Request-Method": http.MethodGet, "Access-Control-Request-Headers": "X-Custom"},
+	} {
+		resp = do(http.MethodOptions, headers)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"), headers)
+		require.Empty(t, resp.Header.Get("Access-Control-Allow-Methods"), headers)
+	}
+
+	// Actual requests
+	resp = do(http.MethodGet, map[string]string{"Origin": "https://app.example.com"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "X-Request-ID", resp.Header.Get("Access-Control-Expose-Headers"))
+	require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	resp = do(http.MethodGet, map[string]string{"Origin": "https://evil.example.com"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	resp = do(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Vary"))
+
+	// OPTIONS requests which are not preflights are served as usual
+	resp = do(http.MethodOptions, map[string]string{"Origin": "https://app.example.com"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Preflights are recorded separately
+	require.Len(t, hts.FindRecords(Preflights()), 4)
+	require.Len(t, hts.FindRecords(Not(Preflights())), 4)
+}
+
+// Test any origin can be allowed and the defaults.
+func TestCORSAnyOrigin(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.Use(CORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}}))
+	req, err := http.NewRequest(http.MethodOptions, hts.GetBaseURL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	resp, err := hts.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, HEAD, POST", resp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "X-Custom", resp.Header.Get("Access-Control-Allow-Headers"))
+	require.Empty(t, resp.Header.Get("Access-Control-Max-Age"))
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,108 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Headers which are not rendered in curl commands because curl sets them itself.
+var curlSkippedHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+// Render the recorded request as an equivalent curl command line: method, headers, body and URL.
+//
+// In case the provided base URL is not empty (ex: https://staging.example.com/api), the scheme and
+// the host of the request URL are replaced by the ones of the base URL and the request path is
+// appended to the base URL path. The URL of the request received by the test server is used
+// otherwise. The raw body is used in case the request body has been compressed by the client.
+func (record *ServerRecord) Curl(baseURL string) string {
+	r := record.Request
+	if r == nil {
+		return ""
+	}
+	args := []string{"curl"}
+	if r.Method != http.MethodGet {
+		args = append(args, "-X", shellQuote(r.Method))
+	}
+	// Render headers sorted by name
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		if !curlSkippedHeaders[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	// Render the body
+	body := record.RequestBody.Bytes()
+	if record.RawRequestBody != nil {
+		body = record.RawRequestBody.Bytes()
+	}
+	if len(body) > 0 {
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+	// Render the URL
+	target := requestURL(r)
+	if baseURL != "" {
+		target = strings.TrimSuffix(baseURL, "/") + r.URL.RequestURI()
+	}
+	args = append(args, shellQuote(target))
+	return strings.Join(args, " ")
+}
+
+// Render the records of the test server, without popping them, as curl commands. See
+// ServerRecord Curl.
+func (hts *HTTPTestServer) CurlCommands(baseURL string) []string {
+	records := hts.snapshotServerRecords()
+	commands := make([]string, 0, len(records))
+	for _, record := range records {
+		commands = append(commands, record.Curl(baseURL))
+	}
+	return commands
+}
+
+// Helper function which quotes the provided string for POSIX shells. Strings which are not
+// valid UTF-8 or which contain control characters are quoted with ANSI-C quoting ($'...').
+func shellQuote(s string) string {
+	if utf8.ValidString(s) && strings.IndexFunc(s, isControl) < 0 {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	quoted := &strings.Builder{}
+	quoted.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c == '\n':
+			quoted.WriteString(`\n`)
+		case c == '\r':
+			quoted.WriteString(`\r`)
+		case c == '\t':
+			quoted.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(quoted, `\x%02x`, c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteString("'")
+	return quoted.String()
+}
+
+// Helper function which returns true if the provided rune is a control character other than a
+// new line or a tab.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\n' && r != '\t') || r == 0x7f
+}
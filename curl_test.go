@@ -0,0 +1,34 @@
+package gosette
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test recorded requests are rendered as curl commands with the original URL or a base URL.
+func (suite *HTTPTestServerUnitTestSuite) TestCurl() {
+	req, err := http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/orders?dry=true", strings.NewReader(`{"note":"it's"}`))
+	require.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Trace", "a b")
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	resp, err = suite.hts.Client().Post(suite.hts.GetBaseURL()+"/bin", "application/octet-stream", bytes.NewReader([]byte{0x00, 'a', '\n', 0xff}))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	commands := suite.hts.CurlCommands("https://staging.example.com/api/")
+	require.Len(suite.T(), commands, 2)
+	require.Equal(suite.T(),
+		`curl -X 'POST' -H 'Accept-Encoding: gzip' -H 'Content-Type: application/json' -H 'User-Agent: Go-http-client/1.1' -H 'X-Trace: a b' `+
+			`--data-binary '{"note":"it'\''s"}' 'https://staging.example.com/api/orders?dry=true'`,
+		commands[0])
+	require.Contains(suite.T(), commands[1], `--data-binary $'\x00a\n\xff'`)
+	// The original URL is used without base URL
+	record := suite.hts.PopServerRecord()
+	require.True(suite.T(), strings.HasSuffix(record.Curl(""), "'"+suite.hts.GetBaseURL()+"/orders?dry=true'"))
+	require.Empty(suite.T(), (&ServerRecord{}).Curl(""))
+}
@@ -0,0 +1,143 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Format of the diagnostic body of the 404 responses served to requests which are not matched by
+// any predefined response (see WithNotFoundDiagnostics).
+type DiagnosticsFormat int
+
+const (
+	// 404 responses have an empty body.
+	NoDiagnostics DiagnosticsFormat = iota
+	// 404 responses have a text/plain diagnostic body.
+	TextDiagnostics
+	// 404 responses have an application/json diagnostic body.
+	JSONDiagnostics
+)
+
+// Option which adds a diagnostic body to the 404 responses served to requests which are not
+// matched by any predefined response. The body describes the request (method, path, query and
+// headers) and which criteria of each registered stub the request matches (see ExplainMismatch)
+// so client logs tell why a request has not been stubbed.
+//
+// The configured default response (see SetDefaultResponse) is served as is. Scopes
+// use the diagnostics format of the test server they have been created from.
+func WithNotFoundDiagnostics(format DiagnosticsFormat) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.notFoundDiagnostics = format
+	}
+}
+
+// JSON diagnostic body.
+type jsonDiagnostics struct {
+	Error   string               `json:"error"`
+	Request jsonDiagnosedRequest `json:"request"`
+	Stubs   []jsonDiagnosedStub  `json:"stubs"`
+}
+
+// Request described in the JSON diagnostic body.
+type jsonDiagnosedRequest struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+}
+
+// Stub described in the JSON diagnostic body.
+type jsonDiagnosedStub struct {
+	Stub      string                `json:"stub"`
+	Exhausted bool                  `json:"exhausted,omitempty"`
+	Criteria  []jsonCriterionResult `json:"criteria"`
+}
+
+// Criterion result described in the JSON diagnostic body.
+type jsonCriterionResult struct {
+	Criterion string `json:"criterion"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Matched   bool   `json:"matched"`
+}
+
+// Helper method which returns the 404 response served to the recorded request when it is not
+// matched by any predefined response and no default response is configured.
+func (srv *HTTPTestServer) notFoundResponse(record *ServerRecord) *PredefinedServerResponse {
+	response := &PredefinedServerResponse{
+		Status:  http.StatusNotFound,
+		Headers: http.Header{},
+	}
+	switch srv.notFoundDiagnostics {
+	case TextDiagnostics:
+		response.Headers.Set("Content-Type", "text/plain; charset=utf-8")
+		response.Body = []byte(srv.textDiagnostics(record))
+	case JSONDiagnostics:
+		body, err := json.Marshal(srv.jsonDiagnostics(record))
+		if err == nil {
+			response.Headers.Set("Content-Type", "application/json")
+			response.Body = body
+		}
+	}
+	return response
+}
+
+// Helper method which builds the text diagnostic body of the recorded request.
+func (srv *HTTPTestServer) textDiagnostics(record *ServerRecord) string {
+	r := record.Request
+	lines := []string{fmt.Sprintf("no predefined response matches request %s", describeRequest(r))}
+	if len(r.Header) > 0 {
+		lines = append(lines, "headers:")
+		keys := make([]string, 0, len(r.Header))
+		for key := range r.Header {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("  %s: %s", key, strings.Join(r.Header[key], ", ")))
+		}
+	}
+	explanations := srv.explainStubs(record)
+	if len(explanations) == 0 {
+		lines = append(lines, "no stubs are registered")
+	}
+	for _, explanation := range explanations {
+		header := explanation.Stub
+		if explanation.Exhausted {
+			header = header + " (exhausted)"
+		}
+		lines = append(lines, header+":")
+		lines = append(lines, indentCriteria(explanation.Criteria, "  ")...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Helper method which builds the JSON diagnostic body of the recorded request.
+func (srv *HTTPTestServer) jsonDiagnostics(record *ServerRecord) jsonDiagnostics {
+	r := record.Request
+	diagnostics := jsonDiagnostics{
+		Error: "no predefined response matches the request",
+		Request: jsonDiagnosedRequest{
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Query:   r.URL.RawQuery,
+			Headers: r.Header,
+		},
+		Stubs: []jsonDiagnosedStub{},
+	}
+	for _, explanation := range srv.explainStubs(record) {
+		stub := jsonDiagnosedStub{
+			Stub:      explanation.Stub,
+			Exhausted: explanation.Exhausted,
+			Criteria:  make([]jsonCriterionResult, 0, len(explanation.Criteria)),
+		}
+		for _, result := range explanation.Criteria {
+			stub.Criteria = append(stub.Criteria, jsonCriterionResult(result))
+		}
+		diagnostics.Stubs = append(diagnostics.Stubs, stub)
+	}
+	return diagnostics
+}
@@ -0,0 +1,79 @@
+package gosette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the diagnostic bodies of 404 responses. Test will ensure:
+//   - Text and JSON bodies describe the request and the registered stubs
+//   - The configured default response is served as is
+//   - 404 responses have an empty body by default
+func TestNotFoundDiagnostics(t *testing.T) {
+	// Helper which sends a GET request with an Accept header and returns the response and its body
+	get := func(hts *HTTPTestServer, path string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, hts.GetBaseURL()+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+		resp, err := hts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	// Text diagnostics
+	hts := NewHTTPTestServer(nil, WithNotFoundDiagnostics(TextDiagnostics))
+	hts.Start()
+	defer hts.Close()
+	resp, body := get(hts, "/user")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.Contains(t, body, "no predefined response matches request GET /user\nheaders:\n  Accept: application/json\n")
+	require.Contains(t, body, "no stubs are registered\n")
+	hts.When().Get("/users").RespondWith().Named("list users")
+	_, body = get(hts, "/user?page=2")
+	require.Contains(t, body, "no predefined response matches request GET /user?page=2\n")
+	require.Contains(t, body, "stub \"list users\":\n  ✓ method: expected \"GET\", got \"GET\"\n  ✗ path: expected \"/users\", got \"/user\"\n")
+
+	// Configured default response
+	hts.SetDefaultResponse(&PredefinedServerResponse{Status: http.StatusTeapot})
+	resp, body = get(hts, "/user")
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+	require.Empty(t, body)
+
+	// JSON diagnostics
+	hts = NewHTTPTestServer(nil, WithNotFoundDiagnostics(JSONDiagnostics))
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/users").RespondWith()
+	resp, body = get(hts, "/user?page=2")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	diagnostics := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(body), &diagnostics))
+	require.Equal(t, "no predefined response matches the request", diagnostics["error"])
+	request := diagnostics["request"].(map[string]interface{})
+	require.Equal(t, "GET", request["method"])
+	require.Equal(t, "/user", request["path"])
+	require.Equal(t, "page=2", request["query"])
+	require.Equal(t, []interface{}{"application/json"}, request["headers"].(map[string]interface{})["Accept"])
+	stubs := diagnostics["stubs"].([]interface{})
+	require.Len(t, stubs, 1)
+	stub := stubs[0].(map[string]interface{})
+	require.Equal(t, "stub #1", stub["stub"])
+	require.Equal(t, map[string]interface{}{"criterion": "path", "expected": `"/users"`, "actual": `"/user"`, "matched": false}, stub["criteria"].([]interface{})[1])
+
+	// No diagnostics
+	hts = NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	resp, body = get(hts, "/user")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Empty(t, body)
+}
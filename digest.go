@@ -0,0 +1,373 @@
+package gosette
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default lifetime of the nonces issued for Digest authentication.
+const defaultDigestNonceLifetime = 5 * time.Minute
+
+// Hash functions of the algorithms supported for Digest authentication.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"MD5":         md5.New,
+	"SHA-256":     sha256.New,
+	"SHA-512-256": sha512.New512_256,
+}
+
+// Credentials used for HTTP Digest authentication (RFC 7616). The same credentials must be reused
+// to validate the responses computed from the nonces they have issued.
+type DigestCredentials struct {
+	// Expected username.
+	Username string
+	// Expected password.
+	Password string
+	// Realm announced in the WWW-Authenticate header of 401 responses. Defaults to gosette.
+	Realm string
+	// Algorithms announced in 401 responses, in order of preference: MD5, SHA-256 or SHA-512-256,
+	// optionally with the -sess suffix. Defaults to SHA-256 and MD5.
+	Algorithms []string
+	// Lifetime of the issued nonces. Responses computed from expired nonces are rejected with a
+	// stale challenge. Defaults to 5 minutes.
+	NonceLifetime time.Duration
+
+	// Mutex which protects the nonces.
+	mu sync.Mutex
+	// Issued nonces with their issue date and the last nonce count presented by clients.
+	nonces map[string]*digestNonce
+	// Opaque value announced in challenges and expected in responses.
+	opaque string
+}
+
+// A nonce issued for Digest authentication.
+type digestNonce struct {
+	// Date the nonce has been issued.
+	issued time.Time
+	// Last nonce count presented by clients with the nonce.
+	count uint64
+}
+
+// The outcome of the Digest authentication of a request.
+type DigestAuthResult struct {
+	// Username presented by the client. Empty if the request has no Digest credentials.
+	Username string
+	// Algorithm used by the client.
+	Algorithm string
+	// Quality of protection used by the client (auth or auth-int).
+	QOP string
+	// Nonce presented by the client.
+	Nonce string
+	// Nonce count presented by the client.
+	NonceCount uint64
+	// True if the client has been authenticated.
+	Authenticated bool
+	// True if the credentials were valid but computed with an expired or unknown nonce.
+	Stale bool
+	// The reason why the client has not been authenticated. Nil if the client has been
+	// authenticated.
+	Err error
+}
+
+// Build a middleware which requires Digest authentication (RFC 7616) for all requests served by
+// the test server. Requests without valid credentials are answered with a 401 response which has
+// one WWW-Authenticate challenge per algorithm. The realm defaults to gosette when empty and the
+// algorithms default to SHA-256 and MD5.
+//
+// The outcome of the authentication is recorded in the ServerRecord DigestAuth.
+func DigestAuth(username string, password string, realm string, algorithms ...string) Middleware {
+	credentials := &DigestCredentials{Username: username, Password: password, Realm: realm, Algorithms: algorithms}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := credentials.check(r)
+			if record := recordFromContext(r.Context()); record != nil {
+				record.DigestAuth = result
+			}
+			if !result.Authenticated {
+				response, err := credentials.unauthorized(r, result.Stale)
+				if err != nil {
+					// Record the error and reply with a 500 response
+					werr := fmt.Errorf("test server failed to challenge the client: %w", err)
+					if record := recordFromContext(r.Context()); record != nil {
+						record.ServerError = werr
+					}
+					http.Error(w, werr.Error(), http.StatusInternalServerError)
+					return
+				}
+				writeHeaders(w, response)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Helper method which validates the Digest credentials presented by the provided request.
+func (credentials *DigestCredentials) check(r *http.Request) *DigestAuthResult {
+	result := &DigestAuthResult{}
+	params, ok := parseDigestAuthorization(r.Header.Get("Authorization"))
+	if !ok {
+		result.Err = fmt.Errorf("request has no Digest credentials")
+		return result
+	}
+	result.Username = params["username"]
+	result.Algorithm = params["algorithm"]
+	if result.Algorithm == "" {
+		result.Algorithm = "MD5"
+	}
+	result.QOP = params["qop"]
+	result.Nonce = params["nonce"]
+	// Check the presented parameters
+	newHash, ok := digestAlgorithms[strings.TrimSuffix(strings.ToUpper(result.Algorithm), "-SESS")]
+	if !ok || !containsFold(credentials.algorithms(), result.Algorithm) {
+		result.Err = fmt.Errorf("unsupported Digest algorithm %s", result.Algorithm)
+		return result
+	}
+	if params["realm"] != credentials.realm() {
+		result.Err = fmt.Errorf("unexpected Digest realm %s", params["realm"])
+		return result
+	}
+	if params["uri"] != r.RequestURI {
+		result.Err = fmt.Errorf("Digest URI %s does not match the request URI %s", params["uri"], r.RequestURI)
+		return result
+	}
+	if result.QOP != "auth" && result.QOP != "auth-int" {
+		result.Err = fmt.Errorf("unsupported Digest qop %s", result.QOP)
+		return result
+	}
+	if opaque, ok := params["opaque"]; ok {
+		expected, err := credentials.getOpaque()
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if opaque != expected {
+			result.Err = fmt.Errorf("unexpected Digest opaque %s", opaque)
+			return result
+		}
+	}
+	count, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid Digest nonce count %s: %w", params["nc"], err)
+		return result
+	}
+	result.NonceCount = count
+	// Check the username
+	h := func(s string) string {
+		digest := newHash()
+		digest.Write([]byte(s))
+		return hex.EncodeToString(digest.Sum(nil))
+	}
+	username := credentials.Username
+	if params["userhash"] == "true" {
+		username = h(credentials.Username + ":" + credentials.realm())
+	}
+	if subtle.ConstantTimeCompare([]byte(result.Username), []byte(username)) != 1 {
+		result.Err = fmt.Errorf("unknown Digest username %s", result.Username)
+		return result
+	}
+	// Compute and check the expected response
+	ha1 := h(credentials.Username + ":" + credentials.realm() + ":" + credentials.Password)
+	if strings.HasSuffix(strings.ToUpper(result.Algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + result.Nonce + ":" + params["cnonce"])
+	}
+	a2 := r.Method + ":" + params["uri"]
+	if result.QOP == "auth-int" {
+		body := []byte{}
+		if record := recordFromContext(r.Context()); record != nil && record.RequestBody != nil {
+			body = record.RequestBody.Bytes()
+		}
+		a2 = a2 + ":" + h(string(body))
+	}
+	expected := h(ha1 + ":" + result.Nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":" + result.QOP + ":" + h(a2))
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(params["response"])), []byte(expected)) != 1 {
+		result.Err = fmt.Errorf("invalid Digest response")
+		return result
+	}
+	// Check the nonce once the credentials have been validated so stale nonces can be reported
+	if err := credentials.useNonce(r, result.Nonce, count); err != nil {
+		result.Err = err
+		result.Stale = true
+		return result
+	}
+	if params["userhash"] == "true" {
+		result.Username = credentials.Username
+	}
+	result.Authenticated = true
+	return result
+}
+
+// Helper method which returns the 401 response served when the credentials are missing or
+// incorrect. The response has one challenge per algorithm, each one with a new nonce. An error is
+// returned if a nonce or the opaque value cannot be generated.
+func (credentials *DigestCredentials) unauthorized(r *http.Request, stale bool) (*PredefinedServerResponse, error) {
+	response := &PredefinedServerResponse{
+		Status:  http.StatusUnauthorized,
+		Headers: http.Header{},
+	}
+	opaque, err := credentials.getOpaque()
+	if err != nil {
+		return nil, err
+	}
+	for _, algorithm := range credentials.algorithms() {
+		nonce, err := credentials.issueNonce(r)
+		if err != nil {
+			return nil, err
+		}
+		challenge := fmt.Sprintf(`Digest realm=%q, qop="auth, auth-int", algorithm=%s, nonce=%q, opaque=%q, charset=UTF-8, userhash=true`,
+			credentials.realm(), algorithm, nonce, opaque)
+		if stale {
+			challenge = challenge + ", stale=true"
+		}
+		response.Headers.Add("Www-Authenticate", challenge)
+	}
+	return response, nil
+}
+
+// Helper method which returns the realm of the credentials.
+func (credentials *DigestCredentials) realm() string {
+	if credentials.Realm == "" {
+		return "gosette"
+	}
+	return credentials.Realm
+}
+
+// Helper method which returns the algorithms announced in challenges.
+func (credentials *DigestCredentials) algorithms() []string {
+	if len(credentials.Algorithms) == 0 {
+		return []string{"SHA-256", "MD5"}
+	}
+	return credentials.Algorithms
+}
+
+// Helper method which returns the opaque value of the credentials. The value is generated the
+// first time the method is called. An error is returned if the value cannot be generated.
+func (credentials *DigestCredentials) getOpaque() (string, error) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	if credentials.opaque == "" {
+		opaque, err := randomDigestValue()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a Digest opaque value: %w", err)
+		}
+		credentials.opaque = opaque
+	}
+	return credentials.opaque, nil
+}
+
+// Helper method which issues a new nonce. An error is returned if the nonce cannot be generated.
+func (credentials *DigestCredentials) issueNonce(r *http.Request) (string, error) {
+	nonce, err := randomDigestValue()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate a Digest nonce: %w", err)
+	}
+	now := clockFromContext(r.Context()).Now()
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	if credentials.nonces == nil {
+		credentials.nonces = map[string]*digestNonce{}
+	}
+	// Forget expired nonces
+	for value, issued := range credentials.nonces {
+		if now.Sub(issued.issued) > credentials.nonceLifetime() {
+			delete(credentials.nonces, value)
+		}
+	}
+	credentials.nonces[nonce] = &digestNonce{issued: now}
+	return nonce, nil
+}
+
+// Helper method which checks the provided nonce has been issued, has not expired and is used with
+// a nonce count greater than the previous one (replay protection).
+func (credentials *DigestCredentials) useNonce(r *http.Request, nonce string, count uint64) error {
+	now := clockFromContext(r.Context()).Now()
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	issued, ok := credentials.nonces[nonce]
+	if !ok {
+		return fmt.Errorf("unknown Digest nonce %s", nonce)
+	}
+	if now.Sub(issued.issued) > credentials.nonceLifetime() {
+		delete(credentials.nonces, nonce)
+		return fmt.Errorf("expired Digest nonce %s", nonce)
+	}
+	if count <= issued.count {
+		return fmt.Errorf("replayed Digest nonce count %d for nonce %s", count, nonce)
+	}
+	issued.count = count
+	return nil
+}
+
+// Helper method which returns the lifetime of the issued nonces.
+func (credentials *DigestCredentials) nonceLifetime() time.Duration {
+	if credentials.NonceLifetime <= 0 {
+		return defaultDigestNonceLifetime
+	}
+	return credentials.NonceLifetime
+}
+
+// Helper function which parses the parameters of the provided Authorization header. Returns false
+// if the header does not use the Digest scheme.
+func parseDigestAuthorization(header string) (map[string]string, bool) {
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Digest ") {
+		return nil, false
+	}
+	params := map[string]string{}
+	rest := strings.TrimSpace(header[7:])
+	for rest != "" {
+		// Parse the parameter name
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		name := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		// Parse the parameter value: a quoted string or a token
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value = b.String()
+			if i < len(rest) {
+				i++
+			}
+			rest = rest[i:]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		params[name] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return params, true
+}
+
+// Helper function which returns a random value suitable for nonces and opaque values. An error is
+// returned if the random value cannot be read.
+func randomDigestValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,157 @@
+package gosette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function which computes the Authorization header a RFC 7616 client sends in response to
+// the provided challenge.
+func digestAuthorization(t *testing.T, challenge string, method string, uri string, username string, password string, nc int, qop string, body string, userhash bool) string {
+	params, ok := parseDigestAuthorization(challenge)
+	require.True(t, ok)
+	algorithm := params["algorithm"]
+	newHash := digestAlgorithms[strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS")]
+	h := func(s string) string {
+		digest := newHash()
+		digest.Write([]byte(s))
+		return fmt.Sprintf("%x", digest.Sum(nil))
+	}
+	cnonce := "0a4f113b"
+	count := fmt.Sprintf("%08x", nc)
+	ha1 := h(username + ":" + params["realm"] + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + params["nonce"] + ":" + cnonce)
+	}
+	a2 := method + ":" + uri
+	if qop == "auth-int" {
+		a2 = a2 + ":" + h(body)
+	}
+	response := h(ha1 + ":" + params["nonce"] + ":" + count + ":" + cnonce + ":" + qop + ":" + h(a2))
+	if userhash {
+		username = h(username + ":" + params["realm"])
+	}
+	return fmt.Sprintf(`Digest username=%q, realm=%q, uri=%q, algorithm=%s, nonce=%q, nc=%s, cnonce=%q, qop=%s, response=%q, opaque=%q, userhash=%t`,
+		username, params["realm"], uri, algorithm, params["nonce"], count, cnonce, qop, response, params["opaque"], userhash)
+}
+
+// Test the DigestAuth middleware. Test will ensure:
+//   - Requests without credentials get one challenge per algorithm
+//   - Valid responses are accepted for all algorithms, qop and userhash
+//   - Replayed nonce counts, invalid passwords and expired nonces are rejected
+//   - The outcome is recorded
+func TestDigestAuthMiddleware(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	srv := NewHTTPTestServer(nil, WithClock(clock))
+	srv.Use(DigestAuth("admin", "s3cret", "admin area", "SHA-256", "MD5", "SHA-512-256-sess"))
+	srv.Start()
+	defer srv.Close()
+	do := func(authorization string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.GetBaseURL()+"/any?x=1", strings.NewReader(body))
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Without credentials
+	resp := do("", "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	challenges := resp.Header.Values("WWW-Authenticate")
+	require.Len(t, challenges, 3)
+	require.Contains(t, challenges[0], `realm="admin area"`)
+	require.Contains(t, challenges[0], "algorithm=SHA-256")
+	require.Contains(t, challenges[1], "algorithm=MD5")
+	record := srv.PopServerRecord()
+	require.False(t, record.DigestAuth.Authenticated)
+	require.Error(t, record.DigestAuth.Err)
+
+	// With valid credentials
+	for i, tc := range []struct {
+		qop      string
+		userhash bool
+	}{{"auth", false}, {"auth-int", false}, {"auth", true}} {
+		for _, challenge := range challenges {
+			resp = do(digestAuthorization(t, challenge, http.MethodPost, "/any?x=1", "admin", "s3cret", i+1, tc.qop, "body", tc.userhash), "body")
+			require.Equal(t, http.StatusNotFound, resp.StatusCode, challenge)
+			record = srv.PopServerRecord()
+			require.True(t, record.DigestAuth.Authenticated)
+			require.NoError(t, record.DigestAuth.Err)
+			require.Equal(t, "admin", record.DigestAuth.Username)
+			require.Equal(t, tc.qop, record.DigestAuth.QOP)
+			require.Equal(t, uint64(i+1), record.DigestAuth.NonceCount)
+		}
+	}
+
+	// Replayed nonce count
+	resp = do(digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 1, "auth", "", false), "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Contains(t, srv.PopServerRecord().DigestAuth.Err.Error(), "replayed")
+
+	// Invalid credentials and parameters
+	for _, authorization := range []string{
+		"Basic YWRtaW46YWRtaW4=",
+		digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "wrong", 10, "auth", "", false),
+		digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "root", "s3cret", 10, "auth", "", false),
+		digestAuthorization(t, challenges[0], http.MethodPost, "/other", "admin", "s3cret", 10, "auth", "", false),
+		digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 10, "auth-conf", "", false),
+		strings.Replace(digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 10, "auth", "", false), "SHA-256", "SHA-512", 1),
+		digestAuthorization(t, strings.Replace(challenges[0], "admin area", "other", 1), http.MethodPost, "/any?x=1", "admin", "s3cret", 10, "auth", "", false),
+		strings.Replace(digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 10, "auth", "", false), "opaque=", "opaque=\"x\", o=", 1),
+		strings.Replace(digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 10, "auth", "", false), "nc=", "nc=zz, n=", 1),
+	} {
+		resp = do(authorization, "")
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, authorization)
+		record = srv.PopServerRecord()
+		require.False(t, record.DigestAuth.Authenticated)
+		require.False(t, record.DigestAuth.Stale)
+		require.Error(t, record.DigestAuth.Err)
+	}
+
+	// Unknown and expired nonces are reported as stale
+	resp = do(digestAuthorization(t, strings.Replace(challenges[0], "nonce=", "nonce=\"unknown\", n=", 1), http.MethodPost, "/any?x=1", "admin", "s3cret", 1, "auth", "", false), "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.True(t, srv.PopServerRecord().DigestAuth.Stale)
+	clock.Advance(defaultDigestNonceLifetime + time.Second)
+	resp = do(digestAuthorization(t, challenges[0], http.MethodPost, "/any?x=1", "admin", "s3cret", 20, "auth", "", false), "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("WWW-Authenticate"), "stale=true")
+	record = srv.PopServerRecord()
+	require.True(t, record.DigestAuth.Stale)
+	require.Contains(t, record.DigestAuth.Err.Error(), "expired")
+}
+
+// Test Digest authentication required by a predefined response.
+func (suite *HTTPTestServerUnitTestSuite) TestRequireDigestAuth() {
+	suite.hts.When().Get("/secret").RespondWith().StringBody("secret").RequireDigestAuth("john", "pa$$")
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/secret")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+	require.Contains(suite.T(), resp.Header.Get("WWW-Authenticate"), `Digest realm="gosette"`)
+	require.False(suite.T(), suite.hts.PopServerRecord().DigestAuth.Authenticated)
+
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/secret", nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Authorization", digestAuthorization(suite.T(), resp.Header.Get("WWW-Authenticate"), http.MethodGet, "/secret", "john", "pa$$", 1, "auth", "", false))
+	resp, err = suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "secret", string(body))
+	require.True(suite.T(), suite.hts.PopServerRecord().DigestAuth.Authenticated)
+}
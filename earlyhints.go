@@ -0,0 +1,50 @@
+package gosette
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A 1xx interim response sent before the final response.
+type InterimResponse struct {
+	// Status code of the interim response (ex: 100, 102, 103).
+	Status int
+	// Headers of the interim response.
+	Header http.Header
+}
+
+// Helper function which writes the provided headers as 103 Early Hints interim responses by using
+// the provided http.ResponseWriter, one interim response per header map. The header map of the
+// writer is restored once the interim responses have been written: 1xx responses do not clear it
+// and the headers of the interim responses must not leak into the final response.
+func writeEarlyHints(w http.ResponseWriter, hints []http.Header) {
+	header := w.Header()
+	saved := header.Clone()
+	for _, hint := range hints {
+		for name := range header {
+			delete(header, name)
+		}
+		for name, values := range hint {
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+	for name := range header {
+		delete(header, name)
+	}
+	for name, values := range saved {
+		header[name] = values
+	}
+}
+
+// Helper function which returns true if the provided status code is the status code of an interim
+// response. 101 Switching Protocols is a final response.
+func isInterimStatus(statusCode int) bool {
+	return statusCode >= 100 && statusCode <= 199 && statusCode != http.StatusSwitchingProtocols
+}
+
+// Helper function which returns true if the server sends a 100 Continue interim response when the
+// body of the provided request is read.
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue") && r.ProtoAtLeast(1, 1) && r.ContentLength != 0
+}
@@ -0,0 +1,112 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test 103 Early Hints interim responses. Test will ensure:
+//   - Interim responses are sent before the final response over HTTP/1.1 and HTTP/2
+//   - Interim response headers do not leak into the final response
+//   - The final response is recorded
+func TestEarlyHints(t *testing.T) {
+	for _, options := range [][]ServerOption{nil, {WithHTTP2()}} {
+		srv := NewHTTPTestServer(nil, options...)
+		srv.When().Get("/").RespondWith().StringBody("<html/>").Header("X-Final", "yes").Delay(10*time.Millisecond).
+			EarlyHints("</app.css>; rel=preload; as=style").
+			EarlyHints("</app.js>; rel=preload; as=script", "<https://cdn.example.com>; rel=preconnect")
+		srv.StartTLS()
+
+		// Send a request and collect interim responses
+		interims := []textproto.MIMEHeader{}
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+				require.Equal(t, http.StatusEarlyHints, code)
+				interims = append(interims, header)
+				return nil
+			},
+		}
+		req, err := http.NewRequest(http.MethodGet, srv.GetBaseURL(), nil)
+		require.NoError(t, err)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		// Check interim and final responses
+		require.Len(t, interims, 2, resp.Proto)
+		require.Equal(t, []string{"</app.css>; rel=preload; as=style"}, interims[0]["Link"])
+		require.Equal(t, []string{"</app.js>; rel=preload; as=script", "<https://cdn.example.com>; rel=preconnect"}, interims[1]["Link"])
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "<html/>", string(body))
+		require.Equal(t, "yes", resp.Header.Get("X-Final"))
+		require.Empty(t, resp.Header.Values("Link"))
+		record := srv.PopServerRecord()
+		require.Equal(t, http.StatusOK, record.Response.Code)
+		require.Empty(t, record.Response.Header().Values("Link"))
+		require.Len(t, record.InterimResponses, 2)
+		require.Equal(t, http.StatusEarlyHints, record.InterimResponses[0].Status)
+		require.Equal(t, []string{"</app.css>; rel=preload; as=style"}, record.InterimResponses[0].Header["Link"])
+		require.Equal(t, http.StatusEarlyHints, record.InterimResponses[1].Status)
+		srv.Close()
+	}
+}
+
+// Test 1xx interim responses written by middlewares are passed through to the client and recorded
+// separately from the final response, and that 100 Continue responses are recorded.
+func TestInterimResponses(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	srv.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Progress", "50")
+			w.WriteHeader(http.StatusProcessing)
+			w.Header().Del("X-Progress")
+			next.ServeHTTP(w, r)
+		})
+	})
+	srv.When().Post("/").RespondWith().Status(http.StatusCreated).Header("X-Final", "yes")
+	srv.Start()
+	defer srv.Close()
+
+	// Send a request which expects a 100 Continue response
+	interims := []int{}
+	progress := []string{}
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			interims = append(interims, code)
+			progress = append(progress, header.Get("X-Progress"))
+			return nil
+		},
+	}
+	req, err := http.NewRequest(http.MethodPost, srv.GetBaseURL(), strings.NewReader("body"))
+	require.NoError(t, err)
+	req.Header.Set("Expect", "100-continue")
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Check interim and final responses
+	require.Equal(t, []int{http.StatusContinue, http.StatusProcessing}, interims)
+	require.Equal(t, []string{"", "50"}, progress)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "yes", resp.Header.Get("X-Final"))
+	require.Empty(t, resp.Header.Get("X-Progress"))
+	record := srv.PopServerRecord()
+	require.Equal(t, http.StatusCreated, record.Response.Code)
+	require.Empty(t, record.Response.Header().Get("X-Progress"))
+	require.Equal(t, "body", record.RequestBody.String())
+	require.Len(t, record.InterimResponses, 2)
+	require.Equal(t, &InterimResponse{Status: http.StatusContinue, Header: http.Header{}}, record.InterimResponses[0])
+	require.Equal(t, http.StatusProcessing, record.InterimResponses[1].Status)
+	require.Equal(t, "50", record.InterimResponses[1].Header.Get("X-Progress"))
+}
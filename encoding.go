@@ -0,0 +1,146 @@
+package gosette
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A content coding (ex: gzip) used to compress response bodies and decompress request bodies.
+type ContentCoding struct {
+	// Function which returns a writer which compresses the data written to it into w. Data must
+	// be flushed to w when the returned writer is closed.
+	Encode func(w io.Writer) (io.WriteCloser, error)
+	// Function which returns a reader which decompresses the data read from r.
+	Decode func(r io.Reader) (io.ReadCloser, error)
+}
+
+// Registered content codings indexed by their lower case name.
+var (
+	contentCodingsMu sync.RWMutex
+	contentCodings   = map[string]ContentCoding{
+		"gzip": {
+			Encode: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+			Decode: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		},
+		// The deflate content coding is the zlib format (RFC 9110)
+		"deflate": {
+			Encode: func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil },
+			Decode: func(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) },
+		},
+	}
+)
+
+// Register a content coding which can then be used by predefined responses and to decode request
+// bodies. An already registered content coding with the same name (case insensitive) is replaced.
+//
+// gzip and deflate are registered by default. Other content codings like brotli (br) can be
+// registered by using a third party implementation.
+func RegisterContentCoding(name string, coding ContentCoding) {
+	contentCodingsMu.Lock()
+	defer contentCodingsMu.Unlock()
+	contentCodings[strings.ToLower(name)] = coding
+}
+
+// Helper function which returns the registered content coding with the provided name.
+func getContentCoding(name string) (ContentCoding, error) {
+	contentCodingsMu.RLock()
+	defer contentCodingsMu.RUnlock()
+	coding, ok := contentCodings[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ContentCoding{}, fmt.Errorf("unknown content coding %q", name)
+	}
+	return coding, nil
+}
+
+// Helper function which encodes the provided data with the content codings listed in the provided
+// Content-Encoding value, in the order they are listed.
+func encodeContent(contentEncoding string, data []byte) ([]byte, error) {
+	for _, name := range strings.Split(contentEncoding, ",") {
+		if strings.EqualFold(strings.TrimSpace(name), "identity") {
+			continue
+		}
+		coding, err := getContentCoding(name)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		writer, err := coding.Encode(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s encoder: %w", name, err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to encode content with %s: %w", name, err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode content with %s: %w", name, err)
+		}
+		data = buf.Bytes()
+	}
+	return data, nil
+}
+
+// Helper function which decodes the provided data with the content codings listed in the provided
+// Content-Encoding value, in the reverse order they are listed.
+func decodeContent(contentEncoding string, data []byte) ([]byte, error) {
+	names := strings.Split(contentEncoding, ",")
+	for i := len(names) - 1; i >= 0; i-- {
+		if strings.TrimSpace(names[i]) == "" || strings.EqualFold(strings.TrimSpace(names[i]), "identity") {
+			continue
+		}
+		coding, err := getContentCoding(names[i])
+		if err != nil {
+			return nil, err
+		}
+		reader, err := coding.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s decoder: %w", strings.TrimSpace(names[i]), err)
+		}
+		data, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode content with %s: %w", strings.TrimSpace(names[i]), err)
+		}
+	}
+	return data, nil
+}
+
+// Helper function which returns a copy of the provided response whose body is encoded with the
+// response ContentEncoding. The Content-Encoding header of the copy is set accordingly.
+func encodeResponse(response *PredefinedServerResponse) (*PredefinedServerResponse, error) {
+	if len(response.Chunks) > 0 {
+		return nil, fmt.Errorf("content encoding cannot be used with chunks")
+	}
+	if len(response.Events) > 0 {
+		return nil, fmt.Errorf("content encoding cannot be used with events")
+	}
+	if len(response.JSONLines) > 0 {
+		return nil, fmt.Errorf("content encoding cannot be used with JSON lines")
+	}
+	body, err := encodeContent(response.ContentEncoding, response.Body)
+	if err != nil {
+		return nil, err
+	}
+	encoded := *response
+	encoded.Headers = response.Headers.Clone()
+	if encoded.Headers == nil {
+		encoded.Headers = http.Header{}
+	}
+	encoded.Headers.Set("Content-Encoding", response.ContentEncoding)
+	encoded.Body = body
+	return &encoded, nil
+}
+
+// Return the body of the recorded response decoded with the content codings listed in its
+// Content-Encoding header. The body is returned as is if the response is not encoded. The recorded
+// response keeps the raw (encoded) body.
+//
+// An error is returned if a content coding is unknown or if the body cannot be decoded.
+func (record *ServerRecord) DecodedResponseBody() ([]byte, error) {
+	return decodeContent(record.Response.Header().Get("Content-Encoding"), record.Response.Body.Bytes())
+}
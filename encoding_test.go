@@ -0,0 +1,204 @@
+package gosette
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test response compression. Test will ensure:
+//   - The body is compressed with the requested content codings and Content-Encoding is set
+//   - Clients transparently decompress gzip responses
+//   - Records keep the raw body and provide the decoded body
+//   - Unknown content codings result in a 500 response
+func (suite *HTTPTestServerUnitTestSuite) TestResponseCompression() {
+	client := suite.hts.Client()
+
+	// gzip - Read raw response
+	suite.hts.When().Get("/gzip").RespondWith().StringBody("hello gzip").ContentEncoding("gzip")
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/gzip", nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "gzip", resp.Header.Get("Content-Encoding"))
+	reader, err := gzip.NewReader(resp.Body)
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(reader)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "hello gzip", string(body))
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.NotEqual(suite.T(), "hello gzip", record.Response.Body.String())
+	decoded, err := record.DecodedResponseBody()
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "hello gzip", string(decoded))
+
+	// gzip - Transparent decompression by the client
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/gzip")
+	require.NoError(suite.T(), err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.True(suite.T(), resp.Uncompressed)
+	require.Equal(suite.T(), "hello gzip", string(body))
+	suite.hts.PopServerRecord()
+
+	// Several content codings
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:          http.StatusOK,
+		Body:            []byte("hello deflate"),
+		ContentEncoding: "deflate, gzip",
+	})
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/deflate")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "deflate, gzip", resp.Header.Get("Content-Encoding"))
+	record = suite.hts.PopServerRecord()
+	decoded, err = record.DecodedResponseBody()
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "hello deflate", string(decoded))
+
+	// Not encoded
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.When().Get("/plain").RespondWith().StringBody("plain")
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/plain")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	decoded, err = suite.hts.PopServerRecord().DecodedResponseBody()
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "plain", string(decoded))
+
+	// Unknown content coding
+	suite.hts.When().Get("/unknown").RespondWith().StringBody("plain").ContentEncoding("compress")
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/unknown")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+	require.Error(suite.T(), suite.hts.PopServerRecord().ServerError)
+}
+
+// Test custom content codings can be registered and used to encode and decode content.
+func (suite *HTTPTestServerUnitTestSuite) TestRegisterContentCoding() {
+	// Register a content coding which reverses data
+	reverse := func(data []byte) []byte {
+		reversed := make([]byte, len(data))
+		for i := range data {
+			reversed[len(data)-1-i] = data[i]
+		}
+		return reversed
+	}
+	RegisterContentCoding("X-Reverse", ContentCoding{
+		Encode: func(w io.Writer) (io.WriteCloser, error) {
+			return &reverseWriter{w: w, reverse: reverse}, nil
+		},
+		Decode: func(r io.Reader) (io.ReadCloser, error) {
+			data, err := io.ReadAll(r)
+			return io.NopCloser(bytes.NewReader(reverse(data))), err
+		},
+	})
+	encoded, err := encodeContent("x-reverse, identity", []byte("abc"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "cba", string(encoded))
+	decoded, err := decodeContent("identity, x-reverse", encoded)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "abc", string(decoded))
+
+	// Failing content coding
+	RegisterContentCoding("x-failing", ContentCoding{
+		Encode: func(w io.Writer) (io.WriteCloser, error) { return nil, fmt.Errorf("PWNED") },
+		Decode: func(r io.Reader) (io.ReadCloser, error) { return nil, fmt.Errorf("PWNED") },
+	})
+	_, err = encodeContent("x-failing", []byte("abc"))
+	require.Error(suite.T(), err)
+	_, err = decodeContent("x-failing", []byte("abc"))
+	require.Error(suite.T(), err)
+	// Invalid data
+	_, err = decodeContent("gzip", []byte("abc"))
+	require.Error(suite.T(), err)
+	_, err = decodeContent("unknown", []byte("abc"))
+	require.True(suite.T(), strings.Contains(err.Error(), "unknown content coding"))
+}
+
+// Writer which writes reversed data to the underlying writer once closed.
+type reverseWriter struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	reverse func([]byte) []byte
+}
+
+func (rw *reverseWriter) Write(p []byte) (int, error) {
+	return rw.buf.Write(p)
+}
+
+func (rw *reverseWriter) Close() error {
+	_, err := rw.w.Write(rw.reverse(rw.buf.Bytes()))
+	return err
+}
+
+// Test compressed request bodies are decoded. Test will ensure:
+//   - The record RequestBody contains the decoded body and RawRequestBody the raw body
+//   - Matchers and form parsing use the decoded body
+//   - Bodies which cannot be decoded result in a 500 response
+func (suite *HTTPTestServerUnitTestSuite) TestRequestDecompression() {
+	client := suite.hts.Client()
+	encoded, err := encodeContent("gzip", []byte(`{"name": "john"}`))
+	require.NoError(suite.T(), err)
+
+	// JSON body
+	suite.hts.When().Post("/users").WithJSONPath("$.name", "john").RespondWith().Status(http.StatusCreated)
+	req, err := http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/users", bytes.NewReader(encoded))
+	require.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := client.Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Equal(suite.T(), `{"name": "john"}`, record.RequestBody.String())
+	require.Equal(suite.T(), encoded, record.RawRequestBody.Bytes())
+
+	// Form body
+	encoded, err = encodeContent("deflate", []byte("name=john"))
+	require.NoError(suite.T(), err)
+	req, err = http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/form", bytes.NewReader(encoded))
+	require.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Encoding", "deflate")
+	resp, err = client.Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record = suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.NoError(suite.T(), record.ServerError)
+	require.Equal(suite.T(), "john", record.Request.PostForm.Get("name"))
+	require.Equal(suite.T(), "name=john", record.RequestBody.String())
+
+	// Not encoded
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/plain", "text/plain", strings.NewReader("plain"))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record = suite.hts.PopServerRecord()
+	require.Equal(suite.T(), "plain", record.RequestBody.String())
+	require.Nil(suite.T(), record.RawRequestBody)
+
+	// Invalid body
+	req, err = http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/users", strings.NewReader("not gzip"))
+	require.NoError(suite.T(), err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = client.Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+	record = suite.hts.PopServerRecord()
+	require.Error(suite.T(), record.ServerError)
+	require.Equal(suite.T(), "not gzip", record.RequestBody.String())
+}
@@ -0,0 +1,121 @@
+package gosette
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expected number of requests served by a stub. Max is negative when there is no upper bound.
+type expectation struct {
+	min int
+	max int
+}
+
+// Helper method which returns true if the provided number of requests satisfies the expectation.
+func (e *expectation) satisfied(served int) bool {
+	return served >= e.min && (e.max < 0 || served <= e.max)
+}
+
+// Helper method which describes the expectation (ex: exactly 2 request(s)).
+func (e *expectation) String() string {
+	switch {
+	case e.min == e.max:
+		return fmt.Sprintf("exactly %d request(s)", e.min)
+	case e.max < 0:
+		return fmt.Sprintf("at least %d request(s)", e.min)
+	case e.min == 0:
+		return fmt.Sprintf("at most %d request(s)", e.max)
+	default:
+		return fmt.Sprintf("between %d and %d request(s)", e.min, e.max)
+	}
+}
+
+// Expect the response to be served exactly n times. Unmet expectations are reported by
+// VerifyAll.
+//
+// Expectations only count requests: use the response Repeat to stop serving the response once it
+// has been served n times.
+func (b *ResponseBuilder) ExpectTimes(n int) *ResponseBuilder {
+	return b.expect(n, n)
+}
+
+// Expect the response to be served at least n times. Unmet expectations are reported by
+// VerifyAll.
+func (b *ResponseBuilder) ExpectAtLeast(n int) *ResponseBuilder {
+	return b.expect(n, -1)
+}
+
+// Expect the response to be served at most n times. Unmet expectations are reported by
+// VerifyAll.
+func (b *ResponseBuilder) ExpectAtMost(n int) *ResponseBuilder {
+	return b.expect(0, n)
+}
+
+// Helper method which sets the expected number of requests served by the stub of the response.
+func (b *ResponseBuilder) expect(min int, max int) *ResponseBuilder {
+	b.hts.mu.Lock()
+	defer b.hts.mu.Unlock()
+	b.stub.expected = &expectation{min: min, max: max}
+	return b
+}
+
+// Verify all the expectations set on the test server: the expected number of requests served by
+// each stub (see ExpectTimes, ExpectAtLeast and ExpectAtMost) and the completion of each session
+// (see Session). The returned error is a consolidated report of all the unmet expectations. Stubs
+// which have not been served enough times are reported with the closest recorded requests.
+//
+// In strict mode (see WithStrictMode), unmet expectations are also reported to the test when the
+// test server is closed.
+func (hts *HTTPTestServer) VerifyAll() error {
+	// Take a snapshot of the expectations and of their state
+	type unmet struct {
+		s      *stub
+		index  int
+		served int
+	}
+	hts.mu.Lock()
+	stubs := []unmet{}
+	for i, s := range hts.stubs {
+		if s.expected != nil && !s.expected.satisfied(s.served) {
+			stubs = append(stubs, unmet{s: s, index: i + 1, served: s.served})
+		}
+	}
+	sessions := make([]*Session, len(hts.sessions))
+	copy(sessions, hts.sessions)
+	hts.mu.Unlock()
+	// Build report
+	records := hts.snapshotServerRecords()
+	report := []string{}
+	count := len(stubs)
+	for _, u := range stubs {
+		report = append(report, fmt.Sprintf("%s: expected %s, got %d", servedBy("stub", u.s.response, fmt.Sprintf("#%d", u.index)), u.s.expected, u.served))
+		if u.served < u.s.expected.min {
+			if explanation := explainClosestRecords(records, u.s.matcher); explanation != "" {
+				report = append(report, "  "+strings.ReplaceAll(explanation, "\n", "\n  "))
+			}
+		}
+	}
+	for _, session := range sessions {
+		if err := session.Verify(); err != nil {
+			report = append(report, err.Error())
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d unmet expectation(s):\n%s", count, strings.Join(report, "\n"))
+}
+
+// Helper method which reports the unmet expectations to the test if the strict mode is enabled.
+func (hts *HTTPTestServer) reportUnmetExpectations() {
+	hts.mu.Lock()
+	t := hts.strictT
+	hts.mu.Unlock()
+	if t == nil {
+		return
+	}
+	if err := hts.VerifyAll(); err != nil {
+		t.Errorf("gosette: %s", err)
+	}
+}
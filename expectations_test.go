@@ -0,0 +1,71 @@
+package gosette
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test expected request counts. Test will ensure:
+//   - Satisfied expectations are not reported
+//   - Unmet expectations of stubs and sessions are reported at once
+//   - Stubs which have not been served enough times are reported with the closest requests
+func (suite *HTTPTestServerUnitTestSuite) TestVerifyAll() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/users").RespondWith().ExpectTimes(2)
+	suite.hts.When().Post("/users").RespondWith().Named("create user").ExpectAtLeast(1)
+	suite.hts.When().Delete("/users").RespondWith().ExpectAtMost(1)
+	suite.hts.When().Get("/health").RespondWith()
+	require.Error(suite.T(), suite.hts.VerifyAll())
+
+	// Satisfy expectations
+	for _, req := range []struct{ method, path string }{{"GET", "/users"}, {"GET", "/users"}, {"POST", "/users"}, {"GET", "/health"}} {
+		r, err := http.NewRequest(req.method, suite.hts.GetBaseURL()+req.path, nil)
+		require.NoError(suite.T(), err)
+		resp, err := client.Do(r)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+	require.NoError(suite.T(), suite.hts.VerifyAll())
+
+	// Unmet expectations
+	for i := 0; i < 2; i++ {
+		r, err := http.NewRequest(http.MethodDelete, suite.hts.GetBaseURL()+"/users", nil)
+		require.NoError(suite.T(), err)
+		resp, err := client.Do(r)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+	suite.hts.When().Put("/users/1").RespondWith().ExpectTimes(1)
+	session := suite.hts.Session("signup")
+	session.Expect().Post("/signup").RespondWith()
+	err := suite.hts.VerifyAll()
+	require.Error(suite.T(), err)
+	lines := strings.Split(err.Error(), "\n")
+	require.Equal(suite.T(), "3 unmet expectation(s):", lines[0])
+	require.Equal(suite.T(), "stub #3: expected at most 1 request(s), got 2", lines[1])
+	require.Equal(suite.T(), "stub #5: expected exactly 1 request(s), got 0", lines[2])
+	require.Equal(suite.T(), "  closest recorded requests:", lines[3])
+	require.Equal(suite.T(), `session "signup": 0 of 1 step(s) served, next expected step 1`, lines[len(lines)-1])
+	require.Contains(suite.T(), err.Error(), `    ✗ path: expected "/users/1", got "/users"`)
+}
+
+// Test unmet expectations are reported to the test when the test server is closed in strict mode.
+func TestVerifyAllStrictMode(t *testing.T) {
+	rt := &recordingT{}
+	hts := NewHTTPTestServer(nil, WithStrictMode(rt))
+	hts.Start()
+	hts.When().Get("/users").RespondWith().Named("list users").ExpectAtLeast(1)
+	hts.Close()
+	require.Equal(t, []string{"gosette: 1 unmet expectation(s):\nstub \"list users\": expected at least 1 request(s), got 0"}, rt.failures)
+
+	// Stopping the test server does not report unmet expectations
+	rt = &recordingT{}
+	hts = NewHTTPTestServer(nil, WithStrictMode(rt))
+	hts.Start()
+	hts.When().Get("/users").RespondWith().ExpectTimes(1)
+	hts.Stop()
+	require.Empty(t, rt.failures)
+}
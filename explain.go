@@ -0,0 +1,328 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Maximum number of request body characters displayed in explanations.
+const explainedBodyLength = 200
+
+// Maximum number of recorded requests displayed when a verification fails.
+const explainedRecordCount = 3
+
+// The outcome of a single matching criterion for a request.
+type CriterionResult struct {
+	// Description of the criterion (ex: method, path, header Accept).
+	Criterion string
+	// The expected value.
+	Expected string
+	// The actual value of the request.
+	Actual string
+	// True if the request satisfies the criterion.
+	Matched bool
+}
+
+// Return a line which describes the outcome (ex: ✗ method: expected "POST", got "GET").
+func (cr CriterionResult) String() string {
+	mark := "✗"
+	if cr.Matched {
+		mark = "✓"
+	}
+	if cr.Expected == "" && cr.Actual == "" {
+		return fmt.Sprintf("%s %s", mark, cr.Criterion)
+	}
+	return fmt.Sprintf("%s %s: expected %s, got %s", mark, cr.Criterion, cr.Expected, cr.Actual)
+}
+
+// A request matcher which can explain how a request is evaluated by each of its criteria. The
+// built-in matchers and the matchers built with When implement this interface.
+type ExplainingMatcher interface {
+	RequestMatcher
+	// Explain how the provided request is evaluated by each criterion of the matcher.
+	Explain(r *http.Request) []CriterionResult
+}
+
+// Explain how the provided request is evaluated by each criterion of the provided matcher.
+// Matchers which do not implement ExplainingMatcher are reported as a single criterion. The
+// matchers are provided with a fresh copy of the request body.
+func ExplainMatch(matcher RequestMatcher, r *http.Request) []CriterionResult {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return []CriterionResult{{Criterion: "request body", Actual: fmt.Sprintf("error: %s", err)}}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if explaining, ok := matcher.(ExplainingMatcher); ok {
+		return explaining.Explain(r)
+	}
+	return []CriterionResult{{Criterion: "custom matcher", Matched: matcher.Match(r)}}
+}
+
+// Describe which criteria of each registered stub the recorded request matches. This helps to
+// find out why a request has not been served the expected predefined response. Stubs are
+// identified as in ServerRecord ServedBy.
+func (hts *HTTPTestServer) ExplainMismatch(record *ServerRecord) string {
+	explanations := hts.explainStubs(record)
+	if len(explanations) == 0 {
+		return fmt.Sprintf("request %s: no stubs are registered", describeRequest(record.Request))
+	}
+	lines := []string{fmt.Sprintf("request %s:", describeRequest(record.Request))}
+	for _, explanation := range explanations {
+		header := explanation.Stub
+		if explanation.Exhausted {
+			header = header + " (exhausted)"
+		}
+		lines = append(lines, header+":")
+		lines = append(lines, indentCriteria(explanation.Criteria, "  ")...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// How a request is evaluated by a registered stub.
+type stubExplanation struct {
+	// Stub identifier (see ServerRecord ServedBy).
+	Stub string
+	// True if the stub has been served as many times as allowed by its Repeat.
+	Exhausted bool
+	// Outcome of each criterion of the stub.
+	Criteria []CriterionResult
+}
+
+// Helper method which explains how the recorded request is evaluated by each registered stub.
+func (hts *HTTPTestServer) explainStubs(record *ServerRecord) []stubExplanation {
+	// Take a snapshot of the stubs and of their state
+	type candidate struct {
+		s         *stub
+		exhausted bool
+		state     string
+	}
+	hts.mu.Lock()
+	candidates := make([]candidate, 0, len(hts.stubs))
+	for _, s := range hts.stubs {
+		c := candidate{s: s, exhausted: s.response.exhausted(s.served, 0)}
+		if s.scenario != "" {
+			c.state = hts.scenarioState(s.scenario)
+		}
+		candidates = append(candidates, c)
+	}
+	hts.mu.Unlock()
+	explanations := make([]stubExplanation, 0, len(candidates))
+	for i, c := range candidates {
+		results := record.explain(c.s.matcher)
+		if c.s.scenario != "" {
+			results = append(results, CriterionResult{
+				Criterion: "scenario " + c.s.scenario,
+				Expected:  fmt.Sprintf("%q", c.s.requiredState),
+				Actual:    fmt.Sprintf("%q", c.state),
+				Matched:   c.state == c.s.requiredState,
+			})
+		}
+		explanations = append(explanations, stubExplanation{
+			Stub:      servedBy("stub", c.s.response, fmt.Sprintf("#%d", i+1)),
+			Exhausted: c.exhausted,
+			Criteria:  results,
+		})
+	}
+	return explanations
+}
+
+// Helper method which explains how the recorded request is evaluated by the provided matcher.
+// The matcher is provided with a fresh copy of the recorded request body.
+func (record *ServerRecord) explain(matcher RequestMatcher) []CriterionResult {
+	if record.Request == nil {
+		return []CriterionResult{{Criterion: "record has no request"}}
+	}
+	record.Request.Body = io.NopCloser(bytes.NewReader(record.RequestBody.Bytes()))
+	return ExplainMatch(matcher, record.Request)
+}
+
+// Helper function which describes the recorded requests which are the closest to be matched by
+// the provided matcher: the requests which satisfy the most criteria come first. Returns an empty
+// string if there are no such requests.
+func explainClosestRecords(records []*ServerRecord, matcher RequestMatcher) string {
+	type candidate struct {
+		record  *ServerRecord
+		results []CriterionResult
+		matched int
+	}
+	candidates := []candidate{}
+	for _, record := range records {
+		if record.Request == nil || record.matches(matcher) {
+			continue
+		}
+		c := candidate{record: record, results: record.explain(matcher)}
+		for _, result := range c.results {
+			if result.Matched {
+				c.matched++
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].matched > candidates[j].matched
+	})
+	lines := []string{"closest recorded requests:"}
+	for i, c := range candidates {
+		if i == explainedRecordCount {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(candidates)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%d/%d criteria matched):", describeRequest(c.record.Request), c.matched, len(c.results)))
+		lines = append(lines, indentCriteria(c.results, "    ")...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Helper function which formats the provided criterion results, one per line, with the provided
+// indentation.
+func indentCriteria(results []CriterionResult, indent string) []string {
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		lines = append(lines, indent+result.String())
+	}
+	return lines
+}
+
+// Helper function which describes the provided request (ex: GET /users?page=2).
+func describeRequest(r *http.Request) string {
+	if r == nil {
+		return "<nil>"
+	}
+	return r.Method + " " + r.URL.RequestURI()
+}
+
+/*************************************************************************************************/
+/* EXPLAINING MATCHERS                                                                           */
+/*************************************************************************************************/
+
+// A request matcher which evaluates a single criterion and can describe the actual value of the
+// request for that criterion.
+type criterionMatcher struct {
+	// Description of the criterion.
+	criterion string
+	// The expected value.
+	expected string
+	// Function which returns the actual value of the request.
+	actual func(r *http.Request) string
+	// Function which evaluates the criterion.
+	match func(r *http.Request) bool
+}
+
+// Helper function which builds a request matcher which evaluates a single criterion.
+func newCriterionMatcher(criterion string, expected string, actual func(r *http.Request) string, match func(r *http.Request) bool) *criterionMatcher {
+	return &criterionMatcher{criterion: criterion, expected: expected, actual: actual, match: match}
+}
+
+// Match calls the criterion function.
+func (cm *criterionMatcher) Match(r *http.Request) bool {
+	return cm.match(r)
+}
+
+// Explain evaluates the criterion and describes the actual value of the request.
+func (cm *criterionMatcher) Explain(r *http.Request) []CriterionResult {
+	// Provide both functions with a fresh copy of the request body
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	matched := cm.match(r)
+	if r.Body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return []CriterionResult{{
+		Criterion: cm.criterion,
+		Expected:  cm.expected,
+		Actual:    cm.actual(r),
+		Matched:   matched,
+	}}
+}
+
+// A request matcher which matches requests matched by all its matchers. See MatchAll.
+type allMatcher []RequestMatcher
+
+// Match returns true if all the matchers match the provided request.
+func (am allMatcher) Match(r *http.Request) bool {
+	// Read the body once so it can be provided to each matcher
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return false
+		}
+	}
+	for _, matcher := range am {
+		if r.Body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if !matcher.Match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Explain concatenates the explanations of all the matchers.
+func (am allMatcher) Explain(r *http.Request) []CriterionResult {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+	results := []CriterionResult{}
+	for _, matcher := range am {
+		if r.Body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		results = append(results, ExplainMatch(matcher, r)...)
+	}
+	return results
+}
+
+// Helper function which returns a quoted, possibly truncated, copy of the request body. The
+// request body is consumed.
+func explainedBody(r *http.Request) string {
+	if r.Body == nil {
+		return `""`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(body) > explainedBodyLength {
+		return fmt.Sprintf("%q... (%d bytes)", body[:explainedBodyLength], len(body))
+	}
+	return fmt.Sprintf("%q", body)
+}
+
+// Helper function which formats the provided values as JSON or returns the provided error.
+func explainedValues(values interface{}, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprintf("%v", values)
+	}
+	return string(encoded)
+}
+
+// Helper function which formats the provided values or returns <none> when empty.
+func explainedStrings(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return fmt.Sprintf("%q", values)
+}
@@ -0,0 +1,117 @@
+package gosette
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test ExplainMatch describes each criterion of the built-in matchers.
+func (suite *HTTPTestServerUnitTestSuite) TestExplainMatch() {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/users?page=2", strings.NewReader(`{"name":"alice"}`))
+	req.Header.Set("Accept", "text/plain")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	matcher := MatchAll(
+		MethodMatcher(http.MethodGet),
+		PathMatcher("/users"),
+		HostMatcher("example.com"),
+		HeaderEqualsMatcher("Accept", "application/json"),
+		HeaderEqualsMatcher("X-Missing", "1"),
+		CookieMatcher("session", "abc"),
+		BodyContainsMatcher("bob"),
+		BodyJSONPathMatcher("$.name", "alice"),
+		BodyXPathExistsMatcher("/user"),
+		SNIMatcher("example.com"),
+		RequestMatcherFunc(func(r *http.Request) bool { return true }),
+	)
+	lines := []string{}
+	for _, result := range ExplainMatch(matcher, req) {
+		lines = append(lines, result.String())
+	}
+	require.Equal(suite.T(), []string{
+		`✗ method: expected "GET", got "POST"`,
+		`✓ path: expected "/users", got "/users"`,
+		`✓ host: expected "example.com", got "example.com"`,
+		`✗ header Accept: expected "application/json", got ["text/plain"]`,
+		`✗ header X-Missing: expected "1", got <none>`,
+		`✓ cookie session: expected "abc", got ["abc"]`,
+		`✗ body: expected to contain "bob", got "{\"name\":\"alice\"}"`,
+		`✓ JSONPath $.name: expected "alice", got ["alice"]`,
+		`✗ XPath /user: expected at least one node, got error: failed to decode XML document: no root element`,
+		`✗ server name: expected "example.com", got no TLS`,
+		`✓ custom matcher`,
+	}, lines)
+	// Matching is not affected by explanations
+	require.False(suite.T(), matcher.Match(req))
+
+	// Long bodies are truncated
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 300)))
+	results := ExplainMatch(BodyContainsMatcher("b"), req)
+	require.Contains(suite.T(), results[0].Actual, "... (300 bytes)")
+
+	// Virtual hosts and SNI
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{ServerName: "api.example.com"}
+	results = ExplainMatch(MatchAll(suite.hts.VirtualHost("other.example.com"), SNIMatcher("api.example.com")), req)
+	require.Equal(suite.T(), `✗ virtual host: expected "other.example.com", got "api.example.com"`, results[0].String())
+	require.Equal(suite.T(), `✓ server name: expected "api.example.com", got "api.example.com"`, results[1].String())
+}
+
+// Test ExplainMismatch describes why a recorded request has not been served by each stub.
+func (suite *HTTPTestServerUnitTestSuite) TestExplainMismatch() {
+	// Without stubs
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/users")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record := suite.hts.PopServerRecord()
+	require.Equal(suite.T(), "request GET /users: no stubs are registered", suite.hts.ExplainMismatch(record))
+
+	// With stubs
+	suite.hts.When().Post("/users").WithHeader("Content-Type", "application/json").RespondWith().Named("create-user")
+	suite.hts.When().Get("/users").InScenario("signup", "done").RespondWith()
+	suite.hts.When().Get("/users").RespondWith().Response().Repeat = Once()
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL() + "/users")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	suite.hts.PopServerRecord()
+	require.Equal(suite.T(), `request GET /users:
+stub "create-user":
+  ✗ method: expected "POST", got "GET"
+  ✓ path: expected "/users", got "/users"
+  ✗ header Content-Type: expected "application/json", got <none>
+stub #2:
+  ✓ method: expected "GET", got "GET"
+  ✓ path: expected "/users", got "/users"
+  ✗ scenario signup: expected "done", got "Started"
+stub #3 (exhausted):
+  ✓ method: expected "GET", got "GET"
+  ✓ path: expected "/users", got "/users"`, suite.hts.ExplainMismatch(record))
+}
+
+// Test failed verifications describe the closest recorded requests first.
+func (suite *HTTPTestServerUnitTestSuite) TestVerifyExplainsClosestRequests() {
+	for _, path := range []string{"/a", "/b", "/orders", "/c", "/d"} {
+		resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+	err := suite.hts.Verify().Requests(http.MethodPost, "/orders").AtLeast(1)
+	require.EqualError(suite.T(), err, `expected at least 1 request(s) matching POST /orders, got 0
+closest recorded requests:
+  GET /orders (1/2 criteria matched):
+    ✗ method: expected "POST", got "GET"
+    ✓ path: expected "/orders", got "/orders"
+  GET /a (0/2 criteria matched):
+    ✗ method: expected "POST", got "GET"
+    ✗ path: expected "/orders", got "/a"
+  GET /b (0/2 criteria matched):
+    ✗ method: expected "POST", got "GET"
+    ✗ path: expected "/orders", got "/b"
+  ... and 2 more`)
+	// Too many requests are not explained
+	err = suite.hts.Verify().Requests(http.MethodGet, "").AtMost(1)
+	require.EqualError(suite.T(), err, "expected at most 1 request(s) matching GET, got 5")
+}
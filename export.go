@@ -0,0 +1,298 @@
+package gosette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats records can be exported to.
+type RecordFormat int
+
+const (
+	// A JSON array which contains one object per record.
+	RecordFormatJSON RecordFormat = iota
+	// A HTTP Archive (HAR 1.2) which can be opened by browser developer tools and HAR viewers.
+	RecordFormatHAR
+)
+
+// A server record exported in JSON.
+type ExportedRecord struct {
+	// Time at which the request has been received.
+	ReceivedAt time.Time `json:"receivedAt"`
+	// Protocol used by the request (ex: HTTP/1.1).
+	Protocol string `json:"protocol"`
+	// Method of the request.
+	Method string `json:"method"`
+	// Absolute URL of the request.
+	URL string `json:"url"`
+	// Headers of the request.
+	RequestHeaders http.Header `json:"requestHeaders,omitempty"`
+	// Body of the request. Base64 encoded when it is not valid UTF-8.
+	RequestBody string `json:"requestBody,omitempty"`
+	// Encoding of the request body: empty for text or base64.
+	RequestBodyEncoding string `json:"requestBodyEncoding,omitempty"`
+	// Status code of the response.
+	Status int `json:"status"`
+	// Headers of the response.
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	// Body of the response. Base64 encoded when it is not valid UTF-8.
+	ResponseBody string `json:"responseBody,omitempty"`
+	// Encoding of the response body: empty for text or base64.
+	ResponseBodyEncoding string `json:"responseBodyEncoding,omitempty"`
+	// Error which has occured while the request was handled.
+	ServerError string `json:"serverError,omitempty"`
+	// Failures which have occured while the request was validated.
+	ValidationErrors []string `json:"validationErrors,omitempty"`
+}
+
+// Export the records of the test server, without popping them, to the provided writer in the
+// provided format. Useful to attach the captured traffic of a failing test as a CI artifact.
+func (hts *HTTPTestServer) ExportRecords(w io.Writer, format RecordFormat) error {
+	records := hts.snapshotServerRecords()
+	var v interface{}
+	switch format {
+	case RecordFormatJSON:
+		exported := make([]*ExportedRecord, 0, len(records))
+		for _, record := range records {
+			exported = append(exported, record.export())
+		}
+		v = exported
+	case RecordFormatHAR:
+		v = newHAR(records)
+	default:
+		return fmt.Errorf("unknown record format %d", format)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to export records: %w", err)
+	}
+	return nil
+}
+
+// Save the records of the test server, without popping them, to the file at the provided path.
+// Records are saved as a HTTP Archive when the file has the .har extension and as JSON otherwise.
+func (hts *HTTPTestServer) SaveRecords(path string) error {
+	format := RecordFormatJSON
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		format = RecordFormatHAR
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := hts.ExportRecords(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Helper method which exports the record.
+func (record *ServerRecord) export() *ExportedRecord {
+	exported := &ExportedRecord{
+		ReceivedAt: record.ReceivedAt,
+		Protocol:   record.Protocol,
+		Status:     record.Response.Code,
+	}
+	if record.Request != nil {
+		exported.Method = record.Request.Method
+		exported.URL = requestURL(record.Request)
+		exported.RequestHeaders = record.Request.Header
+	}
+	exported.RequestBody, exported.RequestBodyEncoding = exportBody(record.RequestBody.Bytes())
+	exported.ResponseHeaders = record.Response.Header()
+	exported.ResponseBody, exported.ResponseBodyEncoding = exportBody(record.Response.Body.Bytes())
+	if record.ServerError != nil {
+		exported.ServerError = record.ServerError.Error()
+	}
+	for _, err := range record.ValidationErrors {
+		exported.ValidationErrors = append(exported.ValidationErrors, err.Error())
+	}
+	return exported
+}
+
+// Helper function which returns the absolute URL of the provided request received by the test
+// server.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// Helper function which returns the provided body as text if it is valid UTF-8 or base64 encoded
+// otherwise along with the used encoding.
+func exportBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+/*************************************************************************************************/
+/* HAR                                                                                           */
+/*************************************************************************************************/
+
+// A HTTP Archive (HAR 1.2). Only the fields required by the specification and the fields known by
+// the test server are exported.
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Helper function which builds a HTTP Archive from the provided records.
+func newHAR(records []*ServerRecord) *har {
+	entries := make([]harEntry, 0, len(records))
+	for _, record := range records {
+		exported := record.export()
+		entry := harEntry{
+			StartedDateTime: exported.ReceivedAt,
+			Request: harRequest{
+				Method:      exported.Method,
+				URL:         exported.URL,
+				HTTPVersion: exported.Protocol,
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(exported.RequestHeaders),
+				QueryString: []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    record.RequestBody.Len(),
+			},
+			Response: harResponse{
+				Status:      exported.Status,
+				StatusText:  http.StatusText(exported.Status),
+				HTTPVersion: exported.Protocol,
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(exported.ResponseHeaders),
+				Content: harContent{
+					Size:     int(record.responseBodySize()),
+					MimeType: exported.ResponseHeaders.Get("Content-Type"),
+					Text:     exported.ResponseBody,
+					Encoding: exported.ResponseBodyEncoding,
+				},
+				RedirectURL: exported.ResponseHeaders.Get("Location"),
+				HeadersSize: -1,
+				BodySize:    int(record.responseBodySize()),
+			},
+			Comment: exported.ServerError,
+		}
+		if record.Request != nil {
+			for _, cookie := range record.Cookies {
+				entry.Request.Cookies = append(entry.Request.Cookies, harNameValue{Name: cookie.Name, Value: cookie.Value})
+			}
+			entry.Request.QueryString = harHeaders(http.Header(record.Request.URL.Query()))
+			if record.RequestBody.Len() > 0 {
+				entry.Request.PostData = &harPostData{
+					MimeType: record.Request.Header.Get("Content-Type"),
+					Text:     exported.RequestBody,
+				}
+			}
+		}
+		for _, cookie := range record.ResponseCookies() {
+			entry.Response.Cookies = append(entry.Response.Cookies, harNameValue{Name: cookie.Name, Value: cookie.Value})
+		}
+		entries = append(entries, entry)
+	}
+	return &har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "gosette", Version: "1"},
+		Entries: entries,
+	}}
+}
+
+// Helper function which converts the provided header map in a list of name/value pairs sorted by
+// name.
+func harHeaders(header http.Header) []harNameValue {
+	pairs := []harNameValue{}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
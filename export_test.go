@@ -0,0 +1,70 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test records are exported in JSON with text and binary bodies.
+func (suite *HTTPTestServerUnitTestSuite) TestExportRecordsJSON() {
+	suite.hts.When().Post("/upload").RespondWith().Status(http.StatusCreated).Header("Content-Type", "text/plain").StringBody("stored")
+	resp, err := suite.hts.Client().Post(suite.hts.GetBaseURL()+"/upload?name=a", "application/octet-stream", bytes.NewReader([]byte{0xff, 0xfe}))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	out := &bytes.Buffer{}
+	require.NoError(suite.T(), suite.hts.ExportRecords(out, RecordFormatJSON))
+	exported := []*ExportedRecord{}
+	require.NoError(suite.T(), json.Unmarshal(out.Bytes(), &exported))
+	require.Len(suite.T(), exported, 1)
+	require.Equal(suite.T(), http.MethodPost, exported[0].Method)
+	require.Equal(suite.T(), suite.hts.GetBaseURL()+"/upload?name=a", exported[0].URL)
+	require.Equal(suite.T(), "HTTP/1.1", exported[0].Protocol)
+	require.Equal(suite.T(), "//4=", exported[0].RequestBody)
+	require.Equal(suite.T(), "base64", exported[0].RequestBodyEncoding)
+	require.Equal(suite.T(), http.StatusCreated, exported[0].Status)
+	require.Equal(suite.T(), "stored", exported[0].ResponseBody)
+	require.Empty(suite.T(), exported[0].ResponseBodyEncoding)
+	require.False(suite.T(), exported[0].ReceivedAt.IsZero())
+	// Records are not popped
+	require.Len(suite.T(), suite.hts.FindRecords(), 1)
+	require.Error(suite.T(), suite.hts.ExportRecords(out, RecordFormat(42)))
+}
+
+// Test records are saved as JSON or as a HTTP archive depending on the file extension.
+func (suite *HTTPTestServerUnitTestSuite) TestSaveRecords() {
+	suite.hts.When().Get("/items").RespondWith().SetCookie(&http.Cookie{Name: "seen", Value: "1"}).JSONBody([]int{1})
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/items?page=2", nil)
+	require.NoError(suite.T(), err)
+	req.AddCookie(&http.Cookie{Name: "export", Value: "abc"})
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	dir := suite.T().TempDir()
+	// HAR
+	require.NoError(suite.T(), suite.hts.SaveRecords(filepath.Join(dir, "traffic.HAR")))
+	data, err := os.ReadFile(filepath.Join(dir, "traffic.HAR"))
+	require.NoError(suite.T(), err)
+	archive := &har{}
+	require.NoError(suite.T(), json.Unmarshal(data, archive))
+	require.Equal(suite.T(), "1.2", archive.Log.Version)
+	require.Len(suite.T(), archive.Log.Entries, 1)
+	entry := archive.Log.Entries[0]
+	require.Equal(suite.T(), []harNameValue{{Name: "page", Value: "2"}}, entry.Request.QueryString)
+	require.Equal(suite.T(), []harNameValue{{Name: "export", Value: "abc"}}, entry.Request.Cookies)
+	require.Equal(suite.T(), []harNameValue{{Name: "seen", Value: "1"}}, entry.Response.Cookies)
+	require.Equal(suite.T(), "OK", entry.Response.StatusText)
+	require.Equal(suite.T(), "application/json", entry.Response.Content.MimeType)
+	require.Equal(suite.T(), "[1]", strings.TrimSpace(entry.Response.Content.Text))
+	// JSON
+	require.NoError(suite.T(), suite.hts.SaveRecords(filepath.Join(dir, "traffic.json")))
+	data, err = os.ReadFile(filepath.Join(dir, "traffic.json"))
+	require.NoError(suite.T(), err)
+	require.True(suite.T(), json.Valid(data))
+	require.Error(suite.T(), suite.hts.SaveRecords(filepath.Join(dir, "missing", "traffic.json")))
+}
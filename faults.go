@@ -0,0 +1,196 @@
+package gosette
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Faults which can be injected by the test server instead of serving a well-formed response.
+type Fault int
+
+const (
+	// No fault is injected: the predefined response is served normally.
+	FaultNone Fault = iota
+	// The test server resets the client connection (TCP RST) instead of writing a response. For
+	// HTTP/2 requests, the stream is reset instead. Useful to test how clients handle ECONNRESET
+	// and io.ErrUnexpectedEOF.
+	FaultConnectionReset
+	// The test server never writes a response: the handler hangs until the client closes the
+	// connection or the test server is closed. When the predefined response has a Delay, the
+	// connection is closed without a response once the delay has elapsed. Useful to test client
+	// timeouts and context cancellation.
+	FaultHang
+	// The test server writes the response headers with a Content-Length which advertises the full
+	// body size (unless a Content-Length header is declared), writes the first FaultAfterBytes
+	// bytes of the body and then closes the connection. Useful to test client handling of short
+	// reads and io.ErrUnexpectedEOF in the middle of a download.
+	FaultTruncatedBody
+	// The test server writes the response Body as is on the client connection instead of a
+	// well-formed response (status code and headers are ignored) and then closes the connection.
+	// Useful to test how clients react to protocol violations: invalid status lines, garbage
+	// headers, HTTP/0.9-style bodies... HTTP/2 requests get a 500 response as their connection
+	// cannot be hijacked.
+	FaultRawResponse
+)
+
+// Helper method which injects the fault declared by the provided predefined response. The server
+// record is added to the record queue before the fault is injected.
+//
+// In case the fault cannot be injected, the server replies with a 500 response and the record
+// ServerError is set with an error which wraps the error that has occured.
+//
+// The provided http.ResponseWriter must write to both the client connection and the server record
+// while the provided conn writer must write to the client connection only.
+func (srv *HTTPTestServer) injectFault(w http.ResponseWriter, conn http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	switch response.Fault {
+	case FaultConnectionReset:
+		srv.resetConnection(w, conn, r, serverRecord)
+	case FaultHang:
+		srv.hang(r, response, serverRecord)
+	case FaultTruncatedBody:
+		srv.truncateBody(w, response, serverRecord)
+	case FaultRawResponse:
+		srv.writeRawResponse(w, conn, r, response, serverRecord)
+	default:
+		// Unknown fault
+		err := fmt.Errorf("test server cannot inject unknown fault %d", response.Fault)
+		srv.handleInternalError(w, serverRecord, err)
+	}
+}
+
+// Helper method which resets the client connection. The server record is added to the record
+// queue before the connection is reset.
+//
+// The connection is hijacked and closed with a zero linger so a TCP RST is sent to the client.
+// HTTP/2 connections cannot be hijacked: the handler is aborted and the stream is reset instead.
+func (srv *HTTPTestServer) resetConnection(w http.ResponseWriter, conn http.ResponseWriter, r *http.Request, serverRecord *ServerRecord) {
+	// Abort the handler for HTTP/2 requests: the server will reset the stream
+	if r.ProtoMajor == 2 {
+		srv.addServerRecord(serverRecord)
+		panic(http.ErrAbortHandler)
+	}
+	// Hijack the client connection
+	c, err := hijack(conn)
+	if err != nil {
+		werr := fmt.Errorf("test server failed to reset the connection: %w", err)
+		srv.handleInternalError(w, serverRecord, werr)
+		return
+	}
+	// Add the record before resetting the connection
+	srv.addServerRecord(serverRecord)
+	// Set linger to zero so closing the connection will send a TCP RST
+	if tcpConn, ok := netConn(c).(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	c.Close()
+}
+
+// Helper method which hangs until the client closes the connection or the test server is closed.
+// The server record is added to the record queue before the handler hangs.
+//
+// In case the predefined response has a Delay, the delay has already elapsed when this method is
+// called: the method does not hang. Once done, the connection is closed without a response.
+func (srv *HTTPTestServer) hang(r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// Add the record before hanging
+	srv.addServerRecord(serverRecord)
+	// Hang until the client goes away or the test server is closed
+	if response.Delay <= 0 {
+		select {
+		case <-r.Context().Done():
+		case <-srv.getClosing():
+		}
+	}
+	// Abort the handler: the server will close the connection without a response
+	panic(http.ErrAbortHandler)
+}
+
+// Helper method which writes the response headers with a Content-Length which advertises the full
+// body size unless a Content-Length is declared, writes the first FaultAfterBytes bytes of the
+// body and then closes the connection. The server record is added to the record queue before the
+// connection is closed.
+func (srv *HTTPTestServer) truncateBody(w http.ResponseWriter, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// Advertise the full body size unless a size is declared and write headers
+	if response.Headers.Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(response.Body)))
+	}
+	writeHeaders(w, response)
+	// Write the truncated body and flush it to the client
+	n := response.FaultAfterBytes
+	if n > len(response.Body) {
+		n = len(response.Body)
+	}
+	if n > 0 {
+		w.Write(response.Body[:n])
+	}
+	flush(w)
+	// Add the record before closing the connection
+	srv.addServerRecord(serverRecord)
+	// Abort the handler: the server will close the connection
+	panic(http.ErrAbortHandler)
+}
+
+// Helper method which writes the body of the provided predefined response as is on the client
+// connection and then closes the connection. The server record is added to the record queue
+// before the raw response is written.
+func (srv *HTTPTestServer) writeRawResponse(w http.ResponseWriter, conn http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// HTTP/2 connections cannot be hijacked
+	if r.ProtoMajor == 2 {
+		err := fmt.Errorf("test server cannot write a raw response on a HTTP/2 connection")
+		srv.handleInternalError(w, serverRecord, err)
+		return
+	}
+	// Hijack the client connection
+	c, err := hijack(conn)
+	if err != nil {
+		werr := fmt.Errorf("test server failed to write the raw response: %w", err)
+		srv.handleInternalError(w, serverRecord, werr)
+		return
+	}
+	// Add the record before writing the raw response
+	srv.addServerRecord(serverRecord)
+	c.Write(response.Body)
+	c.Close()
+}
+
+// Helper function which flushes the provided http.ResponseWriter if it supports flushing.
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Helper function which hijacks the client connection from the provided http.ResponseWriter.
+func hijack(w http.ResponseWriter) (net.Conn, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack the connection: %w", err)
+	}
+	return conn, nil
+}
+
+// Helper function which returns the network connection underlying the provided connection in
+// case it is a TLS connection or a connection wrapped to record timeouts. The provided connection
+// is returned otherwise.
+func netConn(conn net.Conn) net.Conn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if tc, ok := conn.(*timeoutConn); ok {
+		conn = tc.Conn
+	}
+	return conn
+}
+
+// Helper method which returns the channel closed when the test server is closed or stopped.
+func (srv *HTTPTestServer) getClosing() <-chan struct{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closing
+}
@@ -0,0 +1,208 @@
+package gosette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test HTTPTestServer with a connection reset fault. Test will ensure the client request fails
+// and the exchange is recorded.
+func (suite *HTTPTestServerUnitTestSuite) TestWithConnectionResetFault() {
+	// Push a response with a connection reset fault
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+		Fault:  FaultConnectionReset,
+	})
+	// Send a request and expect it to fail
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL())
+	require.Error(suite.T(), err)
+	require.Nil(suite.T(), resp)
+	// Check the exchange has been recorded
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.NoError(suite.T(), record.ServerError)
+}
+
+// Test HTTPTestServer with a connection reset fault over TLS.
+func (suite *HTTPTestServerUnitTestSuite) TestWithConnectionResetFaultOverTLS() {
+	// Create and start a TLS test server
+	srv := NewHTTPTestServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+	// Push a response with a connection reset fault
+	srv.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Fault: FaultConnectionReset,
+	})
+	// Send a request and expect it to fail
+	_, err := srv.Client().Get(srv.GetBaseURL())
+	require.Error(suite.T(), err)
+	require.NotNil(suite.T(), srv.PopServerRecord())
+}
+
+// Test fault injection error paths: the connection cannot be hijacked and unknown faults.
+func (suite *HTTPTestServerUnitTestSuite) TestFaultInjectionErrPaths() {
+	// Connection reset when the response writer cannot be hijacked
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Fault: FaultConnectionReset,
+	})
+	rec := httptest.NewRecorder()
+	suite.hts.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(suite.T(), http.StatusInternalServerError, rec.Result().StatusCode)
+	record := suite.hts.PopServerRecord()
+	require.Error(suite.T(), record.ServerError)
+	// Connection reset for HTTP/2 requests aborts the handler
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.ProtoMajor = 2
+	require.PanicsWithValue(suite.T(), http.ErrAbortHandler, func() {
+		suite.hts.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	require.NotNil(suite.T(), suite.hts.PopServerRecord())
+	// Unknown fault
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Fault: Fault(-1),
+	})
+	rec = httptest.NewRecorder()
+	suite.hts.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(suite.T(), http.StatusInternalServerError, rec.Result().StatusCode)
+	record = suite.hts.PopServerRecord()
+	require.Error(suite.T(), record.ServerError)
+}
+
+// Test HTTPTestServer with a hang fault. Test will ensure:
+//   - The client times out while the server hangs and the exchange is recorded
+//   - When a delay is set, the connection is closed without a response once elapsed
+//   - Closing the test server releases hanging handlers
+func (suite *HTTPTestServerUnitTestSuite) TestWithHangFault() {
+	// Push a response with a hang fault
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+		Fault:  FaultHang,
+	})
+	// Send a request with a context deadline and expect it to fail
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, suite.hts.GetBaseURL(), nil)
+	require.NoError(suite.T(), err)
+	_, err = suite.hts.Client().Do(req)
+	require.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	require.NotNil(suite.T(), suite.hts.PopServerRecord())
+
+	// Hang with a delay: connection is closed without a response
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Delay: 10 * time.Millisecond,
+		Fault: FaultHang,
+	})
+	_, err = suite.hts.Client().Get(suite.hts.GetBaseURL())
+	require.Error(suite.T(), err)
+	require.NotNil(suite.T(), suite.hts.PopServerRecord())
+
+	// Closing the server releases hanging handlers
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	srv.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Fault: FaultHang,
+	})
+	errs := make(chan error)
+	go func() {
+		_, err := srv.Client().Get(srv.GetBaseURL())
+		errs <- err
+	}()
+	require.Eventually(suite.T(), func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return len(srv.records) == 1
+	}, time.Second, time.Millisecond)
+	srv.Close()
+	srv.Close()
+	require.Error(suite.T(), <-errs)
+}
+
+// Test HTTPTestServer with a truncated body fault. Test will ensure the client receives the
+// headers and the first bytes of the body before encountering an unexpected EOF.
+func (suite *HTTPTestServerUnitTestSuite) TestWithTruncatedBodyFault() {
+	// Push a response with a truncated body fault
+	body := []byte("hello world!")
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:          http.StatusOK,
+		Headers:         http.Header{"Content-Type": {"text/plain"}},
+		Body:            body,
+		Fault:           FaultTruncatedBody,
+		FaultAfterBytes: 5,
+	})
+	// Send a request: headers must be received
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), int64(len(body)), resp.ContentLength)
+	// Read body: expect an unexpected EOF after the first bytes
+	received, err := io.ReadAll(resp.Body)
+	require.ErrorIs(suite.T(), err, io.ErrUnexpectedEOF)
+	require.Equal(suite.T(), body[:5], received)
+	// Check the record
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Equal(suite.T(), body[:5], record.Response.Body.Bytes())
+
+	// Truncate after more bytes than the body size: the whole body is written
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:          http.StatusOK,
+		Body:            body,
+		Fault:           FaultTruncatedBody,
+		FaultAfterBytes: 100,
+	})
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	received, err = io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), body, received)
+	require.NotNil(suite.T(), suite.hts.PopServerRecord())
+}
+
+// Test the AbortAfterBytes builder. Test will ensure the declared Content-Length is advertised
+// and the client encounters an unexpected EOF after the first bytes.
+func (suite *HTTPTestServerUnitTestSuite) TestAbortAfterBytes() {
+	suite.hts.When().Get("/download").RespondWith().
+		Header("Content-Length", "1000").StringBody("0123456789").AbortAfterBytes(4)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/download")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), int64(1000), resp.ContentLength)
+	received, err := io.ReadAll(resp.Body)
+	require.ErrorIs(suite.T(), err, io.ErrUnexpectedEOF)
+	require.Equal(suite.T(), "0123", string(received))
+	require.NotNil(suite.T(), suite.hts.PopServerRecord())
+}
+
+// Test raw responses. Test will ensure:
+//   - The raw bytes are written as is on the client connection
+//   - Clients report protocol violations (invalid status line, HTTP/0.9-style body)
+//   - Requests are recorded
+func (suite *HTTPTestServerUnitTestSuite) TestRawResponse() {
+	suite.hts.When().Get("/valid").RespondWith().RawResponse([]byte("HTTP/1.1 202 Maybe\r\nX-Raw: yes\r\nContent-Length: 2\r\n\r\nok"))
+	suite.hts.When().Get("/status").RespondWith().RawResponse([]byte("HTTP/1.1 abc Not A Status\r\n\r\n"))
+	suite.hts.When().Get("/http09").RespondWith().RawResponse([]byte("hello world"))
+	client := suite.hts.Client()
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/valid")
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusAccepted, resp.StatusCode)
+	require.Equal(suite.T(), "202 Maybe", resp.Status)
+	require.Equal(suite.T(), "yes", resp.Header.Get("X-Raw"))
+	require.Equal(suite.T(), "ok", string(body))
+	for _, path := range []string{"/status", "/http09"} {
+		_, err = client.Get(suite.hts.GetBaseURL() + path)
+		require.Error(suite.T(), err, path)
+		require.Contains(suite.T(), err.Error(), "malformed HTTP", path)
+	}
+	require.Len(suite.T(), suite.hts.FindRecords(), 3)
+}
@@ -0,0 +1,75 @@
+package gosette
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Push a predefined 200 response whose body is read from the file at the provided path each time
+// the response is served. The Content-Type header is inferred from the file extension. See
+// PredefinedServerResponse BodyFile.
+func (hts *HTTPTestServer) PushResponseFromFile(path string) {
+	hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:   http.StatusOK,
+		Headers:  http.Header{},
+		BodyFile: path,
+	})
+}
+
+// Helper function which returns a copy of the provided predefined response whose body has been
+// read from its BodyFile. The Content-Type header is inferred from the file extension unless it
+// is already set.
+func loadBodyFile(response *PredefinedServerResponse) (*PredefinedServerResponse, error) {
+	body, err := os.ReadFile(response.BodyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the body file: %w", err)
+	}
+	loaded := *response
+	loaded.Body = body
+	loaded.Headers = response.Headers.Clone()
+	if loaded.Headers == nil {
+		loaded.Headers = http.Header{}
+	}
+	if loaded.Headers.Get("Content-Type") == "" {
+		if contentType := mime.TypeByExtension(filepath.Ext(response.BodyFile)); contentType != "" {
+			loaded.Headers.Set("Content-Type", contentType)
+		}
+	}
+	return &loaded, nil
+}
+
+// Helper function which returns a copy of the provided predefined response whose body and headers
+// are the output of its BodyTemplate and HeaderTemplates executed with the data of the provided
+// record.
+func executeResponseTemplates(response *PredefinedServerResponse, serverRecord *ServerRecord) (*PredefinedServerResponse, error) {
+	data := newTemplateData(serverRecord)
+	templated := *response
+	if response.BodyTemplate != "" {
+		body, err := executeTemplate(response.BodyTemplate, data)
+		if err != nil {
+			return nil, fmt.Errorf("body template: %w", err)
+		}
+		templated.Body = []byte(body)
+	}
+	if len(response.HeaderTemplates) > 0 {
+		templated.Headers = response.Headers.Clone()
+		if templated.Headers == nil {
+			templated.Headers = http.Header{}
+		}
+		for header, texts := range response.HeaderTemplates {
+			values := make([]string, 0, len(texts))
+			for _, text := range texts {
+				value, err := executeTemplate(text, data)
+				if err != nil {
+					return nil, fmt.Errorf("template of header %s: %w", header, err)
+				}
+				values = append(values, value)
+			}
+			templated.Headers[http.CanonicalHeaderKey(header)] = values
+		}
+	}
+	return &templated, nil
+}
@@ -0,0 +1,77 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test response bodies are read from files and their content type is inferred from the
+// extension unless it is set.
+func (suite *HTTPTestServerUnitTestSuite) TestBodyFile() {
+	dir := suite.T().TempDir()
+	jsonFile := filepath.Join(dir, "users.json")
+	require.NoError(suite.T(), os.WriteFile(jsonFile, []byte(`[{"id":1}]`), 0644))
+	suite.hts.PushResponseFromFile(jsonFile)
+	suite.hts.When().Get("/custom").RespondWith().Header("Content-Type", "text/plain").BodyFile(jsonFile)
+	suite.hts.When().Get("/missing").RespondWith().BodyFile(filepath.Join(dir, "missing.bin"))
+	client := suite.hts.Client()
+	for path, contentType := range map[string]string{"/users": "application/json", "/custom": "text/plain"} {
+		resp, err := client.Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+		require.Equal(suite.T(), contentType, resp.Header.Get("Content-Type"))
+		require.Equal(suite.T(), `[{"id":1}]`, string(body))
+	}
+	// The file is read lazily
+	require.NoError(suite.T(), os.WriteFile(jsonFile, []byte(`[]`), 0644))
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/users")
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), `[]`, string(body))
+	// Missing files produce a 500 response
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/missing")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+	require.Len(suite.T(), suite.hts.FindRecords(WithServerError()), 1)
+}
+
+// Test header templates are executed per request. Test will ensure:
+//   - Request data and path parameters are available to header templates
+//   - Templated values replace the static values of the header
+//   - Template errors produce a 500 response
+func (suite *HTTPTestServerUnitTestSuite) TestHeaderTemplates() {
+	suite.hts.When().Matching(MethodMatcher(http.MethodPut)).PathTemplate("/users/{id}").RespondWith().
+		Status(http.StatusCreated).
+		Header("X-Request-ID", "static").
+		HeaderTemplate("X-Request-ID", `{{.Header.Get "X-Request-ID"}}`).
+		HeaderTemplate("location", `/users/{{.PathParams.id}}`).
+		HeaderTemplate("X-Tags", `{{index .Query.tag 0}}`).
+		HeaderTemplate("X-Tags", `{{index .Query.tag 1}}`)
+	suite.hts.When().Get("/broken").RespondWith().HeaderTemplate("X-Broken", `{{.Missing}}`)
+	req, err := http.NewRequest(http.MethodPut, suite.hts.GetBaseURL()+"/users/42?tag=a&tag=b", nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+	require.Equal(suite.T(), []string{"req-1"}, resp.Header.Values("X-Request-ID"))
+	require.Equal(suite.T(), "/users/42", resp.Header.Get("Location"))
+	require.Equal(suite.T(), []string{"a", "b"}, resp.Header.Values("X-Tags"))
+
+	// Template errors produce a 500 response
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL() + "/broken")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+}
@@ -0,0 +1,284 @@
+package gosette
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// State of the forward proxy mode.
+type forwardProxy struct {
+	// CA which signs the certificates presented in CONNECT tunnels.
+	ca *x509.Certificate
+	// Private key of the CA.
+	caKey *ecdsa.PrivateKey
+	// Mutex used to protect the certificate cache.
+	mu sync.Mutex
+	// Certificates presented in CONNECT tunnels, by host name.
+	certificates map[string]*tls.Certificate
+}
+
+// Key used to store the target of the CONNECT tunnel a request has been received through in the
+// request context.
+type forwardProxyContextKey struct{}
+
+// Enable the forward proxy mode: the test server behaves as an HTTP forward proxy so clients
+// configured with a proxy (ex: HTTP_PROXY and HTTPS_PROXY set to the test server base URL) can be
+// tested.
+//
+// Absolute-URI requests (GET http://api.example.com/users HTTP/1.1) are served like any other
+// request: register predefined responses keyed by the target host and path (see HostMatcher).
+// CONNECT requests open a tunnel: the test server terminates TLS in the tunnel with a certificate
+// generated for the target host and signed by a generated CA (see ProxyCACertPool), then serves
+// the tunneled requests the same way. Plain HTTP tunnels are served as well.
+//
+// Proxied requests are recorded with ForwardProxied set; the target is available in the request
+// Host and URL. CONNECT requests themselves are not recorded. Use ProxyClient to get a client
+// configured to use the test server as its proxy.
+//
+// The forward proxy mode is not disabled by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) EnableForwardProxy() error {
+	if hts.scope != nil {
+		return fmt.Errorf("failed to enable the forward proxy mode: not supported on a scope")
+	}
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	if hts.forwardProxy != nil {
+		return nil
+	}
+	ca, caKey, err := generateCA()
+	if err != nil {
+		return fmt.Errorf("failed to enable the forward proxy mode: %w", err)
+	}
+	hts.forwardProxy = &forwardProxy{ca: ca, caKey: caKey, certificates: map[string]*tls.Certificate{}}
+	return nil
+}
+
+// Return the URL of the test server to use as proxy URL. See EnableForwardProxy.
+func (hts *HTTPTestServer) ProxyURL() *url.URL {
+	u, _ := url.Parse(hts.GetBaseURL())
+	return u
+}
+
+// Return a certificate pool which contains the CA which signs the certificates presented in
+// CONNECT tunnels. Returns nil if the forward proxy mode is not enabled.
+func (hts *HTTPTestServer) ProxyCACertPool() *x509.CertPool {
+	proxy := hts.getForwardProxy()
+	if proxy == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(proxy.ca)
+	return pool
+}
+
+// Return the PEM encoded certificate of the CA which signs the certificates presented in CONNECT
+// tunnels (ex: to be written to the file pointed by SSL_CERT_FILE). Returns nil if the forward
+// proxy mode is not enabled.
+func (hts *HTTPTestServer) ProxyCACertificatePEM() []byte {
+	proxy := hts.getForwardProxy()
+	if proxy == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxy.ca.Raw})
+}
+
+// Return a HTTP client which uses the test server as its proxy and which trusts the certificates
+// presented in CONNECT tunnels. The forward proxy mode must be enabled (see EnableForwardProxy).
+func (hts *HTTPTestServer) ProxyClient() *http.Client {
+	transport := hts.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(hts.ProxyURL())
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	pool := transport.TLSClientConfig.RootCAs
+	if pool == nil {
+		pool = x509.NewCertPool()
+	} else {
+		pool = pool.Clone()
+	}
+	if proxy := hts.getForwardProxy(); proxy != nil {
+		pool.AddCert(proxy.ca)
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return &http.Client{Transport: transport}
+}
+
+// Filter which selects the records of the requests received as a forward proxy (see
+// EnableForwardProxy).
+func ForwardProxied() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.ForwardProxied
+	}
+}
+
+// Helper method which returns the state of the forward proxy mode. Nil when the forward proxy
+// mode is disabled.
+func (srv *HTTPTestServer) getForwardProxy() *forwardProxy {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.forwardProxy
+}
+
+// Helper method which returns true if the provided request has been received as a forward proxy:
+// through a CONNECT tunnel or as an absolute-URI request while the forward proxy mode is enabled.
+func (srv *HTTPTestServer) isForwardProxied(r *http.Request) bool {
+	if _, ok := r.Context().Value(forwardProxyContextKey{}).(string); ok {
+		return true
+	}
+	return r.URL.IsAbs() && srv.getForwardProxy() != nil
+}
+
+// Helper method which opens a tunnel for the provided CONNECT request and serves the requests
+// sent through the tunnel until the tunnel or the test server is closed.
+func (srv *HTTPTestServer) serveTunnel(w http.ResponseWriter, r *http.Request, proxy *forwardProxy) {
+	// Hijack the client connection
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "gosette: tunnels cannot be opened over this connection", http.StatusNotImplemented)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("gosette: failed to open the tunnel: %s", err), http.StatusInternalServerError)
+		return
+	}
+	// Acknowledge the tunnel
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		return
+	}
+	// Terminate TLS if the client starts a TLS handshake (record type 22)
+	tunnel := net.Conn(&bufferedConn{Conn: conn, r: rw.Reader})
+	first, err := rw.Reader.Peek(1)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	scheme := "http"
+	if first[0] == 0x16 {
+		tunnel = tls.Server(tunnel, proxy.tlsConfig(requestHostname(r)))
+		scheme = "https"
+	}
+	// Serve the tunneled requests
+	target := r.Host
+	listener := &singleConnListener{conn: tunnel, done: make(chan struct{})}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.URL.Scheme = scheme
+			r.URL.Host = r.Host
+			srv.ServeHTTP(w, r)
+		}),
+		ConnContext: srv.trackConnections(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, forwardProxyContextKey{}, target)
+		}),
+		ConnState: func(c net.Conn, state http.ConnState) {
+			if state == http.StateClosed || state == http.StateHijacked {
+				listener.Close()
+			}
+		},
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	closing := srv.getClosing()
+	go func() {
+		select {
+		case <-closing:
+			server.Close()
+		case <-listener.done:
+		}
+	}()
+	server.Serve(listener)
+}
+
+// Helper method which returns the TLS configuration used to terminate TLS in a tunnel opened to
+// the provided host. The certificate is generated for the server name presented by the client or
+// for the provided host if the client does not present a server name.
+func (proxy *forwardProxy) tlsConfig(host string) *tls.Config {
+	return &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return proxy.certificate(name)
+		},
+	}
+}
+
+// Helper method which returns the certificate presented for the provided host name. Certificates
+// are generated once per host name.
+func (proxy *forwardProxy) certificate(host string) (*tls.Certificate, error) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if cert, ok := proxy.certificates[host]; ok {
+		return cert, nil
+	}
+	cert, err := generateLeafCertificate(proxy.ca, proxy.caKey, []string{host})
+	if err != nil {
+		return nil, err
+	}
+	proxy.certificates[host] = &cert
+	return &cert, nil
+}
+
+// A connection whose reads are served by the provided buffered reader first.
+type bufferedConn struct {
+	net.Conn
+	// Reader which holds the bytes already read from the connection.
+	r *bufio.Reader
+}
+
+// Read reads data from the buffered reader.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// A listener which accepts a single connection.
+type singleConnListener struct {
+	// The connection to accept.
+	conn net.Conn
+	// Mutex used to protect the listener state.
+	mu sync.Mutex
+	// True once the connection has been accepted.
+	accepted bool
+	// Channel closed once the listener is closed.
+	done chan struct{}
+	// Guards the closing of done.
+	closeOnce sync.Once
+}
+
+// Accept returns the connection on the first call and waits for the listener to be closed on
+// subsequent calls.
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if !l.accepted {
+		l.accepted = true
+		l.mu.Unlock()
+		return l.conn, nil
+	}
+	l.mu.Unlock()
+	<-l.done
+	return nil, net.ErrClosed
+}
+
+// Close closes the listener. The accepted connection is not closed.
+func (l *singleConnListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns the local address of the connection.
+func (l *singleConnListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
@@ -0,0 +1,68 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the forward proxy mode. Test will ensure:
+//   - Absolute-URI requests are served by predefined responses keyed by target host and path
+//   - CONNECT tunnels are opened, TLS is terminated with a certificate trusted by the proxy client
+//     and tunneled requests are served and recorded
+//   - Proxied requests are flagged in their record
+func TestForwardProxy(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	require.Nil(t, hts.ProxyCACertPool())
+	require.Nil(t, hts.ProxyCACertificatePEM())
+	require.NoError(t, hts.EnableForwardProxy())
+	require.NoError(t, hts.EnableForwardProxy())
+	require.NotNil(t, hts.ProxyCACertPool())
+	require.Contains(t, string(hts.ProxyCACertificatePEM()), "BEGIN CERTIFICATE")
+	require.Equal(t, hts.GetBaseURL(), hts.ProxyURL().String())
+	hts.When().Host("api.example.com").Get("/users").RespondWith().StringBody("users")
+	hts.When().Host("secure.example.com").Get("/orders").RespondWith().StringBody("orders")
+	client := hts.ProxyClient()
+	get := func(url string) (int, string) {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Absolute-URI request
+	status, body := get("http://api.example.com/users")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "users", body)
+	record := hts.PopServerRecord()
+	require.NotNil(t, record)
+	require.True(t, record.ForwardProxied)
+	require.Equal(t, "api.example.com", record.Request.Host)
+
+	// CONNECT tunnel
+	for i := 0; i < 2; i++ {
+		status, body = get("https://secure.example.com/orders")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "orders", body)
+	}
+	records := hts.FindRecords(ForwardProxied())
+	require.Len(t, records, 2)
+	require.Equal(t, "https://secure.example.com/orders", records[0].Request.URL.String())
+	require.Equal(t, "secure.example.com", records[0].ServerName)
+	require.Equal(t, records[0].ConnectionID, records[1].ConnectionID)
+	status, _ = get("https://secure.example.com/missing")
+	require.Equal(t, http.StatusNotFound, status)
+	hts.ClearServerRecords()
+
+	// Direct request
+	resp, err := hts.Client().Get(hts.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.False(t, hts.PopServerRecord().ForwardProxied)
+}
@@ -2,4 +2,7 @@ module github.com/gbdevw/gosette
 
 go 1.13
 
-require github.com/stretchr/testify v1.8.4
+require (
+	github.com/stretchr/testify v1.8.4
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,230 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// A parsed GraphQL request.
+type GraphQLRequest struct {
+	// The GraphQL document.
+	Query string `json:"query"`
+	// Name of the operation to execute. When the request does not provide it, the name of the
+	// first operation defined in the document is used. Empty for anonymous operations.
+	OperationName string `json:"operationName,omitempty"`
+	// Variables of the operation.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Extensions of the request (ex: persisted queries).
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	// Type of the operation: query, mutation or subscription.
+	OperationType string `json:"-"`
+}
+
+// Parse the recorded request as a GraphQL request. POST requests with a JSON body and GET requests
+// with query, operationName and variables query parameters are supported.
+//
+// An error is returned if the request is not a valid GraphQL request.
+func (record *ServerRecord) GraphQLRequest() (*GraphQLRequest, error) {
+	if record.Request == nil {
+		return nil, fmt.Errorf("record has no request")
+	}
+	return parseGraphQLRequest(record.Request, record.RequestBody.Bytes())
+}
+
+// Build a request matcher which matches GraphQL requests which execute the operation with the
+// provided name.
+func GraphQLOperationMatcher(operationName string) RequestMatcher {
+	return newCriterionMatcher("GraphQL operation", fmt.Sprintf("%q", operationName), func(r *http.Request) string {
+		gql, err := readGraphQLRequest(r)
+		if err != nil {
+			return fmt.Sprintf("error: %s", err)
+		}
+		return fmt.Sprintf("%q", gql.OperationName)
+	}, func(r *http.Request) bool {
+		gql, err := readGraphQLRequest(r)
+		return err == nil && gql.OperationName == operationName
+	})
+}
+
+// Build a request matcher which matches GraphQL requests whose variables include the provided
+// variables. Values are compared once encoded in JSON and decoded: 1, int64(1) and float64(1) are
+// equivalent. Variables which are not provided are not compared.
+func GraphQLVariablesMatcher(variables map[string]interface{}) RequestMatcher {
+	return newCriterionMatcher("GraphQL variables", explainedValues(variables, nil), func(r *http.Request) string {
+		gql, err := readGraphQLRequest(r)
+		if err != nil {
+			return fmt.Sprintf("error: %s", err)
+		}
+		return explainedValues(gql.Variables, nil)
+	}, func(r *http.Request) bool {
+		gql, err := readGraphQLRequest(r)
+		if err != nil {
+			return false
+		}
+		for name, expected := range variables {
+			value, ok := gql.Variables[name]
+			if !ok || !equalJSONValues(value, expected) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Helper function which reads the body of the provided request and parses it as a GraphQL
+// request.
+func readGraphQLRequest(r *http.Request) (*GraphQLRequest, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	return parseGraphQLRequest(r, body)
+}
+
+// Helper function which parses the provided request and body as a GraphQL request.
+func parseGraphQLRequest(r *http.Request, body []byte) (*GraphQLRequest, error) {
+	gql := &GraphQLRequest{}
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		gql.Query = query.Get("query")
+		gql.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &gql.Variables); err != nil {
+				return nil, fmt.Errorf("failed to decode GraphQL variables: %w", err)
+			}
+		}
+		if extensions := query.Get("extensions"); extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &gql.Extensions); err != nil {
+				return nil, fmt.Errorf("failed to decode GraphQL extensions: %w", err)
+			}
+		}
+	case http.MethodPost:
+		if err := json.Unmarshal(body, gql); err != nil {
+			return nil, fmt.Errorf("failed to decode GraphQL request: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported method for a GraphQL request: %s", r.Method)
+	}
+	if gql.Query == "" && gql.Extensions == nil {
+		return nil, fmt.Errorf("not a GraphQL request: missing query")
+	}
+	// Find the executed operation in the document
+	operationType, operationName := findGraphQLOperation(gql.Query, gql.OperationName)
+	gql.OperationType = operationType
+	if gql.OperationName == "" {
+		gql.OperationName = operationName
+	}
+	return gql, nil
+}
+
+// Helper function which returns the type and name of the operation defined in the provided
+// GraphQL document which has the provided name or of the first operation if name is empty.
+// Returns empty strings if no such operation is found.
+func findGraphQLOperation(document string, name string) (string, string) {
+	tokens := graphQLTokens(document)
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			// Anonymous query shorthand
+			if depth == 0 && name == "" && (i == 0 || tokens[i-1] == "}") {
+				return "query", ""
+			}
+			depth++
+		case "}":
+			depth--
+		case "query", "mutation", "subscription":
+			if depth != 0 {
+				continue
+			}
+			opName := ""
+			if i+1 < len(tokens) && isGraphQLName(tokens[i+1]) {
+				opName = tokens[i+1]
+			}
+			if name == "" || name == opName {
+				return token, opName
+			}
+		}
+	}
+	return "", ""
+}
+
+// Helper function which splits the provided GraphQL document in names and punctuators. Comments
+// and strings are skipped.
+func graphQLTokens(document string) []string {
+	tokens := []string{}
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == '#':
+			// Skip comment
+			for i < len(document) && document[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			// Skip string or block string
+			if strings.HasPrefix(document[i:], `"""`) {
+				end := strings.Index(document[i+3:], `"""`)
+				if end < 0 {
+					return tokens
+				}
+				i += end + 6
+				continue
+			}
+			i++
+			for i < len(document) && document[i] != '"' && document[i] != '\n' {
+				if document[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case isGraphQLNameByte(c):
+			start := i
+			for i < len(document) && isGraphQLNameByte(document[i]) {
+				i++
+			}
+			tokens = append(tokens, document[start:i])
+		case c == '{' || c == '}':
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// Helper function which returns true if the provided byte can be used in a GraphQL name.
+func isGraphQLNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Helper function which returns true if the provided token is a GraphQL name.
+func isGraphQLName(token string) bool {
+	return token != "" && isGraphQLNameByte(token[0]) && !(token[0] >= '0' && token[0] <= '9')
+}
+
+// Helper function which returns true if the provided values are equal once encoded in JSON and
+// decoded.
+func equalJSONValues(value interface{}, expected interface{}) bool {
+	var normalizedValue, normalizedExpected interface{}
+	encoded, err := json.Marshal(value)
+	if err != nil || json.Unmarshal(encoded, &normalizedValue) != nil {
+		return false
+	}
+	encoded, err = json.Marshal(expected)
+	if err != nil || json.Unmarshal(encoded, &normalizedExpected) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizedValue, normalizedExpected)
+}
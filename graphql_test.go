@@ -0,0 +1,119 @@
+package gosette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test GraphQL requests parsing.
+func (suite *HTTPTestServerUnitTestSuite) TestGraphQLRequest() {
+	testCases := []struct {
+		name          string
+		request       *http.Request
+		body          string
+		operationType string
+		operationName string
+		variables     map[string]interface{}
+		fails         bool
+	}{
+		{
+			name:          "named query",
+			request:       httptest.NewRequest(http.MethodPost, "/graphql", nil),
+			body:          `{"query": "# comment with query Fake\nquery GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": 1}}`,
+			operationType: "query",
+			operationName: "GetUser",
+			variables:     map[string]interface{}{"id": float64(1)},
+		},
+		{
+			name:          "selected operation",
+			request:       httptest.NewRequest(http.MethodPost, "/graphql", nil),
+			body:          `{"query": "query A { a } mutation B { b(text: \"mutation C\") }", "operationName": "B"}`,
+			operationType: "mutation",
+			operationName: "B",
+		},
+		{
+			name:          "anonymous query",
+			request:       httptest.NewRequest(http.MethodPost, "/graphql", nil),
+			body:          `{"query": "{ users { name } } fragment F on User { name }"}`,
+			operationType: "query",
+		},
+		{
+			name:          "subscription with block string",
+			request:       httptest.NewRequest(http.MethodPost, "/graphql", nil),
+			body:          `{"query": "\"\"\"query Fake\"\"\" subscription OnEvent { event }"}`,
+			operationType: "subscription",
+			operationName: "OnEvent",
+		},
+		{
+			name:          "GET request",
+			request:       httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("query GetUser { user }")+"&variables="+url.QueryEscape(`{"id": "1"}`), nil),
+			operationType: "query",
+			operationName: "GetUser",
+			variables:     map[string]interface{}{"id": "1"},
+		},
+		{name: "invalid JSON", request: httptest.NewRequest(http.MethodPost, "/graphql", nil), body: "{", fails: true},
+		{name: "missing query", request: httptest.NewRequest(http.MethodPost, "/graphql", nil), body: "{}", fails: true},
+		{name: "invalid method", request: httptest.NewRequest(http.MethodPut, "/graphql", nil), body: "{}", fails: true},
+		{name: "invalid variables", request: httptest.NewRequest(http.MethodGet, "/graphql?query=a&variables=x", nil), fails: true},
+		{name: "invalid extensions", request: httptest.NewRequest(http.MethodGet, "/graphql?query=a&extensions=x", nil), fails: true},
+	}
+	for _, tc := range testCases {
+		record := &ServerRecord{Request: tc.request, RequestBody: bytes.NewBufferString(tc.body)}
+		gql, err := record.GraphQLRequest()
+		if tc.fails {
+			require.Error(suite.T(), err, tc.name)
+			continue
+		}
+		require.NoError(suite.T(), err, tc.name)
+		require.Equal(suite.T(), tc.operationType, gql.OperationType, tc.name)
+		require.Equal(suite.T(), tc.operationName, gql.OperationName, tc.name)
+		require.Equal(suite.T(), tc.variables, gql.Variables, tc.name)
+	}
+	_, err := (&ServerRecord{RequestBody: &bytes.Buffer{}}).GraphQLRequest()
+	require.Error(suite.T(), err)
+}
+
+// Test GraphQL matchers and response builder helpers.
+func (suite *HTTPTestServerUnitTestSuite) TestGraphQLMatchers() {
+	client := suite.hts.Client()
+	suite.hts.When().Post("/graphql").GraphQLOperation("GetUser").WithGraphQLVariables(map[string]interface{}{"id": 1}).
+		RespondWith().GraphQLData(map[string]interface{}{"user": map[string]interface{}{"name": "john"}})
+	suite.hts.When().Post("/graphql").GraphQLOperation("GetUser").
+		RespondWith().GraphQLErrors("user not found")
+
+	// Matched by operation and variables
+	query := `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": 1, "other": true}}`
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/graphql", "application/json", strings.NewReader(query))
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.JSONEq(suite.T(), `{"data": {"user": {"name": "john"}}}`, string(body))
+
+	// Matched by operation only
+	query = `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": 2}}`
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/graphql", "application/json", strings.NewReader(query))
+	require.NoError(suite.T(), err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.JSONEq(suite.T(), `{"data": null, "errors": [{"message": "user not found"}]}`, string(body))
+
+	// Not matched
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/graphql", "application/json", strings.NewReader(`{"query": "{ users }"}`))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader("{"))
+	require.False(suite.T(), GraphQLVariablesMatcher(nil).Match(req))
+	req.Body = nil
+	require.False(suite.T(), GraphQLOperationMatcher("").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ a }", "variables": {"a": 1}}`))
+	require.False(suite.T(), GraphQLVariablesMatcher(map[string]interface{}{"a": make(chan int)}).Match(req))
+}
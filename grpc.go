@@ -0,0 +1,175 @@
+package gosette
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A predefined response served to gRPC clients.
+//
+// Messages are provided already serialized so the test server does not depend on a protobuf
+// implementation: use proto.Marshal (or any other codec used by the client) to build them.
+type GRPCResponse struct {
+	// Serialized response messages. Each message is sent in its own gRPC frame. Leave empty for
+	// unary calls which fail or for streaming calls which do not return messages.
+	Messages [][]byte
+	// gRPC status code sent in the grpc-status trailer (ex: 0 for OK, 5 for NOT_FOUND).
+	Code int
+	// Status message sent in the grpc-message trailer. Omitted when empty.
+	Message string
+	// Additional headers (response metadata).
+	Headers http.Header
+	// Additional trailers (trailing metadata).
+	Trailers http.Header
+	// Delay to wait before responding. See PredefinedServerResponse Delay.
+	Delay time.Duration
+	// How many times the response may be served. See PredefinedServerResponse Repeat.
+	Repeat Repetition
+}
+
+// A function used to decode a serialized gRPC request message (ex: a function which uses
+// proto.Unmarshal with a message of the expected type).
+type GRPCDecoder func(message []byte) (interface{}, error)
+
+// Register a predefined response which will be served for gRPC requests which target the provided
+// full method name (ex: /helloworld.Greeter/SayHello or helloworld.Greeter/SayHello).
+//
+// gRPC requires HTTP/2: the test server must be created with WithHTTP2 and started with StartTLS.
+// Registered gRPC responses follow the same rules as the responses registered with
+// RegisterResponse.
+func (hts *HTTPTestServer) RegisterGRPCResponse(fullMethod string, resp *GRPCResponse) {
+	hts.RegisterResponse(GRPCMethodMatcher(fullMethod), resp.predefinedResponse())
+}
+
+// Build a request matcher which matches gRPC requests which target the provided full method name
+// (ex: /helloworld.Greeter/SayHello).
+func GRPCMethodMatcher(fullMethod string) RequestMatcher {
+	path := "/" + strings.TrimPrefix(fullMethod, "/")
+	return newCriterionMatcher("gRPC method", fmt.Sprintf("%q", path), func(r *http.Request) string {
+		return fmt.Sprintf("%q (%s, Content-Type %q)", r.URL.Path, r.Method, r.Header.Get("Content-Type"))
+	}, func(r *http.Request) bool {
+		return r.Method == http.MethodPost && r.URL.Path == path && isGRPCRequest(r)
+	})
+}
+
+// Return the full method name targeted by the recorded gRPC request (ex:
+// /helloworld.Greeter/SayHello). Returns an empty string if the record is not a gRPC request.
+func (record *ServerRecord) GRPCMethod() string {
+	if record.Request == nil || !isGRPCRequest(record.Request) {
+		return ""
+	}
+	return record.Request.URL.Path
+}
+
+// Return the serialized messages sent in the recorded gRPC request. Compressed messages are
+// decompressed with the content coding listed in the grpc-encoding header (see
+// RegisterContentCoding).
+//
+// An error is returned if the request body is not a valid sequence of gRPC frames.
+func (record *ServerRecord) GRPCMessages() ([][]byte, error) {
+	data := record.RequestBody.Bytes()
+	messages := [][]byte{}
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return nil, fmt.Errorf("invalid gRPC frame: incomplete frame header")
+		}
+		compressed, length := data[0], binary.BigEndian.Uint32(data[1:5])
+		if uint32(len(data)-5) < length {
+			return nil, fmt.Errorf("invalid gRPC frame: expected %d bytes, got %d", length, len(data)-5)
+		}
+		message := data[5 : 5+length]
+		data = data[5+length:]
+		if compressed == 1 {
+			encoding := record.Request.Header.Get("Grpc-Encoding")
+			if encoding == "" || encoding == "identity" {
+				return nil, fmt.Errorf("invalid gRPC frame: compressed message without grpc-encoding")
+			}
+			decoded, err := decodeContent(encoding, message)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress gRPC message: %w", err)
+			}
+			message = decoded
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// Decode the messages sent in the recorded gRPC request with the provided decoder.
+//
+// An error is returned if the request body is not a valid sequence of gRPC frames or if a message
+// cannot be decoded.
+func (record *ServerRecord) DecodeGRPCMessages(decode GRPCDecoder) ([]interface{}, error) {
+	messages, err := record.GRPCMessages()
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]interface{}, 0, len(messages))
+	for i, message := range messages {
+		value, err := decode(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gRPC message %d: %w", i, err)
+		}
+		decoded = append(decoded, value)
+	}
+	return decoded, nil
+}
+
+// Helper method which builds the predefined response which serves the gRPC response. gRPC status
+// and trailing metadata are sent as HTTP trailers.
+func (resp *GRPCResponse) predefinedResponse() *PredefinedServerResponse {
+	headers := resp.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	if headers.Get("Content-Type") == "" {
+		headers.Set("Content-Type", "application/grpc")
+	}
+	trailers := resp.Trailers.Clone()
+	if trailers == nil {
+		trailers = http.Header{}
+	}
+	trailers.Set("Grpc-Status", strconv.Itoa(resp.Code))
+	if resp.Message != "" {
+		trailers.Set("Grpc-Message", encodeGRPCMessage(resp.Message))
+	}
+	// Frame messages
+	body := []byte{}
+	for _, message := range resp.Messages {
+		header := make([]byte, 5)
+		binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+		body = append(append(body, header...), message...)
+	}
+	return &PredefinedServerResponse{
+		Status:   http.StatusOK,
+		Headers:  headers,
+		Trailers: trailers,
+		Body:     body,
+		Delay:    resp.Delay,
+		Repeat:   resp.Repeat,
+	}
+}
+
+// Helper function which returns true if the provided request is a gRPC request.
+func isGRPCRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+") || strings.HasPrefix(contentType, "application/grpc;")
+}
+
+// Helper function which percent-encodes the provided gRPC status message.
+func encodeGRPCMessage(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= 0x20 && c <= 0x7E && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function which frames the provided messages as a gRPC request or response body.
+func grpcFrames(compressed bool, messages ...[]byte) []byte {
+	body := []byte{}
+	for _, message := range messages {
+		header := make([]byte, 5)
+		if compressed {
+			header[0] = 1
+		}
+		binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+		body = append(append(body, header...), message...)
+	}
+	return body
+}
+
+// Test the gRPC stubbing layer. Test will ensure:
+//   - Responses are served for gRPC requests which target the registered method
+//   - Messages are framed and status, message and trailers are sent as HTTP/2 trailers
+//   - Recorded request messages can be extracted, decompressed and decoded
+func TestGRPC(t *testing.T) {
+	// Create and start a test server with HTTP/2 enabled
+	srv := NewHTTPTestServer(nil, WithHTTP2())
+	srv.StartTLS()
+	defer srv.Close()
+	srv.RegisterGRPCResponse("helloworld.Greeter/SayHello", &GRPCResponse{
+		Messages: [][]byte{[]byte("hello john")},
+		Headers:  http.Header{"X-Meta": {"header"}},
+		Trailers: http.Header{"x-trailer": {"trailer"}},
+	})
+	srv.RegisterGRPCResponse("/helloworld.Greeter/SayGoodbye", &GRPCResponse{
+		Code:    5,
+		Message: "user not found: 100%",
+	})
+
+	// Unary call
+	encoded, err := encodeContent("gzip", []byte("jane"))
+	require.NoError(t, err)
+	body := append(grpcFrames(false, []byte("john")), grpcFrames(true, encoded)...)
+	req, err := http.NewRequest(http.MethodPost, srv.GetBaseURL()+"/helloworld.Greeter/SayHello", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("Grpc-Encoding", "gzip")
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+	require.Equal(t, "header", resp.Header.Get("X-Meta"))
+	require.Equal(t, grpcFrames(false, []byte("hello john")), respBody)
+	require.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	require.Equal(t, "trailer", resp.Trailer.Get("X-Trailer"))
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Equal(t, "/helloworld.Greeter/SayHello", record.GRPCMethod())
+	messages, err := record.GRPCMessages()
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("john"), []byte("jane")}, messages)
+	decoded, err := record.DecodeGRPCMessages(func(message []byte) (interface{}, error) {
+		return "name=" + string(message), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"name=john", "name=jane"}, decoded)
+	_, err = record.DecodeGRPCMessages(func(message []byte) (interface{}, error) {
+		return nil, fmt.Errorf("PWNED")
+	})
+	require.Error(t, err)
+
+	// Error status
+	req, err = http.NewRequest(http.MethodPost, srv.GetBaseURL()+"/helloworld.Greeter/SayGoodbye", bytes.NewReader(grpcFrames(false)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "5", resp.Trailer.Get("Grpc-Status"))
+	require.Equal(t, "user not found: 100%25", resp.Trailer.Get("Grpc-Message"))
+	srv.PopServerRecord()
+
+	// Not a gRPC request
+	resp, err = srv.Client().Post(srv.GetBaseURL()+"/helloworld.Greeter/SayHello", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	record = srv.PopServerRecord()
+	require.Equal(t, "", record.GRPCMethod())
+
+	// Invalid frames
+	req.Header.Set("Grpc-Encoding", "gzip")
+	for _, invalid := range [][]byte{{0, 0}, {0, 0, 0, 0, 10, 1}, grpcFrames(true, []byte("not gzip"))} {
+		record := &ServerRecord{Request: req, RequestBody: bytes.NewBuffer(invalid)}
+		_, err := record.GRPCMessages()
+		require.Error(t, err)
+		_, err = record.DecodeGRPCMessages(nil)
+		require.Error(t, err)
+	}
+	// Compressed message without encoding
+	req.Header.Del("Grpc-Encoding")
+	_, err = (&ServerRecord{Request: req, RequestBody: bytes.NewBuffer(grpcFrames(true, encoded))}).GRPCMessages()
+	require.Error(t, err)
+}
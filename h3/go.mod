@@ -0,0 +1,11 @@
+module github.com/gbdevw/gosette/h3
+
+go 1.26.0
+
+require (
+	github.com/gbdevw/gosette v0.0.0-00010101000000-000000000000
+	github.com/quic-go/quic-go v0.63.0
+	github.com/stretchr/testify v1.8.4
+)
+
+replace github.com/gbdevw/gosette => ../
@@ -0,0 +1,104 @@
+// Package h3 serves the predefined responses of a gosette test server over HTTP/3 (QUIC).
+//
+// The package is a separate module so the gosette module does not depend on a QUIC
+// implementation: it relies on quic-go (https://github.com/quic-go/quic-go).
+//
+//	testsrv := gosette.NewHTTPTestServer(nil)
+//	testsrv.When().Get("/hello").RespondWith().StringBody("hello")
+//	listener, err := h3.Start(testsrv)
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer listener.Close()
+//	resp, err := listener.Client().Get(listener.GetBaseURL() + "/hello")
+package h3
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gbdevw/gosette"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// A HTTP/3 listener which serves the predefined responses of a test server over QUIC. Requests
+// are recorded by the test server like any other request, with HTTP/3.0 as record Protocol.
+type Listener struct {
+	// The HTTP/3 server.
+	server *http3.Server
+	// The UDP socket the HTTP/3 server listens on.
+	conn net.PacketConn
+	// Certificate pool which trusts the certificate of the listener.
+	roots *x509.CertPool
+	// HTTP/3 transport used by the client returned by Client.
+	transport *http3.Transport
+}
+
+// Start a HTTP/3 listener which serves the provided test server on a random UDP port of the
+// loopback interface. The test server does not need to be started with Start or StartTLS.
+//
+// The listener uses the certificate of the test server when it has been set with
+// gosette.WithCertificate or gosette.WithGeneratedCertificate. Otherwise, a certificate is
+// generated for the test server with gosette.WithGeneratedCertificate. Connection hooks and faults
+// which hijack the connection (see gosette.Fault) are not supported over HTTP/3. An error is
+// returned if the UDP socket cannot be bound.
+func Start(hts *gosette.HTTPTestServer) (*Listener, error) {
+	// Use the certificate of the test server or generate one
+	server := hts.GetUnderlyingHTTPTestServer()
+	if server.TLS == nil || len(server.TLS.Certificates) == 0 {
+		gosette.WithGeneratedCertificate()(hts)
+	}
+	cert := server.TLS.Certificates[0]
+	roots := hts.CACertPool()
+	if roots == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the test server certificate: %w", err)
+		}
+		roots = x509.NewCertPool()
+		roots.AddCert(leaf)
+	}
+	// Bind a UDP socket and serve the test server
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on UDP: %w", err)
+	}
+	listener := &Listener{
+		server: &http3.Server{
+			Handler:   hts,
+			TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		},
+		conn:      conn,
+		roots:     roots,
+		transport: &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+	go listener.server.Serve(conn)
+	return listener, nil
+}
+
+// Return the base URL of the listener of form https://127.0.0.1:port with no trailing slash.
+func (listener *Listener) GetBaseURL() string {
+	return "https://" + listener.conn.LocalAddr().String()
+}
+
+// Return a HTTP/3 client which trusts the certificate of the listener.
+func (listener *Listener) Client() *http.Client {
+	return &http.Client{Transport: listener.transport}
+}
+
+// Return a certificate pool which trusts the certificate of the listener, to configure the HTTP/3
+// client under test.
+func (listener *Listener) CertPool() *x509.CertPool {
+	return listener.roots
+}
+
+// Close the listener and the connections of the client returned by Client.
+func (listener *Listener) Close() error {
+	listener.transport.Close()
+	err := listener.server.Close()
+	listener.conn.Close()
+	return err
+}
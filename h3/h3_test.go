@@ -0,0 +1,39 @@
+package h3
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gbdevw/gosette"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the HTTP/3 listener. Test will ensure:
+//   - Predefined responses are served over HTTP/3
+//   - Requests are recorded with their protocol
+//   - The certificate of the test server is used when set
+func TestStart(t *testing.T) {
+	for _, srv := range []*gosette.HTTPTestServer{
+		gosette.NewHTTPTestServer(nil),
+		gosette.NewHTTPTestServer(nil, gosette.WithGeneratedCertificate()),
+	} {
+		srv.When().Get("/hello").RespondWith().StringBody("hello")
+		listener, err := Start(srv)
+		require.NoError(t, err)
+		resp, err := listener.Client().Get(listener.GetBaseURL() + "/hello")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "hello", string(body))
+		require.Equal(t, 3, resp.ProtoMajor)
+		record := srv.PopServerRecord()
+		require.NotNil(t, record)
+		require.Equal(t, "HTTP/3.0", record.Protocol)
+		require.Equal(t, srv.CACertPool(), listener.CertPool())
+		require.NoError(t, listener.Close())
+		srv.Close()
+	}
+}
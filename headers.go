@@ -0,0 +1,140 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Return the values of the provided request header. The header name is case insensitive: values
+// stored under non-canonical keys (ex: set by assigning the http.Header map directly) are
+// returned too, in the order of the canonical key first.
+func (record *ServerRecord) HeaderValues(name string) []string {
+	if record.Request == nil {
+		return nil
+	}
+	return headerValues(record.Request.Header, name)
+}
+
+// Return the values of the provided header of the recorded response. The header name is case
+// insensitive. See HeaderValues.
+func (record *ServerRecord) ResponseHeaderValues(name string) []string {
+	if record.Response == nil {
+		return nil
+	}
+	return headerValues(record.Response.Result().Header, name)
+}
+
+// Check that the recorded request has at least one value for the provided header which is equal
+// to the provided value.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertHeader(name string, value string) error {
+	return assertValue("header "+name, record.HeaderValues(name), value)
+}
+
+// Check that the recorded request has exactly the provided values for the provided header, in any
+// order. Values sent in a single comma-separated header line are not split.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertHeaderValues(name string, values ...string) error {
+	return assertExactValues("header "+name, record.HeaderValues(name), values)
+}
+
+// Check that the recorded request has at least one value for the provided header which matches
+// the provided regular expression.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertHeaderMatches(name string, pattern *regexp.Regexp) error {
+	return assertValueMatches("header "+name, record.HeaderValues(name), pattern)
+}
+
+// Check that the recorded request does not have the provided header.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertNoHeader(name string) error {
+	return assertNoValue("header "+name, record.HeaderValues(name))
+}
+
+// Check that the recorded response has at least one value for the provided header which is equal
+// to the provided value. See AssertHeader.
+func (record *ServerRecord) AssertResponseHeader(name string, value string) error {
+	return assertValue("response header "+name, record.ResponseHeaderValues(name), value)
+}
+
+// Check that the recorded response has exactly the provided values for the provided header, in
+// any order. See AssertHeaderValues.
+func (record *ServerRecord) AssertResponseHeaderValues(name string, values ...string) error {
+	return assertExactValues("response header "+name, record.ResponseHeaderValues(name), values)
+}
+
+// Check that the recorded response has at least one value for the provided header which matches
+// the provided regular expression. See AssertHeaderMatches.
+func (record *ServerRecord) AssertResponseHeaderMatches(name string, pattern *regexp.Regexp) error {
+	return assertValueMatches("response header "+name, record.ResponseHeaderValues(name), pattern)
+}
+
+// Check that the recorded response does not have the provided header. See AssertNoHeader.
+func (record *ServerRecord) AssertNoResponseHeader(name string) error {
+	return assertNoValue("response header "+name, record.ResponseHeaderValues(name))
+}
+
+// Helper function which returns the values of the provided header, whatever the case of the keys
+// they are stored under.
+func headerValues(header http.Header, name string) []string {
+	canonical := http.CanonicalHeaderKey(name)
+	values := append([]string(nil), header[canonical]...)
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		if key != canonical && strings.EqualFold(key, name) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values = append(values, header[key]...)
+	}
+	return values
+}
+
+// Helper function which checks that one of the provided values is equal to the expected value.
+func assertValue(what string, values []string, expected string) error {
+	for _, value := range values {
+		if value == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %s to be %q, got %s", what, expected, explainedStrings(values))
+}
+
+// Helper function which checks that the provided values are the expected ones, in any order.
+func assertExactValues(what string, values []string, expected []string) error {
+	actual := append([]string(nil), values...)
+	wanted := append([]string(nil), expected...)
+	sort.Strings(actual)
+	sort.Strings(wanted)
+	if strings.Join(actual, "\x00") != strings.Join(wanted, "\x00") || len(actual) != len(wanted) {
+		return fmt.Errorf("expected %s values %s, got %s", what, explainedStrings(expected), explainedStrings(values))
+	}
+	return nil
+}
+
+// Helper function which checks that one of the provided values matches the provided pattern.
+func assertValueMatches(what string, values []string, pattern *regexp.Regexp) error {
+	for _, value := range values {
+		if pattern.MatchString(value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %s to match %q, got %s", what, pattern.String(), explainedStrings(values))
+}
+
+// Helper function which checks that there are no values.
+func assertNoValue(what string, values []string) error {
+	if len(values) > 0 {
+		return fmt.Errorf("expected no %s, got %s", what, explainedStrings(values))
+	}
+	return nil
+}
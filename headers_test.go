@@ -0,0 +1,61 @@
+package gosette
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the header helpers of records. Test will ensure:
+//   - Headers are looked up case insensitively, including values stored under non-canonical keys
+//   - Single values, multiple values in any order, patterns and absence can be checked
+//   - Response headers can be checked the same way
+func (suite *HTTPTestServerUnitTestSuite) TestHeaderAssertions() {
+	suite.hts.When().Get("/users").RespondWith().
+		Header("Cache-Control", "no-cache").
+		Header("Vary", "Accept").
+		Header("Vary", "Accept-Encoding")
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/users", nil)
+	require.NoError(suite.T(), err)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record := suite.hts.PopServerRecord()
+
+	// Request headers
+	require.Equal(suite.T(), []string{"application/json", "text/plain"}, record.HeaderValues("accept"))
+	require.NoError(suite.T(), record.AssertHeader("ACCEPT", "text/plain"))
+	require.EqualError(suite.T(), record.AssertHeader("accept", "text/html"), `expected header accept to be "text/html", got ["application/json" "text/plain"]`)
+	require.NoError(suite.T(), record.AssertHeaderValues("accept", "text/plain", "application/json"))
+	require.EqualError(suite.T(), record.AssertHeaderValues("Accept", "text/plain"), `expected header Accept values ["text/plain"], got ["application/json" "text/plain"]`)
+	require.NoError(suite.T(), record.AssertHeaderMatches("authorization", regexp.MustCompile(`^Bearer \S+$`)))
+	require.EqualError(suite.T(), record.AssertHeaderMatches("X-Missing", regexp.MustCompile(`.+`)), `expected header X-Missing to match ".+", got <none>`)
+	require.NoError(suite.T(), record.AssertNoHeader("x-api-key"))
+	require.EqualError(suite.T(), record.AssertNoHeader("authorization"), `expected no header authorization, got ["Bearer abc.def.ghi"]`)
+
+	// Response headers
+	require.Equal(suite.T(), []string{"Accept", "Accept-Encoding"}, record.ResponseHeaderValues("vary"))
+	require.NoError(suite.T(), record.AssertResponseHeader("cache-control", "no-cache"))
+	require.Error(suite.T(), record.AssertResponseHeader("cache-control", "no-store"))
+	require.NoError(suite.T(), record.AssertResponseHeaderValues("VARY", "Accept-Encoding", "Accept"))
+	require.Error(suite.T(), record.AssertResponseHeaderValues("vary", "Accept"))
+	require.NoError(suite.T(), record.AssertResponseHeaderMatches("vary", regexp.MustCompile(`Encoding$`)))
+	require.Error(suite.T(), record.AssertResponseHeaderMatches("vary", regexp.MustCompile(`^Origin$`)))
+	require.NoError(suite.T(), record.AssertNoResponseHeader("set-cookie"))
+	require.Error(suite.T(), record.AssertNoResponseHeader("cache-control"))
+
+	// Non-canonical keys
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header["x-request-id"] = []string{"lower"}
+	r.Header.Set("X-Request-Id", "canonical")
+	record = &ServerRecord{Request: r}
+	require.Equal(suite.T(), []string{"canonical", "lower"}, record.HeaderValues("X-REQUEST-ID"))
+	require.NoError(suite.T(), record.AssertHeaderValues("x-request-id", "lower", "canonical"))
+	require.Nil(suite.T(), (&ServerRecord{}).HeaderValues("Accept"))
+	require.Nil(suite.T(), (&ServerRecord{}).ResponseHeaderValues("Accept"))
+}
@@ -9,7 +9,15 @@
 //   - Easily add predefined HTTP responses.
 //   - Responses are served in a FIFO fashion until there is only one left: If only one response is
 //     available, it is served indefinitly. The server returns an empty 404 response when no
-//     predefined responses are available.
+//     predefined responses are available. This fallback response can be configured with
+//     SetDefaultResponse.
+//   - Predefined responses can be registered with a RequestMatcher to be served for requests which
+//     match arbitrary predicates (path, method, headers, body, ...). Registered responses are
+//     consulted before any response queue.
+//   - Predefined responses can be bound to a route: a host, a HTTP method and a request path, a
+//     request path only or a HTTP method only. Each route has its own FIFO queue. Route queues are
+//     consulted from the most specific to the least specific one and finally the global queue is
+//     used as fallback, except for paths which have queues bound to other methods only.
 //   - The server records HTTP requests, body and HTTP response in a FIFO fashion. These records can
 //     be extracted from the test server to spy on exchanged requests and responses.
 //   - In case the server encounter an error while processing the request or serving the predefined
@@ -20,24 +28,369 @@
 //   - Pluggable httptest.Server. The server handler will be overriden by the framework. The
 //     underlying httptest.Server is accessible so more experienced users can build more complex
 //     test cases (like shutting down client connections, testing with TLS, ...).
+//   - A fluent builder DSL is available to describe requests and predefined responses:
+//     hts.When().Get("/users").RespondWith().Status(http.StatusOK).JSONBody(users).
+//   - Predefined responses can be delayed to test client timeouts, context deadlines and retry
+//     logic.
+//   - Faults can be injected instead of serving a well-formed response (ex: connection reset, hang,
+//     truncated body) to test how clients handle network errors.
+//   - Predefined responses can declare how many times they may be served (Times(n), Once(),
+//     Forever()) to model retry scenarios precisely. Explicit Sticky and ConsumeOnce flags override
+//     the implicit "last one sticks" behavior.
+//   - A verification API is available to check the recorded requests: hts.Verify().Requests("POST",
+//     "/orders").Count(2).
+//   - WaitForRequests blocks until a given number of requests have been recorded, which helps when
+//     the system under test sends requests asynchronously.
+//   - Middlewares and OnRequest/OnResponse hooks can be registered to run around the stub-serving
+//     logic (auth checks, jitter, logging, ...).
+//   - Records can be searched without being popped by using FindRecords with composable filters
+//     (ByPath, ByMethod, ByHeader, ByStatus, WithServerError, ...).
+//   - Record-and-replay proxy mode: unmatched requests can be proxied to a real upstream, recorded
+//     in a cassette and replayed later as predefined responses without network access.
+//   - LoadOpenAPISpec stubs an API from an OpenAPI 3 document (examples are served as responses)
+//     and can validate incoming requests against it.
+//   - JSON request bodies can be matched, filtered and asserted with JSONPath expressions
+//     (BodyJSONPathMatcher, ByJSONPath, ServerRecord.AssertJSONPath, ...).
+//   - XML request bodies can be matched, filtered and asserted with XPath expressions and namespace
+//     bindings (BodyXPathMatcher, ByXPath, ServerRecord.AssertXPath, ...).
+//   - Multipart request bodies are parsed: records expose the parts (field names, file names,
+//     content types and payload copies) in MultipartParts.
+//   - Response bodies can be compressed (gzip, deflate or any registered content coding like
+//     brotli) by setting ContentEncoding. Records keep the raw body and provide the decoded one.
+//   - Compressed request bodies (Content-Encoding: gzip, deflate, ...) are decoded: records hold
+//     the decoded body in RequestBody and the raw one in RawRequestBody.
+//   - Response bodies can be sent as explicit chunks (Transfer-Encoding: chunked) with a flush and
+//     an optional delay between chunks to test streaming clients.
+//   - Server-sent events (id, event, data, retry, comments and per-event delays) can be streamed
+//     with the text/event-stream framing.
+//   - NewHTTPTestServer accepts options: WithHTTP2 enables HTTP/2 (over TLS) and records keep the
+//     protocol used by each request.
+//   - gRPC stubbing: responses can be registered per full method name with serialized messages,
+//     status codes and trailers. Recorded request messages can be extracted and decoded with a
+//     user-supplied decoder.
+//   - GraphQL requests can be matched by operation name and variables, parsed from records
+//     (GraphQLRequest) and answered with GraphQL data or errors.
+//   - Predefined responses can declare trailers which are announced in the Trailer header and sent
+//     after the body.
+//   - Cookies: predefined responses can set cookies (SetCookies) and records expose the request
+//     cookies (Cookies) with matchers, filters and assertion helpers.
+//   - Basic authentication can be required by a predefined response (RequireBasicAuth) or by the
+//     whole server (BasicAuth middleware). Presented credentials are recorded.
+//   - Digest authentication (RFC 7616) can be required by a predefined response
+//     (RequireDigestAuth) or by the whole server (DigestAuth middleware), with nonce expiry and
+//     replay protection. The outcome is recorded.
+//   - Bearer JWTs can be validated (signature, expiry, issuer, audience) with the ValidateJWT
+//     middleware. Claims are recorded for assertions and invalid tokens can be rejected with a 401
+//     response.
+//   - OpenID Connect provider preset: EnableOIDCProvider serves a discovery document and a JWKS
+//     backed by a generated key and mints signed ID tokens.
+//   - Mutual TLS: StartMTLS requests or requires client certificates verified against a CA pool and
+//     records keep the presented certificate chain.
+//   - TLS certificates: WithCertificate uses a user-provided certificate and
+//     WithGeneratedCertificate generates a CA and a leaf for arbitrary host names. The CA can be
+//     exported for the client under test.
+//   - Virtual hosts: one server can emulate several hosts. Each VirtualHost serves its own
+//     predefined responses selected by the TLS server name (SNI) or the Host header.
+//   - Stop and Restart shut the listener down and later re-bind the same address with the same
+//     protocol, keeping predefined responses and records, to test client reconnection and retries.
+//   - Outage windows: the server can be made unavailable for a duration or for a range of requests
+//     (503 responses or connection resets) and recovers automatically, to test circuit breakers and
+//     health checks.
+//   - Rate limiting: the RateLimit middleware answers requests over the limit with 429 responses
+//     with Retry-After and X-RateLimit-* headers. Throttled requests are recorded.
+//   - Bandwidth throttling: response bodies can be limited to a number of bytes per second, per
+//     predefined response (BytesPerSecond) or server-wide (ThrottleBandwidth middleware), to test
+//     slow downloads.
+//   - Latency jitter: random latencies drawn from seeded uniform, normal or exponential
+//     distributions can be added per predefined response (Jitter) or to all requests (AddLatency
+//     middleware).
+//   - Chaos mode: EnableChaos injects faults (500 responses, connection resets, delays) in a
+//     random, seeded share of the requests. The outcome is recorded for each request.
+//   - Scenarios: predefined responses can belong to a named state machine. They are served only in
+//     a given state and can trigger a transition once served (InScenario, WillSetStateTo,
+//     RegisterScenarioResponse).
+//   - In-memory REST resources: Resource turns a path prefix into a working CRUD backend (POST,
+//     GET, PUT, PATCH, DELETE) whose data store can be inspected and seeded from tests.
+//   - Webhooks: predefined responses can declare outbound HTTP callbacks (templated URL, headers
+//     and body, delay) fired once the response has been served. Deliveries are recorded and can be
+//     awaited.
+//   - File-backed fixtures: response bodies can be read lazily from files (BodyFile,
+//     PushResponseFromFile) with a Content-Type inferred from the file extension.
+//   - Stub catalogs: LoadStubs registers the stubs declared in a directory of YAML or JSON
+//     definition files (request criteria, response, delay, repeat count, fault and scenario).
+//   - Records can be exported as JSON or as a HTTP Archive (ExportRecords, SaveRecords), for
+//     instance to attach the captured traffic of a failing CI test as an artifact.
+//   - Recorded requests can be rendered as equivalent curl commands (ServerRecord.Curl,
+//     CurlCommands), optionally rewritten to another base URL.
+//   - The assertions package provides record assertions with readable diffs: AssertJSONBody,
+//     AssertResponseJSONBody, AssertHeader (exact value or regular expression), AssertQueryParam
+//     and AssertStatus.
+//   - Scopes: Scope gives each test its own predefined responses and records on a shared server,
+//     through a dedicated base URL, so one expensive server can be shared by independent, parallel
+//     tests.
+//   - Requests can be partitioned between scopes by a header (PartitionByHeader) so parallel tests
+//     share the same base URL. Scope transports add the header automatically.
+//   - Metrics: request counts, status codes, bytes in and out and latency percentiles are collected
+//     per route and available with Metrics.
+//   - Trace context: W3C traceparent/tracestate and B3 headers are parsed into the record
+//     TraceContext, with filters and assertions (ByTraceID, AssertSameTrace, AssertDistinctSpans)
+//     to check context propagation.
+//   - Logging: WithLogFunc (or WithLogger with a *slog.Logger) emits one structured entry per
+//     exchange with the method, path, status, latency, error and what has served the response.
+//     Records keep the latter in ServedBy and responses can be named to identify them.
+//   - Mismatch explanations: failed verifications describe which criteria the closest recorded
+//     requests match (expected vs actual method, path, headers, body, ...) and ExplainMismatch
+//     tells why a recorded request has not been served by each stub. Custom matchers can implement
+//     ExplainingMatcher.
+//   - Content negotiation: predefined responses can declare several body variants (JSON, XML,
+//     protobuf, ...) and the one which best matches the request Accept header is served with its
+//     Content-Type and a Vary: Accept header (406 when none is acceptable).
+//   - Conditional requests: predefined responses can declare an ETag and a Last-Modified time. If-
+//     None-Match and If-Modified-Since requests are answered with empty 304 Not Modified responses
+//     to test HTTP caching clients.
+//   - Range requests: predefined responses can accept byte ranges (AcceptRanges). Range requests
+//     are answered with 206 Partial Content (multipart/byteranges for several ranges) or 416
+//     responses, If-Range is supported and records keep the requested ranges.
+//   - CORS: the CORS middleware answers preflight requests and decorates responses with Access-
+//     Control-* headers for the configured origins, methods, headers and max-age. Preflights are
+//     recorded separately (Preflights filter).
+//   - Caching presets: NoStore, MaxAge, StaleWhileRevalidate and a growing Age header, and
+//     versioned resources (Versioned) whose representation, entity tag and modification time change
+//     between requests to validate client-side caches.
+//   - Connection control: predefined responses can close the client connection once written
+//     (CloseConnection) or explicitly keep it alive (KeepAlive). Records keep the connection
+//     identifier and whether the connection has been reused, to test client connection pools.
+//   - Server timeouts: WithReadTimeout, WithReadHeaderTimeout, WithWriteTimeout and WithIdleTimeout
+//     configure the underlying http.Server. Timeouts which fire are recorded (TimeoutEvents) and
+//     mark the affected records (TimedOut).
+//   - Request size limit: WithMaxRequestBodySize answers requests with larger bodies with 413
+//     Payload Too Large responses, before reading the body or mid-stream for chunked uploads.
+//     Records note the truncation (BodyTruncated).
+//   - Protobuf record helpers: recorded application/x-protobuf and gRPC request bodies can be
+//     unmarshaled into typed messages with a registered unmarshaler (ex: proto.Unmarshal).
+//   - NDJSON streaming: responses can stream values as newline-delimited JSON with per-line delays
+//     and flushes to test clients which consume long-lived feeds.
+//   - Scripted sessions: an ordered conversation of expected requests and responses can be
+//     declared. Requests which arrive out of order or do not match the next step fail fast with a
+//     configurable status and are reported by the session.
+//   - Ordered verifications: Verify().InOrder checks that recorded requests occurred in a given
+//     relative order, other requests being allowed in between.
+//   - Strict mode: requests which are not served by a predefined response are reported as test
+//     failures with an explanation, or collected and verified at once, instead of silently getting
+//     a 404.
+//   - Expected request counts: stubs can expect to be served exactly, at least or at most n times.
+//     VerifyAll returns a consolidated report of unmet expectations with the closest recorded
+//     requests, reported when the test server is closed in strict mode.
+//   - Record cap: the number of retained records can be limited with an eviction policy (evict
+//     oldest, drop newest or reject with 507) and a counter of evicted records, to keep long soak
+//     tests bounded in memory.
+//   - Request body spooling: recorded copies of request bodies above a size threshold can be
+//     spooled to temporary files and read back from the record, to test large upload clients
+//     without exhausting memory.
+//   - Bounded response recording: recorded response bodies above a size threshold are truncated to
+//     a prefix while their size and SHA-256 digest are kept, so large download tests do not double
+//     memory usage.
+//   - Not-found diagnostics: 404 responses served to unmatched requests can carry a text or JSON
+//     body which describes the request and how it is evaluated by each registered stub.
+//   - Header assertion helpers: recorded request and response headers can be looked up case
+//     insensitively and checked for a value, an exact set of values in any order, a regular
+//     expression or their absence.
+//   - Query parameter matchers: requests can be matched and records checked on decoded query
+//     parameter values (exact, contains, regular expression, multiple values or presence) instead
+//     of raw URL substrings.
+//   - Path patterns: stubs can match request paths against regular expressions (PathRegexMatcher,
+//     PathMatching) and the captured groups are exposed to response body templates (BodyTemplate).
+//   - Glob paths: stubs can match request paths against glob patterns such as /api/*/items/**
+//     (PathGlobMatcher, PathGlob), a lighter alternative to regular expressions.
+//   - Path templates: stubs can match URI templates such as /users/{id}/orders/{orderId}
+//     (PathTemplateMatcher, PathTemplate); the parameters are recorded on the server record and
+//     exposed to response body templates.
+//   - Priorities: registered responses can be given a priority (Priority,
+//     RegisterResponseWithPriority) so the matching response with the highest priority is served,
+//     ties being resolved by registration order.
+//   - Reproducible randomness: chaos faults, latency distributions and generated test data can draw
+//     from a single seedable random source (WithRandSeed, RandSeed, Rand) so probabilistic tests
+//     can be replayed from a logged seed.
+//   - Injectable clock: delays, Date and Age headers, outage and rate limit windows, token expiries
+//     and webhook delays use a pluggable clock (WithClock); FakeClock tests time-based behaviors
+//     without real sleeps.
+//   - Header templates: response header values can be templates evaluated per request
+//     (HeaderTemplate), for example to echo a request ID or compute a Location from a path
+//     parameter.
+//   - Served callbacks: predefined responses can carry an OnServed callback invoked once the
+//     exchange has been recorded, so tests can synchronize on specific exchanges without polling
+//     records.
+//   - Slow first byte and slow body: responses can wait before the headers (Delay) and, separately,
+//     between the headers and the body (BodyDelay) or between chunks (ChunkDelay).
+//   - Raw responses: arbitrary bytes can be written on the hijacked client connection instead of a
+//     well-formed response (RawResponse) to test how clients react to protocol violations.
+//   - Connection hooks: tests can observe accepted and closed client connections (OnConnAccept,
+//     OnConnClose, OpenConnections) and wrap them at the net.Conn level (WithConnWrapper) to inject
+//     byte-level corruption or force half-closes.
+//   - Forward proxy mode: the test server can act as an HTTP forward proxy (EnableForwardProxy)
+//     which serves absolute-URI requests and CONNECT tunnels, terminating TLS with generated
+//     certificates, so clients configured with HTTP_PROXY can be tested (ProxyClient).
+//   - Passthrough mode: mock only some endpoints and transparently proxy all the other requests to
+//     the real service.
+//   - Stub generation: GenerateStubs turns passthrough traffic into stubs and stub definition files
+//     for offline test runs.
+//   - SOAP requests can be matched by SOAPAction and operation, parsed from records (SOAPEnvelope)
+//     and answered with envelopes, templates or Faults.
+//   - Multipart response bodies (multipart/mixed, multipart/related, ...) can be assembled from
+//     parts with correct boundaries and content types.
+//   - HTTP/2 server push: predefined responses can push resources (Push) and the outcome of each
+//     push is recorded.
+//   - 103 Early Hints interim responses (EarlyHints) can be sent before a predefined response.
+//   - 1xx interim responses (100 Continue, 102 Processing, 103 Early Hints) are passed through to
+//     clients and recorded separately from the final response (InterimResponses).
+//   - HTTP/3: the github.com/gbdevw/gosette/h3 module serves the test server over QUIC (requires
+//     quic-go).
 package gosette
 
 import (
 	"bytes"
+	"context"
+	"crypto/x509"
 	"fmt"
+	"hash"
 	"io"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Data of a predefined server response
 type PredefinedServerResponse struct {
+	// Optional name of the response. The name identifies the response in records and logs (see
+	// ServerRecord ServedBy).
+	Name string
 	// HTTP status code to return
 	Status int
+	// Body template (see text/template) executed with a TemplateData built from the request. When
+	// set, Body is ignored and replaced by the output of the template. A 500 response is served if
+	// the template cannot be executed.
+	BodyTemplate string
 	// Headers to return
 	Headers http.Header
+	// Header templates (see text/template) executed with a TemplateData built from the request.
+	// The output of the templates replaces the values of the headers in Headers. A 500 response is
+	// served if a template cannot be executed.
+	HeaderTemplates http.Header
+	// Basic credentials required to get the response. When set, requests without these credentials
+	// are answered with a 401 response which has a WWW-Authenticate header. The response is
+	// considered as served in both cases.
+	RequireBasicAuth *BasicCredentials
+	// Digest credentials required to get the response (RFC 7616). When set, requests without valid
+	// credentials are answered with a 401 response which has WWW-Authenticate challenges. The
+	// response is considered as served in both cases. The outcome is recorded in the ServerRecord
+	// DigestAuth.
+	RequireDigestAuth *DigestCredentials
+	// Cookies to set with Set-Cookie headers.
+	SetCookies []*http.Cookie
+	// Trailers to return after the body. Trailers are announced in the Trailer header. Over
+	// HTTP/1.1, the response is sent with Transfer-Encoding: chunked.
+	Trailers http.Header
 	// Body to return
 	Body []byte
+	// Representations of the body the served one is selected from based on the request Accept
+	// header (content negotiation). When set, Body is replaced with the body of the selected
+	// variant, the Content-Type header is set with its content type and a Vary: Accept header is
+	// added. The first variant is served to requests without an Accept header and requests which
+	// accept none of the variants are answered with a 406 Not Acceptable response.
+	Variants []BodyVariant
+	// Entity tag of the response (ex: "v1" or W/"v1"), sent in the ETag header. Double quotes are
+	// added when missing. Requests whose If-None-Match header lists the entity tag are answered
+	// with an empty 304 Not Modified response (412 Precondition Failed for methods other than GET
+	// and HEAD).
+	ETag string
+	// Modification time of the response, sent in the Last-Modified header. GET and HEAD requests
+	// whose If-Modified-Since header is not older are answered with an empty 304 Not Modified
+	// response, unless they have an If-None-Match header.
+	LastModified time.Time
+	// When set, an Age header with the number of seconds elapsed since this time is sent, as if
+	// the response was served by a shared cache. See ResponseBuilder Age.
+	AgeOrigin time.Time
+	// When true, byte ranges of the body can be requested with the Range header: GET requests
+	// with a satisfiable Range header are answered with a 206 Partial Content response (with a
+	// multipart/byteranges body for several ranges) and the others with a 416 Range Not
+	// Satisfiable response. The If-Range header is supported. Chunks and events are not served
+	// by range.
+	AcceptRanges bool
+	// How the client connection is handled once the response has been written: closed
+	// (ConnectionClose) or explicitly kept alive (ConnectionKeepAlive). Defaults to
+	// ConnectionDefault.
+	Connection ConnectionMode
+	// Path of a file the body is read from each time the response is served. When set, Body is
+	// ignored and the Content-Type header is inferred from the file extension unless it is set.
+	// Useful to serve large fixtures without embedding them in test files.
+	BodyFile string
+	// Delay to wait before responding. The delay is interrupted if the request context is done.
+	// Useful to test client timeouts, context deadlines and retry logic.
+	Delay time.Duration
+	// Delay to wait between the headers and the body: the headers are sent to the client before
+	// the delay. Useful to test slow bodies (read timeouts) rather than slow first bytes (see
+	// Delay). The Content-Length header is set unless the body is sent as chunks. The delay is
+	// interrupted if the request context is done. Ignored for server-sent events and JSON lines.
+	BodyDelay time.Duration
+	// Distribution of a random latency added to Delay each time the response is served. No
+	// latency is added when nil.
+	Jitter LatencyDistribution
+	// How many times the response may be served. The zero value keeps the default behavior. See
+	// Times, Once and Forever.
+	Repeat Repetition
+	// When true, the response is served indefinitly: it is never popped from its queue, even when
+	// more responses are queued after it. Takes precedence over Repeat and ConsumeOnce.
+	Sticky bool
+	// When true, the response is popped from its queue once it has been served as many times as
+	// allowed by Repeat (once by default), even if it is the last response of the queue. Responses
+	// registered with a request matcher are served once by default instead of indefinitly.
+	ConsumeOnce bool
+	// Fault to inject instead of serving the response. Defaults to FaultNone.
+	Fault Fault
+	// Number of body bytes written before the fault is injected. Used by FaultTruncatedBody.
+	FaultAfterBytes int
+	// Content codings used to compress the body before it is sent (ex: gzip, deflate or
+	// "deflate, gzip"). The Content-Encoding header is set accordingly. See RegisterContentCoding
+	// to use other content codings.
+	ContentEncoding string
+	// Chunks of the body. When set, Body is ignored and the chunks are written one by one with a
+	// flush after each of them: the response is sent with Transfer-Encoding: chunked (HTTP/1.1)
+	// unless a Content-Length header is set. ContentEncoding cannot be used with chunks.
+	Chunks [][]byte
+	// Delay to wait between two chunks. The delay is interrupted if the request context is done.
+	ChunkDelay time.Duration
+	// Server-sent events to stream. When set, Body and Chunks are ignored and the events are
+	// streamed with the text/event-stream framing and a flush after each of them. ContentEncoding
+	// cannot be used with events.
+	Events []ServerSentEvent
+	// JSON lines to stream as newline-delimited JSON (application/x-ndjson). When set, Body and
+	// Chunks are ignored and each line is written with its own delay and a flush after it unless
+	// the line disables it. ContentEncoding cannot be used with JSON lines.
+	JSONLines []JSONLine
+	// Maximum number of body bytes sent per second. The body is written in small slices with a
+	// flush after each of them so it dribbles out slowly. No limit is applied when zero. See
+	// ThrottleBandwidth to limit the bandwidth of all responses.
+	BytesPerSecond int
+	// Outbound HTTP callbacks fired in the background once the response has been served.
+	Webhooks []*Webhook
+	// Function called with the record of the exchange once the response has been written and the
+	// exchange has been recorded, after the OnResponse hooks. Useful to synchronize tests on a
+	// specific exchange without polling records. Nil if no callback is set.
+	OnServed func(record *ServerRecord)
+	// Headers of the 103 Early Hints interim responses sent before the response, one interim
+	// response per header map (ex: Link: </app.css>; rel=preload; as=style). The interim responses
+	// are sent before the delay so clients can preload resources while the response is delayed.
+	EarlyHints []http.Header
+	// Resources pushed with HTTP/2 server push before the response is written. Pushes are only
+	// possible over HTTP/2 and when the client accepts them: the outcome of each push is recorded
+	// in the ServerRecord Pushes.
+	Pushes []*PushedResource
 }
 
 // Data of a server record. The server save in a record each incoming request and the corresponding
@@ -45,6 +398,8 @@ type PredefinedServerResponse struct {
 //
 // In case the test server failed to process
 type ServerRecord struct {
+	// Time at which the request has been received by the test server.
+	ReceivedAt time.Time
 	// The HTTP request received by the test server.
 	//
 	// The body of the request is closed by the test server. Use the RequestBody in the record to
@@ -53,10 +408,113 @@ type ServerRecord struct {
 	// A recorder used to record the HTTP response sent by the test server. Never nil.
 	Response *httptest.ResponseRecorder
 	// A copy of the request body. Will be empty in case request has no body. Never nil.
+	//
+	// In case the request has a Content-Encoding header (ex: gzip), the body is decoded and the
+	// raw body is available in RawRequestBody.
 	RequestBody *bytes.Buffer
+	// A copy of the raw request body in case the request has a Content-Encoding header. Nil
+	// otherwise.
+	RawRequestBody *bytes.Buffer
+	// True if the request body has been spooled to a temporary file because it is larger than the
+	// spooling threshold (see WithRequestBodySpooling). RequestBody then only holds the first bytes
+	// of the body: use OpenRequestBody to read the whole body.
+	BodySpooled bool
+	// Size of the spooled request body in bytes. Zero unless BodySpooled is set.
+	SpooledBodySize int64
+	// True if the recorded response body has been truncated because it is larger than the
+	// recording threshold (see WithResponseRecordingLimit). Response.Body then only holds the first
+	// bytes of the body.
+	ResponseBodyTruncated bool
+	// Number of response body bytes sent by the test server. Only tracked when the recorded
+	// response body is limited (see WithResponseRecordingLimit).
+	ResponseBodySize int64
+	// Digest of the response body. Nil unless the recorded response body is limited.
+	responseBodyHash hash.Hash
+	// Parameters extracted from the request path by the matcher of the served stub (see
+	// PathTemplateMatcher and PathRegexMatcher). Nil if the matcher does not extract parameters.
+	PathParams map[string]string
+	// Path of the temporary file the request body has been spooled to. Empty unless BodySpooled is
+	// set.
+	spoolPath string
 	// This member will be non-nil only in case an error has occured while handling the incoming
 	// request. The member will contain an error which wraps the error that has occured.
 	ServerError error
+	// The parts of the request body in case the request has a multipart content type (ex:
+	// multipart/form-data). Nil otherwise or if the body is malformed: parse errors are reported
+	// in ValidationErrors.
+	MultipartParts []*MultipartPart
+	// The Basic credentials presented by the request. Nil if the request has no Basic credentials.
+	BasicAuth *BasicCredentials
+	// The outcome of the Digest authentication of the request. Only set when Digest credentials
+	// are required (see DigestAuth and PredefinedServerResponse RequireDigestAuth).
+	DigestAuth *DigestAuthResult
+	// The claims of the JWT presented by the request. Only set when the request has been validated
+	// with ValidateJWT and the token could be decoded.
+	JWTClaims map[string]interface{}
+	// The certificate chain presented by the client in case the request has been received over
+	// TLS. Empty if the client has not presented a certificate. See StartMTLS.
+	PeerCertificates []*x509.Certificate
+	// The server name presented by the client with TLS (SNI). Empty if the request has not been
+	// received over TLS or if the client has not presented a server name.
+	ServerName string
+	// The cookies sent with the request.
+	Cookies []*http.Cookie
+	// The protocol used by the request (ex: HTTP/1.1, HTTP/2.0).
+	Protocol string
+	// Failures which have occured while validating the request (see LoadOpenAPISpec, ValidateJWT,
+	// TraceContext and MultipartParts). Empty if the request has not been validated or is valid.
+	ValidationErrors []error
+	// The trace context propagated by the request with the W3C traceparent and tracestate headers
+	// or the B3 headers. Nil if the request does not propagate a trace context. Malformed trace
+	// contexts are reported in ValidationErrors.
+	TraceContext *TraceContext
+	// The byte ranges requested with the Range header. Nil if the request has no Range header or
+	// if the header is malformed.
+	Ranges []ByteRange
+	// True if the request has been rejected with a 413 response because its body is larger than
+	// the configured limit (see WithMaxRequestBodySize). RequestBody then holds the body bytes read
+	// before the rejection only.
+	BodyTruncated bool
+	// True if a timeout of the underlying http.Server has fired while the request body was read or
+	// the response was written (see WithReadTimeout and WithWriteTimeout).
+	TimedOut bool
+	// Identifier of the client connection the request has been received over, starting from 1.
+	// Requests received over the same connection have the same identifier. Zero if the connection
+	// is unknown (ex: the request has not been received by the underlying httptest.Server).
+	ConnectionID int
+	// True if the request is not the first request received over its connection: the client has
+	// reused a pooled connection.
+	ReusedConnection bool
+	// True if the request is a CORS preflight request answered by the CORS middleware (see CORS).
+	Preflight bool
+	// True if the request has been answered with a 429 response by the rate limiter (see
+	// RateLimit).
+	Throttled bool
+	// The fault injected by the chaos mode, ChaosNone if no fault has been injected (see
+	// EnableChaos).
+	ChaosOutcome ChaosOutcome
+	// True if the request has not been served by a predefined response and has got the default
+	// response (see WithStrictMode).
+	Unmatched bool
+	// What has served the response: "outage", "chaos", "session", "stub", "queue", "global queue",
+	// "proxy", "push" or "default response", followed by the quoted response name if any. Stubs
+	// without a name are followed by their registration index (starting from 1), route queues by
+	// their route and session steps by the quoted session name and their step number. Empty if the
+	// response has been written by a middleware.
+	ServedBy string
+	// True if the request has been received as a forward proxy: as an absolute-URI request or
+	// through a CONNECT tunnel (see EnableForwardProxy).
+	ForwardProxied bool
+	// The 1xx interim responses sent before the final response (ex: 100 Continue, 102 Processing,
+	// 103 Early Hints), in the order they have been sent. Response only records the final response.
+	InterimResponses []*InterimResponse
+	// The outcome of the HTTP/2 server pushes declared by the served response (see
+	// PredefinedServerResponse Pushes), in their declaration order.
+	Pushes []*PushResult
+	// True once the record has been added to the record queue.
+	recorded bool
+	// Callback of the served predefined response. Nil if no callback is set.
+	onServed func(record *ServerRecord)
 }
 
 // HTTP test server used to mock real HTTP servers.
@@ -64,14 +522,131 @@ type ServerRecord struct {
 // Predefined responses and recorded requests are voluntary left public to
 // allow users to navigate and manage their data.
 type HTTPTestServer struct {
+	// Last generated client connection identifier. Accessed atomically: kept first for 64-bit
+	// alignment.
+	lastConnectionID int64
 	// Instance of httptest.Server which mocks a real HTTP server and records exchanged data.
 	server *httptest.Server
+	// Mutex used to protect predefined responses and records from concurrent access.
+	mu sync.Mutex
+	// Predefined responses bound to a request matcher. These responses are consulted in their
+	// registration order before any response queue.
+	stubs []*stub
+	// Current state of the scenarios predefined responses belong to, by scenario name. Scenarios
+	// which are not in the map are in the ScenarioStarted state.
+	scenarios map[string]string
+	// Scripted sessions, in their creation order. Sessions are consulted before any other
+	// predefined response.
+	sessions []*Session
 	// Predefined responses. Responses are provided once in a FIFO fashion. If there is only one
 	// response left, this response is served indefinitly. In case no predefined responses are
 	// available, an HTTP response with a 404 status code and an empty body will be returned.
-	responses []*PredefinedServerResponse
+	responses responseQueue
+	// Predefined responses bound to a route: a HTTP method and/or a request path. Each route has
+	// its own queue which follows the same FIFO rules as the global queue. These queues are
+	// consulted before the global queue, from the most specific route to the least specific one.
+	routeResponses map[route]responseQueue
+	// Response served when no predefined responses are available. An empty 404 response is served
+	// when nil.
+	defaultResponse *PredefinedServerResponse
+	// Upstream unmatched requests are proxied to when the proxy or passthrough mode is enabled. Nil
+	// otherwise.
+	upstream *url.URL
+	// Client used to proxy requests to the upstream.
+	upstreamClient *http.Client
+	// Interactions with the upstream recorded in proxy or passthrough mode. Nil otherwise.
+	cassette *Cassette
+	// Responses promised by HTTP/2 server pushes which have not been served yet, per method and
+	// request URI.
+	promised map[string][]*PredefinedServerResponse
 	// Recorded requests and responses. Records are appended to the queue in a FIFO fashion.
 	records []*ServerRecord
+	// Metrics collected per route: a HTTP method and a request path.
+	metrics map[route]*RouteMetrics
+	// Metrics collected for all routes together.
+	totalMetrics *RouteMetrics
+	// Middlewares which wrap the stub-serving logic.
+	middlewares []Middleware
+	// Hooks called for each incoming request before the middlewares.
+	onRequestHooks []func(r *http.Request)
+	// Hooks called each time a record is added to the record queue.
+	onResponseHooks []func(record *ServerRecord)
+	// Hooks called each time a client connection is accepted.
+	onConnAcceptHooks []func(c net.Conn)
+	// Hooks called each time a client connection is closed or hijacked.
+	onConnCloseHooks []func(c net.Conn)
+	// Number of client connections which are currently open.
+	openConnections int
+	// Functions which wrap accepted client connections. Only modified by options.
+	connWrappers []func(c net.Conn) net.Conn
+	// Maximum size of request bodies in bytes. Request bodies are not limited when zero.
+	maxRequestBodySize int64
+	// Function called with a log entry once each exchange is over. Nil if no log function is set.
+	logFunc func(entry LogEntry)
+	// Size above which request bodies are spooled to a temporary file. Request bodies are never
+	// spooled when zero.
+	spoolThreshold int64
+	// Directory temporary files are created in. The default directory for temporary files when
+	// empty.
+	spoolDir string
+	// Temporary files request bodies have been spooled to.
+	spoolFiles []string
+	// Size above which recorded response bodies are truncated. Recorded response bodies are not
+	// limited when zero.
+	responseRecordingThreshold int64
+	// Number of bytes kept in truncated recorded response bodies.
+	responseRecordingPrefix int
+	// Format of the diagnostic body of the 404 responses served to unmatched requests.
+	notFoundDiagnostics DiagnosticsFormat
+	// Maximum number of retained records. The number of records is not limited when lower than 1.
+	maxRecords int
+	// Policy applied when a record must be added while the record queue is full.
+	evictionPolicy EvictionPolicy
+	// Number of records dropped because the record queue was full.
+	evictedRecords int
+	// Test unmatched requests are reported to in strict mode. Nil if the strict mode is disabled.
+	strictT TestingT
+	// Scheduled outage windows during which the test server is unavailable.
+	outages []*outage
+	// Scopes which share the listener of the test server, by id.
+	scopes map[string]*Scope
+	// Last generated scope id.
+	lastScopeID int
+	// Header requests are partitioned by. Requests are not partitioned by a header when empty.
+	scopeHeader string
+	// The scope this test server serves the requests of. Nil unless the test server is the one of
+	// a scope: its base URL, client and underlying server are then the ones of the scope.
+	scope *Scope
+	// State of the chaos mode. Nil when the chaos mode is disabled.
+	chaos *chaos
+	// Clock used by the time-based behaviors of the test server.
+	clock Clock
+	// Seed of the random source of the test server.
+	randSeed int64
+	// Random source of the test server. Safe for concurrent use.
+	rnd *rand.Rand
+	// Timeouts which have fired on client connections.
+	timeoutEvents []TimeoutEvent
+	// Channel closed when the test server is closed. Used to release hanging handlers.
+	closing chan struct{}
+	// Client used to send webhooks.
+	webhookClient *http.Client
+	// Deliveries of the webhooks fired by the test server.
+	webhookDeliveries []*WebhookDelivery
+	// Channel closed and replaced each time a webhook delivery completes. Used to wake up
+	// goroutines which wait for webhooks.
+	webhookDelivered chan struct{}
+	// Channel closed and replaced each time a record is added. Used to wake up goroutines which
+	// wait for records.
+	recordAdded chan struct{}
+	// Address and protocol of the test server when it has been stopped with Stop. Used by Restart.
+	stoppedAddr string
+	stoppedTLS  bool
+	// CA certificate which has signed the server certificate when it has been generated (see
+	// WithGeneratedCertificate). Nil otherwise.
+	caCertificate *x509.Certificate
+	// State of the forward proxy mode. Nil when the forward proxy mode is disabled.
+	forwardProxy *forwardProxy
 }
 
 // The test server handler which records incoming requests, request body and outgoing responses.
@@ -79,29 +654,93 @@ type HTTPTestServer struct {
 // Predefined responses are served once in a FIFO fashion. When there is only one response left in
 // predefined response the queue, this response is served indefinitly. When no responses are
 // available, the test server replies with an empty 404 response.
+//
+// The test server can be mounted on other servers as a regular http.Handler, for instance to
+// serve predefined responses over HTTP/3 with a third party QUIC implementation.
 func (srv *HTTPTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
+	// Let the scope serve the request in case the request targets a scope
+	if scope, scoped := srv.routeToScope(r); scope != nil {
+		scope.ServeHTTP(w, scoped)
+		return
+	}
+
+	// Open a tunnel for CONNECT requests if the forward proxy mode is enabled
+	if proxy := srv.getForwardProxy(); proxy != nil && r.Method == http.MethodConnect {
+		srv.serveTunnel(w, r, proxy)
+		return
+	}
+
 	// Prepare response recorder and server record
 	responseRecorder := httptest.NewRecorder()
 	serverRecord := &ServerRecord{
+		ReceivedAt:  srv.clock.Now(),
 		Request:     r,
 		Response:    responseRecorder,
 		RequestBody: &bytes.Buffer{},
 		ServerError: nil,
+		Protocol:    r.Proto,
+		Cookies:     r.Cookies(),
+		BasicAuth:   presentedBasicCredentials(r),
+		Ranges:      parseRange(r.Header.Get("Range")),
+	}
+	recordConnection(r, serverRecord)
+	serverRecord.ForwardProxied = srv.isForwardProxied(r)
+	if r.TLS != nil {
+		serverRecord.PeerCertificates = r.TLS.PeerCertificates
+		serverRecord.ServerName = r.TLS.ServerName
+	}
+	if tc, err := parseTraceContext(r); err != nil {
+		serverRecord.ValidationErrors = append(serverRecord.ValidationErrors, err)
+	} else {
+		serverRecord.TraceContext = tc
 	}
 
 	// Create a multi target ResponseWriter to write response to both the recorder and the client
 	// connection. Put the recorder as first so it will always record the response even in case
 	// the server fails to write the response to the client connection.
-	mw := newMultiTargetHTTPResponseWriter(responseRecorder, w)
+	mw := newMultiTargetHTTPResponseWriter(srv.responseRecorder(serverRecord), w)
+	mw.record = serverRecord
+
+	// Reject the request if the record queue is full and the eviction policy rejects new records
+	if srv.rejectsNewRecords() {
+		srv.rejectFullRecordQueue(mw, serverRecord)
+		return
+	}
 
-	// Create a TeeReader to spy on body when it will be read.
-	r.Body = io.NopCloser(io.TeeReader(r.Body, serverRecord.RequestBody))
+	// Reject the request if its body is larger than the configured limit
+	if srv.declaresTooLargeBody(r) {
+		srv.rejectTooLargeBody(mw, serverRecord)
+		return
+	}
+	srv.limitRequestBody(w, r)
+
+	// Create a TeeReader to spy on body when it will be read. Large bodies may be spooled to a
+	// temporary file which is closed once the request has been served.
+	bodyWriter := srv.requestBodyWriter(serverRecord)
+	if closer, ok := bodyWriter.(io.Closer); ok {
+		defer closer.Close()
+	}
+	r.Body = io.NopCloser(io.TeeReader(r.Body, bodyWriter))
+
+	// Record the 100 Continue interim response the server sends once the body is read if the
+	// client expects it
+	if expectsContinue(r) {
+		serverRecord.InterimResponses = append(serverRecord.InterimResponses, &InterimResponse{
+			Status: http.StatusContinue,
+			Header: http.Header{},
+		})
+	}
 
 	// Copy body if any and if content-type is not application/x-www-form-urlencoded
 	if r.Body != nil && r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
-		// Read body, tee reader will automatically copy data to buffer
-		_, err := io.ReadAll(r.Body)
+		// Read body, tee reader will automatically copy data to buffer. The body is discarded once
+		// copied so spooled bodies are not buffered in memory.
+		_, err := io.Copy(io.Discard, r.Body)
+		if isBodyTooLarge(err) {
+			srv.rejectTooLargeBody(mw, serverRecord)
+			return
+		}
 		if err != nil {
 			// Create an error which wraps the error that has occured
 			werr := fmt.Errorf("test server failed to read the request body: %w", err)
@@ -112,8 +751,40 @@ func (srv *HTTPTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Decode the request body in case it has been compressed by the client
+	if contentEncoding := r.Header.Get("Content-Encoding"); contentEncoding != "" {
+		// Read remaining body if any, tee reader will automatically copy data to buffer
+		_, err := io.Copy(io.Discard, r.Body)
+		if isBodyTooLarge(err) {
+			srv.rejectTooLargeBody(mw, serverRecord)
+			return
+		}
+		var decoded []byte
+		if err == nil && !serverRecord.BodySpooled {
+			decoded, err = decodeContent(contentEncoding, serverRecord.RequestBody.Bytes())
+		}
+		if err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to decode the request body: %w", err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(mw, serverRecord, werr)
+			// Exit
+			return
+		}
+		// Keep the raw body and provide the form parser with the decoded body. Spooled bodies are
+		// kept as received.
+		if !serverRecord.BodySpooled {
+			serverRecord.RawRequestBody, serverRecord.RequestBody = serverRecord.RequestBody, bytes.NewBuffer(decoded)
+			r.Body = io.NopCloser(bytes.NewReader(decoded))
+		}
+	}
+
 	// Parse request query string and body in case content-type is application/x-www-form-urlencoded
 	err := r.ParseForm()
+	if isBodyTooLarge(err) {
+		srv.rejectTooLargeBody(mw, serverRecord)
+		return
+	}
 	if err != nil {
 		// Create an error which wraps the error that has occured
 		werr := fmt.Errorf("test server failed to parse query string and form data: %w", err)
@@ -123,47 +794,400 @@ func (srv *HTTPTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build default response
-	response := &PredefinedServerResponse{
-		Status: http.StatusNotFound,
+	// Parse the parts of the request body in case content-type is a multipart content type. Spooled
+	// bodies are not parsed. Malformed multipart bodies are reported in ValidationErrors and the
+	// request is served anyway.
+	if !serverRecord.BodySpooled {
+		parts, err := parseMultipartParts(r.Header.Get("Content-Type"), serverRecord.RequestBody.Bytes())
+		if err != nil {
+			serverRecord.ValidationErrors = append(serverRecord.ValidationErrors, fmt.Errorf("malformed multipart body: %w", err))
+		} else {
+			serverRecord.MultipartParts = parts
+		}
 	}
 
-	// Get first predefined response in the queue if any
-	if len(srv.responses) >= 1 {
-		response = srv.responses[0]
+	// Add the server record once the request has been served, even if a handler panics. This has
+	// no effect if the record has already been added.
+	defer srv.addServerRecord(serverRecord)
+
+	// Provide middlewares and handlers with a fresh copy of the request body
+	r.Body = io.NopCloser(bytes.NewReader(serverRecord.RequestBody.Bytes()))
+
+	// Make the server record available to middlewares and handlers
+	ctx := context.WithValue(r.Context(), recordContextKey{}, serverRecord)
+	r = r.WithContext(context.WithValue(ctx, clockContextKey{}, srv.clock))
+
+	// Call the OnRequest hooks
+	for _, hook := range srv.getOnRequestHooks() {
+		hook(r)
 	}
 
-	// If there are other predefined responses in the queue, pop the used response
-	// Keep otherwise
-	if len(srv.responses) > 1 {
-		srv.responses = srv.responses[1:]
+	// Serve the predefined response through the middleware chain
+	handler := http.Handler(http.HandlerFunc(func(cw http.ResponseWriter, cr *http.Request) {
+		srv.servePredefinedResponse(cw, w, cr, serverRecord)
+	}))
+	middlewares := srv.getMiddlewares()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
 	}
+	handler.ServeHTTP(mw, r)
+}
 
-	// Write response headers
-	for header, values := range response.Headers {
-		for _, value := range values {
-			mw.headersAdd(header, value)
+// Key used to store the server record of an exchange in the request context.
+type recordContextKey struct{}
+
+// Helper function which returns the server record stored in the provided context. Returns nil if
+// the context does not contain a server record.
+func recordFromContext(ctx context.Context) *ServerRecord {
+	record, _ := ctx.Value(recordContextKey{}).(*ServerRecord)
+	return record
+}
+
+// Helper method which selects and serves the predefined response for the provided request. This
+// is the innermost handler of the middleware chain.
+//
+// The provided http.ResponseWriter is the writer provided by the middleware chain which must
+// write to both the client connection and the server record. The provided conn writer must write
+// to the client connection only: it is used to inject faults.
+func (srv *HTTPTestServer) servePredefinedResponse(w http.ResponseWriter, conn http.ResponseWriter, r *http.Request, serverRecord *ServerRecord) {
+	// Serve the promised response if the request has been pushed
+	if response := srv.nextPromisedResponse(r); response != nil {
+		serverRecord.ServedBy = servedBy("push", response, "")
+		srv.writePredefinedResponse(w, conn, r, response, serverRecord)
+		return
+	}
+
+	// Serve the outage response instead of any predefined response during an outage
+	if response := srv.nextOutageResponse(srv.clock.Now()); response != nil {
+		serverRecord.ServedBy = "outage"
+		srv.writePredefinedResponse(w, conn, r, response, serverRecord)
+		return
+	}
+
+	// Inject a fault at random if the chaos mode is enabled
+	outcome, delay := srv.nextChaosOutcome()
+	serverRecord.ChaosOutcome = outcome
+	if response := chaosResponse(outcome); response != nil {
+		serverRecord.ServedBy = "chaos"
+		srv.writePredefinedResponse(w, conn, r, response, serverRecord)
+		return
+	}
+	if outcome == ChaosDelay {
+		sleep(r.Context(), delay)
+	}
+
+	// Get the predefined response to serve
+	response, source := srv.nextPredefinedServerResponse(r, serverRecord.RequestBody.Bytes())
+	serverRecord.ServedBy = source
+	if response == nil {
+		// Proxy the request to the upstream if the proxy mode is enabled
+		if upstream := srv.getUpstream(); upstream != nil {
+			serverRecord.ServedBy = "proxy"
+			srv.proxy(w, r, serverRecord, upstream)
+			return
 		}
+		// Use the default response otherwise
+		response = srv.getDefaultResponse(serverRecord)
+		serverRecord.ServedBy = servedBy("default response", response, "")
+		serverRecord.Unmatched = true
+		srv.reportUnmatched(serverRecord)
 	}
 
-	// Write status code
-	mw.WriteHeader(response.Status)
+	// Serve the selected predefined response
+	srv.writePredefinedResponse(w, conn, r, response, serverRecord)
+}
+
+// Helper method which serves the provided predefined response: auth check, compression, delay,
+// fault injection and finally the response itself.
+//
+// The provided http.ResponseWriter must write to both the client connection and the server record
+// while the provided conn writer must write to the client connection only.
+func (srv *HTTPTestServer) writePredefinedResponse(w http.ResponseWriter, conn http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// Fire webhooks once the response has been served
+	defer srv.fireWebhooks(response, serverRecord)
+
+	// Call the response callback once the exchange has been recorded
+	serverRecord.onServed = response.OnServed
+
+	// Read the body from its file if requested
+	if response.BodyFile != "" {
+		loaded, err := loadBodyFile(response)
+		if err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to load the predefined response body: %w", err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		response = loaded
+	}
+
+	// Execute the body and header templates if any
+	if response.BodyTemplate != "" || len(response.HeaderTemplates) > 0 {
+		templated, err := executeResponseTemplates(response, serverRecord)
+		if err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to execute the templates of the predefined response: %w", err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		response = templated
+	}
+
+	// Reply with a 401 response if the required credentials are not presented
+	if response.RequireBasicAuth != nil && !response.RequireBasicAuth.check(r) {
+		response = response.RequireBasicAuth.unauthorized()
+	}
+	if response.RequireDigestAuth != nil {
+		serverRecord.DigestAuth = response.RequireDigestAuth.check(r)
+		if !serverRecord.DigestAuth.Authenticated {
+			challenge, err := response.RequireDigestAuth.unauthorized(r, serverRecord.DigestAuth.Stale)
+			if err != nil {
+				// Create an error which wraps the error that has occured
+				werr := fmt.Errorf("test server failed to challenge the client: %w", err)
+				// Handle the error and return a 500 response
+				srv.handleInternalError(w, serverRecord, werr)
+				// Exit
+				return
+			}
+			response = challenge
+		}
+	}
+
+	// Select the body variant acceptable by the client if any
+	if len(response.Variants) > 0 {
+		response = negotiateResponse(r, response)
+	}
+
+	// Evaluate the conditional headers if the response has validators
+	if response.ETag != "" || !response.LastModified.IsZero() {
+		response = conditionalResponse(r, response)
+	}
+
+	// Set the age of the response if requested
+	if !response.AgeOrigin.IsZero() {
+		aged := *response
+		aged.Headers = response.Headers.Clone()
+		if aged.Headers == nil {
+			aged.Headers = http.Header{}
+		}
+		aged.Headers.Set("Age", strconv.Itoa(int(srv.clock.Now().Sub(response.AgeOrigin)/time.Second)))
+		response = &aged
+	}
+
+	// Serve the requested byte ranges if the response accepts ranges
+	if response.AcceptRanges && len(response.Chunks) == 0 && len(response.Events) == 0 && len(response.JSONLines) == 0 {
+		response = rangeResponse(r, response)
+	}
+
+	// Compress the body if requested
+	if response.ContentEncoding != "" {
+		encoded, err := encodeResponse(response)
+		if err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to encode the predefined response: %w", err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		response = encoded
+	}
+
+	// Close the connection or keep it alive if requested
+	if response.Connection != ConnectionDefault {
+		response = connectionResponse(response)
+	}
+
+	// Send the early hints before waiting for the delay
+	if len(response.EarlyHints) > 0 {
+		writeEarlyHints(w, response.EarlyHints)
+	}
+
+	// Add a random latency to the delay if requested
+	if response.Jitter != nil {
+		jittered := *response
+		jittered.Delay += response.Jitter.Sample()
+		response = &jittered
+	}
+
+	// Wait before responding if a delay is set
+	if response.Delay > 0 {
+		sleep(r.Context(), response.Delay)
+	}
+
+	// Limit the bandwidth if requested
+	if response.BytesPerSecond > 0 {
+		w = newThrottledResponseWriter(w, r.Context(), response.BytesPerSecond)
+	}
+
+	// Set the Date header from the clock of the test server unless it is set
+	if response.Headers.Get("Date") == "" {
+		w.Header().Set("Date", srv.clock.Now().UTC().Format(http.TimeFormat))
+	}
+
+	// Push the declared resources before the response which references them
+	if len(response.Pushes) > 0 {
+		srv.push(conn, response, serverRecord)
+	}
+
+	// Inject a fault instead of writing the response if requested
+	if response.Fault != FaultNone {
+		srv.injectFault(w, conn, r, response, serverRecord)
+		return
+	}
+
+	// Stream server-sent events if any
+	if len(response.Events) > 0 {
+		srv.writeEvents(w, r, response, serverRecord)
+		return
+	}
+
+	// Stream JSON lines if any
+	if len(response.JSONLines) > 0 {
+		srv.writeJSONLines(w, r, response, serverRecord)
+		return
+	}
+
+	// Announce the body length if the body is delayed: headers are flushed before the body
+	if response.BodyDelay > 0 && len(response.Chunks) == 0 && response.Headers.Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(response.Body)))
+	}
+
+	// Write response headers and status code
+	writeHeaders(w, response)
+
+	// Flush the headers and wait before writing the body if requested
+	if response.BodyDelay > 0 {
+		flush(w)
+		if sleep(r.Context(), response.BodyDelay) != nil {
+			return
+		}
+	}
+
+	// Write chunks if any
+	if len(response.Chunks) > 0 {
+		srv.writeChunks(w, r, response, serverRecord)
+		return
+	}
 
 	// Write body if any
 	if len(response.Body) > 0 {
-		_, err := mw.Write(response.Body)
+		_, err := w.Write(response.Body)
 		if err != nil {
 			// Create an error which wraps the error that has occured
 			werr := fmt.Errorf("test server failed to write the predefined response: %w", err)
 			// Handle the error and return a 500 response
-			srv.handleInternalError(mw, serverRecord, werr)
+			srv.handleInternalError(w, serverRecord, werr)
 			// Exit
 			return
 		}
 	}
+}
 
-	// Success - Add the server record and exit
-	srv.records = append(srv.records, serverRecord)
+// Helper method which selects the predefined response to serve for the provided request. The
+// provided body is a copy of the request body which is made available to request matchers.
+//
+// Predefined responses are looked up in the following order:
+//  1. the next step of the first session which is not over
+//  2. the responses registered with a request matcher (stubs)
+//  3. the queue bound to the request host
+//  4. the queue bound to the request method and path
+//  5. the queue bound to the request path
+//  6. the queue bound to the request method
+//  7. the global queue, unless queues are bound to the request path with other methods only
+//
+// Returns nil when no predefined responses are available. The returned description tells what has
+// served the response (see ServerRecord ServedBy).
+func (srv *HTTPTestServer) nextPredefinedServerResponse(r *http.Request, body []byte) (*PredefinedServerResponse, string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	// Use the next step of the first session which is not over if any
+	if response, source := srv.sessionResponse(r, body); response != nil {
+		return response, source
+	}
+	// Use the first registered response whose matcher matches the request if any
+	if s := srv.matchStub(r, body); s != nil {
+		recordPathParams(s.matcher, r)
+		return s.response, servedBy("stub", s.response, "#"+strconv.Itoa(srv.stubIndex(s)))
+	}
+	// Use the most specific route queue if any
+	routes := []route{
+		{host: strings.ToLower(requestHostname(r))},
+		{method: r.Method, path: r.URL.Path},
+		{path: r.URL.Path},
+		{method: r.Method},
+	}
+	for _, rt := range routes {
+		if queue := srv.routeResponses[rt]; len(queue) > 0 {
+			response := queue.next()
+			srv.routeResponses[rt] = queue
+			return response, servedBy("queue", response, rt.String())
+		}
+	}
+	// Do not use the global queue when the request path has queues bound to other methods only:
+	// the request targets an endpoint which has not been set up for the request method
+	for rt, queue := range srv.routeResponses {
+		if rt.host == "" && rt.method != "" && rt.path == r.URL.Path && len(queue) > 0 {
+			return nil, ""
+		}
+	}
+	// Use the global queue if any
+	if len(srv.responses) > 0 {
+		response := srv.responses.next()
+		return response, servedBy("global queue", response, "")
+	}
+	return nil, ""
+}
+
+// Helper method which returns the response served to the recorded request when no predefined
+// responses are available: the configured default response if any or a 404 response, with a
+// diagnostic body if configured (see WithNotFoundDiagnostics).
+func (srv *HTTPTestServer) getDefaultResponse(record *ServerRecord) *PredefinedServerResponse {
+	srv.mu.Lock()
+	defaultResponse := srv.defaultResponse
+	srv.mu.Unlock()
+	// Use the configured default response if any
+	if defaultResponse != nil {
+		return defaultResponse
+	}
+	// Build default response
+	return srv.notFoundResponse(record)
+}
+
+// Helper method which adds a server record to the record queue and calls the OnResponse hooks.
+// The method has no effect if the record has already been added.
+func (srv *HTTPTestServer) addServerRecord(serverRecord *ServerRecord) {
+	srv.mu.Lock()
+	if serverRecord.recorded {
+		srv.mu.Unlock()
+		return
+	}
+	serverRecord.recorded = true
+	srv.retainRecord(serverRecord)
+	srv.collectMetrics(serverRecord)
+	// Wake up goroutines which wait for records
+	close(srv.recordAdded)
+	srv.recordAdded = make(chan struct{})
+	hooks := srv.onResponseHooks
+	logFunc := srv.logFunc
+	clock := srv.clock
+	srv.mu.Unlock()
+	// Call the OnResponse hooks
+	for _, hook := range hooks {
+		hook(serverRecord)
+	}
+	// Emit the log entry of the exchange
+	if logFunc != nil {
+		logFunc(newLogEntry(serverRecord, clock.Now()))
+	}
+	// Call the callback of the served predefined response
+	if serverRecord.onServed != nil {
+		serverRecord.onServed(serverRecord)
+	}
 }
 
 // # Description
@@ -175,19 +1199,51 @@ func (srv *HTTPTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //
 //   - server: The underlying httptest.Server to be used by the HTTPTestServer. In case it is nil,
 //     a new unstarted httptest.Server with default settings will be created.
-func NewHTTPTestServer(server *httptest.Server) *HTTPTestServer {
+//   - options: Options used to configure the HTTPTestServer (ex: WithHTTP2).
+func NewHTTPTestServer(server *httptest.Server, options ...ServerOption) *HTTPTestServer {
 	// Use a default httptest server if nil is provided
 	if server == nil {
 		server = httptest.NewUnstartedServer(nil)
 	}
 	// Create HTTPTestServer to return.
 	r := &HTTPTestServer{
-		server:    server,
-		responses: []*PredefinedServerResponse{},
-		records:   []*ServerRecord{},
+		server:          server,
+		stubs:           []*stub{},
+		sessions:        []*Session{},
+		scenarios:       map[string]string{},
+		responses:       responseQueue{},
+		routeResponses:  map[route]responseQueue{},
+		records:         []*ServerRecord{},
+		metrics:         map[route]*RouteMetrics{},
+		totalMetrics:    newRouteMetrics("", ""),
+		middlewares:     []Middleware{},
+		onRequestHooks:  []func(r *http.Request){},
+		onResponseHooks: []func(record *ServerRecord){},
+		upstreamClient: &http.Client{
+			// Do not follow redirects: they are passed through to the client
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		webhookClient:     &http.Client{Timeout: 30 * time.Second},
+		webhookDeliveries: []*WebhookDelivery{},
+		webhookDelivered:  make(chan struct{}),
+		closing:           make(chan struct{}),
+		recordAdded:       make(chan struct{}),
 	}
-	// Use the HTTPTestServer
+	// Use the wall clock unless a clock is provided
+	r.clock = realClock{}
+	// Seed the random source with the current time unless a seed is provided
+	r.randSeed = time.Now().UnixNano()
+	r.rnd = newLockedRand(r.randSeed)
+	// Use the HTTPTestServer and track client connections
 	server.Config.Handler = r
+	server.Config.ConnContext = r.trackConnections(server.Config.ConnContext)
+	server.Config.ConnState = r.watchConnStates(server.Config.ConnState)
+	// Apply options
+	for _, option := range options {
+		option(r)
+	}
 	return r
 }
 
@@ -201,33 +1257,206 @@ func (hts *HTTPTestServer) StartTLS() {
 	hts.server.StartTLS()
 }
 
-// Close the http test server
+// Close the http test server. Handlers which are hanging (see FaultHang) are released and the
+// temporary files request bodies have been spooled to are removed. In strict mode (see
+// WithStrictMode), unmet expectations are reported to the test (see VerifyAll).
 func (hts *HTTPTestServer) Close() {
-	hts.server.Close()
+	hts.close()
+	hts.removeSpoolFiles()
+	hts.reportUnmetExpectations()
+}
+
+// Helper method which closes the http test server and releases the handlers which are hanging.
+func (hts *HTTPTestServer) close() {
+	hts.mu.Lock()
+	select {
+	case <-hts.closing:
+	default:
+		close(hts.closing)
+	}
+	server := hts.server
+	hts.mu.Unlock()
+	// The server of a scope has no listener
+	if server.Listener != nil {
+		server.Close()
+	}
+}
+
+// Stop the test server: the listener and the client connections are closed and handlers which
+// are hanging (see FaultHang) are released. Predefined responses, records and middlewares are
+// kept. Use Restart to start the test server again on the same address.
+func (hts *HTTPTestServer) Stop() {
+	hts.mu.Lock()
+	hts.stoppedAddr = hts.server.Listener.Addr().String()
+	hts.stoppedTLS = strings.HasPrefix(hts.server.URL, "https://")
+	hts.mu.Unlock()
+	hts.close()
+}
+
+// Restart a test server stopped with Stop on the same address (host and port), with the same
+// protocol (HTTP or HTTPS) and the same TLS configuration. Predefined responses, records and
+// middlewares are kept.
+//
+// The underlying httptest.Server is replaced by a new one which has the same configuration
+// (timeouts, HTTP/2, TLS, ...) except the ConnState hook set by the user on the original
+// http.Server. Connection hooks are kept (see OnConnAccept). An error is returned if the test
+// server has not been stopped or if the address cannot be bound again.
+func (hts *HTTPTestServer) Restart() error {
+	hts.mu.Lock()
+	addr, useTLS := hts.stoppedAddr, hts.stoppedTLS
+	hts.mu.Unlock()
+	if addr == "" {
+		return fmt.Errorf("test server has not been stopped")
+	}
+	// Bind the same address
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	// Build a new httptest.Server with the same configuration
+	old := hts.server
+	watched := old.Listener
+	if wl, ok := watched.(*wrappingListener); ok {
+		watched = wl.Listener
+	}
+	if _, ok := watched.(*timeoutListener); ok {
+		listener = &timeoutListener{Listener: listener, hts: hts}
+	}
+	if len(hts.connWrappers) > 0 {
+		listener = &wrappingListener{Listener: listener, hts: hts}
+	}
+	server := &httptest.Server{
+		Listener:    listener,
+		EnableHTTP2: old.EnableHTTP2,
+		TLS:         old.TLS,
+		Config: &http.Server{
+			Handler:           hts,
+			TLSConfig:         old.Config.TLSConfig,
+			ReadTimeout:       old.Config.ReadTimeout,
+			ReadHeaderTimeout: old.Config.ReadHeaderTimeout,
+			WriteTimeout:      old.Config.WriteTimeout,
+			IdleTimeout:       old.Config.IdleTimeout,
+			MaxHeaderBytes:    old.Config.MaxHeaderBytes,
+			TLSNextProto:      old.Config.TLSNextProto,
+			ErrorLog:          old.Config.ErrorLog,
+			BaseContext:       old.Config.BaseContext,
+			ConnContext:       old.Config.ConnContext,
+			ConnState:         hts.watchConnStates(nil),
+		},
+	}
+	hts.mu.Lock()
+	hts.server = server
+	hts.closing = make(chan struct{})
+	hts.stoppedAddr = ""
+	hts.mu.Unlock()
+	// Start the new server
+	if useTLS {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	return nil
 }
 
 func (hts *HTTPTestServer) Client() *http.Client {
+	if hts.scope != nil {
+		return hts.scope.Client()
+	}
 	return hts.server.Client()
 }
 
 // Get the underlying httptest.Server used by this HTTPTestServer.
 func (hts *HTTPTestServer) GetUnderlyingHTTPTestServer() *httptest.Server {
+	if hts.scope != nil {
+		return hts.scope.GetUnderlyingHTTPTestServer()
+	}
 	return hts.server
 }
 
 // Return the test server base URL of form http://ipaddr:port with no trailing slash.
 func (hts *HTTPTestServer) GetBaseURL() string {
+	if hts.scope != nil {
+		return hts.scope.GetBaseURL()
+	}
 	return hts.server.URL
 }
 
 // Push a predefined response to the server.
 func (hts *HTTPTestServer) PushPredefinedServerResponse(resp *PredefinedServerResponse) {
-	hts.responses = append(hts.responses, resp)
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.responses.push(resp)
+}
+
+// Push a predefined response to the queue bound to the provided request path (ex: /token).
+//
+// Each path has its own queue which follows the same FIFO rules as the global queue. When a
+// request is received, the queue bound to the request path is consulted after the queue bound to
+// the request method and path and before the queue bound to the request method.
+func (hts *HTTPTestServer) PushPredefinedServerResponseForPath(path string, resp *PredefinedServerResponse) {
+	hts.pushPredefinedServerResponseForRoute(route{path: path}, resp)
+}
+
+// Push a predefined response to the queue bound to the provided host name (ex: api.example.com).
+// The host name is compared with the request Host header (without port) in a case insensitive
+// way, whether the request is received over TLS or not.
+//
+// Each host has its own queue which follows the same FIFO rules as the global queue. When a
+// request is received, the queue bound to the request host is consulted before the queues bound
+// to the request method and path. This allows clients which talk to several hosts to be tested
+// with one test server, by using a custom DialContext which connects to the test server.
+func (hts *HTTPTestServer) PushPredefinedServerResponseForHost(host string, resp *PredefinedServerResponse) {
+	hts.pushPredefinedServerResponseForRoute(route{host: strings.ToLower(host)}, resp)
+}
+
+// Push a predefined response to the queue bound to the provided HTTP method (ex: GET, POST).
+//
+// Each method has its own queue which follows the same FIFO rules as the global queue. When a
+// request is received, the queue bound to the request method is consulted after the queues bound
+// to the request path and before the global queue.
+func (hts *HTTPTestServer) PushPredefinedServerResponseForMethod(method string, resp *PredefinedServerResponse) {
+	hts.pushPredefinedServerResponseForRoute(route{method: strings.ToUpper(method)}, resp)
+}
+
+// Push a predefined response to the queue bound to the provided HTTP method and request path (ex:
+// POST /orders).
+//
+// Each route has its own queue which follows the same FIFO rules as the global queue. When a
+// request is received, the queue bound to the request method and path is consulted after the
+// queue bound to the request host, then the queue bound to the request path, then the queue bound
+// to the request method and finally the global queue. This keeps multi-endpoint test setups
+// order-independent. The global queue is not used for requests which target the path with another
+// method: they get the default response (see SetDefaultResponse) instead, for instance a request
+// GET /orders when a response has only been pushed for POST /orders.
+func (hts *HTTPTestServer) PushPredefinedServerResponseForRoute(method string, path string, resp *PredefinedServerResponse) {
+	hts.pushPredefinedServerResponseForRoute(route{method: strings.ToUpper(method), path: path}, resp)
+}
+
+// Helper method which pushes a predefined response to the queue bound to the provided route.
+func (hts *HTTPTestServer) pushPredefinedServerResponseForRoute(rt route, resp *PredefinedServerResponse) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	queue := hts.routeResponses[rt]
+	queue.push(resp)
+	hts.routeResponses[rt] = queue
+}
+
+// Set the response served when no predefined responses are available for an incoming request (ex:
+// a 503 with a JSON error body or a sentinel body which makes test failures obvious). Provide nil
+// to restore the default empty 404 response.
+//
+// The default response is not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) SetDefaultResponse(resp *PredefinedServerResponse) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.defaultResponse = resp
 }
 
 // Pop a server record (received request and response) if any. Server records are recorded and
 // provided in a FIFO fashion. The returned record will be nil if no record is available.
 func (hts *HTTPTestServer) PopServerRecord() *ServerRecord {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
 	// Prepare return value
 	var record *ServerRecord = nil
 	// Pop first record if any
@@ -238,14 +1467,65 @@ func (hts *HTTPTestServer) PopServerRecord() *ServerRecord {
 	return record
 }
 
-// Clear all predefined responses configured for the http test server
+// Atomically remove and return all the server records in the order they have been recorded. The
+// returned slice is empty if no record is available.
+func (hts *HTTPTestServer) DrainServerRecords() []*ServerRecord {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	records := hts.records
+	hts.records = []*ServerRecord{}
+	return records
+}
+
+// Wait until at least n server records are available or the timeout expires. Useful when the
+// system under test sends requests asynchronously (background workers, goroutines, ...).
+//
+// The first n records are returned without being popped from the record queue. In case the
+// timeout expires, the available records are returned with an error.
+func (hts *HTTPTestServer) WaitForRequests(n int, timeout time.Duration) ([]*ServerRecord, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// Return the first n records if available
+		hts.mu.Lock()
+		if len(hts.records) >= n {
+			records := make([]*ServerRecord, n)
+			copy(records, hts.records)
+			hts.mu.Unlock()
+			return records, nil
+		}
+		recordAdded := hts.recordAdded
+		hts.mu.Unlock()
+		// Wait for a new record or the timeout
+		select {
+		case <-recordAdded:
+		case <-timer.C:
+			records := hts.snapshotServerRecords()
+			return records, fmt.Errorf("timed out after %s waiting for %d request(s), got %d", timeout, n, len(records))
+		}
+	}
+}
+
+// Clear all predefined responses configured for the http test server, including the responses
+// bound to a request path, a HTTP method or a request matcher.
 func (hts *HTTPTestServer) ClearPredefinedServerResponses() {
-	hts.responses = []*PredefinedServerResponse{}
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.stubs = []*stub{}
+	hts.responses = responseQueue{}
+	hts.routeResponses = map[route]responseQueue{}
+	hts.scenarios = map[string]string{}
+	hts.sessions = []*Session{}
 }
 
-// Clear all test server records
+// Clear all test server records, including webhook deliveries.
 func (hts *HTTPTestServer) ClearServerRecords() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
 	hts.records = []*ServerRecord{}
+	hts.evictedRecords = 0
+	hts.webhookDeliveries = []*WebhookDelivery{}
+	hts.timeoutEvents = []TimeoutEvent{}
 }
 
 // Clear all server predefined responses & records
@@ -260,19 +1540,105 @@ func (hts *HTTPTestServer) Clear() {
 func (srv *HTTPTestServer) handleInternalError(w http.ResponseWriter, serverRecord *ServerRecord, err error) {
 	// Add the error to the server record
 	serverRecord.ServerError = err
+	if isTimeout(err) {
+		serverRecord.TimedOut = true
+	}
 	// Add the server record to the queue of records
-	srv.records = append(srv.records, serverRecord)
+	srv.addServerRecord(serverRecord)
 	// Send a 500 response with the wrapped error as text as response body
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusInternalServerError)
 	w.Write([]byte(err.Error()))
 }
 
+// Helper function which waits for the provided duration or until the provided context is done,
+// using the clock of the test server which serves the request the context belongs to. Returns the
+// context error if the context is done before the duration has elapsed.
+func sleep(ctx context.Context, d time.Duration) error {
+	return clockFromContext(ctx).Sleep(ctx, d)
+}
+
+/*************************************************************************************************/
+/* RESPONSE QUEUE                                                                                */
+/*************************************************************************************************/
+
+// A route a response queue can be bound to. An empty method or path matches any method or path.
+type route struct {
+	// Request host name in lower case, without port.
+	host string
+	// HTTP method in upper case.
+	method string
+	// Request path.
+	path string
+}
+
+// Helper method which describes the route (ex: GET /users, host example.com).
+func (rt route) String() string {
+	if rt.host != "" {
+		return "host " + rt.host
+	}
+	return strings.TrimSpace(rt.method + " " + rt.path)
+}
+
+// A FIFO queue of predefined responses. Responses are provided as many times as their Repeat
+// allows (once by default) until there is only one response left: the last response is served
+// indefinitly.
+type responseQueue []*queuedResponse
+
+// A predefined response in a response queue.
+type queuedResponse struct {
+	// The predefined response.
+	response *PredefinedServerResponse
+	// Number of times the response has been served.
+	served int
+}
+
+// Push a predefined response at the end of the queue.
+func (q *responseQueue) push(resp *PredefinedServerResponse) {
+	*q = append(*q, &queuedResponse{response: resp})
+}
+
+// Get the next response from the queue. The response is popped from the queue once it has been
+// served as many times as allowed unless it is the last one. Returns nil if the queue is empty.
+func (q *responseQueue) next() *PredefinedServerResponse {
+	// Return nil if queue is empty
+	if len(*q) == 0 {
+		return nil
+	}
+	// Get first predefined response in the queue
+	head := (*q)[0]
+	head.served++
+	// If the response has been served enough times and there are other predefined responses in
+	// the queue or the response must be consumed, pop the used response. Keep otherwise
+	if head.response.exhausted(head.served, 1) && (len(*q) > 1 || head.response.ConsumeOnce) {
+		*q = (*q)[1:]
+	}
+	return head.response
+}
+
+// Return true if the response has been served enough times considering it has been served the
+// provided number of times. The provided default number of times is used when Repeat is the zero
+// value: a default lower than 1 means indefinitly.
+func (resp *PredefinedServerResponse) exhausted(served int, defaultTimes int) bool {
+	if resp.Sticky {
+		return false
+	}
+	if resp.ConsumeOnce && defaultTimes < 1 {
+		defaultTimes = 1
+	}
+	return resp.Repeat.exhausted(served, defaultTimes)
+}
+
 // A package-private implementation of http.ResponseWriter which writes data to multiple
 // http.ResponseWriter at once.
 type multiTargetHTTPResponseWriter struct {
-	// Targets for the multi target ResponseWriter.
+	// Targets for the multi target ResponseWriter. The first target is the recorder of the
+	// response.
 	targets []http.ResponseWriter
+	// True once the headers have been copied to all targets.
+	headersSynced bool
+	// Record the interim responses are added to. Nil if interim responses are not recorded.
+	record *ServerRecord
 }
 
 /*************************************************************************************************/
@@ -314,7 +1680,8 @@ func newMultiTargetHTTPResponseWriter(targets ...http.ResponseWriter) *multiTarg
 func (mw *multiTargetHTTPResponseWriter) Header() http.Header {
 	// Check if the multiTargetHTTPResponseWriter has some targets
 	if len(mw.targets) > 0 {
-		// Return the header map of the first target (there are all equal)
+		// Return the header map of the first target. The header map is copied to the other
+		// targets before the headers are written.
 		return mw.targets[0].Header()
 	}
 	// Return an empty header map
@@ -343,6 +1710,8 @@ func (mw *multiTargetHTTPResponseWriter) Header() http.Header {
 // by all HTTP/2 clients. Handlers should read before writing if
 // possible to maximize compatibility.
 func (mw *multiTargetHTTPResponseWriter) Write(data []byte) (int, error) {
+	// Copy headers to all targets before they are written
+	mw.syncHeaders()
 	// Write data to each target
 	var r int = 0
 	var err error = nil
@@ -376,15 +1745,95 @@ func (mw *multiTargetHTTPResponseWriter) Write(data []byte) (int, error) {
 // on the first read from the request body if the request has
 // an "Expect: 100-continue" header.
 func (mw *multiTargetHTTPResponseWriter) WriteHeader(statusCode int) {
+	// Pass interim responses through without clobbering the final status
+	if isInterimStatus(statusCode) && !mw.headersSynced {
+		mw.writeInterim(statusCode)
+		return
+	}
+	// Copy headers to all targets before they are written
+	mw.syncHeaders()
 	// Call WriteHeader for each target
 	for _, target := range mw.targets {
 		target.WriteHeader(statusCode)
 	}
 }
 
-func (mw *multiTargetHTTPResponseWriter) headersAdd(key string, value string) {
+// Copy the header map of the first target (the one returned by Header) to the other targets. The
+// copy is made only once, before the headers are written.
+func (mw *multiTargetHTTPResponseWriter) syncHeaders() {
+	if mw.headersSynced || len(mw.targets) == 0 {
+		return
+	}
+	mw.headersSynced = true
+	mw.copyHeaders()
+}
+
+// Helper method which makes the header maps of the targets other than the first one a copy of the
+// header map of the first target.
+func (mw *multiTargetHTTPResponseWriter) copyHeaders() {
+	header := mw.targets[0].Header()
+	for _, target := range mw.targets[1:] {
+		for key := range target.Header() {
+			delete(target.Header(), key)
+		}
+		for key, values := range header {
+			target.Header()[key] = append([]string(nil), values...)
+		}
+	}
+}
+
+// Helper method which writes an interim response with the provided 1xx status code and the current
+// headers to the targets other than the first one and adds it to the record. The recorder (first
+// target) only records the final response.
+func (mw *multiTargetHTTPResponseWriter) writeInterim(statusCode int) {
+	if len(mw.targets) == 0 {
+		return
+	}
+	if mw.record != nil {
+		mw.record.InterimResponses = append(mw.record.InterimResponses, &InterimResponse{
+			Status: statusCode,
+			Header: mw.targets[0].Header().Clone(),
+		})
+	}
+	mw.copyHeaders()
+	for _, target := range mw.targets[1:] {
+		target.WriteHeader(statusCode)
+	}
+}
+
+// Helper function which writes the headers and the status code of the provided predefined
+// response by using the provided http.ResponseWriter. Trailers are announced and sent by the
+// http.ResponseWriter once the handler returns.
+func writeHeaders(w http.ResponseWriter, response *PredefinedServerResponse) {
+	// Write response headers
+	for header, values := range response.Headers {
+		for _, value := range values {
+			w.Header().Add(header, value)
+		}
+	}
+	// Set cookies
+	for _, cookie := range response.SetCookies {
+		if v := cookie.String(); v != "" {
+			w.Header().Add("Set-Cookie", v)
+		}
+	}
+	// Announce trailers and provide their values with the trailer prefix so they are sent once
+	// the body has been written
+	for trailer, values := range response.Trailers {
+		w.Header().Add("Trailer", trailer)
+		w.Header()[http.TrailerPrefix+http.CanonicalHeaderKey(trailer)] = append([]string{}, values...)
+	}
+	// Write status code
+	w.WriteHeader(response.Status)
+}
+
+// Flush sends any buffered data to the targets which support flushing.
+func (mw *multiTargetHTTPResponseWriter) Flush() {
+	// Copy headers to all targets before they are written
+	mw.syncHeaders()
 	for _, target := range mw.targets {
-		// Call Header().Add for each target
-		target.Header().Add(key, value)
+		if flusher, ok := target.(http.Flusher); ok {
+			flusher.Flush()
+		}
 	}
 }
@@ -2,6 +2,7 @@ package gosette
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -41,6 +43,7 @@ func (suite *HTTPTestServerUnitTestSuite) SetupSuite() {
 // Clear test server predefined responses and records after each test
 func (suite *HTTPTestServerUnitTestSuite) TearDownTest() {
 	suite.hts.Clear()
+	suite.hts.ClearOutages()
 }
 
 // Close HTTPTestServer before finishing tests
@@ -248,6 +251,305 @@ func (suite *HTTPTestServerUnitTestSuite) TestWithMultipleResponses() {
 	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
 }
 
+// Test HTTPTestServer when predefined responses are bound to request paths. Test will ensure:
+//   - Responses bound to a path are served for requests targeting this path
+//   - Each path has its own FIFO queue where the last response is served indefinitly
+//   - The global queue is used as fallback for paths without predefined responses
+//   - ClearPredefinedServerResponses clears path-bound responses
+func (suite *HTTPTestServerUnitTestSuite) TestWithPathBasedResponses() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Push responses for /token and /orders and a response to the global queue
+	suite.hts.PushPredefinedServerResponseForPath("/token", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte("token"),
+	})
+	suite.hts.PushPredefinedServerResponseForPath("/orders", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+	suite.hts.PushPredefinedServerResponseForPath("/orders", &PredefinedServerResponse{
+		Status: http.StatusAccepted,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusTeapot,
+	})
+
+	// Send requests in a interleaved order and check the served responses
+	expectations := []struct {
+		path   string
+		status int
+	}{
+		{"/orders", http.StatusCreated},
+		{"/token", http.StatusOK},
+		{"/health", http.StatusTeapot},
+		{"/orders", http.StatusAccepted},
+		{"/orders", http.StatusAccepted},
+		{"/token", http.StatusOK},
+	}
+	for _, expectation := range expectations {
+		resp, err := client.Get(suite.hts.GetBaseURL() + expectation.path)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expectation.status, resp.StatusCode)
+		// Check the recorded request path and response
+		record := suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.Equal(suite.T(), expectation.path, record.Request.URL.Path)
+		require.Equal(suite.T(), expectation.status, record.Response.Result().StatusCode)
+	}
+
+	// Clear responses and ensure an empty 404 response is now served for /token
+	suite.hts.ClearPredefinedServerResponses()
+	require.Empty(suite.T(), suite.hts.routeResponses)
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/token")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+// Test HTTPTestServer when predefined responses are bound to HTTP methods. Test will ensure:
+//   - A GET and a POST hitting the same path can be served independently
+//   - Path-bound queues have precedence over method-bound queues
+//   - Unmatched methods fall back to the default 404 response
+func (suite *HTTPTestServerUnitTestSuite) TestWithMethodBasedResponses() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Push responses for GET and POST (lower case method must be accepted)
+	suite.hts.PushPredefinedServerResponseForMethod(http.MethodGet, &PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	suite.hts.PushPredefinedServerResponseForMethod("post", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+	suite.hts.PushPredefinedServerResponseForPath("/health", &PredefinedServerResponse{
+		Status: http.StatusNoContent,
+	})
+
+	// Send a GET and a POST to the same path
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/users")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/users", "text/plain", strings.NewReader("hello"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+
+	// Path-bound response is served in priority
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNoContent, resp.StatusCode)
+
+	// Unmatched method falls back to the default 404 response
+	req, err := http.NewRequest(http.MethodDelete, suite.hts.GetBaseURL()+"/users", nil)
+	require.NoError(suite.T(), err)
+	resp, err = client.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	// Clear responses and ensure method queues are cleared
+	suite.hts.ClearPredefinedServerResponses()
+	require.Empty(suite.T(), suite.hts.routeResponses)
+}
+
+// Test HTTPTestServer when predefined responses are bound to routes (method and path). Test will
+// ensure:
+//   - Route queues are consulted first, then path queues, then method queues and finally the
+//     global queue
+//   - Each route has its own FIFO queue
+func (suite *HTTPTestServerUnitTestSuite) TestWithRouteBasedResponses() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Push responses to each kind of queue
+	suite.hts.PushPredefinedServerResponseForRoute("post", "/orders", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+	suite.hts.PushPredefinedServerResponseForRoute(http.MethodPost, "/orders", &PredefinedServerResponse{
+		Status: http.StatusAccepted,
+	})
+	suite.hts.PushPredefinedServerResponseForRoute(http.MethodGet, "/orders", &PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	suite.hts.PushPredefinedServerResponseForPath("/orders", &PredefinedServerResponse{
+		Status: http.StatusNonAuthoritativeInfo,
+	})
+	suite.hts.PushPredefinedServerResponseForMethod(http.MethodPost, &PredefinedServerResponse{
+		Status: http.StatusResetContent,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusTeapot,
+	})
+
+	// Send requests and check the served responses
+	expectations := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/orders", http.StatusOK},
+		{http.MethodPost, "/orders", http.StatusCreated},
+		{http.MethodPost, "/orders", http.StatusAccepted},
+		{http.MethodPost, "/orders", http.StatusAccepted},
+		{http.MethodDelete, "/orders", http.StatusNonAuthoritativeInfo},
+		{http.MethodPost, "/users", http.StatusResetContent},
+		{http.MethodGet, "/users", http.StatusTeapot},
+	}
+	for _, expectation := range expectations {
+		req, err := http.NewRequest(expectation.method, suite.hts.GetBaseURL()+expectation.path, nil)
+		require.NoError(suite.T(), err)
+		resp, err := client.Do(req)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expectation.status, resp.StatusCode)
+	}
+
+	// Clear responses and ensure route queues are cleared
+	suite.hts.ClearPredefinedServerResponses()
+	require.Empty(suite.T(), suite.hts.routeResponses)
+}
+
+// Test HTTPTestServer when predefined responses are bound to a route and pushed to the global
+// queue. Test will ensure:
+//   - Requests which target the route path with another method get the default response
+//   - Requests which target the route are served by the route queue
+//   - Requests which target other paths are served by the global queue
+func (suite *HTTPTestServerUnitTestSuite) TestWithRouteBasedResponsesAndGlobalQueue() {
+	// Push a response for POST /orders and a response to the global queue
+	suite.hts.PushPredefinedServerResponseForRoute(http.MethodPost, "/orders", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+
+	// Send requests and check the served responses
+	expectations := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/orders", http.StatusNotFound},
+		{http.MethodPost, "/orders", http.StatusCreated},
+		{http.MethodGet, "/users", http.StatusOK},
+	}
+	for _, expectation := range expectations {
+		req, err := http.NewRequest(expectation.method, suite.hts.GetBaseURL()+expectation.path, nil)
+		require.NoError(suite.T(), err)
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expectation.status, resp.StatusCode)
+	}
+}
+
+// Test HTTPTestServer with a delayed predefined response. Test will ensure the server waits
+// before responding and that a client with a shorter timeout fails.
+func (suite *HTTPTestServerUnitTestSuite) TestWithDelayedResponse() {
+	// Push a delayed response
+	delay := 100 * time.Millisecond
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+		Delay:  delay,
+	})
+
+	// Send a request and check elapsed time
+	start := time.Now()
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.GreaterOrEqual(suite.T(), time.Since(start), delay)
+
+	// Send a request with a client which times out before the server responds
+	client := suite.hts.Client()
+	client.Timeout = delay / 4
+	_, err = client.Get(suite.hts.GetBaseURL())
+	require.Error(suite.T(), err)
+
+	// Wait for the server to record the interrupted exchange so it does not leak in other tests
+	require.Eventually(suite.T(), func() bool {
+		suite.hts.mu.Lock()
+		defer suite.hts.mu.Unlock()
+		return len(suite.hts.records) == 2
+	}, time.Second, time.Millisecond)
+}
+
+// Test sleep returns the context error when the context is done before the delay has elapsed.
+func (suite *HTTPTestServerUnitTestSuite) TestSleep() {
+	require.NoError(suite.T(), sleep(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(suite.T(), sleep(ctx, time.Hour), context.Canceled)
+}
+
+// Test HTTPTestServer with a configured default response. Test will ensure the default response
+// is served when no predefined responses are available, is kept by Clear and can be reset.
+func (suite *HTTPTestServerUnitTestSuite) TestWithDefaultResponse() {
+	// Restore the default response at the end of the test
+	defer suite.hts.SetDefaultResponse(nil)
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Set a default response
+	suite.hts.SetDefaultResponse(&PredefinedServerResponse{
+		Status:  http.StatusServiceUnavailable,
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    []byte(`{"error": "no stub"}`),
+	})
+
+	// Default response is served when no predefined responses are available
+	resp, err := client.Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusServiceUnavailable, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), `{"error": "no stub"}`, string(body))
+
+	// Predefined responses have precedence over the default response
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{Status: http.StatusOK})
+	resp, err = client.Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	// Default response is kept by Clear
+	suite.hts.Clear()
+	resp, err = client.Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusServiceUnavailable, resp.StatusCode)
+
+	// Reset the default response
+	suite.hts.SetDefaultResponse(nil)
+	resp, err = client.Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+// Test WaitForRequests. Test will ensure:
+//   - The method blocks until requests sent asynchronously are recorded
+//   - The first n records are returned and are not popped
+//   - An error is returned with the available records when the timeout expires
+func (suite *HTTPTestServerUnitTestSuite) TestWaitForRequests() {
+	// Send requests asynchronously
+	client := suite.hts.Client()
+	for i := 0; i < 3; i++ {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.Get(suite.hts.GetBaseURL())
+		}()
+	}
+
+	// Wait for the first two requests
+	records, err := suite.hts.WaitForRequests(2, time.Second)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), records, 2)
+
+	// Wait for the three requests - records have not been popped
+	records, err = suite.hts.WaitForRequests(3, time.Second)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), records, 3)
+
+	// Wait for more requests and expect a timeout
+	records, err = suite.hts.WaitForRequests(4, 10*time.Millisecond)
+	require.Error(suite.T(), err)
+	require.Len(suite.T(), records, 3)
+}
+
 // Test HTTPServer with TLS enabled
 func (suite *HTTPTestServerUnitTestSuite) TestWithTLSEnabled() {
 	// Create a base httptest server
@@ -432,3 +734,60 @@ func TestMockResponseWriterInterfaceCompliance(t *testing.T) {
 	_, ok := instance.(http.ResponseWriter)
 	require.True(t, ok)
 }
+
+// Test trailers are announced and sent after the body, for plain and chunked bodies, and are
+// recorded.
+func (suite *HTTPTestServerUnitTestSuite) TestTrailers() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/body").RespondWith().StringBody("hello").Trailer("X-Checksum", "abc")
+	suite.hts.When().Get("/chunks").RespondWith().StringChunks("a", "b").Trailer("x-checksum", "def")
+	for path, checksum := range map[string]string{"/body": "abc", "/chunks": "def"} {
+		resp, err := client.Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), []string{"chunked"}, resp.TransferEncoding)
+		require.Contains(suite.T(), resp.Trailer, "X-Checksum")
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), checksum, resp.Trailer.Get("X-Checksum"))
+		record := suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.Equal(suite.T(), checksum, record.Response.Result().Trailer.Get("X-Checksum"))
+	}
+}
+
+// Test the test server can be stopped and restarted on the same address with the same protocol
+// while predefined responses and records are kept.
+func TestStopAndRestart(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		hts := NewHTTPTestServer(nil)
+		if useTLS {
+			hts.StartTLS()
+		} else {
+			hts.Start()
+		}
+		// Restart fails when the server has not been stopped
+		require.Error(t, hts.Restart())
+		hts.When().Get("/ping").RespondWith().StringBody("pong")
+		client := hts.Client()
+		url := hts.GetBaseURL() + "/ping"
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		resp.Body.Close()
+		// Requests fail once the server is stopped
+		hts.Stop()
+		_, err = client.Get(url)
+		require.Error(t, err)
+		// Restart the server: same URL, same stubs and records are kept
+		require.NoError(t, hts.Restart())
+		require.Equal(t, strings.TrimSuffix(url, "/ping"), hts.GetBaseURL())
+		resp, err = client.Get(url)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, "pong", string(body))
+		require.Len(t, hts.FindRecords(ByPath("/ping")), 2)
+		hts.Close()
+	}
+}
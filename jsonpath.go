@@ -0,0 +1,281 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Build a request matcher which matches requests whose body is a JSON document in which at least
+// one value selected by the provided JSONPath expression is equal to the expected value.
+//
+// The expected value is compared with the selected values once encoded in JSON and decoded: 123,
+// int64(123) and float64(123) are equivalent. In addition, an expected string matches a selected
+// number or boolean which has the same textual representation ("123" matches 123).
+//
+// Supported JSONPath syntax: root ($), child (.name or ['name']), array index ([0], [-1]),
+// wildcard (.* or [*]) and recursive descent (..name). The matcher does not match requests whose
+// body is not a valid JSON document or when the expression is invalid.
+func BodyJSONPathMatcher(expr string, expected interface{}) RequestMatcher {
+	return newCriterionMatcher("JSONPath "+expr, explainedValues(expected, nil), func(r *http.Request) string {
+		return explainedValues(requestJSONPath(r, expr))
+	}, func(r *http.Request) bool {
+		values, err := requestJSONPath(r, expr)
+		return err == nil && containsJSONValue(values, expected)
+	})
+}
+
+// Build a request matcher which matches requests whose body is a JSON document in which the
+// provided JSONPath expression selects at least one value. See BodyJSONPathMatcher for the
+// supported syntax.
+func BodyJSONPathExistsMatcher(expr string) RequestMatcher {
+	return newCriterionMatcher("JSONPath "+expr, "at least one value", func(r *http.Request) string {
+		return explainedValues(requestJSONPath(r, expr))
+	}, func(r *http.Request) bool {
+		values, err := requestJSONPath(r, expr)
+		return err == nil && len(values) > 0
+	})
+}
+
+// Build a filter which selects records whose request body is a JSON document in which at least
+// one value selected by the provided JSONPath expression is equal to the expected value. See
+// BodyJSONPathMatcher for the comparison rules.
+func ByJSONPath(expr string, expected interface{}) RecordFilter {
+	return ByMatcher(BodyJSONPathMatcher(expr, expected))
+}
+
+// Evaluate the provided JSONPath expression against the request body of the record and return
+// the selected values. JSON numbers are decoded as float64. See BodyJSONPathMatcher for the
+// supported syntax.
+//
+// An error is returned if the expression is invalid or if the request body is not a valid JSON
+// document.
+func (record *ServerRecord) JSONPath(expr string) ([]interface{}, error) {
+	return evalJSONPath(record.RequestBody.Bytes(), expr)
+}
+
+// Check that at least one value selected by the provided JSONPath expression in the request body
+// of the record is equal to the expected value. See BodyJSONPathMatcher for the comparison rules.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertJSONPath(expr string, expected interface{}) error {
+	values, err := record.JSONPath(expr)
+	if err != nil {
+		return err
+	}
+	if !containsJSONValue(values, expected) {
+		return fmt.Errorf("expected %s to be %v, got %v", expr, expected, values)
+	}
+	return nil
+}
+
+// Helper function which reads the body of the provided request and evaluates the provided
+// JSONPath expression against it.
+func requestJSONPath(r *http.Request, expr string) ([]interface{}, error) {
+	if r.Body == nil {
+		return nil, fmt.Errorf("request has no body")
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return evalJSONPath(body, expr)
+}
+
+// Helper function which decodes the provided JSON document and evaluates the provided JSONPath
+// expression against it.
+func evalJSONPath(data []byte, expr string) ([]interface{}, error) {
+	steps, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON document: %w", err)
+	}
+	nodes := []interface{}{doc}
+	for _, step := range steps {
+		nodes = step.apply(nodes)
+	}
+	return nodes, nil
+}
+
+// Helper function which returns true if one of the provided values is equal to the expected value.
+func containsJSONValue(values []interface{}, expected interface{}) bool {
+	// Normalize the expected value
+	var normalized interface{}
+	encoded, err := json.Marshal(expected)
+	if err != nil || json.Unmarshal(encoded, &normalized) != nil {
+		return false
+	}
+	for _, value := range values {
+		if reflect.DeepEqual(value, normalized) {
+			return true
+		}
+		if str, ok := normalized.(string); ok {
+			switch value.(type) {
+			case float64, bool:
+				if fmt.Sprint(value) == str {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+/*************************************************************************************************/
+/* JSONPATH                                                                                      */
+/*************************************************************************************************/
+
+// A single step of a JSONPath expression.
+type jsonPathStep struct {
+	// Name of the selected member. Empty for index and wildcard steps.
+	name string
+	// Index of the selected array element. Negative indexes start from the end of the array.
+	index int
+	// True if the step selects an array element.
+	isIndex bool
+	// True if the step selects all members or elements.
+	wildcard bool
+	// True if the step applies to all descendants (recursive descent).
+	recursive bool
+}
+
+// Helper function which parses a JSONPath expression.
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: expression must start with $", expr)
+	}
+	steps := []jsonPathStep{}
+	rest := expr[1:]
+	for rest != "" {
+		step := jsonPathStep{}
+		// Parse separator
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expr, rest[:1])
+		}
+		// Parse bracket selector
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ]", expr)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				step.wildcard = true
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				step.name = selector[1 : len(selector)-1]
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: invalid selector [%s]", expr, selector)
+				}
+				step.index, step.isIndex = index, true
+			}
+			steps = append(steps, step)
+			continue
+		}
+		// Parse member name
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		switch name := rest[:end]; name {
+		case "":
+			return nil, fmt.Errorf("invalid JSONPath %q: empty member name", expr)
+		case "*":
+			step.wildcard = true
+		default:
+			step.name = name
+		}
+		rest = rest[end:]
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Helper method which applies the step to the provided nodes and returns the selected nodes.
+func (step jsonPathStep) apply(nodes []interface{}) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		if step.recursive {
+			for _, descendant := range jsonDescendants(node) {
+				selected = append(selected, step.selectChildren(descendant)...)
+			}
+		} else {
+			selected = append(selected, step.selectChildren(node)...)
+		}
+	}
+	return selected
+}
+
+// Helper method which returns the children of the provided node selected by the step.
+func (step jsonPathStep) selectChildren(node interface{}) []interface{} {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			return jsonChildren(typed)
+		}
+		if value, ok := typed[step.name]; ok && !step.isIndex {
+			return []interface{}{value}
+		}
+	case []interface{}:
+		if step.wildcard {
+			return append([]interface{}{}, typed...)
+		}
+		if step.isIndex {
+			index := step.index
+			if index < 0 {
+				index += len(typed)
+			}
+			if index >= 0 && index < len(typed) {
+				return []interface{}{typed[index]}
+			}
+		}
+	}
+	return nil
+}
+
+// Helper function which returns the provided node and all its descendants.
+func jsonDescendants(node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	var children []interface{}
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		children = jsonChildren(typed)
+	case []interface{}:
+		children = typed
+	}
+	for _, child := range children {
+		nodes = append(nodes, jsonDescendants(child)...)
+	}
+	return nodes
+}
+
+// Helper function which returns the members of the provided object sorted by name.
+func jsonChildren(object map[string]interface{}) []interface{} {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	children := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		children = append(children, object[name])
+	}
+	return children
+}
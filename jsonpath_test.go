@@ -0,0 +1,99 @@
+package gosette
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// JSON document used in JSONPath tests.
+const testJSONPathDocument = `{
+	"order": {"id": 123, "paid": true, "ref": "A-1"},
+	"items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 2}],
+	"my key": "spaced"
+}`
+
+// Test the JSONPath evaluation against a JSON document.
+func (suite *HTTPTestServerUnitTestSuite) TestJSONPath() {
+	record := &ServerRecord{RequestBody: bytes.NewBufferString(testJSONPathDocument)}
+	testCases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{"$", nil},
+		{"$.order.id", []interface{}{float64(123)}},
+		{"$['order']['ref']", []interface{}{"A-1"}},
+		{"$[\"my key\"]", []interface{}{"spaced"}},
+		{"$.items[1].sku", []interface{}{"b"}},
+		{"$.items[-1].qty", []interface{}{float64(2)}},
+		{"$.items[*].sku", []interface{}{"a", "b"}},
+		{"$.items.*.qty", []interface{}{float64(1), float64(2)}},
+		{"$..sku", []interface{}{"a", "b"}},
+		{"$..[0].sku", []interface{}{"a"}},
+		{"$.items[5]", []interface{}{}},
+		{"$.missing", []interface{}{}},
+	}
+	for _, tc := range testCases {
+		values, err := record.JSONPath(tc.expr)
+		require.NoError(suite.T(), err, tc.expr)
+		if tc.expected != nil {
+			require.Equal(suite.T(), tc.expected, values, tc.expr)
+		} else {
+			require.Len(suite.T(), values, 1)
+		}
+	}
+	// Invalid expressions
+	for _, expr := range []string{"order.id", "$.", "$.items[1", "$.items[x]", "$order"} {
+		_, err := record.JSONPath(expr)
+		require.Error(suite.T(), err, expr)
+	}
+	// Invalid document
+	_, err := (&ServerRecord{RequestBody: bytes.NewBufferString("{")}).JSONPath("$")
+	require.Error(suite.T(), err)
+	// Assertions
+	require.NoError(suite.T(), record.AssertJSONPath("$.order.id", 123))
+	require.NoError(suite.T(), record.AssertJSONPath("$.order.id", "123"))
+	require.NoError(suite.T(), record.AssertJSONPath("$.order.paid", "true"))
+	require.NoError(suite.T(), record.AssertJSONPath("$.items[*].qty", int64(2)))
+	require.NoError(suite.T(), record.AssertJSONPath("$.items[0]", map[string]interface{}{"sku": "a", "qty": 1}))
+	require.Error(suite.T(), record.AssertJSONPath("$.order.id", 124))
+	require.Error(suite.T(), record.AssertJSONPath("$.order.ref", 1))
+	require.Error(suite.T(), record.AssertJSONPath("$.order.id", make(chan int)))
+	require.Error(suite.T(), record.AssertJSONPath("invalid", 1))
+}
+
+// Test JSONPath request matchers, record filter and builder method.
+func (suite *HTTPTestServerUnitTestSuite) TestJSONPathMatchers() {
+	// Matchers
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(testJSONPathDocument))
+	require.True(suite.T(), BodyJSONPathMatcher("$.order.id", "123").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(testJSONPathDocument))
+	require.False(suite.T(), BodyJSONPathMatcher("$.order.id", "124").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(testJSONPathDocument))
+	require.True(suite.T(), BodyJSONPathExistsMatcher("$.items[1]").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(testJSONPathDocument))
+	require.False(suite.T(), BodyJSONPathExistsMatcher("$.items[2]").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("not json"))
+	require.False(suite.T(), BodyJSONPathExistsMatcher("$").Match(req))
+	req.Body = nil
+	require.False(suite.T(), BodyJSONPathExistsMatcher("$").Match(req))
+
+	// Builder and record filter
+	suite.hts.When().Post("/orders").WithJSONPath("$.order.ref", "A-1").
+		RespondWith().Status(http.StatusCreated)
+	client := suite.hts.Client()
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/orders", "application/json", strings.NewReader(testJSONPathDocument))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/orders", "application/json", strings.NewReader(`{"order": {"ref": "B-2"}}`))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	records := suite.hts.FindRecords(ByJSONPath("$.order.ref", "B-2"))
+	require.Len(suite.T(), records, 1)
+	require.Equal(suite.T(), http.StatusNotFound, records[0].Response.Code)
+}
@@ -0,0 +1,367 @@
+package gosette
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // Register SHA-256 used by JWT algorithms
+	_ "crypto/sha512" // Register SHA-384 and SHA-512 used by JWT algorithms
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A function which returns the key used to verify a JWT given its decoded header (ex: to select
+// a key by using the kid header).
+type JWTKeyFunc func(header map[string]interface{}) (interface{}, error)
+
+// Option used to configure the JWT validation performed by ValidateJWT.
+type JWTOption func(cfg *jwtConfig)
+
+// Configuration of the JWT validation.
+type jwtConfig struct {
+	// Expected issuer (iss claim). Not checked when empty.
+	issuer string
+	// Expected audience (aud claim). Not checked when empty.
+	audience string
+	// Tolerance used when exp and nbf claims are checked.
+	leeway time.Duration
+	// True if requests with a missing or invalid token must be answered with a 401 response.
+	enforce bool
+}
+
+// Option which makes the JWT validation check the token issuer (iss claim).
+func JWTIssuer(issuer string) JWTOption {
+	return func(cfg *jwtConfig) {
+		cfg.issuer = issuer
+	}
+}
+
+// Option which makes the JWT validation check the token audience (aud claim).
+func JWTAudience(audience string) JWTOption {
+	return func(cfg *jwtConfig) {
+		cfg.audience = audience
+	}
+}
+
+// Option which sets the tolerance used when the token expiry (exp claim) and not before (nbf
+// claim) times are checked.
+func JWTLeeway(leeway time.Duration) JWTOption {
+	return func(cfg *jwtConfig) {
+		cfg.leeway = leeway
+	}
+}
+
+// Option which makes the test server answer requests with a missing or invalid token with a 401
+// response which has a WWW-Authenticate header instead of only recording the validation failure.
+func EnforceJWT() JWTOption {
+	return func(cfg *jwtConfig) {
+		cfg.enforce = true
+	}
+}
+
+// Build a middleware which validates the JWT presented by incoming requests in their
+// Authorization header (Bearer scheme).
+//
+// The claims of the token are recorded in the ServerRecord JWTClaims as soon as the token can be
+// decoded. Validation failures (missing token, invalid signature, expired token, ...) are recorded
+// in the ServerRecord ValidationErrors. Use EnforceJWT to answer these requests with a 401
+// response.
+//
+// The key is used to verify the token signature. Supported keys and algorithms are: []byte for
+// HS256, HS384 and HS512, *rsa.PublicKey for RS256, RS384, RS512, PS256, PS384 and PS512,
+// *ecdsa.PublicKey for ES256, ES384 and ES512 and ed25519.PublicKey for EdDSA. The corresponding
+// private keys can be used as well. A JWTKeyFunc can be provided to select the key per token.
+func ValidateJWT(key interface{}, options ...JWTOption) Middleware {
+	cfg := &jwtConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := cfg.validate(r, key)
+			if record := recordFromContext(r.Context()); record != nil {
+				record.JWTClaims = claims
+				if err != nil {
+					record.ValidationErrors = append(record.ValidationErrors, err)
+				}
+			}
+			if err != nil && cfg.enforce {
+				writeHeaders(w, &PredefinedServerResponse{
+					Status:  http.StatusUnauthorized,
+					Headers: http.Header{"Www-Authenticate": {`Bearer error="invalid_token"`}},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Return the token presented in the Authorization header (Bearer scheme) of the recorded request.
+// Returns an empty string if the request has no Bearer token.
+func (record *ServerRecord) BearerToken() string {
+	if record.Request == nil {
+		return ""
+	}
+	return bearerToken(record.Request)
+}
+
+// Check that the recorded JWT claims contain the provided claim with the expected value. Values
+// are compared once encoded in JSON and decoded: 1, int64(1) and float64(1) are equivalent.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertJWTClaim(name string, expected interface{}) error {
+	value, ok := record.JWTClaims[name]
+	if !ok {
+		return fmt.Errorf("expected JWT claim %s to be %v, got no claim", name, expected)
+	}
+	if !equalJSONValues(value, expected) {
+		return fmt.Errorf("expected JWT claim %s to be %v, got %v", name, expected, value)
+	}
+	return nil
+}
+
+// Helper function which returns the Bearer token presented by the provided request or an empty
+// string if there is none.
+func bearerToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(authorization[7:])
+}
+
+// Helper method which validates the token presented by the provided request. The decoded claims
+// are returned as soon as the token can be decoded, even if it is invalid.
+func (cfg *jwtConfig) validate(r *http.Request, key interface{}) (map[string]interface{}, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	_, claims, err := verifyJWT(token, key)
+	if err != nil {
+		return claims, err
+	}
+	if err := cfg.validateClaims(claims, clockFromContext(r.Context()).Now()); err != nil {
+		return claims, err
+	}
+	return claims, nil
+}
+
+// Helper method which validates the registered claims (exp, nbf, iss and aud).
+func (cfg *jwtConfig) validateClaims(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(cfg.leeway)) {
+		return fmt.Errorf("invalid JWT: token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(cfg.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("invalid JWT: token is not valid yet")
+	}
+	if cfg.issuer != "" && claims["iss"] != cfg.issuer {
+		return fmt.Errorf("invalid JWT: expected issuer %s, got %v", cfg.issuer, claims["iss"])
+	}
+	if cfg.audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == cfg.audience
+		case []interface{}:
+			for _, value := range aud {
+				found = found || value == cfg.audience
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid JWT: expected audience %s, got %v", cfg.audience, claims["aud"])
+		}
+	}
+	return nil
+}
+
+/*************************************************************************************************/
+/* JWS                                                                                           */
+/*************************************************************************************************/
+
+// Hash functions used by the supported JWT algorithms.
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// Helper function which decodes the provided JWT and verifies its signature with the provided
+// key. The decoded header and claims are returned as soon as the token can be decoded, even if
+// its signature is invalid.
+func verifyJWT(token string, key interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	// Decode token
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("invalid JWT: expected 3 parts, got %d", len(parts))
+	}
+	header := map[string]interface{}{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, nil, fmt.Errorf("invalid JWT header: %w", err)
+	}
+	claims := map[string]interface{}{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return header, nil, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, claims, fmt.Errorf("invalid JWT signature: %w", err)
+	}
+	// Resolve key
+	if keyFunc, ok := key.(JWTKeyFunc); ok {
+		key, err = keyFunc(header)
+		if err != nil {
+			return header, claims, fmt.Errorf("invalid JWT: failed to get key: %w", err)
+		}
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	// Verify signature
+	alg, _ := header["alg"].(string)
+	input := []byte(parts[0] + "." + parts[1])
+	if err := verifyJWS(alg, key, input, signature); err != nil {
+		return header, claims, fmt.Errorf("invalid JWT: %w", err)
+	}
+	return header, claims, nil
+}
+
+// Helper function which verifies the provided signature of the provided input.
+func verifyJWS(alg string, key interface{}, input []byte, signature []byte) error {
+	var digest []byte
+	hash, ok := jwtHashes[alg]
+	if ok {
+		h := hash.New()
+		h.Write(input)
+		digest = h.Sum(nil)
+	}
+	valid := false
+	switch typed := key.(type) {
+	case []byte:
+		if !strings.HasPrefix(alg, "HS") || !ok {
+			return fmt.Errorf("algorithm %q cannot be used with a HMAC key", alg)
+		}
+		mac := hmac.New(hash.New, typed)
+		mac.Write(input)
+		valid = hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		switch {
+		case strings.HasPrefix(alg, "RS") && ok:
+			valid = rsa.VerifyPKCS1v15(typed, hash, digest, signature) == nil
+		case strings.HasPrefix(alg, "PS") && ok:
+			valid = rsa.VerifyPSS(typed, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		default:
+			return fmt.Errorf("algorithm %q cannot be used with a RSA key", alg)
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") || !ok {
+			return fmt.Errorf("algorithm %q cannot be used with an ECDSA key", alg)
+		}
+		size := (typed.Curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(typed, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			return fmt.Errorf("algorithm %q cannot be used with an Ed25519 key", alg)
+		}
+		valid = ed25519.Verify(typed, input, signature)
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	if !valid {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// Helper function which signs the provided claims with the provided algorithm and key and returns
+// the compact serialization of the token. The provided additional header fields (ex: kid) are
+// added to the token header.
+func signJWT(claims map[string]interface{}, alg string, key interface{}, extraHeader map[string]interface{}) (string, error) {
+	// Encode header and claims
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	for name, value := range extraHeader {
+		header[name] = value
+	}
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedClaims)
+	// Sign
+	var digest []byte
+	hash, ok := jwtHashes[alg]
+	if ok {
+		h := hash.New()
+		h.Write([]byte(input))
+		digest = h.Sum(nil)
+	}
+	var signature []byte
+	switch typed := key.(type) {
+	case []byte:
+		if !strings.HasPrefix(alg, "HS") || !ok {
+			return "", fmt.Errorf("algorithm %q cannot be used with a HMAC key", alg)
+		}
+		mac := hmac.New(hash.New, typed)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		switch {
+		case strings.HasPrefix(alg, "RS") && ok:
+			signature, err = rsa.SignPKCS1v15(rand.Reader, typed, hash, digest)
+		case strings.HasPrefix(alg, "PS") && ok:
+			signature, err = rsa.SignPSS(rand.Reader, typed, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		default:
+			return "", fmt.Errorf("algorithm %q cannot be used with a RSA key", alg)
+		}
+	case *ecdsa.PrivateKey:
+		if !strings.HasPrefix(alg, "ES") || !ok {
+			return "", fmt.Errorf("algorithm %q cannot be used with an ECDSA key", alg)
+		}
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, typed, digest)
+		if err == nil {
+			size := (typed.Curve.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		}
+	case ed25519.PrivateKey:
+		if alg != "EdDSA" {
+			return "", fmt.Errorf("algorithm %q cannot be used with an Ed25519 key", alg)
+		}
+		signature = ed25519.Sign(typed, []byte(input))
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Helper function which decodes a base64url encoded JSON part of a JWT.
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
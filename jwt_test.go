@@ -0,0 +1,157 @@
+package gosette
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test JWT signature verification with all supported algorithms.
+func TestVerifyJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecKey521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alg       string
+		signKey   interface{}
+		verifyKey interface{}
+	}{
+		{"HS256", []byte("secret"), []byte("secret")},
+		{"HS512", []byte("secret"), []byte("secret")},
+		{"RS256", rsaKey, &rsaKey.PublicKey},
+		{"PS384", rsaKey, rsaKey},
+		{"ES256", ecKey256, &ecKey256.PublicKey},
+		{"ES512", ecKey521, &ecKey521.PublicKey},
+		{"EdDSA", edKey, edKey.Public()},
+	}
+	claims := map[string]interface{}{"sub": "john"}
+	for _, tc := range testCases {
+		token, err := signJWT(claims, tc.alg, tc.signKey, map[string]interface{}{"kid": "1"})
+		require.NoError(t, err, tc.alg)
+		header, decoded, err := verifyJWT(token, tc.verifyKey)
+		require.NoError(t, err, tc.alg)
+		require.Equal(t, "1", header["kid"])
+		require.Equal(t, claims, decoded)
+		// Wrong key
+		_, _, err = verifyJWT(token, []byte("wrong"))
+		require.Error(t, err, tc.alg)
+		_, _, err = verifyJWT(token, &otherRSAKey.PublicKey)
+		require.Error(t, err, tc.alg)
+	}
+
+	// Key function
+	token, err := signJWT(claims, "HS256", []byte("secret"), map[string]interface{}{"kid": "k1"})
+	require.NoError(t, err)
+	keyFunc := JWTKeyFunc(func(header map[string]interface{}) (interface{}, error) {
+		if header["kid"] == "k1" {
+			return []byte("secret"), nil
+		}
+		return nil, fmt.Errorf("unknown key")
+	})
+	_, _, err = verifyJWT(token, keyFunc)
+	require.NoError(t, err)
+	token, err = signJWT(claims, "HS256", []byte("secret"), nil)
+	require.NoError(t, err)
+	_, _, err = verifyJWT(token, keyFunc)
+	require.Error(t, err)
+
+	// Invalid tokens and signing errors
+	for _, invalid := range []string{"a.b", "!.e30.", "e30.!.", "e30.e30.!", "e30.e30.e30"} {
+		_, _, err := verifyJWT(invalid, []byte("secret"))
+		require.Error(t, err, invalid)
+	}
+	_, _, err = verifyJWT(token, "unsupported")
+	require.Error(t, err)
+	for _, tc := range []struct {
+		alg string
+		key interface{}
+	}{{"RS256", []byte("secret")}, {"HS256", rsaKey}, {"HS256", ecKey256}, {"HS256", edKey}, {"HS256", "unsupported"}} {
+		_, err := signJWT(claims, tc.alg, tc.key, nil)
+		require.Error(t, err, tc.alg)
+	}
+	_, err = signJWT(map[string]interface{}{"invalid": make(chan int)}, "HS256", []byte("secret"), nil)
+	require.Error(t, err)
+}
+
+// Test the ValidateJWT middleware. Test will ensure:
+//   - Claims are recorded and validation failures are recorded as validation errors
+//   - Registered claims (exp, nbf, iss, aud) are checked
+//   - Requests with invalid tokens are answered with a 401 response when EnforceJWT is used
+func TestValidateJWT(t *testing.T) {
+	key := []byte("secret")
+	srv := NewHTTPTestServer(nil)
+	srv.Use(ValidateJWT(key, JWTIssuer("https://issuer"), JWTAudience("api"), JWTLeeway(time.Second)))
+	srv.Start()
+	defer srv.Close()
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"iss": "https://issuer", "aud": []string{"api", "other"}, "exp": now + 60, "sub": "john"}
+	testCases := []struct {
+		name   string
+		claims map[string]interface{}
+		key    []byte
+		errors int
+	}{
+		{"valid", valid, key, 0},
+		{"wrong key", valid, []byte("wrong"), 1},
+		{"expired", map[string]interface{}{"iss": "https://issuer", "aud": "api", "exp": now - 60}, key, 1},
+		{"not valid yet", map[string]interface{}{"iss": "https://issuer", "aud": "api", "nbf": now + 60}, key, 1},
+		{"wrong issuer", map[string]interface{}{"iss": "https://other", "aud": "api"}, key, 1},
+		{"wrong audience", map[string]interface{}{"iss": "https://issuer", "aud": "other"}, key, 1},
+		{"missing token", nil, nil, 1},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodGet, srv.GetBaseURL(), nil)
+		require.NoError(t, err)
+		if tc.claims != nil {
+			token, err := signJWT(tc.claims, "HS256", tc.key, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode, tc.name)
+		record := srv.PopServerRecord()
+		require.NotNil(t, record)
+		require.Len(t, record.ValidationErrors, tc.errors, tc.name)
+		if tc.claims != nil {
+			require.NotNil(t, record.JWTClaims, tc.name)
+			require.NotEmpty(t, record.BearerToken(), tc.name)
+		} else {
+			require.Empty(t, record.BearerToken(), tc.name)
+		}
+		if tc.name == "valid" {
+			require.NoError(t, record.AssertJWTClaim("sub", "john"))
+			require.NoError(t, record.AssertJWTClaim("exp", now+60))
+			require.Error(t, record.AssertJWTClaim("sub", "jane"))
+			require.Error(t, record.AssertJWTClaim("missing", "jane"))
+		}
+	}
+	require.Empty(t, (&ServerRecord{}).BearerToken())
+
+	// Enforce
+	srv.ClearMiddlewares()
+	srv.Use(ValidateJWT(key, EnforceJWT()))
+	resp, err := srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, `Bearer error="invalid_token"`, resp.Header.Get("WWW-Authenticate"))
+}
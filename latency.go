@@ -0,0 +1,101 @@
+package gosette
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A distribution latencies are drawn from. Implementations must be safe for concurrent use.
+type LatencyDistribution interface {
+	// Draw a latency from the distribution.
+	Sample() time.Duration
+}
+
+// A LatencyDistribution which draws latencies with a function of a seeded random source.
+type latencyDistribution struct {
+	// Mutex used to protect the random source from concurrent access.
+	mu sync.Mutex
+	// Random source.
+	rnd *rand.Rand
+	// Function which draws a latency from the random source.
+	draw func(rnd *rand.Rand) float64
+}
+
+// Draw a latency from the distribution. Negative latencies are replaced by zero.
+func (d *latencyDistribution) Sample() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sample := time.Duration(d.draw(d.rnd))
+	if sample < 0 {
+		return 0
+	}
+	return sample
+}
+
+// Build a distribution which draws latencies uniformly in [min, max). The seed makes the sequence
+// of latencies reproducible. Use HTTPTestServer UniformLatency to draw latencies from the random
+// source of the test server.
+func UniformLatency(min time.Duration, max time.Duration, seed int64) LatencyDistribution {
+	return uniformLatency(min, max, rand.New(rand.NewSource(seed)))
+}
+
+// Build a distribution which draws latencies from a normal distribution with the provided mean and
+// standard deviation. Negative latencies are replaced by zero. The seed makes the sequence of
+// latencies reproducible.
+func NormalLatency(mean time.Duration, stddev time.Duration, seed int64) LatencyDistribution {
+	return normalLatency(mean, stddev, rand.New(rand.NewSource(seed)))
+}
+
+// Build a distribution which draws latencies from an exponential distribution with the provided
+// mean. The seed makes the sequence of latencies reproducible.
+func ExponentialLatency(mean time.Duration, seed int64) LatencyDistribution {
+	return exponentialLatency(mean, rand.New(rand.NewSource(seed)))
+}
+
+// Helper function which builds a distribution which draws latencies uniformly in [min, max) from
+// the provided random source.
+func uniformLatency(min time.Duration, max time.Duration, rnd *rand.Rand) LatencyDistribution {
+	return &latencyDistribution{
+		rnd: rnd,
+		draw: func(rnd *rand.Rand) float64 {
+			return float64(min) + rnd.Float64()*float64(max-min)
+		},
+	}
+}
+
+// Helper function which builds a distribution which draws latencies from a normal distribution
+// with the provided mean and standard deviation using the provided random source.
+func normalLatency(mean time.Duration, stddev time.Duration, rnd *rand.Rand) LatencyDistribution {
+	return &latencyDistribution{
+		rnd: rnd,
+		draw: func(rnd *rand.Rand) float64 {
+			return float64(mean) + rnd.NormFloat64()*float64(stddev)
+		},
+	}
+}
+
+// Helper function which builds a distribution which draws latencies from an exponential
+// distribution with the provided mean using the provided random source.
+func exponentialLatency(mean time.Duration, rnd *rand.Rand) LatencyDistribution {
+	return &latencyDistribution{
+		rnd: rnd,
+		draw: func(rnd *rand.Rand) float64 {
+			return rnd.ExpFloat64() * float64(mean)
+		},
+	}
+}
+
+// Build a middleware which waits for a latency drawn from the provided distribution before each
+// request is served. The wait is interrupted if the request context is done.
+func AddLatency(distribution LatencyDistribution) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sleep(r.Context(), distribution.Sample()) != nil {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
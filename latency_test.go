@@ -0,0 +1,84 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test distributions are reproducible with the same seed and draw latencies in their range.
+func TestLatencyDistributions(t *testing.T) {
+	build := map[string]func() LatencyDistribution{
+		"uniform":     func() LatencyDistribution { return UniformLatency(10*time.Millisecond, 20*time.Millisecond, 42) },
+		"normal":      func() LatencyDistribution { return NormalLatency(10*time.Millisecond, 50*time.Millisecond, 42) },
+		"exponential": func() LatencyDistribution { return ExponentialLatency(10*time.Millisecond, 42) },
+	}
+	for name, newDistribution := range build {
+		first, second := newDistribution(), newDistribution()
+		for i := 0; i < 100; i++ {
+			sample := first.Sample()
+			require.Equal(t, sample, second.Sample(), name)
+			require.GreaterOrEqual(t, sample, time.Duration(0), name)
+			if name == "uniform" {
+				require.GreaterOrEqual(t, sample, 10*time.Millisecond)
+				require.Less(t, sample, 20*time.Millisecond)
+			}
+		}
+	}
+}
+
+// Test jitter is added to the predefined response delay and the AddLatency middleware delays all
+// requests.
+func TestJitter(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.Use(AddLatency(UniformLatency(50*time.Millisecond, 60*time.Millisecond, 1)))
+	hts.When().Get("/slow").RespondWith().Status(http.StatusOK).Jitter(UniformLatency(100*time.Millisecond, 110*time.Millisecond, 1))
+	start := time.Now()
+	resp, err := hts.Client().Get(hts.GetBaseURL() + "/slow")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+// Test headers and body can be delayed separately. Test will ensure:
+//   - Headers are sent once the delay has elapsed
+//   - The body is sent once the body delay has elapsed and its length is announced beforehand
+func TestBodyDelay(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	hts := NewHTTPTestServer(nil, WithClock(clock))
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/slow").RespondWith().Delay(time.Minute).BodyDelay(time.Hour).StringBody("slow body")
+	headers := make(chan *http.Response, 1)
+	body := make(chan string, 1)
+	go func() {
+		resp, err := hts.Client().Get(hts.GetBaseURL() + "/slow")
+		if err != nil {
+			close(headers)
+			return
+		}
+		defer resp.Body.Close()
+		headers <- resp
+		content, _ := io.ReadAll(resp.Body)
+		body <- string(content)
+	}()
+	// Slow first byte
+	clock.WaitForSleepers(1)
+	require.Len(t, headers, 0)
+	clock.Advance(time.Minute)
+	// Slow body
+	resp := <-headers
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(len("slow body")), resp.ContentLength)
+	clock.WaitForSleepers(1)
+	require.Len(t, body, 0)
+	clock.Advance(time.Hour)
+	require.Equal(t, "slow body", <-body)
+}
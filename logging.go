@@ -0,0 +1,73 @@
+package gosette
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A structured log entry emitted by the test server once an exchange is over. See WithLogFunc.
+type LogEntry struct {
+	// Time at which the request has been received by the test server.
+	Time time.Time
+	// HTTP method of the request.
+	Method string
+	// URL path of the request.
+	Path string
+	// Status code of the response.
+	Status int
+	// Time elapsed between the reception of the request and the end of the exchange.
+	Latency time.Duration
+	// What has served the response (see ServerRecord ServedBy). Empty if the response has been
+	// written by a middleware.
+	ServedBy string
+	// The error which has occured while handling the request if any (see ServerRecord
+	// ServerError).
+	Error error
+	// The server record of the exchange.
+	Record *ServerRecord
+}
+
+// Option which makes the test server call the provided function with a structured log entry once
+// each exchange is over. Useful to find out why a predefined response has not been served. See
+// WithLogger to emit the entries with a *slog.Logger.
+//
+// The function is called from the goroutine which serves the request and must be safe for
+// concurrent use. Scopes use the log function of the test server they have been created from.
+func WithLogFunc(fn func(entry LogEntry)) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.logFunc = fn
+	}
+}
+
+// Helper function which builds the log entry of the provided server record. The latency is
+// computed from the provided current time of the test server clock.
+func newLogEntry(serverRecord *ServerRecord, now time.Time) LogEntry {
+	entry := LogEntry{
+		Time:     serverRecord.ReceivedAt,
+		Method:   serverRecord.Request.Method,
+		Path:     serverRecord.Request.URL.Path,
+		Status:   serverRecord.Response.Code,
+		Latency:  now.Sub(serverRecord.ReceivedAt),
+		ServedBy: serverRecord.ServedBy,
+		Error:    serverRecord.ServerError,
+		Record:   serverRecord,
+	}
+	// The record is added before the 500 response is written in case of error
+	if entry.Error != nil {
+		entry.Status = http.StatusInternalServerError
+	}
+	return entry
+}
+
+// Helper function which describes what has served a predefined response for the ServedBy field
+// of records: the kind of source followed by the response name if any or by the provided detail.
+func servedBy(kind string, response *PredefinedServerResponse, detail string) string {
+	if response != nil && response.Name != "" {
+		return kind + " " + strconv.Quote(response.Name)
+	}
+	if detail != "" {
+		return kind + " " + detail
+	}
+	return kind
+}
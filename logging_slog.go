@@ -0,0 +1,30 @@
+//go:build go1.21
+// +build go1.21
+
+package gosette
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Option which makes the test server emit one structured entry per exchange with the provided
+// logger: method, path, status, latency, what has served the response and error if any. Entries
+// are logged at the Info level, or at the Error level when an error has occured. See WithLogFunc.
+func WithLogger(logger *slog.Logger) ServerOption {
+	return WithLogFunc(func(entry LogEntry) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("method", entry.Method),
+			slog.String("path", entry.Path),
+			slog.Int("status", entry.Status),
+			slog.Duration("latency", entry.Latency),
+			slog.String("served_by", entry.ServedBy),
+		}
+		if entry.Error != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", entry.Error.Error()))
+		}
+		logger.LogAttrs(context.Background(), level, "gosette exchange", attrs...)
+	})
+}
@@ -0,0 +1,40 @@
+//go:build go1.21
+// +build go1.21
+
+package gosette
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test WithLogger option. Test will ensure one structured entry is emitted per exchange.
+func TestWithLogger(t *testing.T) {
+	// Create and start a test server which logs to a JSON handler
+	buf := &bytes.Buffer{}
+	srv := NewHTTPTestServer(nil, WithLogger(slog.New(slog.NewJSONHandler(buf, nil))))
+	srv.Start()
+	defer srv.Close()
+	srv.When().Get("/users").RespondWith().Named("list-users").Status(http.StatusOK)
+
+	// Send a request
+	resp, err := srv.Client().Get(srv.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Check the entry
+	entry := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "INFO", entry["level"])
+	require.Equal(t, "GET", entry["method"])
+	require.Equal(t, "/users", entry["path"])
+	require.Equal(t, float64(http.StatusOK), entry["status"])
+	require.Equal(t, `stub "list-users"`, entry["served_by"])
+	require.Contains(t, entry, "latency")
+	require.NotContains(t, entry, "error")
+}
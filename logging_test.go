@@ -0,0 +1,100 @@
+package gosette
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test WithLogFunc option. Test will ensure one log entry is emitted per exchange and tells what
+// has served the response.
+func TestWithLogFunc(t *testing.T) {
+	// Create and start a test server which collects log entries
+	mu := sync.Mutex{}
+	entries := []LogEntry{}
+	srv := NewHTTPTestServer(nil, WithLogFunc(func(entry LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, entry)
+	}))
+	srv.Start()
+	defer srv.Close()
+	srv.When().Post("/users").RespondWith().Named("create-user").Status(http.StatusCreated)
+	srv.RegisterResponse(PathMatcher("/health"), &PredefinedServerResponse{Status: http.StatusOK})
+	srv.PushPredefinedServerResponseForRoute(http.MethodGet, "/orders", &PredefinedServerResponse{Status: http.StatusOK})
+	srv.PushPredefinedServerResponse(&PredefinedServerResponse{Status: http.StatusAccepted})
+
+	// Send requests
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/users"},
+		{http.MethodGet, "/health"},
+		{http.MethodGet, "/orders"},
+		{http.MethodDelete, "/other"},
+	} {
+		request, err := http.NewRequest(req.method, srv.GetBaseURL()+req.path, nil)
+		require.NoError(t, err)
+		resp, err := srv.Client().Do(request)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// Check entries
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, entries, 4)
+	require.Equal(t, http.MethodPost, entries[0].Method)
+	require.Equal(t, "/users", entries[0].Path)
+	require.Equal(t, http.StatusCreated, entries[0].Status)
+	require.Equal(t, `stub "create-user"`, entries[0].ServedBy)
+	require.True(t, entries[0].Latency > 0)
+	require.NotNil(t, entries[0].Record)
+	require.Equal(t, "stub #2", entries[1].ServedBy)
+	require.Equal(t, "queue GET /orders", entries[2].ServedBy)
+	require.Equal(t, "global queue", entries[3].ServedBy)
+	require.Equal(t, http.StatusAccepted, entries[3].Status)
+	for _, entry := range entries {
+		require.NoError(t, entry.Error)
+		require.Equal(t, entry.ServedBy, entry.Record.ServedBy)
+	}
+}
+
+// Test ServedBy is set for default responses, outages and errors.
+func TestServedBy(t *testing.T) {
+	var last LogEntry
+	srv := NewHTTPTestServer(nil, WithLogFunc(func(entry LogEntry) { last = entry }))
+	srv.Start()
+	defer srv.Close()
+
+	// Default response
+	resp, err := srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "default response", srv.PopServerRecord().ServedBy)
+	require.Equal(t, http.StatusNotFound, last.Status)
+
+	// Outage
+	srv.UnavailableBetween(1, 1, OutageServiceUnavailable)
+	resp, err = srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "outage", srv.PopServerRecord().ServedBy)
+
+	// Error: the logged status is the one of the 500 response
+	srv.PushPredefinedServerResponse(&PredefinedServerResponse{BodyFile: "missing.json"})
+	resp, err = srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Error(t, last.Error)
+	require.Equal(t, http.StatusInternalServerError, last.Status)
+	require.Equal(t, "global queue", last.ServedBy)
+
+	// Scopes use the log function of the test server
+	scope := srv.Scope()
+	defer scope.Close()
+	resp, err = scope.Client().Get(scope.GetBaseURL() + "/scoped")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "/scoped", last.Path)
+}
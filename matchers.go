@@ -0,0 +1,171 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Interface for request matchers. A request matcher is a predicate used to decide whether a
+// predefined response must be served for an incoming request.
+//
+// The request body can be read by the matcher: the test server provides each matcher with a
+// fresh copy of the request body.
+//
+// Matchers are evaluated while the test server holds its internal lock so a response is selected
+// and consumed atomically. A matcher must therefore not call methods of the test server (ex:
+// FindRecords, PushPredefinedServerResponse): such calls deadlock. Use a middleware or an
+// OnRequest hook to inspect the test server before responses are selected.
+type RequestMatcher interface {
+	// Return true if the provided request matches the predicate, false otherwise.
+	Match(r *http.Request) bool
+}
+
+// An adapter which allows the use of ordinary functions as request matchers.
+type RequestMatcherFunc func(r *http.Request) bool
+
+// Match calls f(r).
+func (f RequestMatcherFunc) Match(r *http.Request) bool {
+	return f(r)
+}
+
+// A predefined response bound to a request matcher.
+type stub struct {
+	// Matcher used to decide whether the response must be served.
+	matcher RequestMatcher
+	// The predefined response to serve.
+	response *PredefinedServerResponse
+	// Number of times the response has been served.
+	served int
+	// Name of the scenario the stub belongs to. Empty if the stub does not belong to a scenario.
+	scenario string
+	// State the scenario must be in for the stub to be served.
+	requiredState string
+	// State the scenario moves to once the stub has been served. No transition occurs when empty.
+	newState string
+	// Expected number of requests served by the stub. Nil if no expectation has been set.
+	expected *expectation
+	// Priority of the stub. Stubs with a higher priority are consulted first.
+	priority int
+}
+
+// Register a predefined response which will be served for each request matched by the provided
+// matcher.
+//
+// Registered responses are consulted in their registration order before any response queue: the
+// first registered response whose matcher matches the incoming request is served (see
+// RegisterResponseWithPriority to change this order). By default, a registered response is served
+// indefinitly. Use the response Repeat to limit how many times the response may be served: once
+// exhausted, the response is skipped.
+func (hts *HTTPTestServer) RegisterResponse(matcher RequestMatcher, resp *PredefinedServerResponse) {
+	hts.registerStub(&stub{matcher: matcher, response: resp})
+}
+
+// Helper method which registers the provided stub.
+func (hts *HTTPTestServer) registerStub(s *stub) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.stubs = append(hts.stubs, s)
+}
+
+// Helper method which returns the first registered stub, by decreasing priority, which is not
+// exhausted and which matches the provided request or nil if no stub matches the request. The
+// served counter of the returned stub is incremented. The provided body is used to provide each
+// matcher with a fresh copy of the request body.
+func (srv *HTTPTestServer) matchStub(r *http.Request, body []byte) *stub {
+	for _, s := range srv.stubsByPriority() {
+		// Skip exhausted stubs and stubs whose scenario is not in the required state
+		if s.response.exhausted(s.served, 0) || !srv.inScenarioState(s) {
+			continue
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if s.matcher.Match(r) {
+			s.served++
+			srv.applyScenarioTransition(s)
+			return s
+		}
+	}
+	return nil
+}
+
+// Helper method which returns the registration index, starting from 1, of the provided stub or 0
+// if the stub is not registered. The caller must hold the lock.
+func (srv *HTTPTestServer) stubIndex(s *stub) int {
+	for i, registered := range srv.stubs {
+		if registered == s {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+/*************************************************************************************************/
+/* BUILT-IN MATCHERS                                                                             */
+/*************************************************************************************************/
+
+// Build a request matcher which matches requests matched by all the provided matchers. Each
+// matcher is provided with a fresh copy of the request body. A matcher built without any
+// matchers matches all requests.
+func MatchAll(matchers ...RequestMatcher) RequestMatcher {
+	return allMatcher(matchers)
+}
+
+// Build a request matcher which matches requests whose URL path is equal to the provided path.
+func PathMatcher(path string) RequestMatcher {
+	return newCriterionMatcher("path", fmt.Sprintf("%q", path), func(r *http.Request) string {
+		return fmt.Sprintf("%q", r.URL.Path)
+	}, func(r *http.Request) bool {
+		return r.URL.Path == path
+	})
+}
+
+// Build a request matcher which matches requests whose Host header (without port) is equal to the
+// provided host name. Comparison is case insensitive.
+func HostMatcher(host string) RequestMatcher {
+	return newCriterionMatcher("host", fmt.Sprintf("%q", host), func(r *http.Request) string {
+		return fmt.Sprintf("%q", requestHostname(r))
+	}, func(r *http.Request) bool {
+		return strings.EqualFold(requestHostname(r), host)
+	})
+}
+
+// Build a request matcher which matches requests which use the provided HTTP method. Comparison
+// is case insensitive.
+func MethodMatcher(method string) RequestMatcher {
+	return newCriterionMatcher("method", fmt.Sprintf("%q", method), func(r *http.Request) string {
+		return fmt.Sprintf("%q", r.Method)
+	}, func(r *http.Request) bool {
+		return strings.EqualFold(r.Method, method)
+	})
+}
+
+// Build a request matcher which matches requests which have at least one value for the provided
+// header which is equal to the provided value.
+func HeaderEqualsMatcher(header string, value string) RequestMatcher {
+	return newCriterionMatcher("header "+header, fmt.Sprintf("%q", value), func(r *http.Request) string {
+		return explainedStrings(r.Header.Values(header))
+	}, func(r *http.Request) bool {
+		for _, v := range r.Header.Values(header) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Build a request matcher which matches requests whose body contains the provided substring.
+func BodyContainsMatcher(substr string) RequestMatcher {
+	return newCriterionMatcher("body", fmt.Sprintf("to contain %q", substr), explainedBody, func(r *http.Request) bool {
+		if r.Body == nil {
+			return substr == ""
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return false
+		}
+		return strings.Contains(string(body), substr)
+	})
+}
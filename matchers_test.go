@@ -0,0 +1,107 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Test HTTPTestServer when predefined responses are registered with request matchers. Test will
+// ensure:
+//   - Registered responses are served when their matcher matches the request
+//   - The first registered response whose matcher matches is served
+//   - Registered responses have precedence over response queues
+//   - Registered responses are served indefinitly
+func (suite *HTTPTestServerUnitTestSuite) TestRegisterResponse() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Register responses and push a response to the global queue
+	suite.hts.RegisterResponse(BodyContainsMatcher("urgent"), &PredefinedServerResponse{
+		Status: http.StatusAccepted,
+	})
+	suite.hts.RegisterResponse(HeaderEqualsMatcher("X-Version", "2"), &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+
+	// Body matcher is used and the body is still recorded
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(suite.hts.GetBaseURL(), "text/plain", strings.NewReader("urgent order"))
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), http.StatusAccepted, resp.StatusCode)
+		record := suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.Equal(suite.T(), "urgent order", record.RequestBody.String())
+	}
+
+	// Header matcher is used
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL(), nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("X-Version", "2")
+	resp, err := client.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+
+	// Global queue is used when no matchers match
+	resp, err = client.Get(suite.hts.GetBaseURL())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	// Clear responses and ensure registered responses are cleared
+	suite.hts.ClearPredefinedServerResponses()
+	require.Empty(suite.T(), suite.hts.stubs)
+}
+
+// Test built-in request matchers.
+func (suite *HTTPTestServerUnitTestSuite) TestBuiltInMatchers() {
+	// Build a request
+	req := httptest.NewRequest(http.MethodPost, "/users?id=1", strings.NewReader("hello world"))
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Add("Accept", "application/json")
+	// Path matcher
+	require.True(suite.T(), PathMatcher("/users").Match(req))
+	require.False(suite.T(), PathMatcher("/orders").Match(req))
+	// Method matcher
+	require.True(suite.T(), MethodMatcher("post").Match(req))
+	require.False(suite.T(), MethodMatcher(http.MethodGet).Match(req))
+	// Header matcher
+	require.True(suite.T(), HeaderEqualsMatcher("accept", "application/json").Match(req))
+	require.False(suite.T(), HeaderEqualsMatcher("Accept", "text/html").Match(req))
+	// Body matcher
+	require.True(suite.T(), BodyContainsMatcher("world").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+	require.False(suite.T(), BodyContainsMatcher("world").Match(req))
+	req.Body = nil
+	require.False(suite.T(), BodyContainsMatcher("world").Match(req))
+	require.True(suite.T(), BodyContainsMatcher("").Match(req))
+	// Body matcher when body cannot be read
+	mockedReadCloser := mockReadCloser{}
+	mockedReadCloser.On("Read", mock.Anything).Return(0, fmt.Errorf("PWNED"))
+	req.Body = &mockedReadCloser
+	require.False(suite.T(), BodyContainsMatcher("").Match(req))
+}
+
+// Test MatchAll provides each matcher with a fresh copy of the request body.
+func (suite *HTTPTestServerUnitTestSuite) TestMatchAll() {
+	// Build a request
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("hello world"))
+	require.True(suite.T(), MatchAll(BodyContainsMatcher("hello"), BodyContainsMatcher("world")).Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("hello world"))
+	require.False(suite.T(), MatchAll(PathMatcher("/users"), BodyContainsMatcher("bye")).Match(req))
+	// A matcher without matchers matches all requests
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Body = nil
+	require.True(suite.T(), MatchAll().Match(req))
+	// Body cannot be read
+	mockedReadCloser := mockReadCloser{}
+	mockedReadCloser.On("Read", mock.Anything).Return(0, fmt.Errorf("PWNED"))
+	req.Body = &mockedReadCloser
+	require.False(suite.T(), MatchAll().Match(req))
+}
@@ -0,0 +1,161 @@
+package gosette
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Metrics collected by the test server for the requests of a route: a HTTP method and a request
+// path.
+type RouteMetrics struct {
+	// HTTP method of the route. Empty for the metrics of all routes.
+	Method string
+	// Request path of the route. Empty for the metrics of all routes.
+	Path string
+	// Number of requests served.
+	Count int
+	// Number of responses served by status code.
+	StatusCodes map[int]int
+	// Number of request body bytes received (raw bytes when the body has been compressed).
+	BytesIn int64
+	// Number of response body bytes sent.
+	BytesOut int64
+	// Latencies of the requests, in the order they have been served. The latency of a request is
+	// the time elapsed between the reception of the request and the end of its processing.
+	Latencies []time.Duration
+}
+
+// Return the latency below which the provided percentage of the latencies fall (ex: 99 for the
+// 99th percentile), with the nearest-rank method. Returns zero if no requests have been served.
+func (rm *RouteMetrics) Percentile(p float64) time.Duration {
+	if len(rm.Latencies) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(rm.Latencies))
+	copy(sorted, rm.Latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Return the mean latency. Returns zero if no requests have been served.
+func (rm *RouteMetrics) Mean() time.Duration {
+	if len(rm.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, latency := range rm.Latencies {
+		total += latency
+	}
+	return total / time.Duration(len(rm.Latencies))
+}
+
+// Helper method which adds the provided record to the metrics.
+func (rm *RouteMetrics) add(record *ServerRecord, latency time.Duration) {
+	rm.Count++
+	rm.StatusCodes[record.Response.Code]++
+	if record.BodySpooled {
+		rm.BytesIn += record.SpooledBodySize
+	} else if record.RawRequestBody != nil {
+		rm.BytesIn += int64(record.RawRequestBody.Len())
+	} else {
+		rm.BytesIn += int64(record.RequestBody.Len())
+	}
+	rm.BytesOut += record.responseBodySize()
+	rm.Latencies = append(rm.Latencies, latency)
+}
+
+// Helper method which returns a deep copy of the metrics.
+func (rm *RouteMetrics) copy() *RouteMetrics {
+	copied := *rm
+	copied.StatusCodes = make(map[int]int, len(rm.StatusCodes))
+	for status, count := range rm.StatusCodes {
+		copied.StatusCodes[status] = count
+	}
+	copied.Latencies = append([]time.Duration{}, rm.Latencies...)
+	return &copied
+}
+
+// Metrics collected by the test server since it has been created or its metrics reset.
+type Metrics struct {
+	// Metrics of all routes together.
+	Total *RouteMetrics
+	// Metrics per route, sorted by path and method.
+	Routes []*RouteMetrics
+}
+
+// Get the metrics of the route with the provided method and path. Returns nil if no requests have
+// been served for the route.
+func (m *Metrics) Route(method string, path string) *RouteMetrics {
+	for _, rm := range m.Routes {
+		if rm.Method == method && rm.Path == path {
+			return rm
+		}
+	}
+	return nil
+}
+
+// Get a snapshot of the metrics collected by the test server: request count, status codes, bytes
+// in and out and latencies per route. Metrics are collected for each server record, including
+// records which have been popped.
+//
+// Metrics are not reset by ClearServerRecords and Clear. Use ResetMetrics to reset them.
+func (hts *HTTPTestServer) Metrics() *Metrics {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	metrics := &Metrics{
+		Total:  hts.totalMetrics.copy(),
+		Routes: make([]*RouteMetrics, 0, len(hts.metrics)),
+	}
+	for _, rm := range hts.metrics {
+		metrics.Routes = append(metrics.Routes, rm.copy())
+	}
+	sort.Slice(metrics.Routes, func(i, j int) bool {
+		if metrics.Routes[i].Path != metrics.Routes[j].Path {
+			return metrics.Routes[i].Path < metrics.Routes[j].Path
+		}
+		return metrics.Routes[i].Method < metrics.Routes[j].Method
+	})
+	return metrics
+}
+
+// Reset the metrics collected by the test server.
+func (hts *HTTPTestServer) ResetMetrics() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.metrics = map[route]*RouteMetrics{}
+	hts.totalMetrics = newRouteMetrics("", "")
+}
+
+// Helper method which adds the provided record to the metrics. The caller must hold the lock.
+func (srv *HTTPTestServer) collectMetrics(record *ServerRecord) {
+	if record.Request == nil {
+		return
+	}
+	latency := srv.clock.Now().Sub(record.ReceivedAt)
+	key := route{method: record.Request.Method, path: record.Request.URL.Path}
+	rm, ok := srv.metrics[key]
+	if !ok {
+		rm = newRouteMetrics(key.method, key.path)
+		srv.metrics[key] = rm
+	}
+	rm.add(record, latency)
+	srv.totalMetrics.add(record, latency)
+}
+
+// Helper function which builds empty metrics for the provided route.
+func newRouteMetrics(method string, path string) *RouteMetrics {
+	return &RouteMetrics{
+		Method:      method,
+		Path:        path,
+		StatusCodes: map[int]int{},
+		Latencies:   []time.Duration{},
+	}
+}
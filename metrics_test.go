@@ -0,0 +1,67 @@
+package gosette
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test metrics are collected per route and for all routes and can be reset.
+func TestMetrics(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/slow").RespondWith().StringBody("hello").Response().Delay = 20 * time.Millisecond
+	hts.When().Post("/items").RespondWith().Status(http.StatusCreated)
+	client := hts.Client()
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(hts.GetBaseURL() + "/slow")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	resp, err := client.Post(hts.GetBaseURL()+"/items", "text/plain", strings.NewReader("abc"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = client.Get(hts.GetBaseURL() + "/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	hts.ClearServerRecords()
+	metrics := hts.Metrics()
+	require.Len(t, metrics.Routes, 3)
+	require.Equal(t, "/items", metrics.Routes[0].Path)
+	slow := metrics.Route(http.MethodGet, "/slow")
+	require.Equal(t, 2, slow.Count)
+	require.Equal(t, map[int]int{http.StatusOK: 2}, slow.StatusCodes)
+	require.Equal(t, int64(10), slow.BytesOut)
+	require.GreaterOrEqual(t, slow.Percentile(50), 20*time.Millisecond)
+	require.GreaterOrEqual(t, slow.Mean(), 20*time.Millisecond)
+	items := metrics.Route(http.MethodPost, "/items")
+	require.Equal(t, int64(3), items.BytesIn)
+	require.Equal(t, map[int]int{http.StatusCreated: 1}, items.StatusCodes)
+	require.Nil(t, metrics.Route(http.MethodDelete, "/items"))
+	require.Equal(t, 4, metrics.Total.Count)
+	require.Equal(t, map[int]int{http.StatusOK: 2, http.StatusCreated: 1, http.StatusNotFound: 1}, metrics.Total.StatusCodes)
+	require.Equal(t, metrics.Total.Percentile(100), slow.Percentile(100))
+	// Reset metrics
+	hts.ResetMetrics()
+	metrics = hts.Metrics()
+	require.Empty(t, metrics.Routes)
+	require.Zero(t, metrics.Total.Percentile(99))
+	require.Zero(t, metrics.Total.Mean())
+}
+
+// Test percentiles are computed with the nearest-rank method.
+func TestPercentile(t *testing.T) {
+	rm := &RouteMetrics{}
+	for i := 10; i >= 1; i-- {
+		rm.Latencies = append(rm.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	require.Equal(t, 1*time.Millisecond, rm.Percentile(0))
+	require.Equal(t, 5*time.Millisecond, rm.Percentile(50))
+	require.Equal(t, 9*time.Millisecond, rm.Percentile(90))
+	require.Equal(t, 10*time.Millisecond, rm.Percentile(99))
+	require.Equal(t, 10*time.Millisecond, rm.Percentile(150))
+}
@@ -0,0 +1,66 @@
+package gosette
+
+import "net/http"
+
+// A middleware which wraps the stub-serving logic of the test server. Middlewares can be used to
+// inject auth checks, artificial jitter, logging, ... without replacing the whole handler.
+//
+// Middlewares are called once the request body has been recorded: the request body can be read by
+// middlewares. Responses written by middlewares are recorded like predefined responses, even if a
+// middleware does not call the next handler.
+type Middleware func(next http.Handler) http.Handler
+
+// Register middlewares which wrap the stub-serving logic of the test server. Middlewares are
+// called in their registration order: the first registered middleware is the outermost one.
+//
+// Middlewares are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) Use(middlewares ...Middleware) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.middlewares = append(hts.middlewares, middlewares...)
+}
+
+// Register a hook called for each incoming request once the request body has been recorded and
+// before the middlewares are called. Hooks are called in their registration order.
+//
+// Hooks are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) OnRequest(hook func(r *http.Request)) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.onRequestHooks = append(hts.onRequestHooks, hook)
+}
+
+// Register a hook called each time a server record is added to the record queue, once the
+// request has been served. Hooks are called in their registration order.
+//
+// Hooks are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) OnResponse(hook func(record *ServerRecord)) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.onResponseHooks = append(hts.onResponseHooks, hook)
+}
+
+// Remove all registered middlewares and hooks, including connection hooks.
+func (hts *HTTPTestServer) ClearMiddlewares() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.middlewares = []Middleware{}
+	hts.onRequestHooks = []func(r *http.Request){}
+	hts.onResponseHooks = []func(record *ServerRecord){}
+	hts.onConnAcceptHooks = nil
+	hts.onConnCloseHooks = nil
+}
+
+// Helper method which returns the registered middlewares.
+func (srv *HTTPTestServer) getMiddlewares() []Middleware {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.middlewares
+}
+
+// Helper method which returns the registered OnRequest hooks.
+func (srv *HTTPTestServer) getOnRequestHooks() []func(r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.onRequestHooks
+}
@@ -0,0 +1,91 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test middlewares and hooks. Test will ensure:
+//   - Middlewares are called in their registration order around the stub-serving logic
+//   - Middlewares can read the request body and set response headers
+//   - Responses written by middlewares which do not call the next handler are recorded
+//   - OnRequest and OnResponse hooks are called for each exchange
+//   - ClearMiddlewares removes middlewares and hooks
+func TestMiddlewares(t *testing.T) {
+	// Create and start a test server
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	srv.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte("hello"),
+	})
+
+	// Register a middleware which tracks calls and sets a header
+	calls := []string{}
+	srv.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "outer")
+			w.Header().Set("X-Middleware", "outer")
+			next.ServeHTTP(w, r)
+		})
+	})
+	// Register an auth middleware which reads the body
+	srv.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "auth")
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("unauthorized: " + string(body)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	// Register hooks
+	requests, responses := 0, 0
+	srv.OnRequest(func(r *http.Request) { requests++ })
+	srv.OnResponse(func(record *ServerRecord) { responses++ })
+
+	// Send an unauthenticated request: the auth middleware replies
+	resp, err := srv.Client().Post(srv.GetBaseURL(), "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, "outer", resp.Header.Get("X-Middleware"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "unauthorized: payload", string(body))
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Equal(t, http.StatusUnauthorized, record.Response.Code)
+	require.Equal(t, "outer", record.Response.Header().Get("X-Middleware"))
+	require.Equal(t, "payload", record.RequestBody.String())
+	require.Equal(t, []string{"outer", "auth"}, calls)
+
+	// Send an authenticated request: the predefined response is served
+	req, err := http.NewRequest(http.MethodGet, srv.GetBaseURL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "outer", resp.Header.Get("X-Middleware"))
+	require.NotNil(t, srv.PopServerRecord())
+	require.Equal(t, 2, requests)
+	require.Equal(t, 2, responses)
+
+	// Clear middlewares and hooks
+	srv.ClearMiddlewares()
+	resp, err = srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("X-Middleware"))
+	require.Equal(t, 2, requests)
+	require.Equal(t, 2, responses)
+}
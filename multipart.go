@@ -0,0 +1,124 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// A part of a multipart request body recorded by the test server.
+type MultipartPart struct {
+	// Name of the form field the part belongs to. Empty if the part has no form-data disposition.
+	FieldName string
+	// Name of the uploaded file. Empty if the part is not a file.
+	FileName string
+	// Content type of the part. Empty if the part has no Content-Type header.
+	ContentType string
+	// Headers of the part.
+	Header textproto.MIMEHeader
+	// A copy of the part payload.
+	Content []byte
+}
+
+// Return the first recorded multipart part which belongs to the provided form field or nil if
+// there is no such part.
+func (record *ServerRecord) MultipartPart(fieldName string) *MultipartPart {
+	for _, part := range record.MultipartParts {
+		if part.FieldName == fieldName {
+			return part
+		}
+	}
+	return nil
+}
+
+// Helper function which parses the provided request body in case the provided content type is a
+// multipart content type (multipart/form-data, multipart/mixed, ...). Returns nil if the content
+// type is not a multipart content type.
+func parseMultipartParts(contentType string, body []byte) ([]*MultipartPart, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("missing multipart boundary")
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	parts := []*MultipartPart{}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart part: %w", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart part content: %w", err)
+		}
+		parts = append(parts, &MultipartPart{
+			FieldName:   part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Header:      part.Header,
+			Content:     content,
+		})
+	}
+}
+
+// Helper function which encodes the provided parts as a multipart body with the provided subtype
+// (ex: mixed, related, form-data). Returns the body and its content type.
+//
+// Part headers are completed with a Content-Type header built from the part ContentType and a
+// Content-Disposition header built from the part FieldName and FileName (form-data disposition
+// when the part has a field name, attachment disposition otherwise). The content type of
+// multipart/related bodies declares the content type and the Content-ID of the first part as the
+// type and the start of the body.
+func encodeMultipart(subtype string, parts []*MultipartPart) ([]byte, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		for name, values := range part.Header {
+			header[name] = append([]string(nil), values...)
+		}
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		}
+		if part.FieldName != "" || part.FileName != "" {
+			disposition, params := "attachment", map[string]string{}
+			if part.FieldName != "" {
+				disposition = "form-data"
+				params["name"] = part.FieldName
+			}
+			if part.FileName != "" {
+				params["filename"] = part.FileName
+			}
+			header.Set("Content-Disposition", mime.FormatMediaType(disposition, params))
+		}
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create multipart part: %w", err)
+		}
+		if _, err := w.Write(part.Content); err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to close multipart body: %w", err)
+	}
+	params := map[string]string{"boundary": writer.Boundary()}
+	if subtype == "related" && len(parts) > 0 {
+		if contentType := parts[0].ContentType; contentType != "" {
+			params["type"] = contentType
+		}
+		if start := parts[0].Header.Get("Content-ID"); start != "" {
+			params["start"] = start
+		}
+	}
+	return body.Bytes(), mime.FormatMediaType("multipart/"+subtype, params), nil
+}
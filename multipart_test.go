@@ -0,0 +1,133 @@
+package gosette
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test multipart request bodies are parsed and recorded. Test will ensure:
+//   - Fields and files are recorded with their names, content types and payloads
+//   - Requests without multipart content type have no parts
+//   - Malformed multipart bodies are served like any other request and reported in the record
+//     ValidationErrors
+func (suite *HTTPTestServerUnitTestSuite) TestMultipartParts() {
+	client := suite.hts.Client()
+
+	// Build a multipart body with a field and a file
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(suite.T(), writer.WriteField("title", "report"))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="report.csv"`)
+	header.Set("Content-Type", "text/csv")
+	part, err := writer.CreatePart(header)
+	require.NoError(suite.T(), err)
+	_, err = part.Write([]byte("a,b\n1,2\n"))
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), writer.Close())
+
+	// Upload
+	uploaded := body.Bytes()
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/upload", writer.FormDataContentType(), bytes.NewReader(uploaded))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.NoError(suite.T(), record.ServerError)
+	require.Len(suite.T(), record.MultipartParts, 2)
+	title := record.MultipartPart("title")
+	require.NotNil(suite.T(), title)
+	require.Equal(suite.T(), "", title.FileName)
+	require.Equal(suite.T(), "report", string(title.Content))
+	file := record.MultipartPart("file")
+	require.NotNil(suite.T(), file)
+	require.Equal(suite.T(), "report.csv", file.FileName)
+	require.Equal(suite.T(), "text/csv", file.ContentType)
+	require.Equal(suite.T(), "a,b\n1,2\n", string(file.Content))
+	require.Nil(suite.T(), record.MultipartPart("missing"))
+
+	// Not a multipart request
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/upload", "text/plain", strings.NewReader("hello"))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	record = suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Nil(suite.T(), record.MultipartParts)
+
+	// Malformed multipart bodies: missing boundary, garbage, empty and truncated uploads
+	suite.hts.When().Post("/upload").RespondWith().Status(http.StatusCreated)
+	for _, tc := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"multipart/form-data", []byte("garbage")},
+		{"multipart/form-data; boundary=xyz", []byte("garbage")},
+		{"multipart/form-data; boundary=xyz", []byte{}},
+		{writer.FormDataContentType(), uploaded[:len(uploaded)/2]},
+	} {
+		resp, err = client.Post(suite.hts.GetBaseURL()+"/upload", tc.contentType, bytes.NewReader(tc.body))
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), http.StatusCreated, resp.StatusCode, tc.contentType)
+		record = suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.NoError(suite.T(), record.ServerError)
+		require.Nil(suite.T(), record.MultipartParts)
+		require.Len(suite.T(), record.ValidationErrors, 1, tc.contentType)
+		require.Contains(suite.T(), record.ValidationErrors[0].Error(), "malformed multipart body")
+	}
+}
+
+// Test multipart response bodies built with the response builder can be parsed by clients.
+func (suite *HTTPTestServerUnitTestSuite) TestMultipartResponses() {
+	suite.hts.When().Get("/batch").RespondWith().MultipartMixed(
+		&MultipartPart{ContentType: "application/json", Content: []byte(`{"id": 1}`)},
+		&MultipartPart{ContentType: "image/png", FileName: "avatar.png", Content: []byte{0x89, 'P', 'N', 'G'}},
+		&MultipartPart{FieldName: "note", Header: textproto.MIMEHeader{"X-Index": {"3"}}, Content: []byte("text")},
+	)
+	suite.hts.When().Get("/related").RespondWith().MultipartRelated(
+		&MultipartPart{ContentType: "application/xop+xml", Header: textproto.MIMEHeader{"Content-Id": {"<root>"}}, Content: []byte("<doc/>")},
+		&MultipartPart{ContentType: "application/octet-stream", Content: []byte("data")},
+	)
+	get := func(path string) (string, map[string]string, []*MultipartPart) {
+		resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		require.NoError(suite.T(), err)
+		parts, err := parseMultipartParts(resp.Header.Get("Content-Type"), body)
+		require.NoError(suite.T(), err)
+		return mediaType, params, parts
+	}
+
+	// multipart/mixed
+	mediaType, _, parts := get("/batch")
+	require.Equal(suite.T(), "multipart/mixed", mediaType)
+	require.Len(suite.T(), parts, 3)
+	require.Equal(suite.T(), "application/json", parts[0].ContentType)
+	require.Equal(suite.T(), `{"id": 1}`, string(parts[0].Content))
+	require.Equal(suite.T(), "avatar.png", parts[1].FileName)
+	require.Equal(suite.T(), `attachment; filename=avatar.png`, parts[1].Header.Get("Content-Disposition"))
+	require.Equal(suite.T(), []byte{0x89, 'P', 'N', 'G'}, parts[1].Content)
+	require.Equal(suite.T(), "note", parts[2].FieldName)
+	require.Equal(suite.T(), "3", parts[2].Header.Get("X-Index"))
+	require.Equal(suite.T(), "text", string(parts[2].Content))
+
+	// multipart/related
+	mediaType, params, parts := get("/related")
+	require.Equal(suite.T(), "multipart/related", mediaType)
+	require.Equal(suite.T(), "application/xop+xml", params["type"])
+	require.Equal(suite.T(), "<root>", params["start"])
+	require.Len(suite.T(), parts, 2)
+	require.Equal(suite.T(), "<doc/>", string(parts[0].Content))
+	require.Equal(suite.T(), "data", string(parts[1].Content))
+}
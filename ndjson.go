@@ -0,0 +1,67 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A line of a newline-delimited JSON (NDJSON / JSON Lines) stream.
+type JSONLine struct {
+	// Value sent on the line with its JSON encoding. Use json.RawMessage to send an already
+	// encoded value.
+	Value interface{}
+	// Delay to wait before the line is sent. The delay is interrupted if the request context is
+	// done.
+	Delay time.Duration
+	// Do not flush the response writer after the line: the line is sent with the next flushed one
+	// (or at the end of the response).
+	NoFlush bool
+}
+
+// Helper method which streams the JSON lines of the provided response by using the provided
+// http.ResponseWriter. The Content-Type header (application/x-ndjson) is set unless it is defined
+// by the response. The response writer is flushed after each line unless the line disables it.
+// Streaming stops if the request context is done.
+func (srv *HTTPTestServer) writeJSONLines(w http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// Encode lines before writing headers so encoding errors result in a 500 response
+	lines := make([][]byte, 0, len(response.JSONLines))
+	for i, line := range response.JSONLines {
+		encoded, err := json.Marshal(line.Value)
+		if err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to encode JSON line %d of the predefined response: %w", i, err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		lines = append(lines, append(encoded, '\n'))
+	}
+	// Write headers and status code
+	if response.Headers.Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	writeHeaders(w, response)
+	flush(w)
+	// Stream lines
+	for i, line := range response.JSONLines {
+		if line.Delay > 0 {
+			if sleep(r.Context(), line.Delay) != nil {
+				return
+			}
+		}
+		if _, err := w.Write(lines[i]); err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to write JSON line %d of the predefined response: %w", i, err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		if !line.NoFlush {
+			flush(w)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gosette
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test newline-delimited JSON streaming. Test will ensure:
+//   - Values are sent with their JSON encoding, one value per line
+//   - Default Content-Type header is set and headers defined by the response are kept
+//   - Lines are flushed to the client as soon as they are written unless they disable it
+//   - Values which cannot be encoded result in a 500 response
+func (suite *HTTPTestServerUnitTestSuite) TestJSONLines() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/feed").RespondWith().JSONLines(
+		JSONLine{Value: map[string]int{"id": 1}},
+		JSONLine{Value: json.RawMessage(`{"id":2}`), Delay: 100 * time.Millisecond, NoFlush: true},
+		JSONLine{Value: "three"},
+	)
+
+	start := time.Now()
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/feed")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "application/x-ndjson", resp.Header.Get("Content-Type"))
+	// Read the first line before the next ones are written
+	reader := bufio.NewReader(resp.Body)
+	first, err := reader.ReadString('\n')
+	require.NoError(suite.T(), err)
+	require.Less(suite.T(), int64(time.Since(start)), int64(100*time.Millisecond))
+	require.Equal(suite.T(), "{\"id\":1}\n", first)
+	rest, err := io.ReadAll(reader)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.GreaterOrEqual(suite.T(), int64(time.Since(start)), int64(100*time.Millisecond))
+	require.Equal(suite.T(), "{\"id\":2}\n\"three\"\n", string(rest))
+
+	// Headers defined by the response are kept
+	suite.hts.When().Get("/jsonl").RespondWith().
+		Header("Content-Type", "application/jsonl").
+		NDJSON(1, []string{"a"}, nil)
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/jsonl")
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "application/jsonl", resp.Header.Get("Content-Type"))
+	require.Equal(suite.T(), "1\n[\"a\"]\nnull\n", string(body))
+
+	// Values which cannot be encoded
+	suite.hts.When().Get("/invalid").RespondWith().NDJSON(func() {})
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/invalid")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+}
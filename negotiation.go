@@ -0,0 +1,136 @@
+package gosette
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A representation of the body of a predefined response. See PredefinedServerResponse Variants.
+type BodyVariant struct {
+	// Content type of the representation (ex: application/json, application/xml,
+	// application/x-protobuf).
+	ContentType string
+	// Body of the representation.
+	Body []byte
+}
+
+// A media range of an Accept header and its quality.
+type mediaRange struct {
+	// Main type (ex: application or *).
+	mainType string
+	// Subtype (ex: json or *).
+	subType string
+	// Quality, between 0 and 1.
+	quality float64
+}
+
+// Helper function which selects the predefined response body variant which best matches the
+// Accept header of the provided request and returns a copy of the response with the body of the
+// selected variant, its Content-Type header and a Vary: Accept header. A 406 Not Acceptable
+// response is returned if the request accepts none of the variants.
+func negotiateResponse(r *http.Request, response *PredefinedServerResponse) *PredefinedServerResponse {
+	variant := selectVariant(r.Header.Values("Accept"), response.Variants)
+	if variant == nil {
+		types := make([]string, 0, len(response.Variants))
+		for _, v := range response.Variants {
+			types = append(types, v.ContentType)
+		}
+		return &PredefinedServerResponse{
+			Status: http.StatusNotAcceptable,
+			Headers: http.Header{
+				"Content-Type": {"text/plain"},
+				"Vary":         {"Accept"},
+			},
+			Body: []byte("none of the available representations is acceptable: " + strings.Join(types, ", ")),
+		}
+	}
+	negotiated := *response
+	negotiated.Body = variant.Body
+	negotiated.Headers = response.Headers.Clone()
+	if negotiated.Headers == nil {
+		negotiated.Headers = http.Header{}
+	}
+	negotiated.Headers.Set("Content-Type", variant.ContentType)
+	negotiated.Headers.Add("Vary", "Accept")
+	return &negotiated
+}
+
+// Helper function which selects the variant which has the highest quality according to the
+// provided Accept header values. The most specific media range which matches a variant gives its
+// quality. Variants are preferred in their order in case of tie. The first variant is selected
+// when there is no Accept header. Returns nil if no variants are acceptable.
+func selectVariant(accept []string, variants []BodyVariant) *BodyVariant {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return &variants[0]
+	}
+	var selected *BodyVariant
+	best := 0.0
+	for i := range variants {
+		if q := variantQuality(variants[i].ContentType, ranges); q > best {
+			selected = &variants[i]
+			best = q
+		}
+	}
+	return selected
+}
+
+// Helper function which returns the quality of the provided content type according to the most
+// specific matching media range. Returns 0 if no media range matches.
+func variantQuality(contentType string, ranges []mediaRange) float64 {
+	mainType, subType := splitMediaType(contentType)
+	quality, specificity := 0.0, 0
+	for _, mr := range ranges {
+		s := 0
+		switch {
+		case mr.mainType == mainType && mr.subType == subType:
+			s = 3
+		case mr.mainType == mainType && mr.subType == "*":
+			s = 2
+		case mr.mainType == "*" && mr.subType == "*":
+			s = 1
+		}
+		if s > specificity {
+			quality, specificity = mr.quality, s
+		}
+	}
+	return quality
+}
+
+// Helper function which parses the media ranges of the provided Accept header values. Malformed
+// media ranges are ignored.
+func parseAccept(values []string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			params := strings.Split(item, ";")
+			mainType, subType := splitMediaType(params[0])
+			if mainType == "" || subType == "" {
+				continue
+			}
+			mr := mediaRange{mainType: mainType, subType: subType, quality: 1}
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+					if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q >= 0 && q <= 1 {
+						mr.quality = q
+					}
+				}
+			}
+			ranges = append(ranges, mr)
+		}
+	}
+	return ranges
+}
+
+// Helper function which returns the main type and the subtype of the provided media type, in lower
+// case and without parameters. Returns empty strings if the media type is malformed.
+func splitMediaType(mediaType string) (string, string) {
+	mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+	parts := strings.SplitN(strings.ToLower(mediaType), "/", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,58 @@
+package gosette
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the served body variant is selected based on the request Accept header.
+func (suite *HTTPTestServerUnitTestSuite) TestContentNegotiation() {
+	type user struct {
+		XMLName xml.Name `xml:"user" json:"-"`
+		Name    string   `xml:"name" json:"name"`
+	}
+	suite.hts.When().Get("/users/1").RespondWith().
+		Header("Cache-Control", "no-cache").
+		JSONVariant(user{Name: "alice"}).
+		XMLVariant(user{Name: "alice"}).
+		Variant("application/x-protobuf", []byte{0x0a, 0x05, 'a', 'l', 'i', 'c', 'e'})
+	tests := []struct {
+		accept      []string
+		status      int
+		contentType string
+		body        string
+	}{
+		{nil, http.StatusOK, "application/json", `{"name":"alice"}`},
+		{[]string{"application/xml"}, http.StatusOK, "application/xml", `<user><name>alice</name></user>`},
+		{[]string{"application/x-protobuf"}, http.StatusOK, "application/x-protobuf", "\x0a\x05alice"},
+		{[]string{"*/*"}, http.StatusOK, "application/json", `{"name":"alice"}`},
+		{[]string{"application/json;q=0.5, application/xml;q=0.9"}, http.StatusOK, "application/xml", `<user><name>alice</name></user>`},
+		{[]string{"application/*;q=0.2", "application/x-protobuf"}, http.StatusOK, "application/x-protobuf", "\x0a\x05alice"},
+		{[]string{"application/*, application/json;q=0"}, http.StatusOK, "application/xml", `<user><name>alice</name></user>`},
+		{[]string{"text/html, invalid"}, http.StatusNotAcceptable, "text/plain", "none of the available representations is acceptable: application/json, application/xml, application/x-protobuf"},
+	}
+	for _, tc := range tests {
+		req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL()+"/users/1", nil)
+		require.NoError(suite.T(), err)
+		for _, accept := range tc.accept {
+			req.Header.Add("Accept", accept)
+		}
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), tc.status, resp.StatusCode, tc.accept)
+		require.Equal(suite.T(), tc.contentType, resp.Header.Get("Content-Type"), tc.accept)
+		require.Equal(suite.T(), "Accept", resp.Header.Get("Vary"), tc.accept)
+		require.Equal(suite.T(), tc.body, string(body), tc.accept)
+		if tc.status == http.StatusOK {
+			require.Equal(suite.T(), "no-cache", resp.Header.Get("Cache-Control"))
+		}
+	}
+	require.Panics(suite.T(), func() { suite.hts.When().RespondWith().JSONVariant(func() {}) })
+	require.Panics(suite.T(), func() { suite.hts.When().RespondWith().XMLVariant(func() {}) })
+}
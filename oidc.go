@@ -0,0 +1,126 @@
+package gosette
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Path of the OpenID Connect discovery document served by the OIDC provider preset.
+const OIDCDiscoveryPath = "/.well-known/openid-configuration"
+
+// Path of the JWKS served by the OIDC provider preset.
+const OIDCJWKSPath = "/.well-known/jwks.json"
+
+// A mocked OpenID Connect provider backed by a test server. See EnableOIDCProvider.
+type OIDCProvider struct {
+	// Test server which serves the provider endpoints.
+	hts *HTTPTestServer
+	// Key used to sign ID tokens.
+	key *rsa.PrivateKey
+	// ID of the signing key (kid).
+	kid string
+}
+
+// Enable the OpenID Connect provider preset: the test server serves the discovery document
+// (/.well-known/openid-configuration) and a JWKS (/.well-known/jwks.json) which contains the
+// public part of a generated RSA signing key. The issuer is the test server base URL.
+//
+// The returned provider can be used to mint ID tokens signed by the generated key. Other
+// endpoints announced by the discovery document (authorization, token, userinfo) are not served
+// by the preset: register predefined responses for them if needed.
+//
+// The preset is implemented as a middleware: it is not removed by Clear.
+func (hts *HTTPTestServer) EnableOIDCProvider() (*OIDCProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OIDC signing key: %w", err)
+	}
+	provider := &OIDCProvider{hts: hts, key: key, kid: "gosette-1"}
+	hts.Use(provider.middleware)
+	return provider, nil
+}
+
+// Return the issuer of the provider: the test server base URL.
+func (p *OIDCProvider) Issuer() string {
+	return p.hts.GetBaseURL()
+}
+
+// Return the public key used to verify the ID tokens minted by the provider. The key can be used
+// with ValidateJWT.
+func (p *OIDCProvider) PublicKey() *rsa.PublicKey {
+	return &p.key.PublicKey
+}
+
+// Mint an ID token signed by the provider (RS256). The iss, iat and exp (one hour) claims are set
+// unless they are provided.
+func (p *OIDCProvider) MintIDToken(claims map[string]interface{}) (string, error) {
+	now := p.hts.clock.Now()
+	token := map[string]interface{}{
+		"iss": p.Issuer(),
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		token[name] = value
+	}
+	return signJWT(token, "RS256", p.key, map[string]interface{}{"kid": p.kid})
+}
+
+// Helper method which serves the discovery document and the JWKS.
+func (p *OIDCProvider) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var document interface{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == OIDCDiscoveryPath:
+			document = p.discoveryDocument()
+		case r.Method == http.MethodGet && r.URL.Path == OIDCJWKSPath:
+			document = p.jwks()
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, _ := json.Marshal(document)
+		writeHeaders(w, &PredefinedServerResponse{
+			Status:  http.StatusOK,
+			Headers: http.Header{"Content-Type": {"application/json"}},
+		})
+		w.Write(body)
+	})
+}
+
+// Helper method which returns the discovery document of the provider.
+func (p *OIDCProvider) discoveryDocument() map[string]interface{} {
+	issuer := p.Issuer()
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + OIDCJWKSPath,
+		"response_types_supported":              []string{"code", "id_token", "code id_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	}
+}
+
+// Helper method which returns the JWKS of the provider.
+func (p *OIDCProvider) jwks() map[string]interface{} {
+	return map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	}
+}
@@ -0,0 +1,64 @@
+package gosette
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the OIDC provider preset. Test will ensure:
+//   - The discovery document announces the issuer and the JWKS URI
+//   - The JWKS contains the key which signs the minted ID tokens
+//   - The preset survives Clear and other requests are still served
+func TestOIDCProvider(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	provider, err := srv.EnableOIDCProvider()
+	require.NoError(t, err)
+	srv.Start()
+	defer srv.Close()
+	srv.Clear()
+
+	// Discovery document
+	resp, err := srv.Client().Get(srv.GetBaseURL() + OIDCDiscoveryPath)
+	require.NoError(t, err)
+	discovery := map[string]interface{}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&discovery))
+	resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, srv.GetBaseURL(), discovery["issuer"])
+	require.Equal(t, provider.Issuer(), discovery["issuer"])
+
+	// JWKS
+	resp, err = srv.Client().Get(discovery["jwks_uri"].(string))
+	require.NoError(t, err)
+	jwks := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+	resp.Body.Close()
+	require.Len(t, jwks.Keys, 1)
+	n, err := base64.RawURLEncoding.DecodeString(jwks.Keys[0]["n"])
+	require.NoError(t, err)
+	require.Equal(t, 0, new(big.Int).SetBytes(n).Cmp(provider.PublicKey().N))
+
+	// Mint and verify an ID token
+	token, err := provider.MintIDToken(map[string]interface{}{"sub": "john", "aud": "client"})
+	require.NoError(t, err)
+	header, claims, err := verifyJWT(token, provider.PublicKey())
+	require.NoError(t, err)
+	require.Equal(t, jwks.Keys[0]["kid"], header["kid"])
+	require.Equal(t, provider.Issuer(), claims["iss"])
+	require.Equal(t, "john", claims["sub"])
+	require.Contains(t, claims, "exp")
+
+	// Other requests
+	resp, err = srv.Client().Get(srv.GetBaseURL() + "/token")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Len(t, srv.FindRecords(), 3)
+}
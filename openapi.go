@@ -0,0 +1,368 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Option used to configure how an OpenAPI document is loaded by LoadOpenAPISpec.
+type OpenAPIOption func(cfg *openAPIConfig)
+
+// Configuration used to load an OpenAPI document.
+type openAPIConfig struct {
+	// True if incoming requests must be validated against the document.
+	validate bool
+}
+
+// Option which enables the validation of incoming requests against the OpenAPI document. The
+// validation failures are recorded in the ServerRecord ValidationErrors.
+//
+// Requests are validated against the operation they target. The following checks are performed:
+// required path, query and header parameters are present, the request body is present when
+// required and its Content-Type is declared by the operation.
+func ValidateRequests() OpenAPIOption {
+	return func(cfg *openAPIConfig) {
+		cfg.validate = true
+	}
+}
+
+// Load an OpenAPI 3 document (JSON or YAML) from the file at the provided path and register a
+// predefined response for each operation it declares.
+//
+// For each operation, the response with the lowest 2xx status code (or the default response) is
+// served. The response body is the example (or the first example) of the first declared media
+// type, application/json being preferred. Literal paths are registered before templated paths
+// (ex: /users/me before /users/{id}). In case the document declares servers, the path of the first
+// server URL is used as base path.
+//
+// References ($ref) are not resolved: operations, responses and examples must be declared inline.
+func (hts *HTTPTestServer) LoadOpenAPISpec(path string, options ...OpenAPIOption) error {
+	// Apply options
+	cfg := &openAPIConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	// Read and decode the document
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read OpenAPI document from %s: %w", path, err)
+	}
+	doc := &openAPIDocument{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("failed to decode OpenAPI document from %s: %w", path, err)
+	}
+	// Build operations
+	operations, err := doc.operations()
+	if err != nil {
+		return fmt.Errorf("invalid OpenAPI document %s: %w", path, err)
+	}
+	// Register a response for each operation
+	for _, op := range operations {
+		response, err := op.predefinedResponse()
+		if err != nil {
+			return fmt.Errorf("invalid OpenAPI document %s: %s %s: %w", path, op.method, op.path, err)
+		}
+		hts.RegisterResponse(op, response)
+	}
+	// Register the validation middleware
+	if cfg.validate {
+		hts.Use(openAPIValidationMiddleware(operations))
+	}
+	return nil
+}
+
+/*************************************************************************************************/
+/* OPENAPI DOCUMENT                                                                              */
+/*************************************************************************************************/
+
+// Subset of an OpenAPI 3 document used to stub an API.
+type openAPIDocument struct {
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]*openAPIPathItem `yaml:"paths"`
+}
+
+// Subset of an OpenAPI 3 path item.
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation   `yaml:"get"`
+	Put        *openAPIOperation   `yaml:"put"`
+	Post       *openAPIOperation   `yaml:"post"`
+	Delete     *openAPIOperation   `yaml:"delete"`
+	Options    *openAPIOperation   `yaml:"options"`
+	Head       *openAPIOperation   `yaml:"head"`
+	Patch      *openAPIOperation   `yaml:"patch"`
+	Trace      *openAPIOperation   `yaml:"trace"`
+}
+
+// Subset of an OpenAPI 3 operation.
+type openAPIOperation struct {
+	OperationID string                      `yaml:"operationId"`
+	Parameters  []*openAPIParameter         `yaml:"parameters"`
+	RequestBody *openAPIRequestBody         `yaml:"requestBody"`
+	Responses   map[string]*openAPIResponse `yaml:"responses"`
+	// Method, path and compiled path of the operation. Set when operations are built.
+	method  string
+	path    string
+	pattern *regexp.Regexp
+	// Parameters inherited from the path item.
+	inherited []*openAPIParameter
+}
+
+// Subset of an OpenAPI 3 parameter.
+type openAPIParameter struct {
+	Name     string `yaml:"name"`
+	In       string `yaml:"in"`
+	Required bool   `yaml:"required"`
+}
+
+// Subset of an OpenAPI 3 request body.
+type openAPIRequestBody struct {
+	Required bool                         `yaml:"required"`
+	Content  map[string]*openAPIMediaType `yaml:"content"`
+}
+
+// Subset of an OpenAPI 3 response.
+type openAPIResponse struct {
+	Headers map[string]*struct {
+		Example interface{} `yaml:"example"`
+	} `yaml:"headers"`
+	Content map[string]*openAPIMediaType `yaml:"content"`
+}
+
+// Subset of an OpenAPI 3 media type.
+type openAPIMediaType struct {
+	Example  interface{} `yaml:"example"`
+	Examples map[string]*struct {
+		Value interface{} `yaml:"value"`
+	} `yaml:"examples"`
+}
+
+// Regular expression used to find path template parameters.
+var pathTemplateParameterRegexp = regexp.MustCompile(`\{[^/{}]+\}`)
+
+// Helper method which returns the operations declared by the document. Literal paths are
+// returned before templated paths.
+func (doc *openAPIDocument) operations() ([]*openAPIOperation, error) {
+	// Compute base path from the first server
+	basePath := ""
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL %s: %w", doc.Servers[0].URL, err)
+		}
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	// Sort paths: literal paths first, then by number of parameters and finally by name
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		pi := len(pathTemplateParameterRegexp.FindAllString(paths[i], -1))
+		pj := len(pathTemplateParameterRegexp.FindAllString(paths[j], -1))
+		if pi != pj {
+			return pi < pj
+		}
+		return paths[i] < paths[j]
+	})
+	// Build operations
+	operations := []*openAPIOperation{}
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		// Compile the path template
+		pattern, _ := pathTemplateToRegexp(basePath, path)
+		compiled := regexp.MustCompile(pattern)
+		// Add operations in a deterministic order
+		for _, candidate := range []struct {
+			method string
+			op     *openAPIOperation
+		}{
+			{http.MethodGet, item.Get},
+			{http.MethodPut, item.Put},
+			{http.MethodPost, item.Post},
+			{http.MethodDelete, item.Delete},
+			{http.MethodOptions, item.Options},
+			{http.MethodHead, item.Head},
+			{http.MethodPatch, item.Patch},
+			{http.MethodTrace, item.Trace},
+		} {
+			if candidate.op == nil {
+				continue
+			}
+			candidate.op.method = candidate.method
+			candidate.op.path = basePath + path
+			candidate.op.pattern = compiled
+			candidate.op.inherited = item.Parameters
+			operations = append(operations, candidate.op)
+		}
+	}
+	return operations, nil
+}
+
+// Match returns true if the provided request targets the operation.
+func (op *openAPIOperation) Match(r *http.Request) bool {
+	return r.Method == op.method && op.pattern.MatchString(r.URL.Path)
+}
+
+// Helper method which builds the predefined response served for the operation.
+func (op *openAPIOperation) predefinedResponse() (*PredefinedServerResponse, error) {
+	// Select the response with the lowest 2xx status code or the default response
+	status, key := 0, ""
+	for code := range op.Responses {
+		value, err := strconv.Atoi(code)
+		if err == nil && value >= 200 && value < 300 && (status == 0 || value < status) {
+			status, key = value, code
+		}
+	}
+	if key == "" {
+		if _, ok := op.Responses["default"]; !ok {
+			return nil, fmt.Errorf("no 2xx or default response declared")
+		}
+		status, key = http.StatusOK, "default"
+	}
+	response := &PredefinedServerResponse{
+		Status:  status,
+		Headers: http.Header{},
+	}
+	declared := op.Responses[key]
+	if declared == nil {
+		return response, nil
+	}
+	// Set header examples
+	for name, header := range declared.Headers {
+		if header != nil && header.Example != nil {
+			response.Headers.Set(name, fmt.Sprint(header.Example))
+		}
+	}
+	// Select the media type: application/json is preferred, the first one otherwise
+	mediaTypes := make([]string, 0, len(declared.Content))
+	for mediaType := range declared.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	for i, mediaType := range mediaTypes {
+		if mediaType == "application/json" {
+			mediaTypes[0], mediaTypes[i] = mediaTypes[i], mediaTypes[0]
+		}
+	}
+	if len(mediaTypes) == 0 {
+		return response, nil
+	}
+	mediaType := mediaTypes[0]
+	response.Headers.Set("Content-Type", mediaType)
+	// Build the body from the example
+	example := declared.Content[mediaType].example()
+	if example == nil {
+		return response, nil
+	}
+	if str, ok := example.(string); ok && !strings.Contains(mediaType, "json") {
+		response.Body = []byte(str)
+		return response, nil
+	}
+	body, err := json.Marshal(example)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode example: %w", err)
+	}
+	response.Body = body
+	return response, nil
+}
+
+// Helper method which returns the example of the media type or its first example (by name).
+// Returns nil if the media type has no examples.
+func (mt *openAPIMediaType) example() interface{} {
+	if mt == nil {
+		return nil
+	}
+	if mt.Example != nil {
+		return mt.Example
+	}
+	names := make([]string, 0, len(mt.Examples))
+	for name := range mt.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if mt.Examples[name] != nil {
+			return mt.Examples[name].Value
+		}
+	}
+	return nil
+}
+
+/*************************************************************************************************/
+/* VALIDATION                                                                                    */
+/*************************************************************************************************/
+
+// Helper function which builds a middleware which validates incoming requests against the
+// operation they target and records validation failures in the server record.
+func openAPIValidationMiddleware(operations []*openAPIOperation) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record := recordFromContext(r.Context())
+			for _, op := range operations {
+				if op.Match(r) {
+					if record != nil {
+						record.ValidationErrors = append(record.ValidationErrors, op.validate(r, record.RequestBody.Len())...)
+					}
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Helper method which validates the provided request against the operation. The size of the
+// request body must be provided. Returns the validation failures.
+func (op *openAPIOperation) validate(r *http.Request, bodySize int) []error {
+	failures := []error{}
+	// Check required parameters
+	for _, param := range append(append([]*openAPIParameter{}, op.inherited...), op.Parameters...) {
+		if param == nil || !(param.Required || param.In == "path") {
+			continue
+		}
+		missing := false
+		switch param.In {
+		case "query":
+			_, ok := r.URL.Query()[param.Name]
+			missing = !ok
+		case "header":
+			missing = r.Header.Get(param.Name) == ""
+		case "cookie":
+			_, err := r.Cookie(param.Name)
+			missing = err != nil
+		}
+		if missing {
+			failures = append(failures, fmt.Errorf("%s %s: missing required %s parameter %s", op.method, op.path, param.In, param.Name))
+		}
+	}
+	// Check request body
+	if op.RequestBody != nil {
+		if bodySize == 0 {
+			if op.RequestBody.Required {
+				failures = append(failures, fmt.Errorf("%s %s: missing required request body", op.method, op.path))
+			}
+		} else if len(op.RequestBody.Content) > 0 {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if _, ok := op.RequestBody.Content[mediaType]; !ok {
+				failures = append(failures, fmt.Errorf("%s %s: unsupported request content type %q", op.method, op.path, r.Header.Get("Content-Type")))
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,150 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// OpenAPI document used in tests.
+const testOpenAPISpec = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+    get:
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+      responses:
+        "404":
+          description: Not found
+        "200":
+          description: User
+          headers:
+            X-Rate-Limit:
+              example: 100
+          content:
+            application/json:
+              example:
+                id: 42
+                name: john
+  /users/me:
+    get:
+      responses:
+        default:
+          description: Current user
+          content:
+            text/plain:
+              examples:
+                me:
+                  value: john
+  /users:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json: {}
+      responses:
+        "201":
+          description: Created
+`
+
+// Test LoadOpenAPISpec. Test will ensure:
+//   - A response is registered for each operation with the example of the 2xx/default response
+//   - Literal paths take precedence over templated paths
+//   - The base path of the first server is used
+//   - Requests are validated when ValidateRequests is used
+func TestLoadOpenAPISpec(t *testing.T) {
+	// Write the document
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testOpenAPISpec), 0o600))
+
+	// Create and start a test server
+	srv := NewHTTPTestServer(nil)
+	require.NoError(t, srv.LoadOpenAPISpec(path, ValidateRequests()))
+	srv.Start()
+	defer srv.Close()
+
+	// Get a user - Expect example and header example, missing header parameter
+	resp, err := http.Get(srv.GetBaseURL() + "/v1/users/42")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, "100", resp.Header.Get("X-Rate-Limit"))
+	require.JSONEq(t, `{"id": 42, "name": "john"}`, string(body))
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Len(t, record.ValidationErrors, 1)
+	require.Contains(t, record.ValidationErrors[0].Error(), "X-Tenant")
+
+	// Get current user - Expect the literal path to be matched
+	resp, err = http.Get(srv.GetBaseURL() + "/v1/users/me")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "john", string(body))
+
+	// Create a user with a wrong content type - Expect a validation error
+	resp, err = http.Post(srv.GetBaseURL()+"/v1/users", "text/plain", strings.NewReader("john"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	// Create a user without body - Expect a validation error
+	resp, err = http.Post(srv.GetBaseURL()+"/v1/users", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	// Create a user - Expect no validation errors
+	resp, err = http.Post(srv.GetBaseURL()+"/v1/users", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	records := srv.FindRecords(ByMethod(http.MethodPost))
+	require.Len(t, records, 3)
+	require.Len(t, records[0].ValidationErrors, 1)
+	require.Contains(t, records[0].ValidationErrors[0].Error(), "unsupported request content type")
+	require.Len(t, records[1].ValidationErrors, 1)
+	require.Contains(t, records[1].ValidationErrors[0].Error(), "missing required request body")
+	require.Empty(t, records[2].ValidationErrors)
+
+	// Unknown path - Expect default response
+	resp, err = http.Get(srv.GetBaseURL() + "/users/42")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// Test LoadOpenAPISpec with invalid documents. Test will ensure an error is returned when the
+// document cannot be read or decoded or when an operation has no usable response.
+func TestLoadOpenAPISpecErrors(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	dir := t.TempDir()
+	// Missing file
+	require.Error(t, srv.LoadOpenAPISpec(filepath.Join(dir, "missing.yaml")))
+	// Invalid document
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"paths": [`), 0o600))
+	require.Error(t, srv.LoadOpenAPISpec(invalid))
+	// No usable response
+	noResponse := filepath.Join(dir, "noresponse.json")
+	require.NoError(t, os.WriteFile(noResponse, []byte(`{"paths": {"/a": {"get": {"responses": {"500": {}}}}}}`), 0o600))
+	require.Error(t, srv.LoadOpenAPISpec(noResponse))
+}
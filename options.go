@@ -0,0 +1,15 @@
+package gosette
+
+// Option used to configure a HTTPTestServer when it is created by NewHTTPTestServer.
+type ServerOption func(hts *HTTPTestServer)
+
+// Option which enables HTTP/2 on the underlying httptest.Server.
+//
+// HTTP/2 is negotiated with TLS (ALPN): the server must be started with StartTLS and the client
+// returned by Client must be used (or any client configured for HTTP/2 which trusts the server
+// certificate). The protocol used by each request is recorded in the ServerRecord Protocol.
+func WithHTTP2() ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.server.EnableHTTP2 = true
+	}
+}
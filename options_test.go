@@ -0,0 +1,59 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test WithHTTP2 option. Test will ensure requests are served over HTTP/2 and the protocol is
+// recorded.
+func TestWithHTTP2(t *testing.T) {
+	// Create and start a test server with HTTP/2 enabled
+	srv := NewHTTPTestServer(nil, WithHTTP2())
+	srv.StartTLS()
+	defer srv.Close()
+	srv.When().Get("/h2").RespondWith().StringBody("hello")
+
+	// Send a request
+	resp, err := srv.Client().Get(srv.GetBaseURL() + "/h2")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "HTTP/2.0", resp.Proto)
+	require.Equal(t, "hello", string(body))
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Equal(t, "HTTP/2.0", record.Protocol)
+	require.Equal(t, "h2", record.Request.TLS.NegotiatedProtocol)
+
+	// Without the option, HTTP/1.1 is used
+	srv2 := NewHTTPTestServer(nil)
+	srv2.StartTLS()
+	defer srv2.Close()
+	resp, err = srv2.Client().Get(srv2.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "HTTP/1.1", srv2.PopServerRecord().Protocol)
+}
+
+// Test the test server can be used as a handler by another server without being started (ex: a
+// HTTP/3 server).
+func TestMountedHandler(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	srv.When().Get("/hello").RespondWith().StringBody("hello")
+	// Serve a request as a HTTP/3 server would do
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/3.0", 3, 0
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.Equal(t, "hello", w.Body.String())
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Equal(t, "HTTP/3.0", record.Protocol)
+}
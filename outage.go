@@ -0,0 +1,137 @@
+package gosette
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// How the test server behaves during an outage.
+type OutageMode int
+
+const (
+	// The test server replies with an empty 503 Service Unavailable response.
+	OutageServiceUnavailable OutageMode = iota
+	// The test server resets the client connection (TCP RST) instead of writing a response (see
+	// FaultConnectionReset). Connections are still accepted: use Stop and Restart to make the test
+	// server refuse connections.
+	OutageConnectionReset
+)
+
+// An outage window during which the test server is unavailable. The window can be bounded in time,
+// by a number of requests or both: the test server is unavailable while all the declared bounds
+// are satisfied. Zero values mean unbounded.
+type OutageWindow struct {
+	// How the test server behaves during the outage.
+	Mode OutageMode
+	// Time at which the outage starts. The outage starts immediately when zero.
+	Start time.Time
+	// Time at which the outage ends. The outage does not end in time when zero.
+	End time.Time
+	// Index of the first request, starting from 1, which is affected by the outage. Requests are
+	// counted from the moment the outage is scheduled.
+	FromRequest int
+	// Index of the last request, starting from 1, which is affected by the outage. Requests are
+	// counted from the moment the outage is scheduled.
+	ToRequest int
+	// Value of the Retry-After header of 503 responses. The header is not set when zero.
+	RetryAfter time.Duration
+}
+
+// A scheduled outage window and the number of requests received since it has been scheduled.
+type outage struct {
+	window   OutageWindow
+	requests int
+}
+
+// Schedule an outage window. During the outage, the test server serves neither predefined
+// responses nor proxied responses and behaves as declared by the window mode. The test server
+// recovers automatically once the outage is over. Requests are still recorded.
+//
+// Outages are not removed by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) ScheduleOutage(window OutageWindow) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.outages = append(hts.outages, &outage{window: window})
+}
+
+// Make the test server unavailable for the provided duration, starting now.
+func (hts *HTTPTestServer) Unavailable(d time.Duration, mode OutageMode) {
+	now := hts.clock.Now()
+	hts.ScheduleOutage(OutageWindow{Mode: mode, Start: now, End: now.Add(d)})
+}
+
+// Make the test server unavailable from the nth request to the mth request (both included)
+// received from now on. Requests are counted starting from 1.
+func (hts *HTTPTestServer) UnavailableBetween(n int, m int, mode OutageMode) {
+	hts.ScheduleOutage(OutageWindow{Mode: mode, FromRequest: n, ToRequest: m})
+}
+
+// Remove all scheduled outages: the test server recovers immediately.
+func (hts *HTTPTestServer) ClearOutages() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.outages = []*outage{}
+}
+
+// Helper method which counts a new request for each scheduled outage and returns the response to
+// serve in case the test server is unavailable at the provided time. Returns nil otherwise.
+//
+// Outages which are over are removed.
+func (srv *HTTPTestServer) nextOutageResponse(now time.Time) *PredefinedServerResponse {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var active *outage
+	remaining := []*outage{}
+	for _, o := range srv.outages {
+		o.requests++
+		if o.over(now) {
+			continue
+		}
+		remaining = append(remaining, o)
+		if active == nil && o.active(now) {
+			active = o
+		}
+	}
+	srv.outages = remaining
+	if active == nil {
+		return nil
+	}
+	return active.window.response()
+}
+
+// Helper method which returns true if the outage is in progress at the provided time.
+func (o *outage) active(now time.Time) bool {
+	w := o.window
+	if !w.Start.IsZero() && now.Before(w.Start) {
+		return false
+	}
+	if w.FromRequest > 0 && o.requests < w.FromRequest {
+		return false
+	}
+	return !o.over(now)
+}
+
+// Helper method which returns true if the outage is over at the provided time.
+func (o *outage) over(now time.Time) bool {
+	w := o.window
+	if !w.End.IsZero() && !now.Before(w.End) {
+		return true
+	}
+	return w.ToRequest > 0 && o.requests > w.ToRequest
+}
+
+// Helper method which builds the response served during the outage.
+func (w OutageWindow) response() *PredefinedServerResponse {
+	if w.Mode == OutageConnectionReset {
+		return &PredefinedServerResponse{Fault: FaultConnectionReset}
+	}
+	response := &PredefinedServerResponse{
+		Status:  http.StatusServiceUnavailable,
+		Headers: map[string][]string{},
+	}
+	if w.RetryAfter > 0 {
+		response.Headers["Retry-After"] = []string{strconv.Itoa(seconds(w.RetryAfter))}
+	}
+	return response
+}
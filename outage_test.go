@@ -0,0 +1,60 @@
+package gosette
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the test server is unavailable for the declared number of requests and then recovers.
+func (suite *HTTPTestServerUnitTestSuite) TestUnavailableBetween() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/health").RespondWith().Status(http.StatusOK)
+	suite.hts.UnavailableBetween(2, 3, OutageServiceUnavailable)
+	expected := []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}
+	for _, status := range expected {
+		resp, err := client.Get(suite.hts.GetBaseURL() + "/health")
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), status, resp.StatusCode)
+	}
+	require.Len(suite.T(), suite.hts.FindRecords(ByPath("/health")), 4)
+}
+
+// Test the test server is unavailable for the declared duration, resets connections when requested
+// and recovers automatically.
+func (suite *HTTPTestServerUnitTestSuite) TestUnavailable() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/health").RespondWith().Status(http.StatusOK)
+	suite.hts.Unavailable(200*time.Millisecond, OutageConnectionReset)
+	_, err := client.Get(suite.hts.GetBaseURL() + "/health")
+	require.Error(suite.T(), err)
+	time.Sleep(250 * time.Millisecond)
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+}
+
+// Test scheduled windows in the future, Retry-After headers and ClearOutages.
+func (suite *HTTPTestServerUnitTestSuite) TestScheduleOutage() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/health").RespondWith().Status(http.StatusOK)
+	suite.hts.ScheduleOutage(OutageWindow{Start: time.Now().Add(time.Hour)})
+	suite.hts.ScheduleOutage(OutageWindow{FromRequest: 2, RetryAfter: 1500 * time.Millisecond})
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(suite.T(), "2", resp.Header.Get("Retry-After"))
+	suite.hts.ClearOutages()
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,186 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A request matcher which extracts parameters from the path of the requests it matches. The
+// parameters of the matcher of the served stub are recorded in the ServerRecord PathParams.
+type pathParamsMatcher interface {
+	RequestMatcher
+	// Return the parameters extracted from the path of the provided request. Nil if the path does
+	// not have parameters.
+	pathParams(r *http.Request) map[string]string
+}
+
+// Build a request matcher which matches requests whose URL path matches the provided regular
+// expression. The expression is not anchored: use ^ and $ to match the whole path.
+//
+// The groups captured by the expression are recorded in the ServerRecord PathParams when the
+// response is served and are available to response templates (see TemplateData): named groups by
+// their name and all groups by their index (1, 2, ...). Example:
+//
+//	hts.When().PathMatching(regexp.MustCompile(`^/users/(?P<id>\d+)$`)).
+//		RespondWith().BodyTemplate(`{"id": {{.PathParams.id}}}`)
+func PathRegexMatcher(pattern *regexp.Regexp) RequestMatcher {
+	return &regexPathMatcher{
+		criterionMatcher: newCriterionMatcher("path", fmt.Sprintf("to match %q", pattern.String()), func(r *http.Request) string {
+			return fmt.Sprintf("%q", r.URL.Path)
+		}, func(r *http.Request) bool {
+			return pattern.MatchString(r.URL.Path)
+		}),
+		pattern: pattern,
+	}
+}
+
+// Build a request matcher which matches requests whose URL path matches the provided glob
+// pattern. The pattern must match the whole path:
+//   - * matches any sequence of characters within a path segment (/users/*/orders)
+//   - ** matches any number of path segments, including none (/api/**, /api/**/items)
+//   - ? matches a single character within a path segment
+//
+// Other characters are matched literally. The sequences matched by * and ** are recorded in the
+// ServerRecord PathParams by their index (1, 2, ...) like the groups captured by PathRegexMatcher.
+func PathGlobMatcher(pattern string) RequestMatcher {
+	compiled := regexp.MustCompile(globToRegexp(pattern))
+	return &regexPathMatcher{
+		criterionMatcher: newCriterionMatcher("path", fmt.Sprintf("to match glob %q", pattern), func(r *http.Request) string {
+			return fmt.Sprintf("%q", r.URL.Path)
+		}, func(r *http.Request) bool {
+			return compiled.MatchString(r.URL.Path)
+		}),
+		pattern: compiled,
+	}
+}
+
+// Helper function which translates the provided glob pattern into an anchored regular expression.
+func globToRegexp(pattern string) string {
+	expr := &strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "/**") && (i+3 == len(pattern) || pattern[i+3] == '/'):
+			// Any number of segments, including none
+			expr.WriteString("(?:/(.*))?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString("(.*)")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("([^/]*)")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
+
+// Build a request matcher which matches requests whose URL path matches the provided URI
+// template (ex: /users/{id}/orders/{orderId}). Each parameter matches a single non-empty path
+// segment and the template must match the whole path.
+//
+// The parameters are recorded in the ServerRecord PathParams by their name when the response is
+// served and are available to response templates (see TemplateData).
+func PathTemplateMatcher(template string) RequestMatcher {
+	expr, names := pathTemplateToRegexp("", template)
+	compiled := regexp.MustCompile(expr)
+	return &regexPathMatcher{
+		criterionMatcher: newCriterionMatcher("path", fmt.Sprintf("to match template %q", template), func(r *http.Request) string {
+			return fmt.Sprintf("%q", r.URL.Path)
+		}, func(r *http.Request) bool {
+			return compiled.MatchString(r.URL.Path)
+		}),
+		pattern: compiled,
+		names:   names,
+	}
+}
+
+// Helper function which translates the provided URI template into an anchored regular expression
+// which captures each parameter. The provided prefix is matched literally before the template.
+// The names of the parameters are returned in order.
+func pathTemplateToRegexp(prefix string, template string) (string, []string) {
+	expr := "^" + regexp.QuoteMeta(prefix)
+	names := []string{}
+	last := 0
+	for _, loc := range pathTemplateParameterRegexp.FindAllStringIndex(template, -1) {
+		expr += regexp.QuoteMeta(template[last:loc[0]]) + "([^/]+)"
+		names = append(names, template[loc[0]+1:loc[1]-1])
+		last = loc[1]
+	}
+	return expr + regexp.QuoteMeta(template[last:]) + "$", names
+}
+
+// A request matcher which matches request paths against a regular expression. See
+// PathRegexMatcher, PathGlobMatcher and PathTemplateMatcher.
+type regexPathMatcher struct {
+	*criterionMatcher
+	// Expression the paths are matched against.
+	pattern *regexp.Regexp
+	// Names of the parameters captured by the groups of the expression, in order. When set, the
+	// parameters are recorded by their name only.
+	names []string
+}
+
+// Return the groups captured from the path of the provided request.
+func (rm *regexPathMatcher) pathParams(r *http.Request) map[string]string {
+	submatches := rm.pattern.FindStringSubmatch(r.URL.Path)
+	if len(submatches) < 2 {
+		return nil
+	}
+	params := map[string]string{}
+	if rm.names != nil {
+		for i, name := range rm.names {
+			params[name] = submatches[i+1]
+		}
+		return params
+	}
+	for i, name := range rm.pattern.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		params[strconv.Itoa(i)] = submatches[i]
+		if name != "" {
+			params[name] = submatches[i]
+		}
+	}
+	return params
+}
+
+// Return the path parameters extracted by the matchers which extract parameters. Parameters
+// extracted by the last matchers win.
+func (am allMatcher) pathParams(r *http.Request) map[string]string {
+	var params map[string]string
+	for _, matcher := range am {
+		for name, value := range extractPathParams(matcher, r) {
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// Helper function which returns the parameters extracted from the path of the provided request
+// by the provided matcher. Nil if the matcher does not extract parameters.
+func extractPathParams(matcher RequestMatcher, r *http.Request) map[string]string {
+	if extractor, ok := matcher.(pathParamsMatcher); ok {
+		return extractor.pathParams(r)
+	}
+	return nil
+}
+
+// Helper function which records the parameters extracted from the path of the provided request
+// by the matcher of the served stub in the record of the request.
+func recordPathParams(matcher RequestMatcher, r *http.Request) {
+	if record := recordFromContext(r.Context()); record != nil {
+		record.PathParams = extractPathParams(matcher, r)
+	}
+}
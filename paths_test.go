@@ -0,0 +1,112 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the path regular expression matcher. Test will ensure:
+//   - Paths are matched against the expression and mismatches are explained
+//   - Captured groups are recorded by name and by index
+//   - Captured groups are available to response body templates
+func (suite *HTTPTestServerUnitTestSuite) TestPathRegexMatcher() {
+	pattern := regexp.MustCompile(`^/users/(?P<id>\d+)/orders/(\w+)$`)
+	r := httptest.NewRequest(http.MethodGet, "/users/42/orders/abc", nil)
+	require.True(suite.T(), PathRegexMatcher(pattern).Match(r))
+	require.Equal(suite.T(), map[string]string{"id": "42", "1": "42", "2": "abc"}, extractPathParams(PathRegexMatcher(pattern), r))
+	mismatch := httptest.NewRequest(http.MethodGet, "/users/john/orders/abc", nil)
+	require.False(suite.T(), PathRegexMatcher(pattern).Match(mismatch))
+	require.Equal(suite.T(), []CriterionResult{{Criterion: "path", Expected: `to match "^/users/(?P<id>\\d+)/orders/(\\w+)$"`, Actual: `"/users/john/orders/abc"`}}, ExplainMatch(PathRegexMatcher(pattern), mismatch))
+
+	// Builder and templates
+	suite.hts.When().Matching(MethodMatcher(http.MethodGet)).PathMatching(pattern).
+		RespondWith().Header("Content-Type", "application/json").BodyTemplate(`{"user": {{.PathParams.id}}, "order": "{{index .PathParams "2"}}"}`)
+	suite.hts.When().Get("/broken").RespondWith().BodyTemplate(`{{.Missing}}`)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/users/42/orders/abc")
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), `{"user": 42, "order": "abc"}`, string(body))
+	record := suite.hts.PopServerRecord()
+	require.NotNil(suite.T(), record)
+	require.Equal(suite.T(), map[string]string{"id": "42", "1": "42", "2": "abc"}, record.PathParams)
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL() + "/users/john/orders/abc")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	require.Nil(suite.T(), suite.hts.PopServerRecord().PathParams)
+
+	// Template errors produce a 500 response
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL() + "/broken")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+}
+
+// Test the path glob matcher. Test will ensure:
+//   - * and ? match within a single segment and ** matches any number of segments
+//   - Other characters are matched literally
+//   - Wildcards are recorded as path parameters
+func (suite *HTTPTestServerUnitTestSuite) TestPathGlobMatcher() {
+	for pattern, cases := range map[string]map[string]bool{
+		"/api/*/items":  {"/api/v1/items": true, "/api//items": true, "/api/v1/v2/items": false, "/api/items": false},
+		"/api/**":       {"/api": true, "/api/": true, "/api/a/b": true, "/apix": false},
+		"/api/**/items": {"/api/items": true, "/api/a/b/items": true, "/api/a/b/item": false},
+		"/files/*.json": {"/files/a.json": true, "/files/ajson": false, "/files/a/b.json": false},
+		"/v?/users":     {"/v1/users": true, "/v12/users": false, "/v/users": false},
+		"/a+b/(c)":      {"/a+b/(c)": true, "/aab/c": false},
+	} {
+		for path, expected := range cases {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+			require.Equal(suite.T(), expected, PathGlobMatcher(pattern).Match(r), "%s %s", pattern, path)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/a/b/items", nil)
+	require.Equal(suite.T(), map[string]string{"1": "v1", "2": "a/b"}, extractPathParams(PathGlobMatcher("/api/*/**/items"), r))
+	require.Equal(suite.T(), []CriterionResult{{Criterion: "path", Expected: `to match glob "/other/*"`, Actual: `"/api/v1/a/b/items"`}}, ExplainMatch(PathGlobMatcher("/other/*"), r))
+
+	// Builder
+	suite.hts.When().PathGlob("/api/*/items").RespondWith().BodyTemplate(`{{index .PathParams "1"}}`)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/api/v2/items")
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "v2", string(body))
+}
+
+// Test the path template matcher. Test will ensure:
+//   - Parameters match a single non-empty segment and literal parts are matched as is
+//   - Parameters are recorded by name, including names which are not valid group names
+//   - Parameters are available to response body templates
+func (suite *HTTPTestServerUnitTestSuite) TestPathTemplateMatcher() {
+	matcher := PathTemplateMatcher("/users/{id}/orders/{order-id}.json")
+	r := httptest.NewRequest(http.MethodGet, "/users/42/orders/abc.json", nil)
+	require.True(suite.T(), matcher.Match(r))
+	require.Equal(suite.T(), map[string]string{"id": "42", "order-id": "abc"}, extractPathParams(matcher, r))
+	for _, path := range []string{"/users//orders/abc.json", "/users/42/orders/a/b.json", "/users/42/orders/abcxjson", "/users/42/orders/abc.json/"} {
+		require.False(suite.T(), matcher.Match(httptest.NewRequest(http.MethodGet, path, nil)), path)
+	}
+	require.Equal(suite.T(), []CriterionResult{{Criterion: "path", Expected: `to match template "/users/{id}"`, Actual: `"/users/42/orders/abc.json"`}}, ExplainMatch(PathTemplateMatcher("/users/{id}"), r))
+
+	// Builder
+	suite.hts.When().Matching(MethodMatcher(http.MethodDelete)).PathTemplate("/users/{id}/orders/{orderId}").
+		RespondWith().BodyTemplate(`deleted order {{.PathParams.orderId}} of user {{.PathParams.id}}`)
+	req, err := http.NewRequest(http.MethodDelete, suite.hts.GetBaseURL()+"/users/7/orders/o-1", nil)
+	require.NoError(suite.T(), err)
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "deleted order o-1 of user 7", string(body))
+	require.Equal(suite.T(), map[string]string{"id": "7", "orderId": "o-1"}, suite.hts.PopServerRecord().PathParams)
+}
@@ -0,0 +1,43 @@
+package gosette
+
+import (
+	"sort"
+)
+
+// Register a predefined response which will be served for each request matched by the provided
+// matcher, with the provided priority.
+//
+// Registered responses are consulted by decreasing priority: when several registered responses
+// match a request, the response with the highest priority is served. Responses with the same
+// priority are consulted in their registration order. Responses registered with RegisterResponse
+// have priority 0.
+func (hts *HTTPTestServer) RegisterResponseWithPriority(matcher RequestMatcher, resp *PredefinedServerResponse, priority int) {
+	hts.registerStub(&stub{matcher: matcher, response: resp, priority: priority})
+}
+
+// Set the priority of the response. When several registered responses match a request, the
+// response with the highest priority is served (see RegisterResponseWithPriority). Responses have
+// priority 0 by default.
+//
+// The served stub is identified in the records and logs by its name or by its registration index
+// (see ServerRecord ServedBy). Example:
+//
+//	hts.When().PathTemplate("/users/{id}").RespondWith().Status(http.StatusOK)
+//	hts.When().Get("/users/admin").RespondWith().Priority(10).Status(http.StatusForbidden)
+func (b *ResponseBuilder) Priority(priority int) *ResponseBuilder {
+	b.hts.mu.Lock()
+	defer b.hts.mu.Unlock()
+	b.stub.priority = priority
+	return b
+}
+
+// Helper method which returns the registered stubs in the order they must be consulted: by
+// decreasing priority, then by registration order. The caller must hold the lock.
+func (srv *HTTPTestServer) stubsByPriority() []*stub {
+	ordered := make([]*stub, len(srv.stubs))
+	copy(ordered, srv.stubs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].priority > ordered[j].priority
+	})
+	return ordered
+}
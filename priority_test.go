@@ -0,0 +1,42 @@
+package gosette
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test registered responses are consulted by priority. Test will ensure:
+//   - The matching response with the highest priority is served whatever the registration order
+//   - Responses with the same priority are consulted in their registration order
+//   - The served stub is identified by its registration index
+func (suite *HTTPTestServerUnitTestSuite) TestPriority() {
+	suite.hts.When().PathTemplate("/users/{id}").RespondWith().Status(http.StatusOK)
+	suite.hts.When().Get("/users/admin").RespondWith().Priority(10).Status(http.StatusForbidden)
+	suite.hts.RegisterResponseWithPriority(PathGlobMatcher("/users/*"), &PredefinedServerResponse{Status: http.StatusAccepted}, 5)
+	suite.hts.RegisterResponseWithPriority(PathMatcher("/users/guest"), &PredefinedServerResponse{Status: http.StatusCreated}, 5)
+	suite.hts.When().Get("/users/guest").RespondWith().Priority(-1).Status(http.StatusGone)
+	for path, expected := range map[string]struct {
+		status   int
+		servedBy string
+	}{
+		"/users/admin": {http.StatusForbidden, "stub #2"},
+		"/users/guest": {http.StatusAccepted, "stub #3"},
+	} {
+		resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), expected.status, resp.StatusCode, path)
+		record := suite.hts.PopServerRecord()
+		require.NotNil(suite.T(), record)
+		require.Equal(suite.T(), expected.servedBy, record.ServedBy, path)
+	}
+	// Default priority is 0
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.When().Get("/users/guest").RespondWith().Priority(-1).Status(http.StatusGone)
+	suite.hts.When().PathTemplate("/users/{id}").RespondWith().Status(http.StatusOK)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/users/guest")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+}
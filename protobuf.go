@@ -0,0 +1,117 @@
+package gosette
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A function which deserializes a protobuf message into the provided message.
+//
+// The test server does not depend on a protobuf implementation: register a function which calls
+// proto.Unmarshal (or any other codec used by the client) with RegisterProtoUnmarshaler. Example:
+//
+//	gosette.RegisterProtoUnmarshaler(func(data []byte, msg interface{}) error {
+//		return proto.Unmarshal(data, msg.(proto.Message))
+//	})
+type ProtoUnmarshaler func(data []byte, msg interface{}) error
+
+var (
+	// Mutex which protects protoUnmarshaler
+	protoUnmarshalerMu sync.RWMutex
+	// Registered protobuf unmarshaler. Nil when no unmarshaler has been registered.
+	protoUnmarshaler ProtoUnmarshaler
+)
+
+// Content types which carry a single serialized protobuf message.
+var protobufContentTypes = []string{
+	"application/x-protobuf",
+	"application/protobuf",
+	"application/vnd.google.protobuf",
+	"application/x-google-protobuf",
+}
+
+// Register the function used to unmarshal recorded request bodies into protobuf messages. An
+// already registered unmarshaler is replaced.
+//
+// Messages which provide a Unmarshal([]byte) error method (ex: gogo/protobuf generated messages)
+// are unmarshaled with this method and do not require an unmarshaler to be registered.
+func RegisterProtoUnmarshaler(unmarshal ProtoUnmarshaler) {
+	protoUnmarshalerMu.Lock()
+	defer protoUnmarshalerMu.Unlock()
+	protoUnmarshaler = unmarshal
+}
+
+// Unmarshal the protobuf message sent in the recorded request into the provided message.
+//
+// The request body is used as is for application/x-protobuf (and its aliases
+// application/protobuf, application/vnd.google.protobuf and application/x-google-protobuf)
+// requests. For gRPC requests, the request must carry exactly one message (unary call): use
+// UnmarshalProtoMessages for streaming calls.
+//
+// An error is returned if the request does not carry a protobuf message, if no unmarshaler is
+// available (see RegisterProtoUnmarshaler) or if the message cannot be unmarshaled.
+func (record *ServerRecord) UnmarshalProto(msg interface{}) error {
+	messages, err := record.protoMessages()
+	if err != nil {
+		return err
+	}
+	if len(messages) != 1 {
+		return fmt.Errorf("expected 1 protobuf message in the recorded request, got %d", len(messages))
+	}
+	return unmarshalProto(messages[0], msg)
+}
+
+// Unmarshal all the protobuf messages sent in the recorded request. The provided function is
+// called to create the message each serialized message is unmarshaled into (ex: a function which
+// returns &pb.HelloRequest{}).
+//
+// See UnmarshalProto for the supported content types. Non-gRPC requests always carry exactly one
+// message.
+func (record *ServerRecord) UnmarshalProtoMessages(newMessage func() interface{}) ([]interface{}, error) {
+	messages, err := record.protoMessages()
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]interface{}, 0, len(messages))
+	for i, message := range messages {
+		msg := newMessage()
+		if err := unmarshalProto(message, msg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal protobuf message %d: %w", i, err)
+		}
+		decoded = append(decoded, msg)
+	}
+	return decoded, nil
+}
+
+// Helper method which returns the serialized protobuf messages sent in the recorded request
+// according to its Content-Type.
+func (record *ServerRecord) protoMessages() ([][]byte, error) {
+	if record.Request == nil {
+		return nil, fmt.Errorf("no recorded request")
+	}
+	if isGRPCRequest(record.Request) {
+		return record.GRPCMessages()
+	}
+	contentType := strings.ToLower(strings.TrimSpace(strings.SplitN(record.Request.Header.Get("Content-Type"), ";", 2)[0]))
+	for _, protobufContentType := range protobufContentTypes {
+		if contentType == protobufContentType {
+			return [][]byte{record.RequestBody.Bytes()}, nil
+		}
+	}
+	return nil, fmt.Errorf("recorded request does not carry a protobuf message (content type %q)", contentType)
+}
+
+// Helper function which unmarshals the provided serialized message into msg.
+func unmarshalProto(data []byte, msg interface{}) error {
+	if unmarshaler, ok := msg.(interface{ Unmarshal([]byte) error }); ok {
+		return unmarshaler.Unmarshal(data)
+	}
+	protoUnmarshalerMu.RLock()
+	unmarshal := protoUnmarshaler
+	protoUnmarshalerMu.RUnlock()
+	if unmarshal == nil {
+		return fmt.Errorf("no protobuf unmarshaler registered for %T: use RegisterProtoUnmarshaler", msg)
+	}
+	return unmarshal(data, msg)
+}
@@ -0,0 +1,91 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Fake message which unmarshals itself like gogo/protobuf generated messages.
+type selfUnmarshalingMessage struct {
+	Name string
+}
+
+func (msg *selfUnmarshalingMessage) Unmarshal(data []byte) error {
+	if bytes.Equal(data, []byte("invalid")) {
+		return fmt.Errorf("invalid message")
+	}
+	msg.Name = string(data)
+	return nil
+}
+
+// Fake message which is unmarshaled by the registered unmarshaler.
+type registeredMessage struct {
+	Name string
+}
+
+// Test the protobuf record helpers. Test will ensure:
+//   - Bodies of application/x-protobuf requests and its aliases are unmarshaled
+//   - Messages of gRPC requests are unmarshaled and unary helpers reject several messages
+//   - Registered unmarshaler is used for messages which cannot unmarshal themselves
+//   - Errors are returned for other content types and when no unmarshaler is available
+func TestUnmarshalProto(t *testing.T) {
+	// Create and start a test server
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+
+	// Protobuf request
+	for _, contentType := range []string{"application/x-protobuf", "application/protobuf; proto=helloworld.HelloRequest", "Application/Vnd.Google.Protobuf"} {
+		resp, err := srv.Client().Post(srv.GetBaseURL(), contentType, bytes.NewReader([]byte("john")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		record := srv.PopServerRecord()
+		require.NotNil(t, record)
+		msg := &selfUnmarshalingMessage{}
+		require.NoError(t, record.UnmarshalProto(msg))
+		require.Equal(t, "john", msg.Name)
+	}
+
+	// gRPC request
+	req := httptest.NewRequest(http.MethodPost, "/helloworld.Greeter/SayHello", nil)
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	record := &ServerRecord{Request: req, RequestBody: bytes.NewBuffer(grpcFrames(false, []byte("john")))}
+	msg := &selfUnmarshalingMessage{}
+	require.NoError(t, record.UnmarshalProto(msg))
+	require.Equal(t, "john", msg.Name)
+	record = &ServerRecord{Request: req, RequestBody: bytes.NewBuffer(grpcFrames(false, []byte("john"), []byte("jane")))}
+	require.Error(t, record.UnmarshalProto(msg))
+	messages, err := record.UnmarshalProtoMessages(func() interface{} { return &selfUnmarshalingMessage{} })
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{&selfUnmarshalingMessage{Name: "john"}, &selfUnmarshalingMessage{Name: "jane"}}, messages)
+	record = &ServerRecord{Request: req, RequestBody: bytes.NewBuffer(grpcFrames(false, []byte("john"), []byte("invalid")))}
+	_, err = record.UnmarshalProtoMessages(func() interface{} { return &selfUnmarshalingMessage{} })
+	require.Error(t, err)
+	record = &ServerRecord{Request: req, RequestBody: bytes.NewBuffer([]byte{0, 0})}
+	require.Error(t, record.UnmarshalProto(msg))
+
+	// Registered unmarshaler
+	record = &ServerRecord{Request: req, RequestBody: bytes.NewBuffer(grpcFrames(false, []byte("john")))}
+	require.Error(t, record.UnmarshalProto(&registeredMessage{}))
+	RegisterProtoUnmarshaler(func(data []byte, msg interface{}) error {
+		msg.(*registeredMessage).Name = string(data)
+		return nil
+	})
+	defer RegisterProtoUnmarshaler(nil)
+	registered := &registeredMessage{}
+	require.NoError(t, record.UnmarshalProto(registered))
+	require.Equal(t, "john", registered.Name)
+
+	// Not a protobuf request
+	record = &ServerRecord{Request: httptest.NewRequest(http.MethodPost, "/", nil), RequestBody: bytes.NewBufferString("{}")}
+	record.Request.Header.Set("Content-Type", "application/json")
+	require.Error(t, record.UnmarshalProto(msg))
+	_, err = record.UnmarshalProtoMessages(func() interface{} { return &selfUnmarshalingMessage{} })
+	require.Error(t, err)
+	require.Error(t, (&ServerRecord{}).UnmarshalProto(msg))
+}
@@ -0,0 +1,274 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Hop-by-hop headers which must not be forwarded by a proxy.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// An exchange between the test server and the upstream recorded in proxy mode.
+type Interaction struct {
+	// HTTP method of the request.
+	Method string `json:"method"`
+	// Path of the request.
+	Path string `json:"path"`
+	// Raw query string of the request, without the leading '?'.
+	RawQuery string `json:"rawQuery,omitempty"`
+	// Headers of the request.
+	RequestHeaders http.Header `json:"requestHeaders,omitempty"`
+	// Body of the request.
+	RequestBody []byte `json:"requestBody,omitempty"`
+	// Status code of the upstream response.
+	Status int `json:"status"`
+	// Headers of the upstream response.
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	// Body of the upstream response.
+	ResponseBody []byte `json:"responseBody,omitempty"`
+}
+
+// A set of interactions recorded in proxy mode which can be replayed later as predefined
+// responses, without network access.
+type Cassette struct {
+	// Recorded interactions, in the order they have been recorded.
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Save the cassette as JSON to the file at the provided path.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette to %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load a cassette saved as JSON from the file at the provided path.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette from %s: %w", path, err)
+	}
+	c := &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette from %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Enable the proxy mode: requests for which no predefined responses are available are proxied
+// to the provided upstream URL (ex: https://api.example.com) and the exchanges are recorded in a
+// cassette. Proxied exchanges are also recorded as server records like any other exchange.
+//
+// The proxy mode is not disabled by ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) StartRecording(upstream string) error {
+	u, err := parseUpstream(upstream)
+	if err != nil {
+		return err
+	}
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.upstream = u
+	hts.cassette = &Cassette{Interactions: []*Interaction{}}
+	return nil
+}
+
+// Disable the proxy mode and return the cassette which contains the interactions recorded since
+// StartRecording has been called. Returns an empty cassette if the proxy mode was not enabled.
+func (hts *HTTPTestServer) StopRecording() *Cassette {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	cassette := hts.cassette
+	if cassette == nil {
+		cassette = &Cassette{Interactions: []*Interaction{}}
+	}
+	hts.upstream = nil
+	hts.cassette = nil
+	return cassette
+}
+
+// Enable the passthrough mode: requests matched by a predefined response are mocked while all the
+// other requests are transparently proxied to the provided upstream URL (ex:
+// https://api.example.com). This allows large APIs to be mocked incrementally: register predefined
+// responses only for the endpoints which must be mocked.
+//
+// Proxied exchanges are recorded as server records like any other exchange (see Proxied) and in a
+// cassette which can be turned into stubs (see GenerateStubs). The cassette grows without bound
+// until GenerateStubs or StopPassthrough is called: keep passthrough sessions short when a lot of
+// traffic is proxied. The passthrough mode is not disabled by ClearPredefinedServerResponses and
+// Clear.
+func (hts *HTTPTestServer) Passthrough(upstream string) error {
+	return hts.StartRecording(upstream)
+}
+
+// Disable the passthrough mode (or the proxy mode, see StartRecording) and discard the recorded
+// interactions: requests for which no predefined responses are available get the default response
+// again.
+func (hts *HTTPTestServer) StopPassthrough() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.upstream = nil
+	hts.cassette = nil
+}
+
+// Filter which selects the records of the requests which have been proxied to the upstream (see
+// Passthrough and StartRecording).
+func Proxied() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.ServedBy == "proxy"
+	}
+}
+
+// Register the interactions of the provided cassette as predefined responses. Each interaction is
+// served for requests with the same method, path and query parameters. When several interactions
+// have been recorded for the same request, they are served in their recording order and the last
+// one is served indefinitly.
+func (hts *HTTPTestServer) Replay(cassette *Cassette) {
+	// Count interactions per request to detect the last one of each request
+	remaining := map[string]int{}
+	for _, interaction := range cassette.Interactions {
+		remaining[interaction.key()]++
+	}
+	for _, interaction := range cassette.Interactions {
+		response := &PredefinedServerResponse{
+			Status:  interaction.Status,
+			Headers: interaction.ResponseHeaders.Clone(),
+			Body:    interaction.ResponseBody,
+		}
+		// Serve the interaction once unless it is the last one recorded for the request
+		remaining[interaction.key()]--
+		if remaining[interaction.key()] > 0 {
+			response.Repeat = Once()
+		}
+		hts.RegisterResponse(interaction.matcher(), response)
+	}
+}
+
+// Helper method which returns a key which identifies the request of the interaction.
+func (interaction *Interaction) key() string {
+	return interaction.Method + " " + interaction.Path + "?" + interaction.RawQuery
+}
+
+// Helper method which builds a matcher which matches requests with the same method, path and
+// query parameters as the request of the interaction.
+func (interaction *Interaction) matcher() RequestMatcher {
+	return MatchAll(
+		MethodMatcher(interaction.Method),
+		PathMatcher(interaction.Path),
+		QueryEqualsMatcher(interaction.RawQuery),
+	)
+}
+
+// Helper method which returns the upstream requests are proxied to. Returns nil if the proxy mode
+// is disabled.
+func (srv *HTTPTestServer) getUpstream() *url.URL {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.upstream
+}
+
+// Helper method which proxies the provided request to the provided upstream, writes the upstream
+// response by using the provided http.ResponseWriter and records the interaction.
+//
+// In case the request cannot be proxied, the server replies with a 500 response and the record
+// ServerError is set with an error which wraps the error that has occured.
+func (srv *HTTPTestServer) proxy(w http.ResponseWriter, r *http.Request, serverRecord *ServerRecord, upstream *url.URL) {
+	// Build the outgoing request
+	body := serverRecord.RequestBody.Bytes()
+	if serverRecord.RawRequestBody != nil {
+		// Forward the raw body along with its Content-Encoding header
+		body = serverRecord.RawRequestBody.Bytes()
+	}
+	target := *upstream
+	target.Path = strings.TrimSuffix(upstream.Path, "/") + r.URL.Path
+	target.RawPath = ""
+	target.RawQuery = r.URL.RawQuery
+	outreq, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		srv.handleInternalError(w, serverRecord, fmt.Errorf("test server failed to build the upstream request: %w", err))
+		return
+	}
+	outreq.Header = r.Header.Clone()
+	removeHopByHopHeaders(outreq.Header)
+	// Send the request to the upstream and read the response
+	resp, err := srv.upstreamClient.Do(outreq)
+	if err != nil {
+		srv.handleInternalError(w, serverRecord, fmt.Errorf("test server failed to proxy the request: %w", err))
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		srv.handleInternalError(w, serverRecord, fmt.Errorf("test server failed to read the upstream response: %w", err))
+		return
+	}
+	// Record the interaction
+	interaction := &Interaction{
+		Method:          r.Method,
+		Path:            r.URL.Path,
+		RawQuery:        r.URL.RawQuery,
+		RequestHeaders:  r.Header.Clone(),
+		RequestBody:     body,
+		Status:          resp.StatusCode,
+		ResponseHeaders: resp.Header.Clone(),
+		ResponseBody:    respBody,
+	}
+	removeHopByHopHeaders(interaction.ResponseHeaders)
+	srv.mu.Lock()
+	if srv.cassette != nil {
+		srv.cassette.Interactions = append(srv.cassette.Interactions, interaction)
+	}
+	srv.mu.Unlock()
+	// Write the upstream response
+	writeHeaders(w, &PredefinedServerResponse{
+		Status:  interaction.Status,
+		Headers: interaction.ResponseHeaders,
+	})
+	if _, err := w.Write(respBody); err != nil {
+		srv.handleInternalError(w, serverRecord, fmt.Errorf("test server failed to write the upstream response: %w", err))
+	}
+}
+
+// Helper function which parses and validates the provided upstream URL.
+func parseUpstream(upstream string) (*url.URL, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream URL %s: %w", upstream, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream URL %s: scheme and host are required", upstream)
+	}
+	return u, nil
+}
+
+// Helper function which removes hop-by-hop headers from the provided header map.
+func removeHopByHopHeaders(header http.Header) {
+	for _, h := range header.Values("Connection") {
+		for _, name := range strings.Split(h, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+}
@@ -0,0 +1,206 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the record-and-replay proxy mode. Test will ensure:
+//   - Unmatched requests are proxied to the upstream and the interactions are recorded
+//   - Matched requests are served with predefined responses and not proxied
+//   - Cassettes can be saved, loaded and replayed without the upstream
+func TestRecordAndReplay(t *testing.T) {
+	// Create an upstream which replies to GET /users and POST /users
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	defer upstream.Close()
+	upstream.PushPredefinedServerResponseForRoute(http.MethodGet, "/api/users", &PredefinedServerResponse{
+		Status:  http.StatusOK,
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    []byte(`[{"id": 1}]`),
+		Repeat:  Once(),
+	})
+	upstream.PushPredefinedServerResponseForRoute(http.MethodGet, "/api/users", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte(`[{"id": 1}, {"id": 2}]`),
+	})
+	upstream.PushPredefinedServerResponseForRoute(http.MethodPost, "/api/users", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+		Body:   []byte(`{"id": 2}`),
+	})
+
+	// Create a test server in proxy mode
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.StartRecording(upstream.GetBaseURL()+"/api/"))
+	srv.PushPredefinedServerResponseForPath("/health", &PredefinedServerResponse{
+		Status: http.StatusNoContent,
+	})
+
+	// Send requests
+	get := func(s *HTTPTestServer, path string) (int, string) {
+		resp, err := s.Client().Get(s.GetBaseURL() + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	status, body := get(srv, "/users?page=1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}]`, body)
+	status, body = get(srv, "/users?page=1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}, {"id": 2}]`, body)
+	resp, err := srv.Client().Post(srv.GetBaseURL()+"/users", "application/json", strings.NewReader(`{"name": "bob"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	status, _ = get(srv, "/health")
+	require.Equal(t, http.StatusNoContent, status)
+
+	// Check the upstream has received the proxied requests only
+	require.NoError(t, upstream.Verify().Requests("", "").Count(3))
+	require.NoError(t, upstream.Verify().Requests(http.MethodPost, "/api/users").Once())
+	require.Len(t, upstream.FindRecords(ByMatcher(BodyContainsMatcher("bob"))), 1)
+	// Check the proxied exchanges are recorded as server records
+	require.Len(t, srv.FindRecords(ByStatus(http.StatusOK)), 2)
+
+	// Stop recording and save the cassette
+	cassette := srv.StopRecording()
+	require.Len(t, cassette.Interactions, 3)
+	require.Equal(t, "page=1", cassette.Interactions[0].RawQuery)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, cassette.Save(path))
+
+	// Replay the cassette on a new server without the upstream
+	upstream.Close()
+	loaded, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Equal(t, cassette, loaded)
+	replay := NewHTTPTestServer(nil)
+	replay.Start()
+	defer replay.Close()
+	replay.Replay(loaded)
+	status, body = get(replay, "/users?page=1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}]`, body)
+	for i := 0; i < 2; i++ {
+		status, body = get(replay, "/users?page=1")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, `[{"id": 1}, {"id": 2}]`, body)
+	}
+	status, _ = get(replay, "/users?page=2")
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+// Test the passthrough mode. Test will ensure:
+//   - Requests matched by a predefined response are mocked
+//   - Other requests are proxied to the upstream and recorded in the cassette
+//   - Requests get the default response once the passthrough mode is disabled
+func TestPassthrough(t *testing.T) {
+	// Create an upstream which replies to all requests
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	defer upstream.Close()
+	upstream.PushPredefinedServerResponseForPath("/users", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte("real"),
+	})
+
+	// Create a test server in passthrough mode which mocks /users/1
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.Error(t, srv.Passthrough("/relative"))
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	srv.PushPredefinedServerResponseForPath("/users/1", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte("mock"),
+	})
+
+	// Send requests
+	get := func(path string) (int, string) {
+		resp, err := srv.Client().Get(srv.GetBaseURL() + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	status, body := get("/users/1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "mock", body)
+	status, body = get("/users")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "real", body)
+
+	// Check records
+	proxied := srv.FindRecords(Proxied())
+	require.Len(t, proxied, 1)
+	require.Equal(t, "/users", proxied[0].Request.URL.Path)
+	require.False(t, proxied[0].Unmatched)
+	require.Len(t, srv.FindRecords(), 2)
+	require.Len(t, upstream.FindRecords(), 1)
+	interactions := srv.StopRecording().Interactions
+	require.Len(t, interactions, 1)
+	require.Equal(t, http.MethodGet, interactions[0].Method)
+	require.Equal(t, "/users", interactions[0].Path)
+	require.Equal(t, http.StatusOK, interactions[0].Status)
+	require.Equal(t, []byte("real"), interactions[0].ResponseBody)
+
+	// Disable the passthrough mode
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	srv.StopPassthrough()
+	status, _ = get("/users")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Len(t, upstream.FindRecords(), 1)
+}
+
+// Test proxy mode error paths.
+func TestProxyErrPaths(t *testing.T) {
+	// Invalid upstreams
+	srv := NewHTTPTestServer(nil)
+	require.Error(t, srv.StartRecording("://invalid"))
+	require.Error(t, srv.StartRecording("/relative"))
+	// Stop recording when not recording
+	require.Empty(t, srv.StopRecording().Interactions)
+	// Load invalid cassettes
+	_, err := LoadCassette(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	require.NoError(t, (&Cassette{}).Save(path))
+	require.Error(t, (&Cassette{}).Save(t.TempDir()))
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0644))
+	_, err = LoadCassette(path)
+	require.Error(t, err)
+
+	// Proxy to an unreachable upstream
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	upstream.Close()
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.StartRecording(upstream.GetBaseURL()))
+	resp, err := srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	record := srv.PopServerRecord()
+	require.Error(t, record.ServerError)
+}
+
+// Test hop-by-hop headers are removed.
+func TestRemoveHopByHopHeaders(t *testing.T) {
+	header := http.Header{
+		"Connection":   {"close, X-Custom"},
+		"X-Custom":     {"1"},
+		"Keep-Alive":   {"timeout=5"},
+		"Content-Type": {"text/plain"},
+	}
+	removeHopByHopHeaders(header)
+	require.Equal(t, http.Header{"Content-Type": {"text/plain"}}, header)
+}
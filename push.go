@@ -0,0 +1,114 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// A resource pushed with HTTP/2 server push (see PredefinedServerResponse Pushes).
+type PushedResource struct {
+	// Absolute path (ex: /static/app.css) or absolute URL of the pushed resource.
+	Path string
+	// Method of the promised request. Defaults to GET.
+	Method string
+	// Headers of the promised request.
+	Header http.Header
+	// Response served for the promised request.
+	Response *PredefinedServerResponse
+}
+
+// The outcome of a HTTP/2 server push.
+type PushResult struct {
+	// Path of the pushed resource.
+	Path string
+	// True if the push promise has been sent to the client.
+	Accepted bool
+	// The reason why the push has not been accepted: http.ErrNotSupported if the connection does
+	// not support pushes (HTTP/1.x) or if the client has disabled them. Nil if the push has been
+	// accepted.
+	Err error
+}
+
+// Helper method which pushes the resources declared by the provided response by using the
+// provided client connection writer and records the outcome of each push.
+//
+// The promised responses are stored until the server handles the promised requests.
+func (srv *HTTPTestServer) push(conn http.ResponseWriter, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	pusher, _ := conn.(http.Pusher)
+	for _, resource := range response.Pushes {
+		result := &PushResult{Path: resource.Path}
+		serverRecord.Pushes = append(serverRecord.Pushes, result)
+		if pusher == nil {
+			result.Err = http.ErrNotSupported
+			continue
+		}
+		key, err := promiseKey(resource.Method, resource.Path)
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		// Store the promised response before pushing: the promised request may be handled before
+		// Push returns
+		srv.mu.Lock()
+		if srv.promised == nil {
+			srv.promised = map[string][]*PredefinedServerResponse{}
+		}
+		srv.promised[key] = append(srv.promised[key], resource.Response)
+		srv.mu.Unlock()
+		result.Err = pusher.Push(resource.Path, &http.PushOptions{Method: resource.Method, Header: resource.Header})
+		result.Accepted = result.Err == nil
+		if !result.Accepted {
+			srv.forgetPromise(key, resource.Response)
+		}
+	}
+}
+
+// Helper method which returns the promised response to serve for the provided request if the
+// request has been pushed. Returns nil otherwise.
+func (srv *HTTPTestServer) nextPromisedResponse(r *http.Request) *PredefinedServerResponse {
+	key, err := promiseKey(r.Method, r.URL.RequestURI())
+	if err != nil {
+		return nil
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	responses := srv.promised[key]
+	if len(responses) == 0 {
+		return nil
+	}
+	srv.promised[key] = responses[1:]
+	if len(srv.promised[key]) == 0 {
+		delete(srv.promised, key)
+	}
+	return responses[0]
+}
+
+// Helper method which removes the provided promised response.
+func (srv *HTTPTestServer) forgetPromise(key string, response *PredefinedServerResponse) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	responses := srv.promised[key]
+	for i, promised := range responses {
+		if promised == response {
+			srv.promised[key] = append(responses[:i:i], responses[i+1:]...)
+			break
+		}
+	}
+	if len(srv.promised[key]) == 0 {
+		delete(srv.promised, key)
+	}
+}
+
+// Helper function which returns the key which identifies the promised request with the provided
+// method and target.
+func promiseKey(method string, target string) (string, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid push target %s: %w", target, err)
+	}
+	return method + " " + u.RequestURI(), nil
+}
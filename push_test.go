@@ -0,0 +1,92 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A response writer which supports pushes: promised requests are served by the provided handler.
+type pushingResponseWriter struct {
+	*httptest.ResponseRecorder
+	// Handler which serves the promised requests.
+	handler http.Handler
+	// Recorders of the promised responses.
+	pushed []*httptest.ResponseRecorder
+}
+
+// Serve the promised request with the handler.
+func (w *pushingResponseWriter) Push(target string, opts *http.PushOptions) error {
+	req := httptest.NewRequest(opts.Method, target, nil)
+	for name, values := range opts.Header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	w.handler.ServeHTTP(rec, req)
+	w.pushed = append(w.pushed, rec)
+	return nil
+}
+
+// Test HTTP/2 server pushes. Test will ensure:
+//   - Accepted pushes are recorded and the promised requests are served with the pushed responses
+//   - Pushes are recorded as not accepted when the client or the protocol does not support them
+//   - The response is served in all cases
+func TestPush(t *testing.T) {
+	// Accepted pushes
+	srv := NewHTTPTestServer(nil)
+	srv.When().Get("/").RespondWith().StringBody("<html/>").
+		Push("/app.css", &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("body {}")}).
+		Push("/app.js?v=1", &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("main()")})
+	w := &pushingResponseWriter{ResponseRecorder: httptest.NewRecorder(), handler: srv}
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "<html/>", w.Body.String())
+	require.Len(t, w.pushed, 2)
+	require.Equal(t, "body {}", w.pushed[0].Body.String())
+	require.Equal(t, "main()", w.pushed[1].Body.String())
+	records := srv.FindRecords()
+	require.Len(t, records, 3)
+	require.Equal(t, "push", records[0].ServedBy)
+	require.Equal(t, "/app.css", records[0].Request.URL.Path)
+	require.Equal(t, "push", records[1].ServedBy)
+	require.Equal(t, []*PushResult{{Path: "/app.css", Accepted: true}, {Path: "/app.js?v=1", Accepted: true}}, records[2].Pushes)
+	srv.ClearServerRecords()
+
+	// Promised responses are served once
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/app.css", nil))
+	require.Equal(t, "default response", srv.PopServerRecord().ServedBy)
+
+	// Pushes are not supported over HTTP/1.1
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	record := srv.PopServerRecord()
+	require.Len(t, record.Pushes, 2)
+	require.False(t, record.Pushes[0].Accepted)
+	require.ErrorIs(t, record.Pushes[0].Err, http.ErrNotSupported)
+
+	// Invalid targets are not pushed
+	srv.When().Get("/invalid").RespondWith().Push("%zz", &PredefinedServerResponse{})
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/invalid", nil))
+	record = srv.PopServerRecord()
+	require.False(t, record.Pushes[0].Accepted)
+	require.Error(t, record.Pushes[0].Err)
+
+	// Go clients do not accept pushes over HTTP/2
+	h2 := NewHTTPTestServer(nil, WithHTTP2())
+	h2.When().Get("/").RespondWith().StringBody("<html/>").
+		Push("/app.css", &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("body {}")})
+	h2.StartTLS()
+	defer h2.Close()
+	resp, err := h2.Client().Get(h2.GetBaseURL())
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "HTTP/2.0", resp.Proto)
+	require.Equal(t, "<html/>", string(body))
+	record = h2.PopServerRecord()
+	require.False(t, record.Pushes[0].Accepted)
+	require.ErrorIs(t, record.Pushes[0].Err, http.ErrNotSupported)
+	require.Nil(t, h2.nextPromisedResponse(httptest.NewRequest(http.MethodGet, "/app.css", nil)))
+}
@@ -0,0 +1,151 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Build a request matcher which matches requests which have at least one value for the provided
+// query parameter which is equal to the provided value. Parameter names and values are compared
+// once decoded (ex: a%20b and a+b are both equal to "a b").
+func QueryParamMatcher(name string, value string) RequestMatcher {
+	return newCriterionMatcher("query parameter "+name, fmt.Sprintf("%q", value), explainedQueryParam(name), func(r *http.Request) bool {
+		return assertValue("", queryValues(r, name), value) == nil
+	})
+}
+
+// Build a request matcher which matches requests which have at least one value for the provided
+// query parameter which contains the provided substring. See QueryParamMatcher.
+func QueryParamContainsMatcher(name string, substr string) RequestMatcher {
+	return newCriterionMatcher("query parameter "+name, fmt.Sprintf("to contain %q", substr), explainedQueryParam(name), func(r *http.Request) bool {
+		for _, value := range queryValues(r, name) {
+			if strings.Contains(value, substr) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Build a request matcher which matches requests which have at least one value for the provided
+// query parameter which matches the provided regular expression. See QueryParamMatcher.
+func QueryParamRegexMatcher(name string, pattern *regexp.Regexp) RequestMatcher {
+	return newCriterionMatcher("query parameter "+name, fmt.Sprintf("to match %q", pattern.String()), explainedQueryParam(name), func(r *http.Request) bool {
+		return assertValueMatches("", queryValues(r, name), pattern) == nil
+	})
+}
+
+// Build a request matcher which matches requests which have exactly the provided values for the
+// provided query parameter, in any order. See QueryParamMatcher.
+func QueryParamValuesMatcher(name string, values ...string) RequestMatcher {
+	return newCriterionMatcher("query parameter "+name, explainedStrings(values), explainedQueryParam(name), func(r *http.Request) bool {
+		return assertExactValues("", queryValues(r, name), values) == nil
+	})
+}
+
+// Build a request matcher which matches requests which have the provided query parameter,
+// whatever its value is (ex: ?debug or ?debug=).
+func QueryParamExistsMatcher(name string) RequestMatcher {
+	return newCriterionMatcher("query parameter "+name, "to be present", explainedQueryParam(name), func(r *http.Request) bool {
+		_, ok := parseQuery(r.URL)[name]
+		return ok
+	})
+}
+
+// Build a request matcher which matches requests whose query parameters are exactly the ones of
+// the provided query string (ex: page=2&sort=asc): each parameter must have the same values in the
+// same order but the parameters themselves may come in any order. An empty query string matches
+// requests without query parameters.
+func QueryEqualsMatcher(rawQuery string) RequestMatcher {
+	expected, _ := url.ParseQuery(rawQuery)
+	return newCriterionMatcher("query", fmt.Sprintf("%q", rawQuery), func(r *http.Request) string {
+		return fmt.Sprintf("%q", r.URL.RawQuery)
+	}, func(r *http.Request) bool {
+		actual := parseQuery(r.URL)
+		if len(actual) != len(expected) {
+			return false
+		}
+		for name, values := range expected {
+			if !reflect.DeepEqual(values, actual[name]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Build a filter which selects records whose request has at least one value for the provided
+// query parameter which is equal to the provided value.
+func ByQueryParam(name string, value string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Request != nil && QueryParamMatcher(name, value).Match(record.Request)
+	}
+}
+
+// Return the decoded values of the provided query parameter of the recorded request.
+func (record *ServerRecord) QueryValues(name string) []string {
+	if record.Request == nil {
+		return nil
+	}
+	return queryValues(record.Request, name)
+}
+
+// Check that the recorded request has at least one value for the provided query parameter which
+// is equal to the provided value. Values are compared once decoded.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertQueryParam(name string, value string) error {
+	return assertValue("query parameter "+name, record.QueryValues(name), value)
+}
+
+// Check that the recorded request has exactly the provided values for the provided query
+// parameter, in any order.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertQueryParamValues(name string, values ...string) error {
+	return assertExactValues("query parameter "+name, record.QueryValues(name), values)
+}
+
+// Check that the recorded request has at least one value for the provided query parameter which
+// matches the provided regular expression.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertQueryParamMatches(name string, pattern *regexp.Regexp) error {
+	return assertValueMatches("query parameter "+name, record.QueryValues(name), pattern)
+}
+
+// Check that the recorded request does not have the provided query parameter.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertNoQueryParam(name string) error {
+	if record.Request != nil {
+		if _, ok := parseQuery(record.Request.URL)[name]; ok {
+			return fmt.Errorf("expected no query parameter %s, got %s", name, explainedStrings(record.QueryValues(name)))
+		}
+	}
+	return nil
+}
+
+// Helper function which parses the query string of the provided URL. Malformed pairs are
+// ignored.
+func parseQuery(u *url.URL) url.Values {
+	values, _ := url.ParseQuery(u.RawQuery)
+	return values
+}
+
+// Helper function which returns the decoded values of the provided query parameter.
+func queryValues(r *http.Request, name string) []string {
+	return parseQuery(r.URL)[name]
+}
+
+// Helper function which returns a function which describes the values of the provided query
+// parameter of a request.
+func explainedQueryParam(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return explainedStrings(queryValues(r, name))
+	}
+}
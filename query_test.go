@@ -0,0 +1,65 @@
+package gosette
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the query parameter matchers. Test will ensure:
+//   - Values are compared once decoded
+//   - Exact, contains, regular expression, multi-value, presence and whole query criteria are
+//     supported
+//   - Mismatches are explained with the actual values
+func (suite *HTTPTestServerUnitTestSuite) TestQueryParamMatchers() {
+	r := httptest.NewRequest(http.MethodGet, "/search?q=hello%20world&tag=b&tag=a&name=a+b&debug", nil)
+	require.True(suite.T(), QueryParamMatcher("q", "hello world").Match(r))
+	require.True(suite.T(), QueryParamMatcher("name", "a b").Match(r))
+	require.True(suite.T(), QueryParamMatcher("tag", "a").Match(r))
+	require.False(suite.T(), QueryParamMatcher("q", "hello%20world").Match(r))
+	require.True(suite.T(), QueryParamContainsMatcher("q", "world").Match(r))
+	require.False(suite.T(), QueryParamContainsMatcher("q", "planet").Match(r))
+	require.True(suite.T(), QueryParamRegexMatcher("q", regexp.MustCompile(`^hello \w+$`)).Match(r))
+	require.False(suite.T(), QueryParamRegexMatcher("missing", regexp.MustCompile(`.*`)).Match(r))
+	require.True(suite.T(), QueryParamValuesMatcher("tag", "a", "b").Match(r))
+	require.False(suite.T(), QueryParamValuesMatcher("tag", "a").Match(r))
+	require.True(suite.T(), QueryParamExistsMatcher("debug").Match(r))
+	require.False(suite.T(), QueryParamExistsMatcher("verbose").Match(r))
+	require.Equal(suite.T(), []CriterionResult{{Criterion: "query parameter tag", Expected: `["c"]`, Actual: `["b" "a"]`}}, ExplainMatch(QueryParamValuesMatcher("tag", "c"), r))
+	require.True(suite.T(), QueryEqualsMatcher("name=a%20b&q=hello+world&tag=b&tag=a&debug=").Match(r))
+	require.False(suite.T(), QueryEqualsMatcher("name=a%20b&q=hello+world&tag=a&tag=b&debug=").Match(r))
+	require.False(suite.T(), QueryEqualsMatcher("q=hello+world").Match(r))
+	require.True(suite.T(), QueryEqualsMatcher("").Match(httptest.NewRequest(http.MethodGet, "/search", nil)))
+	require.False(suite.T(), QueryEqualsMatcher("").Match(r))
+
+	// Builder
+	suite.hts.When().Get("/search").WithQueryParam("q", "hello world").WithQueryParamValues("tag", "a", "b").
+		WithQueryParamMatching("page", regexp.MustCompile(`^\d+$`)).RespondWith().Status(http.StatusOK)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/search?tag=a&q=hello+world&page=2&tag=b")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	resp, err = suite.hts.Client().Get(suite.hts.GetBaseURL() + "/search?tag=a&q=hello+world&page=two&tag=b")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	require.Len(suite.T(), suite.hts.FindRecords(ByQueryParam("page", "2")), 1)
+}
+
+// Test the query parameter assertions of records.
+func (suite *HTTPTestServerUnitTestSuite) TestQueryParamAssertions() {
+	record := &ServerRecord{Request: httptest.NewRequest(http.MethodGet, "/search?q=caf%C3%A9&tag=b&tag=a&debug", nil)}
+	require.Equal(suite.T(), []string{"café"}, record.QueryValues("q"))
+	require.NoError(suite.T(), record.AssertQueryParam("q", "café"))
+	require.EqualError(suite.T(), record.AssertQueryParam("q", "cafe"), `expected query parameter q to be "cafe", got ["café"]`)
+	require.NoError(suite.T(), record.AssertQueryParamValues("tag", "a", "b"))
+	require.EqualError(suite.T(), record.AssertQueryParamValues("tag", "a"), `expected query parameter tag values ["a"], got ["b" "a"]`)
+	require.NoError(suite.T(), record.AssertQueryParamMatches("q", regexp.MustCompile(`^caf`)))
+	require.Error(suite.T(), record.AssertQueryParamMatches("q", regexp.MustCompile(`^tea`)))
+	require.NoError(suite.T(), record.AssertNoQueryParam("page"))
+	require.EqualError(suite.T(), record.AssertNoQueryParam("debug"), `expected no query parameter debug, got [""]`)
+	require.Nil(suite.T(), (&ServerRecord{}).QueryValues("q"))
+	require.NoError(suite.T(), (&ServerRecord{}).AssertNoQueryParam("q"))
+}
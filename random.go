@@ -0,0 +1,94 @@
+package gosette
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Option which seeds the random source of the test server with the provided seed.
+//
+// The random source of the test server is used by all the features which draw random values
+// unless they have their own seed: the chaos mode (see ChaosConfig Seed), the latency
+// distributions built by the test server (see HTTPTestServer UniformLatency) and Rand. The same
+// seed and the same sequence of requests produce the same outcomes.
+//
+// Without this option, the random source is seeded with the current time: use RandSeed to log the
+// seed so a failing run can be reproduced. Scopes share the random source of the test server they
+// have been created from.
+func WithRandSeed(seed int64) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.randSeed = seed
+		hts.rnd = newLockedRand(seed)
+	}
+}
+
+// Return the seed of the random source of the test server. Example:
+//
+//	hts := gosette.NewHTTPTestServer(nil)
+//	t.Logf("test server seed: %d", hts.RandSeed())
+func (hts *HTTPTestServer) RandSeed() int64 {
+	return hts.randSeed
+}
+
+// Return the random source of the test server. Use it to generate test data so that the data are
+// reproduced from the test server seed as well (see WithRandSeed).
+//
+// The returned source is safe for concurrent use, except its Read method.
+func (hts *HTTPTestServer) Rand() *rand.Rand {
+	return hts.rnd
+}
+
+// Build a distribution which draws latencies uniformly in [min, max) from the random source of the
+// test server (see WithRandSeed).
+func (hts *HTTPTestServer) UniformLatency(min time.Duration, max time.Duration) LatencyDistribution {
+	return uniformLatency(min, max, hts.rnd)
+}
+
+// Build a distribution which draws latencies from a normal distribution with the provided mean and
+// standard deviation, using the random source of the test server (see WithRandSeed). Negative
+// latencies are replaced by zero.
+func (hts *HTTPTestServer) NormalLatency(mean time.Duration, stddev time.Duration) LatencyDistribution {
+	return normalLatency(mean, stddev, hts.rnd)
+}
+
+// Build a distribution which draws latencies from an exponential distribution with the provided
+// mean, using the random source of the test server (see WithRandSeed).
+func (hts *HTTPTestServer) ExponentialLatency(mean time.Duration) LatencyDistribution {
+	return exponentialLatency(mean, hts.rnd)
+}
+
+// A random source which is safe for concurrent use.
+type lockedSource struct {
+	// Mutex used to protect the source from concurrent access.
+	mu sync.Mutex
+	// The protected source.
+	src rand.Source64
+}
+
+// Return a random 63-bit integer.
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+// Return a random 64-bit integer.
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+// Seed the source.
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// Helper function which builds a random source seeded with the provided seed which is safe for
+// concurrent use.
+func newLockedRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
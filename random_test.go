@@ -0,0 +1,44 @@
+package gosette
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the random source of the test server. Test will ensure:
+//   - Test servers with the same seed draw the same values, chaos outcomes and latencies
+//   - Test servers are seeded with the current time by default and expose their seed
+//   - Scopes share the random source of the test server
+func TestRandSeed(t *testing.T) {
+	draw := func(hts *HTTPTestServer) []interface{} {
+		hts.EnableChaos(ChaosConfig{Probability: 0.5})
+		distribution := hts.NormalLatency(time.Second, time.Second)
+		values := []interface{}{}
+		for i := 0; i < 20; i++ {
+			outcome, _ := hts.nextChaosOutcome()
+			values = append(values, hts.Rand().Int63(), outcome, distribution.Sample())
+		}
+		return values
+	}
+	first := NewHTTPTestServer(nil, WithRandSeed(42))
+	second := NewHTTPTestServer(nil, WithRandSeed(42))
+	require.Equal(t, int64(42), first.RandSeed())
+	require.Equal(t, draw(first), draw(second))
+	require.NotEqual(t, draw(NewHTTPTestServer(nil, WithRandSeed(42))), draw(NewHTTPTestServer(nil, WithRandSeed(43))))
+
+	// Default seed
+	hts := NewHTTPTestServer(nil)
+	require.NotZero(t, hts.RandSeed())
+	require.Equal(t, draw(hts), draw(NewHTTPTestServer(nil, WithRandSeed(hts.RandSeed()))))
+
+	// Scopes
+	hts = NewHTTPTestServer(nil, WithRandSeed(7))
+	scope := hts.Scope()
+	defer scope.Close()
+	require.Equal(t, int64(7), scope.RandSeed())
+	expected := NewHTTPTestServer(nil, WithRandSeed(7)).Rand()
+	require.Equal(t, expected.Int63(), hts.Rand().Int63())
+	require.Equal(t, expected.Int63(), scope.Rand().Int63())
+}
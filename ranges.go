@@ -0,0 +1,181 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A byte range requested with the Range header (ex: bytes=0-99, bytes=100- or bytes=-500).
+type ByteRange struct {
+	// Position of the first byte. -1 for a suffix range.
+	Start int64
+	// Position of the last byte, included. -1 when the range is open-ended or is a suffix range.
+	End int64
+	// Number of bytes requested at the end of the representation for a suffix range (ex: 500
+	// for bytes=-500). Zero otherwise.
+	SuffixLength int64
+}
+
+// Return the range in the Range header syntax (ex: 0-99, 100- or -500).
+func (br ByteRange) String() string {
+	switch {
+	case br.Start < 0:
+		return fmt.Sprintf("-%d", br.SuffixLength)
+	case br.End < 0:
+		return fmt.Sprintf("%d-", br.Start)
+	default:
+		return fmt.Sprintf("%d-%d", br.Start, br.End)
+	}
+}
+
+// Helper method which resolves the range against a representation of the provided size and
+// returns the positions of the first and last bytes. Returns false if the range is not
+// satisfiable.
+func (br ByteRange) resolve(size int64) (int64, int64, bool) {
+	if br.Start < 0 {
+		if br.SuffixLength == 0 || size == 0 {
+			return 0, 0, false
+		}
+		start := size - br.SuffixLength
+		if start < 0 {
+			start = 0
+		}
+		return start, size - 1, true
+	}
+	if br.Start >= size {
+		return 0, 0, false
+	}
+	end := br.End
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+	return br.Start, end, true
+}
+
+// Helper function which parses the byte ranges of the provided Range header value. Returns nil if
+// the value is empty or malformed: malformed Range headers are ignored.
+func parseRange(value string) []ByteRange {
+	if !strings.HasPrefix(value, "bytes=") {
+		return nil
+	}
+	ranges := []ByteRange{}
+	for _, spec := range strings.Split(strings.TrimPrefix(value, "bytes="), ",") {
+		spec = strings.TrimSpace(spec)
+		dash := strings.Index(spec, "-")
+		if dash < 0 {
+			return nil
+		}
+		first, last := spec[:dash], spec[dash+1:]
+		if first == "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil
+			}
+			ranges = append(ranges, ByteRange{Start: -1, End: -1, SuffixLength: n})
+			continue
+		}
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil
+		}
+		br := ByteRange{Start: start, End: -1}
+		if last != "" {
+			end, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || end < start {
+				return nil
+			}
+			br.End = end
+		}
+		ranges = append(ranges, br)
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	return ranges
+}
+
+// Helper function which serves the byte ranges requested by the provided request from the body of
+// the provided predefined response.
+//
+// Returns a copy of the response with an Accept-Ranges header in case the request is not a GET
+// request with a valid Range header, in case its If-Range precondition is not satisfied or in case
+// the response is not a 200 response. Otherwise, returns a 206 Partial Content response with the
+// requested range (a multipart/byteranges body for several ranges) or a 416 Range Not Satisfiable
+// response if none of the ranges is satisfiable.
+func rangeResponse(r *http.Request, response *PredefinedServerResponse) *PredefinedServerResponse {
+	full := *response
+	full.Headers = response.Headers.Clone()
+	if full.Headers == nil {
+		full.Headers = http.Header{}
+	}
+	full.Headers.Set("Accept-Ranges", "bytes")
+	ranges := parseRange(r.Header.Get("Range"))
+	if r.Method != http.MethodGet || ranges == nil || response.Status != http.StatusOK || !ifRangeSatisfied(r, response) {
+		return &full
+	}
+	// Resolve the ranges against the body
+	size := int64(len(response.Body))
+	type span struct{ start, end int64 }
+	spans := []span{}
+	for _, br := range ranges {
+		if start, end, ok := br.resolve(size); ok {
+			spans = append(spans, span{start, end})
+		}
+	}
+	partial := full
+	partial.Headers = full.Headers.Clone()
+	partial.Headers.Del("Content-Length")
+	if len(spans) == 0 {
+		partial.Status = http.StatusRequestedRangeNotSatisfiable
+		partial.Headers.Del("Content-Type")
+		partial.Headers.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		partial.Body = nil
+		return &partial
+	}
+	partial.Status = http.StatusPartialContent
+	// Serve a single range
+	if len(spans) == 1 {
+		partial.Headers.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", spans[0].start, spans[0].end, size))
+		partial.Body = response.Body[spans[0].start : spans[0].end+1]
+		return &partial
+	}
+	// Serve several ranges in a multipart/byteranges body
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	contentType := full.Headers.Get("Content-Type")
+	for _, s := range spans {
+		header := textproto.MIMEHeader{}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", s.start, s.end, size))
+		part, _ := mw.CreatePart(header)
+		part.Write(response.Body[s.start : s.end+1])
+	}
+	mw.Close()
+	partial.Headers.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	partial.Body = body.Bytes()
+	return &partial
+}
+
+// Helper function which returns true if the request has no If-Range header or if its If-Range
+// header matches the validators of the provided predefined response: the entity tag (strong
+// comparison) or the modification time.
+func ifRangeSatisfied(r *http.Request, response *PredefinedServerResponse) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		etag := quoteETag(response.ETag)
+		return response.ETag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !response.LastModified.IsZero() && response.LastModified.Truncate(time.Second).Equal(t)
+}
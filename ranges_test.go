@@ -0,0 +1,95 @@
+package gosette
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test byte ranges of the body are served with 206 and 416 responses and recorded.
+func (suite *HTTPTestServerUnitTestSuite) TestRangeRequests() {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	suite.hts.When().Path("/file").RespondWith().
+		Header("Content-Type", "text/plain").
+		ETag("v1").
+		LastModified(modified).
+		AcceptRanges().
+		StringBody("0123456789")
+	do := func(method string, headers map[string]string) (*http.Response, string) {
+		req, err := http.NewRequest(method, suite.hts.GetBaseURL()+"/file", nil)
+		require.NoError(suite.T(), err)
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		return resp, string(body)
+	}
+	tests := []struct {
+		headers      map[string]string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{nil, http.StatusOK, "", "0123456789"},
+		{map[string]string{"Range": "bytes=0-3"}, http.StatusPartialContent, "bytes 0-3/10", "0123"},
+		{map[string]string{"Range": "bytes=7-"}, http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{map[string]string{"Range": "bytes=-2"}, http.StatusPartialContent, "bytes 8-9/10", "89"},
+		{map[string]string{"Range": "bytes=5-100"}, http.StatusPartialContent, "bytes 5-9/10", "56789"},
+		{map[string]string{"Range": "bytes=10-"}, http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{map[string]string{"Range": "bytes=5-2"}, http.StatusOK, "", "0123456789"},
+		{map[string]string{"Range": "items=0-1"}, http.StatusOK, "", "0123456789"},
+		{map[string]string{"Range": "bytes=2-3", "If-Range": `"v1"`}, http.StatusPartialContent, "bytes 2-3/10", "23"},
+		{map[string]string{"Range": "bytes=2-3", "If-Range": `"v0"`}, http.StatusOK, "", "0123456789"},
+		{map[string]string{"Range": "bytes=2-3", "If-Range": modified.Format(http.TimeFormat)}, http.StatusPartialContent, "bytes 2-3/10", "23"},
+		{map[string]string{"Range": "bytes=2-3", "If-Range": "invalid"}, http.StatusOK, "", "0123456789"},
+	}
+	for _, tc := range tests {
+		resp, body := do(http.MethodGet, tc.headers)
+		require.Equal(suite.T(), tc.status, resp.StatusCode, tc.headers)
+		require.Equal(suite.T(), tc.contentRange, resp.Header.Get("Content-Range"), tc.headers)
+		require.Equal(suite.T(), tc.body, body, tc.headers)
+		require.Equal(suite.T(), "bytes", resp.Header.Get("Accept-Ranges"))
+	}
+
+	// Several ranges are served in a multipart/byteranges body
+	resp, body := do(http.MethodGet, map[string]string{"Range": "bytes=0-1, 20-30, -1"})
+	require.Equal(suite.T(), http.StatusPartialContent, resp.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "multipart/byteranges", mediaType)
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for _, expected := range []struct{ contentRange, body string }{{"bytes 0-1/10", "01"}, {"bytes 9-9/10", "9"}} {
+		part, err := reader.NextPart()
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected.contentRange, part.Header.Get("Content-Range"))
+		require.Equal(suite.T(), "text/plain", part.Header.Get("Content-Type"))
+		data, err := io.ReadAll(part)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected.body, string(data))
+	}
+	_, err = reader.NextPart()
+	require.Equal(suite.T(), io.EOF, err)
+
+	// Ranges are ignored for other methods
+	resp, body = do(http.MethodPost, map[string]string{"Range": "bytes=0-1"})
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "0123456789", body)
+
+	// Requested ranges are recorded
+	records := suite.hts.FindRecords(ByMethod(http.MethodGet))
+	require.Nil(suite.T(), records[0].Ranges)
+	require.Equal(suite.T(), []ByteRange{{Start: 0, End: 3}}, records[1].Ranges)
+	require.Equal(suite.T(), []ByteRange{{Start: 0, End: 1}, {Start: 20, End: 30}, {Start: -1, End: -1, SuffixLength: 1}}, records[len(records)-1].Ranges)
+	require.Equal(suite.T(), "7-", records[2].Ranges[0].String())
+	require.Equal(suite.T(), "-2", records[3].Ranges[0].String())
+	require.Equal(suite.T(), "0-3", records[1].Ranges[0].String())
+}
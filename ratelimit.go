@@ -0,0 +1,118 @@
+package gosette
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Option used to configure the rate limiter built by RateLimit.
+type RateLimitOption func(cfg *rateLimitConfig)
+
+// Configuration of the rate limiter.
+type rateLimitConfig struct {
+	// Fixed value of the Retry-After header. The time left before the window resets is used
+	// when zero.
+	retryAfter time.Duration
+	// True if X-RateLimit-* headers must be added to responses.
+	headers bool
+	// Function which returns the key requests are counted by.
+	key func(r *http.Request) string
+}
+
+// Option which sets a fixed value for the Retry-After header of 429 responses instead of the time
+// left before the window resets.
+func RateLimitRetryAfter(retryAfter time.Duration) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.retryAfter = retryAfter
+	}
+}
+
+// Option which disables the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers.
+func WithoutRateLimitHeaders() RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.headers = false
+	}
+}
+
+// Option which makes the rate limiter count requests per key (ex: per API key header) instead of
+// counting all requests together. Each key has its own window.
+func RateLimitKey(key func(r *http.Request) string) RateLimitOption {
+	return func(cfg *rateLimitConfig) {
+		cfg.key = key
+	}
+}
+
+// A fixed window of the rate limiter.
+type rateLimitWindow struct {
+	// Time at which the window resets.
+	reset time.Time
+	// Number of requests accepted during the window.
+	count int
+}
+
+// Build a middleware which simulates a rate limited API: at most limit requests are served per
+// fixed window of the provided duration. The window starts with the first request. Other requests
+// are answered with an empty 429 Too Many Requests response which has a Retry-After header.
+//
+// By default, the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (seconds left
+// before the window resets) headers are added to all responses. Throttled requests are recorded
+// with Throttled set.
+func RateLimit(limit int, window time.Duration, options ...RateLimitOption) Middleware {
+	cfg := &rateLimitConfig{
+		headers: true,
+		key:     func(r *http.Request) string { return "" },
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	var mu sync.Mutex
+	windows := map[string]*rateLimitWindow{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Count the request in the current window of its key
+			now := clockFromContext(r.Context()).Now()
+			key := cfg.key(r)
+			mu.Lock()
+			current, ok := windows[key]
+			if !ok || !now.Before(current.reset) {
+				current = &rateLimitWindow{reset: now.Add(window)}
+				windows[key] = current
+			}
+			throttled := current.count >= limit
+			if !throttled {
+				current.count++
+			}
+			remaining, reset := limit-current.count, current.reset.Sub(now)
+			mu.Unlock()
+			// Add rate limit headers
+			if cfg.headers {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+				w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(reset)))
+			}
+			if !throttled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Throttle the request
+			if record := recordFromContext(r.Context()); record != nil {
+				record.Throttled = true
+			}
+			retryAfter := reset
+			if cfg.retryAfter > 0 {
+				retryAfter = cfg.retryAfter
+			}
+			writeHeaders(w, &PredefinedServerResponse{
+				Status:  http.StatusTooManyRequests,
+				Headers: http.Header{"Retry-After": {strconv.Itoa(seconds(retryAfter))}},
+			})
+		})
+	}
+}
+
+// Helper function which returns the provided duration in seconds, rounded up.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,60 @@
+package gosette
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test requests over the limit are throttled with a 429 response, rate limit headers and a
+// Retry-After header, and are recorded as throttled.
+func TestRateLimit(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.Use(RateLimit(2, time.Minute))
+	hts.When().Get("/items").RespondWith().Status(http.StatusOK)
+	client := hts.Client()
+	for i, status := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		resp, err := client.Get(hts.GetBaseURL() + "/items")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode)
+		require.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+		require.Equal(t, map[int]string{0: "1", 1: "0", 2: "0"}[i], resp.Header.Get("X-RateLimit-Remaining"))
+		require.Equal(t, "60", resp.Header.Get("X-RateLimit-Reset"))
+		if status == http.StatusTooManyRequests {
+			require.Equal(t, "60", resp.Header.Get("Retry-After"))
+		}
+	}
+	require.Len(t, hts.FindRecords(Throttled()), 1)
+	require.Len(t, hts.FindRecords(Not(Throttled())), 2)
+}
+
+// Test the window resets, a fixed Retry-After can be set, headers can be disabled and requests can
+// be counted per key.
+func TestRateLimitOptions(t *testing.T) {
+	handler := RateLimit(1, 100*time.Millisecond,
+		RateLimitRetryAfter(3*time.Second),
+		WithoutRateLimitHeaders(),
+		RateLimitKey(func(r *http.Request) string { return r.Header.Get("X-Api-Key") }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(key string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+	require.Equal(t, http.StatusOK, serve("a").StatusCode)
+	require.Equal(t, http.StatusOK, serve("b").StatusCode)
+	resp := serve("a")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "3", resp.Header.Get("Retry-After"))
+	require.Empty(t, resp.Header.Get("X-RateLimit-Limit"))
+	time.Sleep(150 * time.Millisecond)
+	require.Equal(t, http.StatusOK, serve("a").StatusCode)
+}
@@ -0,0 +1,76 @@
+package gosette
+
+import "net/http"
+
+// Policy applied when a record must be added while the record queue is full (see WithMaxRecords).
+type EvictionPolicy int
+
+const (
+	// The oldest retained record is evicted to make room for the new record.
+	EvictOldest EvictionPolicy = iota
+	// The new record is dropped: the retained records are kept as is.
+	DropNewest
+	// Requests received while the record queue is full are answered with an empty 507 Insufficient
+	// Storage response and are not recorded.
+	RejectWhenFull
+)
+
+// Option which limits the number of records retained by the test server to the provided maximum.
+// Once the limit is reached, the provided eviction policy decides which records are dropped. The
+// number of records which have been dropped is available with EvictedRecords.
+//
+// Metrics, OnResponse hooks and logs still see the dropped records. The number of records is not
+// limited when the maximum is lower than 1. Scopes use the limit and the policy of the test server
+// they have been created from.
+func WithMaxRecords(max int, policy EvictionPolicy) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.maxRecords = max
+		hts.evictionPolicy = policy
+	}
+}
+
+// Get the number of records which have been dropped because the record queue was full (see
+// WithMaxRecords). The counter is reset by ClearServerRecords.
+func (hts *HTTPTestServer) EvictedRecords() int {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	return hts.evictedRecords
+}
+
+// Helper method which returns true if the record queue is full and new requests must be rejected.
+func (srv *HTTPTestServer) rejectsNewRecords() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.evictionPolicy == RejectWhenFull && srv.recordQueueFull()
+}
+
+// Helper method which rejects a request received while the record queue is full with an empty 507
+// Insufficient Storage response. The record is counted as evicted.
+func (srv *HTTPTestServer) rejectFullRecordQueue(w http.ResponseWriter, serverRecord *ServerRecord) {
+	writeHeaders(w, &PredefinedServerResponse{
+		Status:  http.StatusInsufficientStorage,
+		Headers: http.Header{"Content-Length": {"0"}},
+	})
+	srv.addServerRecord(serverRecord)
+}
+
+// Helper method which returns true if the record queue has reached its maximum size. The caller
+// must hold the lock.
+func (srv *HTTPTestServer) recordQueueFull() bool {
+	return srv.maxRecords > 0 && len(srv.records) >= srv.maxRecords
+}
+
+// Helper method which adds the provided record to the record queue according to the eviction
+// policy. The caller must hold the lock.
+func (srv *HTTPTestServer) retainRecord(serverRecord *ServerRecord) {
+	if srv.recordQueueFull() {
+		srv.evictedRecords++
+		if srv.evictionPolicy != EvictOldest {
+			return
+		}
+		// Release the oldest record before dropping it
+		srv.records[0] = nil
+		srv.records = srv.records[1:]
+	}
+	srv.records = append(srv.records, serverRecord)
+}
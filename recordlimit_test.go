@@ -0,0 +1,71 @@
+package gosette
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the record cap with each eviction policy. Test will ensure:
+//   - Records over the limit are evicted or dropped according to the policy and counted
+//   - Requests are rejected with 507 responses when the policy rejects new records
+//   - The counter is reset by ClearServerRecords
+func TestMaxRecords(t *testing.T) {
+	// Helper which sends GET requests to the provided paths and returns the status codes
+	send := func(hts *HTTPTestServer, paths ...string) []int {
+		statuses := []int{}
+		for _, path := range paths {
+			resp, err := hts.Client().Get(hts.GetBaseURL() + path)
+			require.NoError(t, err)
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+		}
+		return statuses
+	}
+	// Helper which returns the paths of the retained records
+	paths := func(hts *HTTPTestServer) []string {
+		paths := []string{}
+		for _, record := range hts.FindRecords() {
+			paths = append(paths, record.Request.URL.Path)
+		}
+		return paths
+	}
+
+	// Evict oldest
+	hts := NewHTTPTestServer(nil, WithMaxRecords(2, EvictOldest))
+	hts.Start()
+	defer hts.Close()
+	require.Equal(t, []int{404, 404, 404, 404}, send(hts, "/a", "/b", "/c", "/d"))
+	require.Equal(t, []string{"/c", "/d"}, paths(hts))
+	require.Equal(t, 2, hts.EvictedRecords())
+	require.Equal(t, 4, hts.Metrics().Total.Count)
+	hts.ClearServerRecords()
+	require.Equal(t, 0, hts.EvictedRecords())
+
+	// Drop newest
+	hts = NewHTTPTestServer(nil, WithMaxRecords(2, DropNewest))
+	hts.Start()
+	defer hts.Close()
+	require.Equal(t, []int{404, 404, 404}, send(hts, "/a", "/b", "/c"))
+	require.Equal(t, []string{"/a", "/b"}, paths(hts))
+	require.Equal(t, 1, hts.EvictedRecords())
+
+	// Reject when full
+	hts = NewHTTPTestServer(nil, WithMaxRecords(1, RejectWhenFull))
+	hts.Start()
+	defer hts.Close()
+	require.Equal(t, []int{404, http.StatusInsufficientStorage, http.StatusInsufficientStorage}, send(hts, "/a", "/b", "/c"))
+	require.Equal(t, []string{"/a"}, paths(hts))
+	require.Equal(t, 2, hts.EvictedRecords())
+	hts.PopServerRecord()
+	require.Equal(t, []int{404}, send(hts, "/d"))
+	require.Equal(t, []string{"/d"}, paths(hts))
+
+	// No limit
+	hts = NewHTTPTestServer(nil, WithMaxRecords(0, RejectWhenFull))
+	hts.Start()
+	defer hts.Close()
+	require.Equal(t, []int{404, 404}, send(hts, "/a", "/b"))
+	require.Equal(t, 0, hts.EvictedRecords())
+}
@@ -0,0 +1,103 @@
+package gosette
+
+import "strings"
+
+// A filter used to select server records. Returns true if the record must be selected.
+type RecordFilter func(record *ServerRecord) bool
+
+// Find the server records which are selected by all the provided filters. Records are returned in
+// the order they have been recorded and are not popped from the record queue. All records are
+// returned if no filters are provided.
+func (hts *HTTPTestServer) FindRecords(filters ...RecordFilter) []*ServerRecord {
+	found := []*ServerRecord{}
+	for _, record := range hts.snapshotServerRecords() {
+		if AllOf(filters...)(record) {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+// Build a filter which selects records selected by all the provided filters.
+func AllOf(filters ...RecordFilter) RecordFilter {
+	return func(record *ServerRecord) bool {
+		for _, filter := range filters {
+			if !filter(record) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Build a filter which selects records selected by at least one of the provided filters.
+func AnyOf(filters ...RecordFilter) RecordFilter {
+	return func(record *ServerRecord) bool {
+		for _, filter := range filters {
+			if filter(record) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Build a filter which selects records which are not selected by the provided filter.
+func Not(filter RecordFilter) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return !filter(record)
+	}
+}
+
+// Build a filter which selects records whose request URL path is equal to the provided path.
+func ByPath(path string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Request != nil && record.Request.URL.Path == path
+	}
+}
+
+// Build a filter which selects records whose request uses the provided HTTP method. Comparison is
+// case insensitive.
+func ByMethod(method string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Request != nil && strings.EqualFold(record.Request.Method, method)
+	}
+}
+
+// Build a filter which selects records whose request has at least one value for the provided
+// header which is equal to the provided value.
+func ByHeader(header string, value string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Request != nil && HeaderEqualsMatcher(header, value).Match(record.Request)
+	}
+}
+
+// Build a filter which selects records whose recorded response has the provided status code.
+func ByStatus(status int) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Response != nil && record.Response.Code == status
+	}
+}
+
+// Build a filter which selects records whose request is matched by the provided request matcher.
+// The matcher is provided with a fresh copy of the recorded request body.
+func ByMatcher(matcher RequestMatcher) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.matches(matcher)
+	}
+}
+
+// Build a filter which selects records which have their ServerError set.
+func WithServerError() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.ServerError != nil
+	}
+}
+
+// Build a filter which selects records of requests which have been throttled by the rate limiter
+// (see RateLimit).
+func Throttled() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Throttled
+	}
+}
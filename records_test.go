@@ -0,0 +1,109 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test FindRecords and record filters. Test will ensure records are selected without being popped
+// and that filters can be composed.
+func (suite *HTTPTestServerUnitTestSuite) TestFindRecords() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Serve 201 for POST /orders and 404 otherwise
+	suite.hts.PushPredefinedServerResponseForRoute(http.MethodPost, "/orders", &PredefinedServerResponse{
+		Status: http.StatusCreated,
+	})
+
+	// Send requests
+	req, err := http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/orders", strings.NewReader("order"))
+	require.NoError(suite.T(), err)
+	req.Header.Set("X-Request-Id", "1")
+	_, err = client.Do(req)
+	require.NoError(suite.T(), err)
+	_, err = client.Get(suite.hts.GetBaseURL() + "/orders")
+	require.NoError(suite.T(), err)
+	_, err = client.Get(suite.hts.GetBaseURL() + "/health")
+	require.NoError(suite.T(), err)
+
+	// Add a record with a server error
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{Fault: Fault(-1)})
+	suite.hts.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+
+	// Find records
+	require.Len(suite.T(), suite.hts.FindRecords(), 4)
+	require.Len(suite.T(), suite.hts.FindRecords(ByPath("/orders")), 2)
+	require.Len(suite.T(), suite.hts.FindRecords(ByPath("/orders"), ByMethod("post")), 1)
+	require.Len(suite.T(), suite.hts.FindRecords(ByHeader("X-Request-Id", "1")), 1)
+	require.Len(suite.T(), suite.hts.FindRecords(ByStatus(http.StatusNotFound)), 2)
+	require.Len(suite.T(), suite.hts.FindRecords(ByMatcher(BodyContainsMatcher("order"))), 1)
+	require.Len(suite.T(), suite.hts.FindRecords(AnyOf(ByPath("/health"), ByStatus(http.StatusCreated))), 2)
+	require.Len(suite.T(), suite.hts.FindRecords(Not(ByPath("/orders"))), 2)
+	errRecords := suite.hts.FindRecords(WithServerError())
+	require.Len(suite.T(), errRecords, 1)
+	require.Equal(suite.T(), "/error", errRecords[0].Request.URL.Path)
+	require.Empty(suite.T(), suite.hts.FindRecords(ByPath("/admin")))
+
+	// Records have not been popped
+	require.Len(suite.T(), suite.hts.records, 4)
+
+	// Filters do not select records without request or response
+	record := &ServerRecord{}
+	require.False(suite.T(), ByPath("/")(record))
+	require.False(suite.T(), ByMethod(http.MethodGet)(record))
+	require.False(suite.T(), ByHeader("X", "Y")(record))
+	require.False(suite.T(), ByStatus(http.StatusOK)(record))
+}
+
+// Test DrainServerRecords. Test will ensure all records are removed and returned in order and
+// that concurrent drains never return a record twice nor lose any.
+func (suite *HTTPTestServerUnitTestSuite) TestDrainServerRecords() {
+	client := suite.hts.Client()
+	require.Empty(suite.T(), suite.hts.DrainServerRecords())
+
+	// Records are returned in order
+	for _, path := range []string{"/a", "/b", "/c"} {
+		resp, err := client.Get(suite.hts.GetBaseURL() + path)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+	records := suite.hts.DrainServerRecords()
+	require.Len(suite.T(), records, 3)
+	for i, path := range []string{"/a", "/b", "/c"} {
+		require.Equal(suite.T(), path, records[i].Request.URL.Path)
+	}
+	require.Nil(suite.T(), suite.hts.PopServerRecord())
+	require.Empty(suite.T(), suite.hts.DrainServerRecords())
+
+	// Concurrent requests and drains
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get(fmt.Sprintf("%s/%d", suite.hts.GetBaseURL(), i))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	seen := map[string]bool{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(seen) < n && time.Now().Before(deadline) {
+		for _, record := range suite.hts.DrainServerRecords() {
+			require.False(suite.T(), seen[record.Request.URL.Path])
+			seen[record.Request.URL.Path] = true
+		}
+	}
+	wg.Wait()
+	require.Empty(suite.T(), suite.hts.DrainServerRecords())
+	require.Len(suite.T(), seen, n)
+}
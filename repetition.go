@@ -0,0 +1,52 @@
+package gosette
+
+// Repetition controls how many times a predefined response may be served.
+//
+// The zero value keeps the default behavior: responses from a queue are served once and the last
+// response of a queue is served indefinitly while responses registered with a request matcher are
+// served indefinitly. Use Times, Once or Forever to build a Repetition.
+type Repetition struct {
+	// Number of times the response may be served. Zero means the default behavior.
+	times int
+	// True if the response may be served indefinitly.
+	forever bool
+}
+
+// Build a Repetition which allows a predefined response to be served n times before the queue
+// advances. Responses registered with a request matcher stop matching once served n times.
+//
+// The last response of a queue is still served indefinitly as the queue cannot advance. A value
+// of n lower than 1 is treated as 1.
+func Times(n int) Repetition {
+	if n < 1 {
+		n = 1
+	}
+	return Repetition{times: n}
+}
+
+// Build a Repetition which allows a predefined response to be served once. Equivalent to Times(1).
+func Once() Repetition {
+	return Times(1)
+}
+
+// Build a Repetition which allows a predefined response to be served indefinitly: the queue never
+// advances past the response, even when more responses are queued after it.
+func Forever() Repetition {
+	return Repetition{forever: true}
+}
+
+// Return true if a response with this Repetition has been served enough times considering it has
+// been served the provided number of times. The provided default number of times is used when
+// the Repetition is the zero value: a default lower than 1 means indefinitly.
+func (rep Repetition) exhausted(served int, defaultTimes int) bool {
+	switch {
+	case rep.forever:
+		return false
+	case rep.times > 0:
+		return served >= rep.times
+	case defaultTimes > 0:
+		return served >= defaultTimes
+	default:
+		return false
+	}
+}
@@ -0,0 +1,136 @@
+package gosette
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test repetition control of queued responses. Test will ensure "two 503s then a 200" retry
+// scenarios can be modeled and that Forever responses are never popped.
+func (suite *HTTPTestServerUnitTestSuite) TestQueuedResponseRepetition() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Push two 503s, one 202, then a 200 (which will be served indefinitly)
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusServiceUnavailable,
+		Repeat: Times(2),
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusAccepted,
+		Repeat: Once(),
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	for _, expected := range []int{503, 503, 202, 200, 200} {
+		resp, err := client.Get(suite.hts.GetBaseURL())
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected, resp.StatusCode)
+	}
+
+	// Push a response served forever even if more responses are queued after it
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusTeapot,
+		Repeat: Forever(),
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(suite.hts.GetBaseURL())
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), http.StatusTeapot, resp.StatusCode)
+	}
+}
+
+// Test repetition control of responses registered with a request matcher. Test will ensure
+// exhausted responses are skipped so the next matching response is served.
+func (suite *HTTPTestServerUnitTestSuite) TestRegisteredResponseRepetition() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Register two 503s then a 200 for the same path
+	suite.hts.RegisterResponse(PathMatcher("/orders"), &PredefinedServerResponse{
+		Status: http.StatusServiceUnavailable,
+		Repeat: Times(2),
+	})
+	suite.hts.RegisterResponse(PathMatcher("/orders"), &PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	for _, expected := range []int{503, 503, 200, 200} {
+		resp, err := client.Get(suite.hts.GetBaseURL() + "/orders")
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected, resp.StatusCode)
+	}
+}
+
+// Test Repetition constructors and exhaustion rules.
+func (suite *HTTPTestServerUnitTestSuite) TestRepetition() {
+	require.Equal(suite.T(), Times(1), Once())
+	require.Equal(suite.T(), Times(1), Times(0))
+	// Explicit times
+	require.False(suite.T(), Times(2).exhausted(1, 1))
+	require.True(suite.T(), Times(2).exhausted(2, 1))
+	// Forever
+	require.False(suite.T(), Forever().exhausted(100, 1))
+	// Default behavior
+	require.True(suite.T(), Repetition{}.exhausted(1, 1))
+	require.False(suite.T(), Repetition{}.exhausted(100, 0))
+}
+
+// Test Sticky and ConsumeOnce flags. Test will ensure:
+//   - ConsumeOnce responses are consumed even when they are the last of their queue so the server
+//     goes back to the default 404 response
+//   - Sticky responses are never popped even when more responses are queued after them
+//   - ConsumeOnce responses registered with a matcher are served once by default
+func (suite *HTTPTestServerUnitTestSuite) TestStickyAndConsumeOnce() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Push responses which must be consumed exactly once
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:      http.StatusCreated,
+		ConsumeOnce: true,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status:      http.StatusOK,
+		Repeat:      Times(2),
+		ConsumeOnce: true,
+	})
+	for _, expected := range []int{201, 200, 200, 404} {
+		resp, err := client.Get(suite.hts.GetBaseURL())
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected, resp.StatusCode)
+	}
+	require.Empty(suite.T(), suite.hts.responses)
+
+	// Push a sticky response followed by another response
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusTeapot,
+		Repeat: Once(),
+		Sticky: true,
+	})
+	suite.hts.PushPredefinedServerResponse(&PredefinedServerResponse{
+		Status: http.StatusOK,
+	})
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(suite.hts.GetBaseURL())
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), http.StatusTeapot, resp.StatusCode)
+	}
+
+	// Register a response which must be consumed once
+	suite.hts.ClearPredefinedServerResponses()
+	suite.hts.RegisterResponse(PathMatcher("/once"), &PredefinedServerResponse{
+		Status:      http.StatusAccepted,
+		ConsumeOnce: true,
+	})
+	for _, expected := range []int{202, 404} {
+		resp, err := client.Get(suite.hts.GetBaseURL() + "/once")
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expected, resp.StatusCode)
+	}
+}
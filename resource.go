@@ -0,0 +1,294 @@
+package gosette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An in-memory REST resource served by the test server under a path prefix (ex: /users). Items are
+// JSON objects identified by a string id stored in their id field:
+//
+//   - POST {prefix} creates an item from the JSON body with a generated id and replies 201 with
+//     the item and a Location header.
+//   - GET {prefix} lists the items in their creation order.
+//   - GET {prefix}/{id} fetches an item.
+//   - PUT {prefix}/{id} replaces an item.
+//   - PATCH {prefix}/{id} merges the JSON body into an item (JSON merge patch, RFC 7386: nested
+//     objects are merged recursively and null removes a field).
+//   - DELETE {prefix}/{id} removes an item and replies 204.
+//
+// Unknown items are answered with a 404 response, invalid JSON bodies with a 400 response and
+// unsupported methods with a 405 response. The data store can be inspected and modified by tests.
+type Resource struct {
+	// Path prefix the resource is served under, without trailing slash.
+	prefix string
+	// Mutex used to protect items from concurrent access.
+	mu sync.Mutex
+	// Items by id.
+	items map[string]map[string]interface{}
+	// Ids of the items in their creation order.
+	ids []string
+	// Last generated id.
+	lastID int
+	// The test server the resource is served by.
+	hts *HTTPTestServer
+}
+
+// Serve an in-memory REST resource under the provided path prefix (ex: /users). Requests under
+// the prefix are served by the resource before predefined responses are consulted. The resource
+// is registered as a middleware: it is removed by ClearMiddlewares.
+func (hts *HTTPTestServer) Resource(prefix string) *Resource {
+	resource := &Resource{
+		prefix: "/" + strings.Trim(prefix, "/"),
+		items:  map[string]map[string]interface{}{},
+		ids:    []string{},
+		hts:    hts,
+	}
+	hts.Use(resource.middleware)
+	return resource
+}
+
+// Get the items of the resource in their creation order. Returned items are copies.
+func (res *Resource) Items() []map[string]interface{} {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	items := make([]map[string]interface{}, 0, len(res.ids))
+	for _, id := range res.ids {
+		items = append(items, copyItem(res.items[id]))
+	}
+	return items
+}
+
+// Get a copy of the item with the provided id. Returns false if the item does not exist.
+func (res *Resource) Get(id string) (map[string]interface{}, bool) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	item, ok := res.items[id]
+	if !ok {
+		return nil, false
+	}
+	return copyItem(item), true
+}
+
+// Create or replace the item with the provided id. The id field of the item is set with the id.
+func (res *Resource) Put(id string, item map[string]interface{}) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.put(id, copyItem(item))
+}
+
+// Remove the item with the provided id. Returns false if the item does not exist.
+func (res *Resource) Delete(id string) bool {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	return res.delete(id)
+}
+
+// Get the number of items of the resource.
+func (res *Resource) Len() int {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	return len(res.ids)
+}
+
+// Remove all items of the resource.
+func (res *Resource) Clear() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.items = map[string]map[string]interface{}{}
+	res.ids = []string{}
+}
+
+// Middleware which serves the requests under the resource prefix and passes other requests to
+// the next handler.
+func (res *Resource) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Split the path in the resource prefix and an optional id
+		var id string
+		switch {
+		case r.URL.Path == res.prefix || r.URL.Path == res.prefix+"/":
+		case strings.HasPrefix(r.URL.Path, res.prefix+"/") && !strings.Contains(r.URL.Path[len(res.prefix)+1:], "/"):
+			id = r.URL.Path[len(res.prefix)+1:]
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		if id == "" {
+			res.serveCollection(w, r)
+		} else {
+			res.serveItem(w, r, id)
+		}
+	})
+}
+
+// Helper method which serves a request which targets the collection. The caller must hold the
+// lock.
+func (res *Resource) serveCollection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items := make([]map[string]interface{}, 0, len(res.ids))
+		for _, id := range res.ids {
+			items = append(items, res.items[id])
+		}
+		writeJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		item, ok := readItem(w, r)
+		if !ok {
+			return
+		}
+		id := res.nextID()
+		res.put(id, item)
+		w.Header().Set("Location", res.location(id))
+		writeJSON(w, http.StatusCreated, item)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Helper method which serves a request which targets the item with the provided id. The caller
+// must hold the lock.
+func (res *Resource) serveItem(w http.ResponseWriter, r *http.Request, id string) {
+	item, found := res.items[id]
+	switch r.Method {
+	case http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, item)
+	case http.MethodPut:
+		replacement, ok := readItem(w, r)
+		if !ok {
+			return
+		}
+		res.put(id, replacement)
+		writeJSON(w, http.StatusOK, replacement)
+	case http.MethodPatch:
+		patch, ok := readItem(w, r)
+		if !ok {
+			return
+		}
+		mergePatch(item, patch)
+		item["id"] = id
+		writeJSON(w, http.StatusOK, item)
+	case http.MethodDelete:
+		res.delete(id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Helper method which returns the path of the item with the provided id, prefixed with the scope
+// path when the resource is served by a scope.
+func (res *Resource) location(id string) string {
+	location := res.prefix + "/" + id
+	if u, err := url.Parse(res.hts.GetBaseURL()); err == nil {
+		location = u.Path + location
+	}
+	return location
+}
+
+// Helper method which returns an id which is not used by any item. The caller must hold the lock.
+func (res *Resource) nextID() string {
+	for {
+		res.lastID++
+		id := strconv.Itoa(res.lastID)
+		if _, ok := res.items[id]; !ok {
+			return id
+		}
+	}
+}
+
+// Helper method which creates or replaces the item with the provided id. The caller must hold
+// the lock.
+func (res *Resource) put(id string, item map[string]interface{}) {
+	if _, ok := res.items[id]; !ok {
+		res.ids = append(res.ids, id)
+	}
+	item["id"] = id
+	res.items[id] = item
+}
+
+// Helper method which removes the item with the provided id. The caller must hold the lock.
+func (res *Resource) delete(id string) bool {
+	if _, ok := res.items[id]; !ok {
+		return false
+	}
+	delete(res.items, id)
+	for i, existing := range res.ids {
+		if existing == id {
+			res.ids = append(res.ids[:i], res.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Helper function which decodes the JSON object in the request body. A 400 response is written
+// and false is returned if the body is not a JSON object.
+func readItem(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	item := map[string]interface{}{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &item)
+	}
+	if err != nil || item == nil {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("gosette: request body must be a JSON object"))
+		return nil, false
+	}
+	return item, true
+}
+
+// Helper function which applies the provided JSON merge patch to the provided target (RFC 7386)
+// and returns the result. Objects are merged recursively, null values remove fields and other
+// values replace the target. Target objects are modified in place.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for field, value := range patchObject {
+		if value == nil {
+			delete(targetObject, field)
+		} else {
+			targetObject[field] = mergePatch(targetObject[field], value)
+		}
+	}
+	return targetObject
+}
+
+// Helper function which writes a response with the provided status and value encoded in JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Helper function which returns a deep copy of the provided item.
+func copyItem(item map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(item)
+	copied := map[string]interface{}{}
+	json.Unmarshal(data, &copied)
+	return copied
+}
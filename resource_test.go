@@ -0,0 +1,89 @@
+package gosette
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the CRUD operations of an in-memory resource and the inspection of its data store.
+func TestResource(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	users := hts.Resource("/users/")
+	users.Put("42", map[string]interface{}{"name": "seed"})
+	client := hts.Client()
+	do := func(method string, path string, body string) (int, http.Header, interface{}) {
+		req, err := http.NewRequest(method, hts.GetBaseURL()+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var v interface{}
+		json.Unmarshal(data, &v)
+		return resp.StatusCode, resp.Header, v
+	}
+	// Create
+	status, header, v := do(http.MethodPost, "/users", `{"name":"alice","age":30}`)
+	require.Equal(t, http.StatusCreated, status)
+	require.Equal(t, "/users/1", header.Get("Location"))
+	require.Equal(t, map[string]interface{}{"id": "1", "name": "alice", "age": float64(30)}, v)
+	status, _, _ = do(http.MethodPost, "/users", `[1]`)
+	require.Equal(t, http.StatusBadRequest, status)
+	// List and fetch
+	status, _, v = do(http.MethodGet, "/users", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, v, 2)
+	status, _, v = do(http.MethodGet, "/users/1", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "alice", v.(map[string]interface{})["name"])
+	// Update
+	status, _, _ = do(http.MethodPut, "/users/42", `{"name":"bob"}`)
+	require.Equal(t, http.StatusOK, status)
+	status, _, v = do(http.MethodPatch, "/users/1", `{"age":null,"city":"Paris"}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]interface{}{"id": "1", "name": "alice", "city": "Paris"}, v)
+	status, _, _ = do(http.MethodPatch, "/users/1", `{"address":{"city":"Lyon","zip":"69001"},"tags":["a","b"]}`)
+	require.Equal(t, http.StatusOK, status)
+	status, _, v = do(http.MethodPatch, "/users/1", `{"address":{"zip":null,"street":{"name":"Rue"}},"tags":["c"],"city":{"name":null}}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]interface{}{
+		"id":      "1",
+		"name":    "alice",
+		"city":    map[string]interface{}{},
+		"address": map[string]interface{}{"city": "Lyon", "street": map[string]interface{}{"name": "Rue"}},
+		"tags":    []interface{}{"c"},
+	}, v)
+	item, ok := users.Get("42")
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{"id": "42", "name": "bob"}, item)
+	// Delete
+	status, _, _ = do(http.MethodDelete, "/users/42", "")
+	require.Equal(t, http.StatusNoContent, status)
+	status, _, _ = do(http.MethodGet, "/users/42", "")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, 1, users.Len())
+	require.False(t, users.Delete("42"))
+	require.True(t, users.Delete("1"))
+	// Unsupported methods and other paths
+	status, header, _ = do(http.MethodDelete, "/users", "")
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+	require.Equal(t, "GET, POST", header.Get("Allow"))
+	status, _, _ = do(http.MethodPost, "/users/1", "{}")
+	require.Equal(t, http.StatusMethodNotAllowed, status)
+	status, _, _ = do(http.MethodGet, "/users/1/roles", "")
+	require.Equal(t, http.StatusNotFound, status)
+	require.Len(t, hts.FindRecords(ByPath("/users")), 4)
+	// Clear the data store
+	users.Put("7", map[string]interface{}{})
+	require.Len(t, users.Items(), 1)
+	users.Clear()
+	require.Empty(t, users.Items())
+}
@@ -0,0 +1,95 @@
+package gosette
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Option which limits the response body kept in records: once a response body exceeds the
+// provided threshold (in bytes), the record only keeps its first prefix bytes in Response.Body
+// and has ResponseBodyTruncated set. Status code, headers and trailers are always recorded. The
+// size of the response body is available in ResponseBodySize and its SHA-256 digest with
+// ResponseBodySHA256 so large downloads can still be verified.
+//
+// Responses are sent as is to the client. Scopes use the limit of the test server they have been
+// created from.
+func WithResponseRecordingLimit(threshold int64, prefix int) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.responseRecordingThreshold = threshold
+		hts.responseRecordingPrefix = prefix
+	}
+}
+
+// Return the hex encoded SHA-256 digest of the whole response body sent by the test server, even
+// if the recorded body has been truncated (see WithResponseRecordingLimit).
+func (record *ServerRecord) ResponseBodySHA256() string {
+	if record.responseBodyHash != nil {
+		return hex.EncodeToString(record.responseBodyHash.Sum(nil))
+	}
+	sum := sha256.Sum256(record.Response.Body.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// Helper method which returns the number of response body bytes sent by the test server.
+func (record *ServerRecord) responseBodySize() int64 {
+	if record.responseBodyHash != nil {
+		return record.ResponseBodySize
+	}
+	return int64(record.Response.Body.Len())
+}
+
+// An http.ResponseWriter which records a response in a httptest.ResponseRecorder and truncates
+// the recorded body once it exceeds a threshold.
+type limitedRecorder struct {
+	*httptest.ResponseRecorder
+	// The record of the response.
+	record *ServerRecord
+	// Size above which the recorded body is truncated.
+	threshold int64
+	// Number of bytes kept in the recorded body once truncated.
+	prefix int
+}
+
+// Helper method which returns the http.ResponseWriter used to record the response of the
+// provided record: a limitedRecorder if the recorded response body is limited, the record
+// Response otherwise.
+func (srv *HTTPTestServer) responseRecorder(record *ServerRecord) http.ResponseWriter {
+	if srv.responseRecordingThreshold <= 0 {
+		return record.Response
+	}
+	record.responseBodyHash = sha256.New()
+	return &limitedRecorder{
+		ResponseRecorder: record.Response,
+		record:           record,
+		threshold:        srv.responseRecordingThreshold,
+		prefix:           srv.responseRecordingPrefix,
+	}
+}
+
+func (lr *limitedRecorder) Write(p []byte) (int, error) {
+	lr.record.ResponseBodySize += int64(len(p))
+	lr.record.responseBodyHash.Write(p)
+	// Truncate the recorded body once it exceeds the threshold
+	body := lr.ResponseRecorder.Body
+	if !lr.record.ResponseBodyTruncated && int64(body.Len()+len(p)) > lr.threshold {
+		lr.record.ResponseBodyTruncated = true
+		if body.Len() > lr.prefix {
+			body.Truncate(lr.prefix)
+		}
+	}
+	if !lr.record.ResponseBodyTruncated {
+		return lr.ResponseRecorder.Write(p)
+	}
+	// Only keep the bytes which fit in the prefix
+	keep := lr.prefix - body.Len()
+	if keep < 0 {
+		keep = 0
+	}
+	if keep > len(p) {
+		keep = len(p)
+	}
+	lr.ResponseRecorder.Write(p[:keep])
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package gosette
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the recorded response bodies larger than the threshold are truncated. Test will ensure:
+//   - Small response bodies are fully recorded
+//   - Large response bodies, written at once or in chunks, are sent as is and only their prefix is
+//     recorded with their size and digest
+//   - Metrics count the bytes sent
+func TestResponseRecordingLimit(t *testing.T) {
+	hts := NewHTTPTestServer(nil, WithResponseRecordingLimit(100, 10))
+	hts.Start()
+	defer hts.Close()
+	large := strings.Repeat("0123456789", 100)
+	hts.When().Get("/small").RespondWith().StringBody("small")
+	hts.When().Get("/large").RespondWith().StringBody(large)
+	hts.When().Get("/chunks").RespondWith().StringChunks(large[:60], large[60:120], large[120:])
+	digest := sha256.Sum256([]byte(large))
+	client := hts.Client()
+
+	// Small response body
+	resp, err := client.Get(hts.GetBaseURL() + "/small")
+	require.NoError(t, err)
+	resp.Body.Close()
+	record := hts.PopServerRecord()
+	require.False(t, record.ResponseBodyTruncated)
+	require.Equal(t, "small", record.Response.Body.String())
+	require.Equal(t, int64(5), record.ResponseBodySize)
+	smallDigest := sha256.Sum256([]byte("small"))
+	require.Equal(t, hex.EncodeToString(smallDigest[:]), record.ResponseBodySHA256())
+
+	// Large response bodies
+	for _, path := range []string{"/large", "/chunks"} {
+		resp, err = client.Get(hts.GetBaseURL() + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, large, string(body))
+		record = hts.PopServerRecord()
+		require.True(t, record.ResponseBodyTruncated)
+		require.Equal(t, http.StatusOK, record.Response.Code)
+		require.Equal(t, "0123456789", record.Response.Body.String())
+		require.Equal(t, int64(len(large)), record.ResponseBodySize)
+		require.Equal(t, hex.EncodeToString(digest[:]), record.ResponseBodySHA256())
+	}
+	require.Equal(t, int64(5+2*len(large)), hts.Metrics().Total.BytesOut)
+
+	// Digest of records whose response body is not limited
+	recorder := httptest.NewRecorder()
+	recorder.WriteString("small")
+	record = &ServerRecord{Response: recorder}
+	require.Equal(t, hex.EncodeToString(smallDigest[:]), record.ResponseBodySHA256())
+}
@@ -0,0 +1,73 @@
+package gosette
+
+// State every scenario is in until a transition occurs or its state is set.
+const ScenarioStarted = "Started"
+
+// Register a predefined response which belongs to a scenario: a named state machine shared by
+// several predefined responses. The response is served for requests matched by the provided
+// matcher only while the scenario is in the required state. Once the response has been served,
+// the scenario moves to the new state, unless the new state is empty.
+//
+// Scenarios are in the ScenarioStarted state until a transition occurs. Responses which belong
+// to a scenario follow the same rules as responses registered with RegisterResponse: they are
+// consulted in their registration order and are served indefinitly unless their Repeat is set.
+//
+// Example of a create, poll pending, poll done flow:
+//
+//	hts.RegisterScenarioResponse("order", ScenarioStarted, "pending", MethodMatcher("POST"), created)
+//	hts.RegisterScenarioResponse("order", "pending", "done", MethodMatcher("GET"), pending)
+//	hts.RegisterScenarioResponse("order", "done", "", MethodMatcher("GET"), done)
+func (hts *HTTPTestServer) RegisterScenarioResponse(scenario string, requiredState string, newState string, matcher RequestMatcher, resp *PredefinedServerResponse) {
+	hts.registerStub(&stub{
+		matcher:       matcher,
+		response:      resp,
+		scenario:      scenario,
+		requiredState: requiredState,
+		newState:      newState,
+	})
+}
+
+// Get the current state of the scenario with the provided name.
+func (hts *HTTPTestServer) ScenarioState(scenario string) string {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	return hts.scenarioState(scenario)
+}
+
+// Set the current state of the scenario with the provided name.
+func (hts *HTTPTestServer) SetScenarioState(scenario string, state string) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.scenarios[scenario] = state
+}
+
+// Move all scenarios back to the ScenarioStarted state. Scenarios are also reset by
+// ClearPredefinedServerResponses and Clear.
+func (hts *HTTPTestServer) ResetScenarios() {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.scenarios = map[string]string{}
+}
+
+// Helper method which returns the current state of the scenario with the provided name. The
+// caller must hold the lock.
+func (srv *HTTPTestServer) scenarioState(scenario string) string {
+	if state, ok := srv.scenarios[scenario]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// Helper method which returns true if the provided stub does not belong to a scenario or if its
+// scenario is in the required state. The caller must hold the lock.
+func (srv *HTTPTestServer) inScenarioState(s *stub) bool {
+	return s.scenario == "" || srv.scenarioState(s.scenario) == s.requiredState
+}
+
+// Helper method which moves the scenario of the provided stub to its new state once the stub has
+// been selected. The caller must hold the lock.
+func (srv *HTTPTestServer) applyScenarioTransition(s *stub) {
+	if s.scenario != "" && s.newState != "" {
+		srv.scenarios[s.scenario] = s.newState
+	}
+}
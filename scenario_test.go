@@ -0,0 +1,62 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test a create, poll pending, poll done flow modeled with a scenario and the builder.
+func (suite *HTTPTestServerUnitTestSuite) TestScenarioBuilder() {
+	suite.hts.When().InScenario("order", ScenarioStarted).Post("/orders").
+		RespondWith().Status(http.StatusCreated).WillSetStateTo("pending")
+	suite.hts.When().InScenario("order", "pending").Get("/orders/1").
+		RespondWith().StringBody("pending").WillSetStateTo("done")
+	suite.hts.When().InScenario("order", "done").Get("/orders/1").
+		RespondWith().StringBody("done")
+	client := suite.hts.Client()
+	// Polling before the order is created is not matched
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/orders/1")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	// Create the order
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/orders", "application/json", strings.NewReader("{}"))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+	require.Equal(suite.T(), "pending", suite.hts.ScenarioState("order"))
+	// Poll the order
+	for _, expected := range []string{"pending", "done", "done"} {
+		resp, err = client.Get(suite.hts.GetBaseURL() + "/orders/1")
+		require.NoError(suite.T(), err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		require.Equal(suite.T(), expected, string(body))
+	}
+	// Clear resets scenarios
+	suite.hts.Clear()
+	require.Equal(suite.T(), ScenarioStarted, suite.hts.ScenarioState("order"))
+}
+
+// Test scenario responses registered directly and scenario state management.
+func (suite *HTTPTestServerUnitTestSuite) TestRegisterScenarioResponse() {
+	suite.hts.RegisterScenarioResponse("auth", "locked", "", PathMatcher("/login"), &PredefinedServerResponse{Status: http.StatusLocked})
+	suite.hts.RegisterResponse(PathMatcher("/login"), &PredefinedServerResponse{Status: http.StatusOK})
+	client := suite.hts.Client()
+	get := func() int {
+		resp, err := client.Get(suite.hts.GetBaseURL() + "/login")
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Equal(suite.T(), http.StatusOK, get())
+	suite.hts.SetScenarioState("auth", "locked")
+	require.Equal(suite.T(), http.StatusLocked, get())
+	require.Equal(suite.T(), "locked", suite.hts.ScenarioState("auth"))
+	suite.hts.ResetScenarios()
+	require.Equal(suite.T(), http.StatusOK, get())
+}
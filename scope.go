@@ -0,0 +1,193 @@
+package gosette
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+)
+
+// Path prefix of the URLs of scopes: requests sent to {base URL}/_gosette/scopes/{id}/... are
+// served by the scope with the provided id.
+const ScopePathPrefix = "/_gosette/scopes/"
+
+// A logical test server with its own predefined responses, records, middlewares and hooks which
+// shares the listener of another test server. Scopes allow an expensive test server (ex: with TLS)
+// to be started once per suite while tests (or subtests) remain independent and parallelizable:
+// each test uses its own scope.
+//
+// Requests are routed to the scope when they target the scope base URL (see GetBaseURL): the
+// scope prefix is removed from the request path so the scope sees and records the same paths as
+// a dedicated test server. Requests routed to a scope are not seen by the shared test server.
+//
+// The HTTPTestServer API is available on a scope, and its methods use the scope base URL and
+// client (ex: the OIDC provider issuer, the Location headers of resources). The following methods
+// are not supported on a scope:
+//   - the lifecycle methods (Start, StartTLS, StartMTLS, Stop, Restart) which must be called on the
+//     shared test server
+//   - the forward proxy mode (EnableForwardProxy returns an error): proxied requests do not carry
+//     the scope path
+//
+// Close releases the scope.
+type Scope struct {
+	*HTTPTestServer
+	// The shared test server.
+	parent *HTTPTestServer
+	// Id of the scope.
+	id string
+}
+
+// Create a new scope which shares the listener of the test server. Close the scope once the test
+// is done to release it.
+func (hts *HTTPTestServer) Scope() *Scope {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.lastScopeID++
+	// The scope has no listener of its own: requests are routed to it by the shared test server
+	scope := &Scope{
+		HTTPTestServer: NewHTTPTestServer(&httptest.Server{Config: &http.Server{}}),
+		parent:         hts,
+		id:             strconv.Itoa(hts.lastScopeID),
+	}
+	scope.HTTPTestServer.scope = scope
+	scope.logFunc = hts.logFunc
+	scope.maxRequestBodySize = hts.maxRequestBodySize
+	scope.strictT = hts.strictT
+	scope.maxRecords = hts.maxRecords
+	scope.evictionPolicy = hts.evictionPolicy
+	scope.spoolThreshold = hts.spoolThreshold
+	scope.spoolDir = hts.spoolDir
+	scope.responseRecordingThreshold = hts.responseRecordingThreshold
+	scope.responseRecordingPrefix = hts.responseRecordingPrefix
+	scope.notFoundDiagnostics = hts.notFoundDiagnostics
+	scope.randSeed = hts.randSeed
+	scope.rnd = hts.rnd
+	scope.clock = hts.clock
+	scope.caCertificate = hts.caCertificate
+	if hts.scopes == nil {
+		hts.scopes = map[string]*Scope{}
+	}
+	hts.scopes[scope.id] = scope
+	return scope
+}
+
+// Partition the requests by the provided header (ex: X-Test-ID): requests whose header value is
+// the id of a scope are routed to that scope, whatever their URL is. This allows parallel tests
+// to share the test server base URL without cross-talk. Use the scope Client or Transport to add
+// the header to requests automatically.
+//
+// Partitioning is disabled when the header is empty. Requests which target a scope base URL are
+// routed to the scope in both cases.
+func (hts *HTTPTestServer) PartitionByHeader(header string) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.scopeHeader = header
+}
+
+// Get the id of the scope.
+func (scope *Scope) ID() string {
+	return scope.id
+}
+
+// Return the scope base URL of form http://ipaddr:port/_gosette/scopes/{id} with no trailing
+// slash. Requests sent to this URL are served by the scope.
+func (scope *Scope) GetBaseURL() string {
+	return scope.parent.GetBaseURL() + ScopePathPrefix + scope.id
+}
+
+// Get a http.Client which trusts the shared test server certificate if TLS is enabled. In case
+// requests are partitioned by a header (see PartitionByHeader), the client adds the header with
+// the scope id to each request.
+func (scope *Scope) Client() *http.Client {
+	client := *scope.parent.Client()
+	client.Transport = scope.Transport(client.Transport)
+	return &client
+}
+
+// Wrap the provided http.RoundTripper (http.DefaultTransport if nil) in a http.RoundTripper which
+// adds the partition header with the scope id to each request (see PartitionByHeader). Requests
+// are left untouched when requests are not partitioned by a header.
+func (scope *Scope) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &scopeTransport{scope: scope, base: base}
+}
+
+// Get the underlying httptest.Server of the shared test server.
+func (scope *Scope) GetUnderlyingHTTPTestServer() *httptest.Server {
+	return scope.parent.GetUnderlyingHTTPTestServer()
+}
+
+// Release the scope: requests sent to the scope base URL are not routed to the scope anymore and
+// handlers of the scope which are hanging (see FaultHang) are released.
+func (scope *Scope) Close() {
+	scope.parent.mu.Lock()
+	delete(scope.parent.scopes, scope.id)
+	scope.parent.mu.Unlock()
+	scope.HTTPTestServer.Close()
+}
+
+// A http.RoundTripper which adds the partition header with the scope id to each request.
+type scopeTransport struct {
+	// The scope requests are sent to.
+	scope *Scope
+	// The wrapped http.RoundTripper.
+	base http.RoundTripper
+}
+
+// Add the partition header to a copy of the request and send it with the wrapped RoundTripper.
+func (st *scopeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	st.scope.parent.mu.Lock()
+	header := st.scope.parent.scopeHeader
+	st.scope.parent.mu.Unlock()
+	if header == "" {
+		return st.base.RoundTrip(r)
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set(header, st.scope.id)
+	return st.base.RoundTrip(r)
+}
+
+// Close the idle connections of the wrapped RoundTripper if it supports it.
+func (st *scopeTransport) CloseIdleConnections() {
+	if closer, ok := st.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Helper method which returns the scope the provided request must be routed to along with the
+// request to provide the scope with. Returns nil if the request is not routed to a scope.
+func (srv *HTTPTestServer) routeToScope(r *http.Request) (*Scope, *http.Request) {
+	// Route by header if requests are partitioned by a header
+	srv.mu.Lock()
+	var scope *Scope
+	if srv.scopeHeader != "" {
+		scope = srv.scopes[r.Header.Get(srv.scopeHeader)]
+	}
+	srv.mu.Unlock()
+	if scope != nil {
+		return scope, r
+	}
+	// Route by path otherwise
+	if !strings.HasPrefix(r.URL.Path, ScopePathPrefix) {
+		return nil, nil
+	}
+	id := strings.TrimPrefix(r.URL.Path, ScopePathPrefix)
+	path := "/"
+	if i := strings.Index(id, "/"); i >= 0 {
+		id, path = id[:i], id[i:]
+	}
+	srv.mu.Lock()
+	scope = srv.scopes[id]
+	srv.mu.Unlock()
+	if scope == nil {
+		return nil, nil
+	}
+	// Remove the scope prefix from the request path
+	scoped := r.Clone(r.Context())
+	scoped.URL.Path = path
+	scoped.URL.RawPath = ""
+	scoped.RequestURI = scoped.URL.RequestURI()
+	return scope, scoped
+}
@@ -0,0 +1,149 @@
+package gosette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test parallel subtests use their own scope on a shared TLS test server without cross-talk.
+func TestScope(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.StartTLS()
+	defer hts.Close()
+	hts.When().Get("/users").RespondWith().StringBody("shared")
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			i := i
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				scope := hts.Scope()
+				defer scope.Close()
+				scope.When().Get("/users").RespondWith().StringBody(fmt.Sprint(i))
+				for j := 0; j < 3; j++ {
+					resp, err := scope.Client().Get(scope.GetBaseURL() + "/users?page=1")
+					require.NoError(t, err)
+					body, err := io.ReadAll(resp.Body)
+					require.NoError(t, err)
+					resp.Body.Close()
+					require.Equal(t, fmt.Sprint(i), string(body))
+				}
+				records := scope.FindRecords(ByPath("/users"))
+				require.Len(t, records, 3)
+				require.Equal(t, "/users?page=1", records[0].Request.RequestURI)
+			})
+		}
+	})
+	// The shared test server has not seen scoped requests
+	require.Empty(t, hts.FindRecords())
+	resp, err := hts.Client().Get(hts.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "shared", string(body))
+}
+
+// Test requests sent to the root of a scope and to released or unknown scopes.
+func TestScopeRouting(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	scope := hts.Scope()
+	require.Equal(t, hts.GetUnderlyingHTTPTestServer(), scope.GetUnderlyingHTTPTestServer())
+	scope.When().Get("/").RespondWith().Status(http.StatusNoContent)
+	resp, err := scope.Client().Get(scope.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "/", scope.PopServerRecord().Request.URL.Path)
+	// Released and unknown scopes are served by the shared test server
+	scope.Close()
+	for _, url := range []string{scope.GetBaseURL(), hts.GetBaseURL() + ScopePathPrefix + "unknown/x"} {
+		resp, err = hts.Client().Get(url)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+	require.Len(t, hts.FindRecords(), 2)
+	require.Equal(t, "2", hts.Scope().ID())
+}
+
+// Test methods of the HTTPTestServer API which depend on the base URL use the scope base URL and
+// scopes do not bind a listener of their own.
+func TestScopeBaseURL(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	scope := hts.Scope()
+	defer scope.Close()
+	require.Nil(t, scope.HTTPTestServer.server.Listener)
+	require.Equal(t, hts.GetBaseURL()+ScopePathPrefix+"1", scope.HTTPTestServer.GetBaseURL())
+	require.Equal(t, hts.GetUnderlyingHTTPTestServer(), scope.HTTPTestServer.GetUnderlyingHTTPTestServer())
+
+	// OIDC provider
+	provider, err := scope.EnableOIDCProvider()
+	require.NoError(t, err)
+	require.Equal(t, scope.GetBaseURL(), provider.Issuer())
+
+	// Resource
+	scope.Resource("/users")
+	resp, err := scope.HTTPTestServer.Client().Post(scope.GetBaseURL()+"/users", "application/json", strings.NewReader(`{"name":"alice"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, ScopePathPrefix+"1/users/1", resp.Header.Get("Location"))
+	resp, err = scope.Client().Get(hts.GetBaseURL() + resp.Header.Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Forward proxy
+	require.Error(t, scope.EnableForwardProxy())
+}
+
+// Test requests are partitioned by header and routed to scopes whatever their URL is.
+func TestPartitionByHeader(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.StartTLS()
+	defer hts.Close()
+	hts.PartitionByHeader("X-Test-ID")
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			i := i
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				scope := hts.Scope()
+				defer scope.Close()
+				scope.When().Get("/users").RespondWith().StringBody(fmt.Sprint(i))
+				// The client under test uses the shared base URL and the scope transport
+				client := &http.Client{Transport: scope.Transport(hts.Client().Transport)}
+				resp, err := client.Get(hts.GetBaseURL() + "/users")
+				require.NoError(t, err)
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, fmt.Sprint(i), string(body))
+				record := scope.PopServerRecord()
+				require.Equal(t, scope.ID(), record.Request.Header.Get("X-Test-ID"))
+			})
+		}
+	})
+	require.Empty(t, hts.FindRecords())
+	// Requests are not partitioned once partitioning is disabled
+	hts.PartitionByHeader("")
+	scope := hts.Scope()
+	defer scope.Close()
+	client := scope.Client()
+	defer client.CloseIdleConnections()
+	resp, err := client.Get(hts.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Len(t, hts.FindRecords(), 1)
+	require.Empty(t, hts.PopServerRecord().Request.Header.Get("X-Test-ID"))
+	require.NotNil(t, scope.Transport(nil))
+}
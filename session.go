@@ -0,0 +1,171 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A scripted conversation: an ordered list of expected requests, each of them answered with its
+// own predefined response.
+//
+// While a session is not over, each incoming request must match the next step of the session:
+// the step response is served and the session moves to the next step. Requests which do not match
+// the next step (out of order or unexpected requests) are answered with the session failure
+// status (500 by default) and a text/plain body which explains the mismatch. The session stays on
+// the same step and the mismatch is recorded (see Err).
+//
+// Sessions are consulted in their creation order before any other predefined response. Once all
+// its steps have been served, a session no longer intercepts requests.
+//
+// Example:
+//
+//	session := hts.Session("checkout")
+//	session.Expect().Post("/carts").RespondWith().Status(http.StatusCreated)
+//	session.Expect().Put("/carts/1/items").RespondWith().Status(http.StatusOK)
+//	session.Expect().Post("/carts/1/checkout").RespondWith().Status(http.StatusAccepted)
+//	// ... exercise the client ...
+//	require.NoError(t, session.Verify())
+type Session struct {
+	// The test server the session has been registered to.
+	hts *HTTPTestServer
+	// Name of the session.
+	name string
+	// Steps of the session, in order.
+	steps []*stub
+	// Index of the next step to serve.
+	next int
+	// Status code of the responses served to requests which do not match the next step.
+	failureStatus int
+	// Mismatches which have occured, in order.
+	errs []error
+}
+
+// Create a new session with the provided name and register it to the test server. Use Expect or
+// ExpectRequest to add steps to the session.
+func (hts *HTTPTestServer) Session(name string) *Session {
+	session := &Session{
+		hts:           hts,
+		name:          name,
+		steps:         []*stub{},
+		failureStatus: http.StatusInternalServerError,
+		errs:          []error{},
+	}
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.sessions = append(hts.sessions, session)
+	return session
+}
+
+// Start describing the request expected by the next step of the session. Use RespondWith to add
+// the step to the session and describe its response.
+func (s *Session) Expect() *RequestMatcherBuilder {
+	return &RequestMatcherBuilder{
+		hts:      s.hts,
+		matchers: []RequestMatcher{},
+		session:  s,
+	}
+}
+
+// Add a step to the session: the next request must be matched by the provided matcher and is
+// answered with the provided predefined response.
+func (s *Session) ExpectRequest(matcher RequestMatcher, resp *PredefinedServerResponse) *Session {
+	s.addStep(&stub{matcher: matcher, response: resp})
+	return s
+}
+
+// Set the status code of the responses served to requests which do not match the next step of
+// the session.
+func (s *Session) FailWith(status int) *Session {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	s.failureStatus = status
+	return s
+}
+
+// Return true if all the steps of the session have been served.
+func (s *Session) Done() bool {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	return s.next >= len(s.steps)
+}
+
+// Return the number of steps of the session which have not been served yet.
+func (s *Session) Remaining() int {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	return len(s.steps) - s.next
+}
+
+// Return the first mismatch which has occured or nil if all requests have matched their step.
+func (s *Session) Err() error {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return s.errs[0]
+}
+
+// Return an error if a mismatch has occured or if some steps of the session have not been served.
+func (s *Session) Verify() error {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	if len(s.errs) > 0 {
+		return s.errs[0]
+	}
+	if s.next < len(s.steps) {
+		return fmt.Errorf("session %q: %d of %d step(s) served, next expected step %d", s.name, s.next, len(s.steps), s.next+1)
+	}
+	return nil
+}
+
+// Move the session back to its first step and forget the mismatches which have occured.
+func (s *Session) Reset() {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	s.next = 0
+	s.errs = []error{}
+}
+
+// Helper method which adds the provided step to the session.
+func (s *Session) addStep(step *stub) {
+	s.hts.mu.Lock()
+	defer s.hts.mu.Unlock()
+	s.steps = append(s.steps, step)
+}
+
+// Helper method which returns the response to serve for the provided request if a session is not
+// over, nil otherwise. The provided body is a copy of the request body which is made available to
+// request matchers. The caller must hold the lock.
+func (srv *HTTPTestServer) sessionResponse(r *http.Request, body []byte) (*PredefinedServerResponse, string) {
+	for _, s := range srv.sessions {
+		if s.next >= len(s.steps) {
+			continue
+		}
+		step := s.steps[s.next]
+		detail := strconv.Quote(s.name) + " step " + strconv.Itoa(s.next+1)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if step.matcher.Match(r) {
+			step.served++
+			s.next++
+			recordPathParams(step.matcher, r)
+			return step.response, servedBy("session", step.response, detail)
+		}
+		// Record the mismatch and fail the request
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		results := ExplainMatch(step.matcher, r)
+		err := fmt.Errorf("session %q: unexpected request %s at step %d", s.name, describeRequest(r), s.next+1)
+		s.errs = append(s.errs, err)
+		lines := append([]string{err.Error()}, indentCriteria(results, "  ")...)
+		return &PredefinedServerResponse{
+			Status:  s.failureStatus,
+			Headers: http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			Body:    []byte(strings.Join(lines, "\n") + "\n"),
+		}, "session " + detail + " (mismatch)"
+	}
+	return nil, ""
+}
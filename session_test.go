@@ -0,0 +1,77 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test scripted sessions. Test will ensure:
+//   - Steps are served in order and are consulted before other predefined responses
+//   - Out of order requests are answered with the failure status and an explanation
+//   - Mismatches and unserved steps are reported by Verify
+//   - Sessions no longer intercept requests once over and can be reset
+func (suite *HTTPTestServerUnitTestSuite) TestSession() {
+	client := suite.hts.Client()
+	send := func(method string, path string) int {
+		req, err := http.NewRequest(method, suite.hts.GetBaseURL()+path, nil)
+		require.NoError(suite.T(), err)
+		resp, err := client.Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	suite.hts.When().Path("/carts").RespondWith().Status(http.StatusTeapot)
+	session := suite.hts.Session("checkout")
+	session.Expect().Post("/carts").RespondWith().Status(http.StatusCreated)
+	session.ExpectRequest(MethodMatcher(http.MethodPut), &PredefinedServerResponse{Status: http.StatusOK, Name: "add item"})
+	session.Expect().Post("/carts/1/checkout").RespondWith().Status(http.StatusAccepted)
+	require.False(suite.T(), session.Done())
+	require.Equal(suite.T(), 3, session.Remaining())
+
+	// First step is served before the stub
+	require.Equal(suite.T(), http.StatusCreated, send(http.MethodPost, "/carts"))
+	require.Equal(suite.T(), `session "checkout" step 1`, suite.hts.PopServerRecord().ServedBy)
+	require.Error(suite.T(), session.Verify())
+	require.NoError(suite.T(), session.Err())
+
+	// Out of order request
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/carts/1/checkout", "", nil)
+	require.NoError(suite.T(), err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusInternalServerError, resp.StatusCode)
+	require.True(suite.T(), strings.HasPrefix(string(body), `session "checkout": unexpected request POST /carts/1/checkout at step 2`))
+	require.Contains(suite.T(), string(body), "✗")
+	require.Equal(suite.T(), `session "checkout" step 2 (mismatch)`, suite.hts.PopServerRecord().ServedBy)
+	require.EqualError(suite.T(), session.Err(), `session "checkout": unexpected request POST /carts/1/checkout at step 2`)
+
+	// Session stays on the same step
+	session.FailWith(http.StatusConflict)
+	require.Equal(suite.T(), http.StatusConflict, send(http.MethodGet, "/carts"))
+	require.Equal(suite.T(), http.StatusOK, send(http.MethodPut, "/carts/1/items"))
+	require.Equal(suite.T(), http.StatusAccepted, send(http.MethodPost, "/carts/1/checkout"))
+	require.True(suite.T(), session.Done())
+	require.Equal(suite.T(), 0, session.Remaining())
+	require.Error(suite.T(), session.Verify())
+
+	// Over sessions no longer intercept requests
+	require.Equal(suite.T(), http.StatusTeapot, send(http.MethodGet, "/carts"))
+
+	// Reset session
+	session.Reset()
+	require.NoError(suite.T(), session.Err())
+	require.Equal(suite.T(), 3, session.Remaining())
+	require.Equal(suite.T(), http.StatusCreated, send(http.MethodPost, "/carts"))
+	require.Equal(suite.T(), http.StatusOK, send(http.MethodPut, "/carts/1/items"))
+	require.Equal(suite.T(), http.StatusAccepted, send(http.MethodPost, "/carts/1/checkout"))
+	require.NoError(suite.T(), session.Verify())
+
+	// Sessions are removed with predefined responses
+	session.Reset()
+	suite.hts.ClearPredefinedServerResponses()
+	require.Equal(suite.T(), http.StatusNotFound, send(http.MethodGet, "/carts"))
+}
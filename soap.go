@@ -0,0 +1,160 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Namespaces of the SOAP envelopes.
+const (
+	// Namespace of SOAP 1.1 envelopes.
+	SOAP11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	// Namespace of SOAP 1.2 envelopes.
+	SOAP12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// A parsed SOAP request envelope.
+type SOAPEnvelope struct {
+	// SOAP version of the envelope: 1.1 or 1.2.
+	Version string
+	// Action of the request: the SOAPAction header for SOAP 1.1 or the action parameter of the
+	// Content-Type header for SOAP 1.2, without quotes. Empty if the request has no action.
+	Action string
+	// Local name of the operation element: the first element of the envelope body.
+	Operation string
+	// Namespace of the operation element.
+	OperationNamespace string
+	// Local names of the header blocks of the envelope, in document order.
+	Headers []string
+}
+
+// Parse the recorded request body as a SOAP 1.1 or 1.2 envelope. Use XPath to inspect the content
+// of the envelope.
+//
+// An error is returned if the request body is not a SOAP envelope.
+func (record *ServerRecord) SOAPEnvelope() (*SOAPEnvelope, error) {
+	if record.Request == nil {
+		return nil, fmt.Errorf("record has no request")
+	}
+	return parseSOAPEnvelope(record.Request, record.RequestBody.Bytes())
+}
+
+// Build a request matcher which matches SOAP requests with the provided action (see SOAPEnvelope
+// Action). Quotes around the action are ignored.
+func SOAPActionMatcher(action string) RequestMatcher {
+	action = strings.Trim(action, `"`)
+	return newCriterionMatcher("SOAP action", fmt.Sprintf("%q", action), func(r *http.Request) string {
+		return fmt.Sprintf("%q", soapAction(r))
+	}, func(r *http.Request) bool {
+		return soapAction(r) == action
+	})
+}
+
+// Build a request matcher which matches SOAP requests whose envelope body has an operation element
+// with the provided local name, whatever its namespace.
+func SOAPOperationMatcher(operation string) RequestMatcher {
+	return newCriterionMatcher("SOAP operation", fmt.Sprintf("%q", operation), func(r *http.Request) string {
+		envelope, err := readSOAPEnvelope(r)
+		if err != nil {
+			return fmt.Sprintf("error: %s", err)
+		}
+		return fmt.Sprintf("%q", envelope.Operation)
+	}, func(r *http.Request) bool {
+		envelope, err := readSOAPEnvelope(r)
+		return err == nil && envelope.Operation == operation
+	})
+}
+
+// Helper function which returns the SOAP action of the provided request.
+func soapAction(r *http.Request) string {
+	if action := r.Header.Get("SOAPAction"); action != "" {
+		return strings.Trim(action, `"`)
+	}
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(params["action"], `"`)
+}
+
+// Helper function which reads the body of the provided request and parses it as a SOAP envelope.
+func readSOAPEnvelope(r *http.Request) (*SOAPEnvelope, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	return parseSOAPEnvelope(r, body)
+}
+
+// Helper function which parses the provided request and body as a SOAP envelope.
+func parseSOAPEnvelope(r *http.Request, body []byte) (*SOAPEnvelope, error) {
+	doc, err := parseXMLDocument(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SOAP envelope: %w", err)
+	}
+	root := doc.children[0]
+	envelope := &SOAPEnvelope{Action: soapAction(r), Headers: []string{}}
+	switch {
+	case root.name.Local != "Envelope":
+		return nil, fmt.Errorf("not a SOAP envelope: root element is %s", root.name.Local)
+	case root.name.Space == SOAP11Namespace:
+		envelope.Version = "1.1"
+	case root.name.Space == SOAP12Namespace:
+		envelope.Version = "1.2"
+	default:
+		return nil, fmt.Errorf("not a SOAP envelope: unknown namespace %s", root.name.Space)
+	}
+	hasBody := false
+	for _, child := range root.children {
+		if child.kind != xmlElementNode || child.name.Space != root.name.Space {
+			continue
+		}
+		switch child.name.Local {
+		case "Header":
+			for _, block := range child.children {
+				if block.kind == xmlElementNode {
+					envelope.Headers = append(envelope.Headers, block.name.Local)
+				}
+			}
+		case "Body":
+			hasBody = true
+			for _, operation := range child.children {
+				if operation.kind == xmlElementNode {
+					envelope.Operation = operation.name.Local
+					envelope.OperationNamespace = operation.name.Space
+					break
+				}
+			}
+		}
+	}
+	if !hasBody {
+		return nil, fmt.Errorf("not a SOAP envelope: missing body")
+	}
+	return envelope, nil
+}
+
+// Helper function which wraps the provided body content in a SOAP 1.1 envelope.
+func soapEnvelope(content string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soap:Envelope xmlns:soap="` + SOAP11Namespace + `"><soap:Body>` +
+		content +
+		`</soap:Body></soap:Envelope>`
+}
+
+// Helper function which returns the content of a SOAP 1.1 Fault with the provided code (ex:
+// Client, Server) and reason.
+func soapFault(code string, reason string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(reason))
+	return `<soap:Fault><faultcode>soap:` + code + `</faultcode><faultstring>` + escaped.String() +
+		`</faultstring></soap:Fault>`
+}
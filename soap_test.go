@@ -0,0 +1,127 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test SOAP envelopes are parsed from records.
+func (suite *HTTPTestServerUnitTestSuite) TestSOAPEnvelope() {
+	testCases := []struct {
+		name        string
+		contentType string
+		soapAction  string
+		body        string
+		version     string
+		action      string
+		operation   string
+		namespace   string
+		headers     []string
+		fails       bool
+	}{
+		{
+			name:        "SOAP 1.1",
+			contentType: "text/xml",
+			soapAction:  `"urn:GetUser"`,
+			body: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:u="urn:users">` +
+				`<soap:Header><u:Auth>token</u:Auth></soap:Header>` +
+				`<soap:Body><u:GetUser><u:id>1</u:id></u:GetUser></soap:Body></soap:Envelope>`,
+			version:   "1.1",
+			action:    "urn:GetUser",
+			operation: "GetUser",
+			namespace: "urn:users",
+			headers:   []string{"Auth"},
+		},
+		{
+			name:        "SOAP 1.2",
+			contentType: `application/soap+xml; charset=utf-8; action="urn:DeleteUser"`,
+			body: `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope">` +
+				`<env:Body> <DeleteUser xmlns="urn:users"/></env:Body></env:Envelope>`,
+			version:   "1.2",
+			action:    "urn:DeleteUser",
+			operation: "DeleteUser",
+			namespace: "urn:users",
+			headers:   []string{},
+		},
+		{name: "not XML", body: "{}", fails: true},
+		{name: "not an envelope", body: "<Envelope/>", fails: true},
+		{name: "not an envelope root", body: "<Body/>", fails: true},
+		{name: "missing body", body: `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"/>`, fails: true},
+	}
+	for _, tc := range testCases {
+		req, err := http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/soap", strings.NewReader(tc.body))
+		require.NoError(suite.T(), err)
+		req.Header.Set("Content-Type", tc.contentType)
+		if tc.soapAction != "" {
+			req.Header.Set("SOAPAction", tc.soapAction)
+		}
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		envelope, err := suite.hts.PopServerRecord().SOAPEnvelope()
+		if tc.fails {
+			require.Error(suite.T(), err, tc.name)
+			continue
+		}
+		require.NoError(suite.T(), err, tc.name)
+		require.Equal(suite.T(), &SOAPEnvelope{
+			Version:            tc.version,
+			Action:             tc.action,
+			Operation:          tc.operation,
+			OperationNamespace: tc.namespace,
+			Headers:            tc.headers,
+		}, envelope, tc.name)
+	}
+	_, err := (&ServerRecord{}).SOAPEnvelope()
+	require.Error(suite.T(), err)
+}
+
+// Test SOAP matchers and response builders.
+func (suite *HTTPTestServerUnitTestSuite) TestSOAPMatchersAndResponses() {
+	suite.hts.When().Post("/soap").SOAPAction("urn:GetUser").SOAPOperation("GetUser").
+		RespondWith().SOAPBodyTemplate(`<GetUserResponse><method>{{.Method}}</method></GetUserResponse>`)
+	suite.hts.When().Post("/soap").SOAPOperation("DeleteUser").
+		RespondWith().SOAPFault("Client", "user <1> not found")
+	suite.hts.When().Post("/soap").SOAPAction(`"urn:Ping"`).
+		RespondWith().SOAPBody(`<Pong/>`)
+	send := func(action string, operation string) (int, string, string) {
+		body := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><` +
+			operation + ` xmlns="urn:users"/></soap:Body></soap:Envelope>`
+		req, err := http.NewRequest(http.MethodPost, suite.hts.GetBaseURL()+"/soap", strings.NewReader(body))
+		require.NoError(suite.T(), err)
+		req.Header.Set("Content-Type", "text/xml")
+		req.Header.Set("SOAPAction", action)
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		content, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(content)
+	}
+
+	// Matched by action and operation, answered with a templated envelope
+	status, contentType, body := send(`"urn:GetUser"`, "GetUser")
+	require.Equal(suite.T(), http.StatusOK, status)
+	require.Equal(suite.T(), "text/xml; charset=utf-8", contentType)
+	require.Equal(suite.T(), `<?xml version="1.0" encoding="UTF-8"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">`+
+		`<soap:Body><GetUserResponse><method>POST</method></GetUserResponse></soap:Body></soap:Envelope>`, body)
+
+	// Matched by operation, answered with a fault
+	status, _, body = send("urn:Other", "DeleteUser")
+	require.Equal(suite.T(), http.StatusInternalServerError, status)
+	require.Contains(suite.T(), body, `<soap:Fault><faultcode>soap:Client</faultcode><faultstring>user &lt;1&gt; not found</faultstring></soap:Fault>`)
+
+	// Matched by action only
+	status, _, body = send("urn:Ping", "Ping")
+	require.Equal(suite.T(), http.StatusOK, status)
+	require.Contains(suite.T(), body, "<soap:Body><Pong/></soap:Body>")
+
+	// Not matched
+	status, _, _ = send("urn:GetUser", "Other")
+	require.Equal(suite.T(), http.StatusNotFound, status)
+	explanation := suite.hts.ExplainMismatch(suite.hts.FindRecords(ByStatus(http.StatusNotFound))[0])
+	require.Contains(suite.T(), explanation, `SOAP operation: expected "GetUser", got "Other"`)
+}
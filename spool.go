@@ -0,0 +1,115 @@
+package gosette
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// Option which spools the recorded copy of request bodies larger than the provided threshold (in
+// bytes) to a temporary file created in the provided directory (the default directory for
+// temporary files when empty) instead of buffering them in memory.
+//
+// The record of a spooled request has BodySpooled set and its RequestBody only holds the first
+// threshold bytes of the body: matchers, middlewares and handlers are provided with these first
+// bytes only. Use the record OpenRequestBody to read the whole body. Spooled bodies are kept as
+// received: they are neither decoded (Content-Encoding) nor parsed as multipart bodies.
+//
+// Temporary files are removed when the test server is closed. Scopes use the spooling settings
+// of the test server they have been created from.
+func WithRequestBodySpooling(threshold int64, dir string) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.spoolThreshold = threshold
+		hts.spoolDir = dir
+	}
+}
+
+// Open the whole recorded request body. The returned reader must be closed once done.
+//
+// The body is read from the temporary file it has been spooled to when the record has
+// BodySpooled set (see WithRequestBodySpooling) and from RequestBody otherwise. An error is
+// returned if the temporary file cannot be opened (ex: the test server has been closed).
+func (record *ServerRecord) OpenRequestBody() (io.ReadSeekCloser, error) {
+	if record.BodySpooled {
+		return os.Open(record.spoolPath)
+	}
+	return nopSeekCloser{bytes.NewReader(record.RequestBody.Bytes())}, nil
+}
+
+// An io.ReadSeekCloser which reads from memory. Close has no effect.
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// An io.Writer which copies the request body to the record RequestBody until the spooling
+// threshold is exceeded. The whole body is then written to a temporary file.
+type spoolWriter struct {
+	// The test server the request has been received by.
+	srv *HTTPTestServer
+	// The record of the request.
+	record *ServerRecord
+	// The temporary file the body is spooled to. Nil until the threshold is exceeded.
+	file *os.File
+}
+
+// Helper method which returns the writer used to copy the body of the provided record: a
+// spoolWriter if spooling is enabled, the record RequestBody otherwise.
+func (srv *HTTPTestServer) requestBodyWriter(record *ServerRecord) io.Writer {
+	if srv.spoolThreshold <= 0 {
+		return record.RequestBody
+	}
+	return &spoolWriter{srv: srv, record: record}
+}
+
+func (sw *spoolWriter) Write(p []byte) (int, error) {
+	threshold := sw.srv.spoolThreshold
+	if sw.file == nil {
+		// Buffer the body in memory while it is below the threshold
+		if int64(sw.record.RequestBody.Len()+len(p)) <= threshold {
+			return sw.record.RequestBody.Write(p)
+		}
+		// Move the buffered bytes to a temporary file
+		file, err := os.CreateTemp(sw.srv.spoolDir, "gosette-body-*")
+		if err != nil {
+			return 0, err
+		}
+		sw.srv.mu.Lock()
+		sw.srv.spoolFiles = append(sw.srv.spoolFiles, file.Name())
+		sw.srv.mu.Unlock()
+		sw.file = file
+		sw.record.BodySpooled = true
+		sw.record.spoolPath = file.Name()
+		if _, err := file.Write(sw.record.RequestBody.Bytes()); err != nil {
+			return 0, err
+		}
+		sw.record.SpooledBodySize = int64(sw.record.RequestBody.Len())
+		// Keep the first bytes of the body in memory
+		sw.record.RequestBody.Write(p[:threshold-int64(sw.record.RequestBody.Len())])
+	}
+	n, err := sw.file.Write(p)
+	sw.record.SpooledBodySize += int64(n)
+	return n, err
+}
+
+// Helper method which closes the temporary file the body has been spooled to if any.
+func (sw *spoolWriter) Close() error {
+	if sw.file == nil {
+		return nil
+	}
+	return sw.file.Close()
+}
+
+// Helper method which removes the temporary files request bodies have been spooled to.
+func (srv *HTTPTestServer) removeSpoolFiles() {
+	srv.mu.Lock()
+	files := srv.spoolFiles
+	srv.spoolFiles = nil
+	srv.mu.Unlock()
+	for _, file := range files {
+		os.Remove(file)
+	}
+}
@@ -0,0 +1,130 @@
+package gosette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test request bodies larger than the threshold are spooled to temporary files. Test will ensure:
+//   - Small bodies are buffered in memory and can be opened too
+//   - Large bodies, with a known or unknown length, are spooled and RequestBody keeps their first
+//     bytes which are provided to matchers
+//   - Spooled bodies are kept as received and temporary files are removed by Close
+func TestRequestBodySpooling(t *testing.T) {
+	dir := t.TempDir()
+	hts := NewHTTPTestServer(nil, WithRequestBodySpooling(10, dir))
+	hts.Start()
+	hts.When().Post("/upload").WithBodyContaining("0123456789").RespondWith().Status(http.StatusCreated)
+	client := hts.Client()
+	large := strings.Repeat("0123456789", 100)
+
+	// Small body
+	resp, err := client.Post(hts.GetBaseURL()+"/upload", "text/plain", strings.NewReader("0123456789"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	record := hts.PopServerRecord()
+	require.False(t, record.BodySpooled)
+	body, err := record.OpenRequestBody()
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "0123456789", string(content))
+
+	// Large bodies with a known and an unknown length
+	for _, reader := range []io.Reader{strings.NewReader(large), io.MultiReader(strings.NewReader(large))} {
+		resp, err = client.Post(hts.GetBaseURL()+"/upload", "text/plain", reader)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		record = hts.PopServerRecord()
+		require.True(t, record.BodySpooled)
+		require.Equal(t, int64(len(large)), record.SpooledBodySize)
+		require.Equal(t, "0123456789", record.RequestBody.String())
+		body, err = record.OpenRequestBody()
+		require.NoError(t, err)
+		content, err = io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, large, string(content))
+		_, err = body.Seek(990, io.SeekStart)
+		require.NoError(t, err)
+		content, err = io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, "0123456789", string(content))
+		require.NoError(t, body.Close())
+	}
+
+	// Spooled bodies are kept as received
+	encoded, err := encodeContent("gzip", []byte(large))
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, hts.GetBaseURL()+"/raw", bytes.NewReader(encoded))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	record = hts.PopServerRecord()
+	require.NoError(t, record.ServerError)
+	require.True(t, record.BodySpooled)
+	require.Nil(t, record.RawRequestBody)
+	body, err = record.OpenRequestBody()
+	require.NoError(t, err)
+	content, err = io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, encoded, content)
+
+	// Temporary files are removed by Close
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	hts.Close()
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	_, err = record.OpenRequestBody()
+	require.Error(t, err)
+}
+
+// An io.Reader which reads zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// Test spooled request bodies are not buffered in memory: the memory allocated to serve a large
+// upload stays far below the size of the body.
+func TestRequestBodySpoolingMemory(t *testing.T) {
+	hts := NewHTTPTestServer(nil, WithRequestBodySpooling(1024, t.TempDir()))
+	hts.Start()
+	defer hts.Close()
+	const size = 64 << 20
+	req, err := http.NewRequest(http.MethodPost, hts.GetBaseURL()+"/upload", io.LimitReader(zeroReader{}, size))
+	require.NoError(t, err)
+	req.ContentLength = size
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	resp, err := hts.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	runtime.ReadMemStats(&after)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(size/4))
+	record := hts.PopServerRecord()
+	require.True(t, record.BodySpooled)
+	require.Equal(t, int64(size), record.SpooledBodySize)
+	require.Equal(t, 1024, record.RequestBody.Len())
+}
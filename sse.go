@@ -0,0 +1,90 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A server-sent event streamed by the test server (text/event-stream).
+type ServerSentEvent struct {
+	// ID of the event (id field). Omitted when empty.
+	ID string
+	// Type of the event (event field). Omitted when empty.
+	Event string
+	// Data of the event. Each line is sent in its own data field.
+	Data string
+	// Reconnection time sent to the client (retry field). Omitted when zero.
+	Retry time.Duration
+	// Comment sent before the event fields. Omitted when empty. An event with only a comment can
+	// be used as a keep-alive.
+	Comment string
+	// Delay to wait before the event is sent. The delay is interrupted if the request context is
+	// done.
+	Delay time.Duration
+}
+
+// Helper method which returns the event encoded with the text/event-stream framing.
+func (event ServerSentEvent) encode() []byte {
+	var sb strings.Builder
+	if event.Comment != "" {
+		for _, line := range splitEventLines(event.Comment) {
+			sb.WriteString(": " + line + "\n")
+		}
+	}
+	if event.ID != "" {
+		sb.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		sb.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", event.Retry.Milliseconds()))
+	}
+	if event.Data != "" || (event.Comment == "" && event.ID == "" && event.Event == "" && event.Retry <= 0) {
+		for _, line := range splitEventLines(event.Data) {
+			sb.WriteString("data: " + line + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	return []byte(sb.String())
+}
+
+// Helper function which splits the provided text in lines (CRLF, LF and CR are line endings).
+func splitEventLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n"), "\n")
+}
+
+// Helper method which streams the events of the provided response by using the provided
+// http.ResponseWriter. Content-Type (text/event-stream) and Cache-Control (no-cache) headers are
+// set unless they are defined by the response. The response writer is flushed after each event.
+// Streaming stops if the request context is done.
+func (srv *HTTPTestServer) writeEvents(w http.ResponseWriter, r *http.Request, response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	// Write headers and status code
+	if response.Headers.Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	if response.Headers.Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	writeHeaders(w, response)
+	flush(w)
+	// Stream events
+	for i, event := range response.Events {
+		if event.Delay > 0 {
+			if sleep(r.Context(), event.Delay) != nil {
+				return
+			}
+		}
+		if _, err := w.Write(event.encode()); err != nil {
+			// Create an error which wraps the error that has occured
+			werr := fmt.Errorf("test server failed to write event %d of the predefined response: %w", i, err)
+			// Handle the error and return a 500 response
+			srv.handleInternalError(w, serverRecord, werr)
+			// Exit
+			return
+		}
+		flush(w)
+	}
+}
@@ -0,0 +1,57 @@
+package gosette
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test server-sent events streaming. Test will ensure:
+//   - Events are encoded with the text/event-stream framing
+//   - Default Content-Type and Cache-Control headers are set
+//   - Events are flushed to the client as soon as they are written
+func (suite *HTTPTestServerUnitTestSuite) TestServerSentEvents() {
+	client := suite.hts.Client()
+	suite.hts.When().Get("/events").RespondWith().Events(
+		ServerSentEvent{ID: "1", Event: "greeting", Data: "hello", Retry: 3 * time.Second},
+		ServerSentEvent{Comment: "keep-alive", Delay: 100 * time.Millisecond},
+		ServerSentEvent{ID: "2", Data: "multi\nline"},
+		ServerSentEvent{},
+	)
+
+	start := time.Now()
+	resp, err := client.Get(suite.hts.GetBaseURL() + "/events")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Equal(suite.T(), "no-cache", resp.Header.Get("Cache-Control"))
+	// Read the first event before the next ones are written
+	reader := bufio.NewReader(resp.Body)
+	first := ""
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(suite.T(), err)
+		first += line
+		if line == "\n" {
+			break
+		}
+	}
+	require.Less(suite.T(), int64(time.Since(start)), int64(100*time.Millisecond))
+	require.Equal(suite.T(), "id: 1\nevent: greeting\nretry: 3000\ndata: hello\n\n", first)
+	rest, err := io.ReadAll(reader)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), ": keep-alive\n\nid: 2\ndata: multi\ndata: line\n\ndata: \n\n", string(rest))
+
+	// Headers defined by the response are kept
+	suite.hts.When().Get("/custom").RespondWith().
+		Header("Content-Type", "text/event-stream; charset=utf-8").
+		Events(ServerSentEvent{Data: "hello"})
+	resp, err = client.Get(suite.hts.GetBaseURL() + "/custom")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), "text/event-stream; charset=utf-8", resp.Header.Get("Content-Type"))
+}
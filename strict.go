@@ -0,0 +1,60 @@
+package gosette
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interface implemented by *testing.T and *testing.B used to report unmatched requests in strict
+// mode.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Option which enables the strict mode: each request which is not served by a predefined response
+// (a request which gets the default response) is reported as a failure of the provided test with
+// an explanation of why it has not been matched by the registered stubs (see ExplainMismatch).
+// Unmatched requests still get the default response.
+//
+// The test server must be closed before the test ends as failures cannot be reported once the
+// test has completed. Unmatched requests are always flagged in their record (see ServerRecord
+// Unmatched): use Verify().NoUnmatchedRequests to report them at once instead of failing the test
+// immediately. Scopes use the strict mode of the test server they have been created from.
+func WithStrictMode(t TestingT) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.strictT = t
+	}
+}
+
+// Filter which selects the records of the requests which have not been served by a predefined
+// response (see ServerRecord Unmatched).
+func Unmatched() RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.Unmatched
+	}
+}
+
+// Verify that all recorded requests have been served by a predefined response. The error lists
+// the unmatched requests with an explanation of why they have not been matched by the registered
+// stubs.
+func (v *Verifier) NoUnmatchedRequests() error {
+	unmatched := v.hts.FindRecords(Unmatched())
+	if len(unmatched) == 0 {
+		return nil
+	}
+	explanations := make([]string, 0, len(unmatched))
+	for _, record := range unmatched {
+		explanations = append(explanations, v.hts.ExplainMismatch(record))
+	}
+	return fmt.Errorf("expected no unmatched requests, got %d\n%s", len(unmatched), strings.Join(explanations, "\n"))
+}
+
+// Helper method which reports the provided unmatched request if the strict mode is enabled.
+func (srv *HTTPTestServer) reportUnmatched(record *ServerRecord) {
+	srv.mu.Lock()
+	t := srv.strictT
+	srv.mu.Unlock()
+	if t != nil {
+		t.Errorf("gosette: unmatched request\n%s", srv.ExplainMismatch(record))
+	}
+}
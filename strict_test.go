@@ -0,0 +1,75 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A TestingT which collects reported failures.
+type recordingT struct {
+	mu       sync.Mutex
+	failures []string
+}
+
+func (rt *recordingT) Errorf(format string, args ...interface{}) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.failures = append(rt.failures, fmt.Sprintf(format, args...))
+}
+
+// Test the strict mode. Test will ensure:
+//   - Unmatched requests are reported to the test with an explanation and flagged in records
+//   - Matched requests are not reported
+//   - Unmatched requests can be verified at once
+//   - Scopes use the strict mode of the test server
+func TestStrictMode(t *testing.T) {
+	rt := &recordingT{}
+	hts := NewHTTPTestServer(nil, WithStrictMode(rt))
+	hts.Start()
+	defer hts.Close()
+	hts.When().Get("/users").RespondWith().Status(http.StatusOK)
+	client := hts.Client()
+
+	// Matched request
+	resp, err := client.Get(hts.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Empty(t, rt.failures)
+	require.NoError(t, hts.Verify().NoUnmatchedRequests())
+
+	// Unmatched request
+	resp, err = client.Get(hts.GetBaseURL() + "/user")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, []string{"gosette: unmatched request\nrequest GET /user:\nstub #1:\n  ✓ method: expected \"GET\", got \"GET\"\n  ✗ path: expected \"/users\", got \"/user\""}, rt.failures)
+	unmatched := hts.FindRecords(Unmatched())
+	require.Len(t, unmatched, 1)
+	require.Equal(t, "/user", unmatched[0].Request.URL.Path)
+	err = hts.Verify().NoUnmatchedRequests()
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "expected no unmatched requests, got 1\nrequest GET /user:\n"))
+
+	// Scopes use the strict mode of the test server
+	scope := hts.Scope()
+	defer scope.Close()
+	resp, err = scope.Client().Get(scope.GetBaseURL() + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Len(t, rt.failures, 2)
+	require.Equal(t, "gosette: unmatched request\nrequest GET /users: no stubs are registered", rt.failures[1])
+}
+
+// Test unmatched requests are flagged without being reported when the strict mode is disabled.
+func (suite *HTTPTestServerUnitTestSuite) TestUnmatchedRequests() {
+	suite.hts.When().Get("/users").RespondWith().Status(http.StatusOK)
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/orders")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.True(suite.T(), suite.hts.PopServerRecord().Unmatched)
+}
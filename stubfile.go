@@ -0,0 +1,292 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A stub definition file: a list of stubs.
+type stubFile struct {
+	Stubs []*stubDefinition `yaml:"stubs" json:"stubs"`
+}
+
+// A stub definition: a request matcher and the predefined response served for matched requests.
+type stubDefinition struct {
+	Name     string                  `yaml:"name,omitempty" json:"name,omitempty"`
+	Request  stubRequestDefinition   `yaml:"request,omitempty" json:"request,omitempty"`
+	Response stubResponseDefinition  `yaml:"response,omitempty" json:"response,omitempty"`
+	Scenario *stubScenarioDefinition `yaml:"scenario,omitempty" json:"scenario,omitempty"`
+}
+
+// The requests a stub is served for. All declared criteria must match.
+type stubRequestDefinition struct {
+	Method       string                 `yaml:"method,omitempty" json:"method,omitempty"`
+	Path         string                 `yaml:"path,omitempty" json:"path,omitempty"`
+	Host         string                 `yaml:"host,omitempty" json:"host,omitempty"`
+	Headers      map[string]string      `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Query        map[string]string      `yaml:"query,omitempty" json:"query,omitempty"`
+	ExactQuery   *string                `yaml:"exactQuery,omitempty" json:"exactQuery,omitempty"`
+	Cookies      map[string]string      `yaml:"cookies,omitempty" json:"cookies,omitempty"`
+	BodyContains string                 `yaml:"bodyContains,omitempty" json:"bodyContains,omitempty"`
+	JSONPath     map[string]interface{} `yaml:"jsonPath,omitempty" json:"jsonPath,omitempty"`
+}
+
+// The predefined response served by a stub.
+type stubResponseDefinition struct {
+	Status   int                         `yaml:"status,omitempty" json:"status,omitempty"`
+	Headers  map[string]stubHeaderValues `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body     string                      `yaml:"body,omitempty" json:"body,omitempty"`
+	JSON     interface{}                 `yaml:"json,omitempty" json:"json,omitempty"`
+	BodyFile string                      `yaml:"bodyFile,omitempty" json:"bodyFile,omitempty"`
+	Delay    string                      `yaml:"delay,omitempty" json:"delay,omitempty"`
+	Repeat   int                         `yaml:"repeat,omitempty" json:"repeat,omitempty"`
+	Fault    string                      `yaml:"fault,omitempty" json:"fault,omitempty"`
+}
+
+// The values of a response header declared in a stub definition file: a single value or a list
+// of values for headers which are sent several times (ex: Set-Cookie).
+type stubHeaderValues []string
+
+// Decode a single value or a list of values.
+func (values *stubHeaderValues) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*values = stubHeaderValues{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*values = list
+	return nil
+}
+
+// Encode a single value as a string and several values as a list.
+func (values stubHeaderValues) MarshalYAML() (interface{}, error) {
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return []string(values), nil
+}
+
+// Encode a single value as a string and several values as a list.
+func (values stubHeaderValues) MarshalJSON() ([]byte, error) {
+	if len(values) == 1 {
+		return json.Marshal(values[0])
+	}
+	return json.Marshal([]string(values))
+}
+
+// The scenario a stub belongs to.
+type stubScenarioDefinition struct {
+	Name          string `yaml:"name,omitempty" json:"name,omitempty"`
+	RequiredState string `yaml:"requiredState,omitempty" json:"requiredState,omitempty"`
+	NewState      string `yaml:"newState,omitempty" json:"newState,omitempty"`
+}
+
+// Names of the faults which can be declared in stub definition files.
+var stubFaults = map[string]Fault{
+	"":                 FaultNone,
+	"connection_reset": FaultConnectionReset,
+	"hang":             FaultHang,
+	"truncated_body":   FaultTruncatedBody,
+	"raw_response":     FaultRawResponse,
+}
+
+// Load the stub definition files (.yaml, .yml and .json) of the provided directory in their
+// lexical order and register the stubs they declare as predefined responses bound to request
+// matchers (see RegisterResponse). Subdirectories are ignored.
+//
+// A stub definition file declares a list of stubs. Each stub declares the requests it is served
+// for (all declared criteria must match) and the predefined response to serve. Example:
+//
+//	stubs:
+//	  - name: list-users          # optional, identifies the stub in records and logs
+//	    request:
+//	      method: GET
+//	      path: /users
+//	      headers: {Accept: application/json}
+//	      query: {page: "1"}
+//	      exactQuery: page=1&sort=asc # no other query parameters allowed ("" for none)
+//	      cookies: {session: abc}
+//	      bodyContains: alice
+//	      jsonPath: {"$.name": alice}
+//	    response:
+//	      status: 200
+//	      headers: {Content-Type: application/json, Set-Cookie: [a=1, b=2]}
+//	      body: '[{"id": 1}]'     # or json: {id: 1}, or bodyFile: fixtures/users.json
+//	      delay: 100ms
+//	      repeat: 2               # served indefinitly when zero
+//	      fault: connection_reset # or hang, truncated_body, raw_response (body sent as is)
+//	    scenario: {name: order, requiredState: Started, newState: pending}
+//
+// The status defaults to 200. Body files are relative to the directory: keep them in a
+// subdirectory so they are not loaded as stub definition files. In case a file cannot be
+// loaded, an error is returned and no stubs are registered.
+func (hts *HTTPTestServer) LoadStubs(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read stub directory %s: %w", dir, err)
+	}
+	stubs := []*stub{}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		loaded, err := loadStubFile(path, dir)
+		if err != nil {
+			return err
+		}
+		stubs = append(stubs, loaded...)
+	}
+	for _, s := range stubs {
+		hts.registerStub(s)
+	}
+	return nil
+}
+
+// Helper function which loads the stubs declared by the stub definition file at the provided
+// path. Body files are resolved relatively to the provided directory.
+func loadStubFile(path string, dir string) ([]*stub, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stub file %s: %w", path, err)
+	}
+	file := &stubFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to decode stub file %s: %w", path, err)
+	}
+	stubs := make([]*stub, 0, len(file.Stubs))
+	for i, definition := range file.Stubs {
+		s, err := definition.build(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stub #%d in %s: %w", i+1, path, err)
+		}
+		stubs = append(stubs, s)
+	}
+	return stubs, nil
+}
+
+// Helper method which builds the stub declared by the definition.
+func (definition *stubDefinition) build(dir string) (*stub, error) {
+	response, err := definition.Response.build(dir)
+	if err != nil {
+		return nil, err
+	}
+	response.Name = definition.Name
+	s := &stub{
+		matcher:  definition.Request.build(),
+		response: response,
+	}
+	if definition.Scenario != nil {
+		if definition.Scenario.Name == "" {
+			return nil, fmt.Errorf("scenario name is required")
+		}
+		s.scenario = definition.Scenario.Name
+		s.requiredState = definition.Scenario.RequiredState
+		if s.requiredState == "" {
+			s.requiredState = ScenarioStarted
+		}
+		s.newState = definition.Scenario.NewState
+	}
+	return s, nil
+}
+
+// Helper method which builds the request matcher declared by the definition.
+func (definition *stubRequestDefinition) build() RequestMatcher {
+	matchers := []RequestMatcher{}
+	if definition.Method != "" {
+		matchers = append(matchers, MethodMatcher(definition.Method))
+	}
+	if definition.Path != "" {
+		matchers = append(matchers, PathMatcher(definition.Path))
+	}
+	if definition.Host != "" {
+		matchers = append(matchers, HostMatcher(definition.Host))
+	}
+	for header, value := range definition.Headers {
+		matchers = append(matchers, HeaderEqualsMatcher(header, value))
+	}
+	for name, value := range definition.Query {
+		name, value := name, value
+		matchers = append(matchers, RequestMatcherFunc(func(r *http.Request) bool {
+			values, ok := r.URL.Query()[name]
+			return ok && containsString(values, value)
+		}))
+	}
+	if definition.ExactQuery != nil {
+		matchers = append(matchers, QueryEqualsMatcher(*definition.ExactQuery))
+	}
+	for name, value := range definition.Cookies {
+		matchers = append(matchers, CookieMatcher(name, value))
+	}
+	if definition.BodyContains != "" {
+		matchers = append(matchers, BodyContainsMatcher(definition.BodyContains))
+	}
+	for expr, expected := range definition.JSONPath {
+		matchers = append(matchers, BodyJSONPathMatcher(expr, expected))
+	}
+	return MatchAll(matchers...)
+}
+
+// Helper method which builds the predefined response declared by the definition.
+func (definition *stubResponseDefinition) build(dir string) (*PredefinedServerResponse, error) {
+	response := &PredefinedServerResponse{
+		Status:  definition.Status,
+		Headers: http.Header{},
+		Body:    []byte(definition.Body),
+	}
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	for header, values := range definition.Headers {
+		for _, value := range values {
+			response.Headers.Add(header, value)
+		}
+	}
+	if definition.JSON != nil {
+		body, err := json.Marshal(definition.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON body: %w", err)
+		}
+		response.Body = body
+		if response.Headers.Get("Content-Type") == "" {
+			response.Headers.Set("Content-Type", "application/json")
+		}
+	}
+	if definition.BodyFile != "" {
+		response.BodyFile = definition.BodyFile
+		if !filepath.IsAbs(response.BodyFile) {
+			response.BodyFile = filepath.Join(dir, response.BodyFile)
+		}
+	}
+	if definition.Delay != "" {
+		delay, err := time.ParseDuration(definition.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delay %s: %w", definition.Delay, err)
+		}
+		response.Delay = delay
+	}
+	if definition.Repeat > 0 {
+		response.Repeat = Times(definition.Repeat)
+	}
+	fault, ok := stubFaults[definition.Fault]
+	if !ok {
+		return nil, fmt.Errorf("unknown fault %s", definition.Fault)
+	}
+	response.Fault = fault
+	return response, nil
+}
@@ -0,0 +1,109 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test stubs declared in YAML and JSON definition files are registered.
+func (suite *HTTPTestServerUnitTestSuite) TestLoadStubs() {
+	dir := suite.T().TempDir()
+	files := map[string]string{
+		"01-users.yaml": `
+stubs:
+  - name: list-users
+    request:
+      method: GET
+      path: /users
+      headers: {Accept: application/json}
+      query: {page: "2"}
+    response:
+      status: 206
+      headers: {X-Page: "2"}
+      bodyFile: fixtures/users.json
+  - request:
+      method: POST
+      path: /users
+      jsonPath: {"$.age": 30}
+      bodyContains: alice
+    response:
+      status: 201
+      json: {id: 1, name: alice}
+      repeat: 1
+  - request: {path: /order}
+    response: {body: pending}
+    scenario: {name: order, newState: done}
+  - request: {path: /order}
+    response: {body: done, delay: 1ms}
+    scenario: {name: order, requiredState: done}
+`,
+		"02-health.json":      `{"stubs": [{"request": {"path": "/health", "cookies": {"session": "abc"}}, "response": {"body": "ok"}}]}`,
+		"fixtures/users.json": `[{"id":1}]`,
+		"README.md":           `ignored`,
+	}
+	require.NoError(suite.T(), os.Mkdir(filepath.Join(dir, "fixtures"), 0755))
+	for name, content := range files {
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	require.NoError(suite.T(), os.Mkdir(filepath.Join(dir, "sub.yaml"), 0755))
+	require.NoError(suite.T(), suite.hts.LoadStubs(dir))
+	client := suite.hts.Client()
+	do := func(method string, path string, body string, header http.Header) (int, string) {
+		req, err := http.NewRequest(method, suite.hts.GetBaseURL()+path, strings.NewReader(body))
+		require.NoError(suite.T(), err)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := client.Do(req)
+		require.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(suite.T(), err)
+		return resp.StatusCode, string(data)
+	}
+	status, body := do(http.MethodGet, "/users?page=2", "", http.Header{"Accept": {"application/json"}})
+	require.Equal(suite.T(), http.StatusPartialContent, status)
+	require.Equal(suite.T(), `[{"id":1}]`, body)
+	require.Equal(suite.T(), `stub "list-users"`, suite.hts.PopServerRecord().ServedBy)
+	status, _ = do(http.MethodGet, "/users?page=1", "", http.Header{"Accept": {"application/json"}})
+	require.Equal(suite.T(), http.StatusNotFound, status)
+	status, body = do(http.MethodPost, "/users", `{"name":"alice","age":30}`, nil)
+	require.Equal(suite.T(), http.StatusCreated, status)
+	require.JSONEq(suite.T(), `{"id":1,"name":"alice"}`, body)
+	status, _ = do(http.MethodPost, "/users", `{"name":"alice","age":30}`, nil)
+	require.Equal(suite.T(), http.StatusNotFound, status)
+	_, body = do(http.MethodGet, "/order", "", nil)
+	require.Equal(suite.T(), "pending", body)
+	_, body = do(http.MethodGet, "/order", "", nil)
+	require.Equal(suite.T(), "done", body)
+	status, body = do(http.MethodGet, "/health", "", http.Header{"Cookie": {"session=abc"}})
+	require.Equal(suite.T(), http.StatusOK, status)
+	require.Equal(suite.T(), "ok", body)
+}
+
+// Test invalid stub definition files are reported and no stubs are registered.
+func (suite *HTTPTestServerUnitTestSuite) TestLoadStubsErrors() {
+	require.Error(suite.T(), suite.hts.LoadStubs(filepath.Join(suite.T().TempDir(), "missing")))
+	invalid := []string{
+		`stubs: [`,
+		`stubs: [{response: {delay: soon}}]`,
+		`stubs: [{response: {fault: explode}}]`,
+		`stubs: [{scenario: {newState: done}}]`,
+		`stubs: [{response: {json: {.inf: 1}}}]`,
+	}
+	for _, content := range invalid {
+		dir := suite.T().TempDir()
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("stubs: [{request: {path: /a}}]"), 0644))
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(dir, "b.yml"), []byte(content), 0644))
+		require.Error(suite.T(), suite.hts.LoadStubs(dir), content)
+	}
+	resp, err := suite.hts.Client().Get(suite.hts.GetBaseURL() + "/a")
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
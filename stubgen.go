@@ -0,0 +1,127 @@
+package gosette
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Convert the interactions recorded in passthrough mode (or in proxy mode, see StartRecording)
+// into stubs: the interactions are registered as predefined responses (see Replay) and removed from
+// the recorded interactions. Requests which have already been proxied are therefore mocked from
+// now on.
+//
+// If a path is provided, the stubs are also written to a stub definition file which can be loaded
+// later with LoadStubs so next test runs work fully offline. The file is written as JSON if the
+// path has a .json extension and as YAML otherwise. Response bodies which are not valid UTF-8 are
+// written to body files in a 'bodies' subdirectory next to the stub definition file.
+//
+// Returns an error if the stub definition file cannot be written. In that case no stubs are
+// registered and the recorded interactions are kept.
+func (hts *HTTPTestServer) GenerateStubs(path string) error {
+	hts.mu.Lock()
+	recording := hts.cassette
+	interactions := []*Interaction{}
+	if recording != nil {
+		interactions = recording.Interactions
+	}
+	hts.mu.Unlock()
+	cassette := &Cassette{Interactions: interactions}
+	// Write the stub definition file if requested
+	if path != "" {
+		if err := writeStubFile(path, cassette); err != nil {
+			return err
+		}
+	}
+	// Remove the converted interactions and register the stubs. The lock is not held while the file
+	// is written: the interactions are only removed if they are still the first recorded ones, that
+	// is if recording has not been restarted or stopped and no concurrent call removed them.
+	hts.mu.Lock()
+	if hts.cassette == recording && len(interactions) > 0 && len(recording.Interactions) >= len(interactions) &&
+		recording.Interactions[0] == interactions[0] {
+		recording.Interactions = recording.Interactions[len(interactions):]
+	}
+	hts.mu.Unlock()
+	hts.Replay(cassette)
+	return nil
+}
+
+// Helper function which writes the interactions of the provided cassette as a stub definition file
+// at the provided path.
+func writeStubFile(path string, cassette *Cassette) error {
+	dir := filepath.Dir(path)
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	// Count interactions per request to detect the last one of each request
+	remaining := map[string]int{}
+	for _, interaction := range cassette.Interactions {
+		remaining[interaction.key()]++
+	}
+	file := &stubFile{Stubs: make([]*stubDefinition, 0, len(cassette.Interactions))}
+	for i, interaction := range cassette.Interactions {
+		definition := &stubDefinition{
+			Name: interaction.Method + " " + interaction.Path,
+			Request: stubRequestDefinition{
+				Method: interaction.Method,
+				Path:   interaction.Path,
+			},
+			Response: stubResponseDefinition{
+				Status: interaction.Status,
+			},
+		}
+		// Match the recorded query exactly: a request without query must not match the stubs of
+		// requests with a query
+		rawQuery := interaction.RawQuery
+		definition.Request.ExactQuery = &rawQuery
+		// Keep each header value: the values of some headers cannot be joined (ex: Set-Cookie). The
+		// headers which depend on the connection or on the time the response has been sent are
+		// dropped so the test server computes them.
+		headers := interaction.ResponseHeaders.Clone()
+		removeHopByHopHeaders(headers)
+		headers.Del("Date")
+		headers.Del("Content-Length")
+		for header, values := range headers {
+			if definition.Response.Headers == nil {
+				definition.Response.Headers = map[string]stubHeaderValues{}
+			}
+			definition.Response.Headers[header] = stubHeaderValues(values)
+		}
+		if utf8.Valid(interaction.ResponseBody) {
+			definition.Response.Body = string(interaction.ResponseBody)
+		} else {
+			// Write the body to a body file
+			definition.Response.BodyFile = filepath.ToSlash(filepath.Join("bodies", fmt.Sprintf("%s-%d.bin", base, i+1)))
+			if err := os.MkdirAll(filepath.Join(dir, "bodies"), 0755); err != nil {
+				return fmt.Errorf("failed to create body file directory: %w", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, definition.Response.BodyFile), interaction.ResponseBody, 0644); err != nil {
+				return fmt.Errorf("failed to write body file: %w", err)
+			}
+		}
+		// Serve the interaction once unless it is the last one recorded for the request
+		remaining[interaction.key()]--
+		if remaining[interaction.key()] > 0 {
+			definition.Response.Repeat = 1
+		}
+		file.Stubs = append(file.Stubs, definition)
+	}
+	// Encode and write the stub definition file
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(file, "", "  ")
+	} else {
+		data, err = yaml.Marshal(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode stub file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write stub file to %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package gosette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test stubs generated from passthrough traffic. Test will ensure:
+//   - Proxied requests are mocked once the stubs are generated
+//   - Generated YAML and JSON stub definition files can be loaded by another server
+//   - Interactions recorded for the same request are served in their recording order
+//   - Binary bodies are written to body files
+func TestGenerateStubs(t *testing.T) {
+	// Create an upstream
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	upstream.PushPredefinedServerResponseForPath("/users", &PredefinedServerResponse{
+		Status:  http.StatusOK,
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    []byte(`[{"id": 1}]`),
+		Repeat:  Once(),
+	})
+	upstream.PushPredefinedServerResponseForPath("/users", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte(`[{"id": 1}, {"id": 2}]`),
+	})
+	upstream.PushPredefinedServerResponseForPath("/avatar", &PredefinedServerResponse{
+		Status: http.StatusOK,
+		Body:   []byte{0xff, 0xfe, 0x00},
+	})
+
+	// Send requests in passthrough mode
+	get := func(s *HTTPTestServer, path string) (int, string) {
+		resp, err := s.Client().Get(s.GetBaseURL() + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	get(srv, "/users?page=1")
+	get(srv, "/users?page=1")
+	get(srv, "/avatar")
+
+	// Generate stubs
+	dir := t.TempDir()
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0644))
+	require.Error(t, srv.GenerateStubs(filepath.Join(blocker, "stubs.yaml")))
+	require.NoError(t, srv.GenerateStubs(filepath.Join(dir, "stubs.yaml")))
+	require.NoError(t, srv.GenerateStubs(filepath.Join(t.TempDir(), "empty.json")))
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	get(srv, "/users?page=2")
+	require.NoError(t, srv.GenerateStubs(filepath.Join(dir, "stubs.json")))
+
+	// Proxied requests are mocked: the upstream is closed
+	upstream.Close()
+	srv.StopPassthrough()
+	status, body := get(srv, "/users?page=1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}]`, body)
+	status, body = get(srv, "/users?page=2")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}, {"id": 2}]`, body)
+
+	// Load generated stubs in another server
+	offline := NewHTTPTestServer(nil)
+	offline.Start()
+	defer offline.Close()
+	require.NoError(t, offline.LoadStubs(dir))
+	status, body = get(offline, "/users?page=1")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `[{"id": 1}]`, body)
+	status, body = get(offline, "/users?page=1")
+	require.Equal(t, `[{"id": 1}, {"id": 2}]`, body)
+	_, body = get(offline, "/avatar")
+	require.Equal(t, string([]byte{0xff, 0xfe, 0x00}), body)
+	_, body = get(offline, "/users?page=2")
+	require.Equal(t, `[{"id": 1}, {"id": 2}]`, body)
+	records := offline.FindRecords()
+	require.Equal(t, `stub "GET /users"`, records[0].ServedBy)
+}
+
+// Test generated stub definition files match query strings exactly: a path recorded with and
+// without query parameters is served the same way once the stubs are loaded offline.
+func TestGenerateStubsQuery(t *testing.T) {
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	upstream.When().Get("/items").RespondWith().BodyTemplate(`items {{.Query.Encode}}`)
+
+	// Send requests in passthrough mode
+	get := func(s *HTTPTestServer, path string) (int, string) {
+		resp, err := s.Client().Get(s.GetBaseURL() + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	get(srv, "/items")
+	get(srv, "/items?page=2")
+	get(srv, "/items?tag=a&tag=b")
+	upstream.Close()
+
+	// Generate stubs as YAML and JSON and load them offline
+	yamlDir, jsonDir := t.TempDir(), t.TempDir()
+	cassette := &Cassette{Interactions: srv.StopRecording().Interactions}
+	require.NoError(t, writeStubFile(filepath.Join(yamlDir, "stubs.yaml"), cassette))
+	require.NoError(t, writeStubFile(filepath.Join(jsonDir, "stubs.json"), cassette))
+	for _, dir := range []string{yamlDir, jsonDir} {
+		offline := NewHTTPTestServer(nil)
+		offline.Start()
+		require.NoError(t, offline.LoadStubs(dir))
+		for path, expected := range map[string]string{
+			"/items":             "items ",
+			"/items?page=2":      "items page=2",
+			"/items?tag=b&tag=a": "",
+			"/items?tag=a&tag=b": "items tag=a&tag=b",
+			"/items?page=3":      "",
+			"/items?page=2&x=1":  "",
+			"/items?tag=a":       "",
+		} {
+			status, body := get(offline, path)
+			if expected == "" {
+				require.Equal(t, http.StatusNotFound, status, path)
+				continue
+			}
+			require.Equal(t, http.StatusOK, status, path)
+			require.Equal(t, expected, body, path)
+		}
+		offline.Close()
+	}
+}
+
+// Test response headers of generated stub definition files. Test will ensure:
+//   - Each value of multi-valued headers is kept (Set-Cookie values contain commas)
+//   - Date, Content-Length and hop-by-hop headers are not frozen in the stub definition file
+func TestGenerateStubsHeaders(t *testing.T) {
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	cookies := []string{
+		"session=abc; Expires=Wed, 21 Oct 2026 07:28:00 GMT",
+		"theme=dark; Expires=Thu, 22 Oct 2026 07:28:00 GMT",
+	}
+	upstream.PushPredefinedServerResponseForPath("/login", &PredefinedServerResponse{
+		Status:  http.StatusOK,
+		Headers: http.Header{"Set-Cookie": cookies, "Content-Type": {"text/plain"}, "Keep-Alive": {"timeout=5"}},
+		Body:    []byte("welcome"),
+	})
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+	resp, err := srv.Client().Get(srv.GetBaseURL() + "/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	upstream.Close()
+
+	// Generate stubs as YAML and JSON and load them offline
+	yamlDir, jsonDir := t.TempDir(), t.TempDir()
+	cassette := &Cassette{Interactions: srv.StopRecording().Interactions}
+	require.NoError(t, writeStubFile(filepath.Join(yamlDir, "stubs.yaml"), cassette))
+	require.NoError(t, writeStubFile(filepath.Join(jsonDir, "stubs.json"), cassette))
+	for _, path := range []string{filepath.Join(yamlDir, "stubs.yaml"), filepath.Join(jsonDir, "stubs.json")} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "Date", path)
+		require.NotContains(t, string(data), "Content-Length", path)
+		require.NotContains(t, string(data), "Keep-Alive", path)
+		offline := NewHTTPTestServer(nil)
+		offline.Start()
+		require.NoError(t, offline.LoadStubs(filepath.Dir(path)))
+		resp, err := offline.Client().Get(offline.GetBaseURL() + "/login")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, cookies, resp.Header.Values("Set-Cookie"), path)
+		require.Equal(t, "text/plain", resp.Header.Get("Content-Type"), path)
+		require.Equal(t, "7", resp.Header.Get("Content-Length"), path)
+		offline.Close()
+	}
+}
+
+// Test stubs can be generated while requests are proxied and passthrough is restarted
+// concurrently: the cassette may be replaced while the stub definition file is written.
+func TestGenerateStubsConcurrentRestart(t *testing.T) {
+	upstream := NewHTTPTestServer(nil)
+	upstream.Start()
+	defer upstream.Close()
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	require.NoError(t, srv.Passthrough(upstream.GetBaseURL()))
+
+	// Send requests and restart passthrough while stubs are generated
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			resp, err := srv.Client().Get(fmt.Sprintf("%s/%d", srv.GetBaseURL(), i))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				srv.Passthrough(upstream.GetBaseURL())
+			}
+		}
+	}()
+	dir := t.TempDir()
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+		require.NoError(t, srv.GenerateStubs(filepath.Join(dir, fmt.Sprintf("stubs-%d.yaml", i))))
+	}
+}
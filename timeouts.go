@@ -0,0 +1,196 @@
+package gosette
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// A timeout which has fired on a client connection of the test server.
+type TimeoutEvent struct {
+	// The operation which has timed out: "read" (ReadTimeout, ReadHeaderTimeout or IdleTimeout)
+	// or "write" (WriteTimeout).
+	Op string
+	// Network address of the client (see http.Request RemoteAddr).
+	RemoteAddr string
+	// Time at which the timeout has fired.
+	At time.Time
+}
+
+// Option which sets the ReadTimeout of the underlying http.Server: the maximum duration for
+// reading the entire request, including the body. Timeouts which fire are recorded (see
+// TimeoutEvents).
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.server.Config.ReadTimeout = d
+		hts.watchTimeouts()
+	}
+}
+
+// Option which sets the ReadHeaderTimeout of the underlying http.Server: the maximum duration for
+// reading the request headers. Timeouts which fire are recorded (see TimeoutEvents).
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.server.Config.ReadHeaderTimeout = d
+		hts.watchTimeouts()
+	}
+}
+
+// Option which sets the WriteTimeout of the underlying http.Server: the maximum duration before
+// timing out writes of the response. Timeouts which fire are recorded (see TimeoutEvents).
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.server.Config.WriteTimeout = d
+		hts.watchTimeouts()
+	}
+}
+
+// Option which sets the IdleTimeout of the underlying http.Server: the maximum amount of time to
+// wait for the next request on a kept-alive connection. Timeouts which fire are recorded (see
+// TimeoutEvents).
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(hts *HTTPTestServer) {
+		hts.server.Config.IdleTimeout = d
+		hts.watchTimeouts()
+	}
+}
+
+// Return the timeouts which have fired on the client connections of the test server since it has
+// been created or since the records have been cleared. Timeouts are recorded only when they are
+// configured with WithReadTimeout, WithReadHeaderTimeout, WithWriteTimeout or WithIdleTimeout.
+//
+// The record of a request whose body read or response write has timed out has TimedOut set. The
+// other timeouts (ex: the client is too slow to send the request headers) fire before any request
+// is received and have no record.
+func (hts *HTTPTestServer) TimeoutEvents() []TimeoutEvent {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	events := make([]TimeoutEvent, len(hts.timeoutEvents))
+	copy(events, hts.timeoutEvents)
+	return events
+}
+
+// Helper method which wraps the listener of the underlying httptest.Server so timeouts which fire
+// on client connections are recorded. The method has no effect if the listener is already
+// wrapped.
+func (hts *HTTPTestServer) watchTimeouts() {
+	if _, ok := hts.server.Listener.(*timeoutListener); ok || hts.server.Listener == nil {
+		return
+	}
+	hts.server.Listener = &timeoutListener{Listener: hts.server.Listener, hts: hts}
+}
+
+// Helper method which records a timeout which has fired on the connection of the provided client.
+// Write timeouts mark the last record of the client as timed out.
+func (srv *HTTPTestServer) recordTimeout(op string, remoteAddr string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.timeoutEvents = append(srv.timeoutEvents, TimeoutEvent{Op: op, RemoteAddr: remoteAddr, At: srv.clock.Now()})
+	if op != "write" {
+		return
+	}
+	for i := len(srv.records) - 1; i >= 0; i-- {
+		if record := srv.records[i]; record.Request != nil && record.Request.RemoteAddr == remoteAddr {
+			record.TimedOut = true
+			return
+		}
+	}
+}
+
+// A listener which wraps accepted connections so timeouts are recorded.
+type timeoutListener struct {
+	net.Listener
+	// The test server which records timeouts.
+	hts *HTTPTestServer
+}
+
+// Accept waits for and returns the next connection, wrapped.
+func (tl *timeoutListener) Accept() (net.Conn, error) {
+	conn, err := tl.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutConn{Conn: conn, hts: tl.hts}, nil
+}
+
+// A connection which records the timeouts which fire on reads and writes. Each kind of timeout is
+// recorded once per connection.
+type timeoutConn struct {
+	net.Conn
+	// The test server which records timeouts.
+	hts *HTTPTestServer
+	// Mutex used to protect the deadlines and flags.
+	mu sync.Mutex
+	// Current read and write deadlines.
+	readDeadline  time.Time
+	writeDeadline time.Time
+	// True once a read or a write timeout has been recorded.
+	readTimedOut  bool
+	writeTimedOut bool
+}
+
+// Read reads data from the connection and records the timeout if any.
+func (tc *timeoutConn) Read(b []byte) (int, error) {
+	n, err := tc.Conn.Read(b)
+	if isTimeout(err) && tc.firstTimeout(true) {
+		tc.hts.recordTimeout("read", tc.RemoteAddr().String())
+	}
+	return n, err
+}
+
+// Write writes data to the connection and records the timeout if any.
+func (tc *timeoutConn) Write(b []byte) (int, error) {
+	n, err := tc.Conn.Write(b)
+	if isTimeout(err) && tc.firstTimeout(false) {
+		tc.hts.recordTimeout("write", tc.RemoteAddr().String())
+	}
+	return n, err
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (tc *timeoutConn) SetDeadline(t time.Time) error {
+	tc.mu.Lock()
+	tc.readDeadline, tc.writeDeadline = t, t
+	tc.mu.Unlock()
+	return tc.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (tc *timeoutConn) SetReadDeadline(t time.Time) error {
+	tc.mu.Lock()
+	tc.readDeadline = t
+	tc.mu.Unlock()
+	return tc.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the connection.
+func (tc *timeoutConn) SetWriteDeadline(t time.Time) error {
+	tc.mu.Lock()
+	tc.writeDeadline = t
+	tc.mu.Unlock()
+	return tc.Conn.SetWriteDeadline(t)
+}
+
+// Helper method which returns true if the read (or write) timeout which has fired is the first
+// one of its kind and has been caused by a configured timeout. The http.Server also sets
+// deadlines in the distant past (time.Unix(1, 0)) to abort pending reads: they are not timeouts.
+func (tc *timeoutConn) firstTimeout(read bool) bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	reported, deadline := &tc.writeTimedOut, tc.writeDeadline
+	if read {
+		reported, deadline = &tc.readTimedOut, tc.readDeadline
+	}
+	if *reported || !deadline.After(time.Unix(1, 0)) {
+		return false
+	}
+	*reported = true
+	return true
+}
+
+// Helper function which returns true if the provided error is a network timeout.
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
@@ -0,0 +1,94 @@
+package gosette
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test timeout options configure the underlying http.Server.
+func TestTimeoutOptions(t *testing.T) {
+	hts := NewHTTPTestServer(nil,
+		WithReadTimeout(time.Second),
+		WithReadHeaderTimeout(2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithIdleTimeout(4*time.Second),
+	)
+	config := hts.GetUnderlyingHTTPTestServer().Config
+	require.Equal(t, time.Second, config.ReadTimeout)
+	require.Equal(t, 2*time.Second, config.ReadHeaderTimeout)
+	require.Equal(t, 3*time.Second, config.WriteTimeout)
+	require.Equal(t, 4*time.Second, config.IdleTimeout)
+	// The listener is wrapped once
+	listener := hts.GetUnderlyingHTTPTestServer().Listener.(*timeoutListener)
+	require.IsType(t, &net.TCPListener{}, listener.Listener)
+	hts.Close()
+}
+
+// Test read timeouts which fire are recorded with the time of the test server clock.
+func TestReadTimeouts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	hts := NewHTTPTestServer(nil, WithReadTimeout(100*time.Millisecond), WithClock(NewFakeClock(start)))
+	hts.Start()
+	defer hts.Close()
+	addr := strings.TrimPrefix(hts.GetBaseURL(), "http://")
+
+	// Slow request headers: the timeout fires before any request is received
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n"))
+	require.NoError(t, err)
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err)
+	conn.Close()
+	events := hts.TimeoutEvents()
+	require.Len(t, events, 1)
+	require.Equal(t, "read", events[0].Op)
+	require.Equal(t, conn.LocalAddr().String(), events[0].RemoteAddr)
+	require.Equal(t, start, events[0].At)
+	require.Empty(t, hts.FindRecords())
+
+	// Slow request body: the record is marked as timed out
+	conn, err = net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: test\r\nContent-Length: 10\r\n\r\nab"))
+	require.NoError(t, err)
+	io.ReadAll(conn)
+	conn.Close()
+	records, err := hts.WaitForRequests(1, time.Second)
+	require.NoError(t, err)
+	record := records[0]
+	require.True(t, record.TimedOut)
+	require.Error(t, record.ServerError)
+	require.Equal(t, start, record.ReceivedAt)
+	require.Len(t, hts.TimeoutEvents(), 2)
+
+	// Timeout events are cleared with the records
+	hts.ClearServerRecords()
+	require.Empty(t, hts.TimeoutEvents())
+}
+
+// Test write timeouts which fire are recorded and mark the record as timed out.
+func TestWriteTimeout(t *testing.T) {
+	hts := NewHTTPTestServer(nil, WithWriteTimeout(50*time.Millisecond))
+	hts.Start()
+	defer hts.Close()
+	hts.PushPredefinedServerResponse(&PredefinedServerResponse{Status: http.StatusOK, Body: []byte("too late"), Delay: 150 * time.Millisecond})
+	_, err := hts.Client().Get(hts.GetBaseURL() + "/other")
+	require.Error(t, err)
+	require.Eventually(t, func() bool { return len(hts.TimeoutEvents()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "write", hts.TimeoutEvents()[0].Op)
+	require.True(t, hts.PopServerRecord().TimedOut)
+
+	// Stop and Restart keep recording timeouts
+	hts.Stop()
+	require.NoError(t, hts.Restart())
+	_, err = hts.Client().Get(hts.GetBaseURL() + "/other")
+	require.Error(t, err)
+	require.Eventually(t, func() bool { return len(hts.TimeoutEvents()) == 2 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,151 @@
+package gosette
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Start the test server with mutual TLS activated: the server requests client certificates
+// according to the provided client authentication policy (ex: tls.RequireAndVerifyClientCert)
+// and verifies them against the provided pool of CA certificates.
+//
+// The certificate chain presented by clients is recorded in the ServerRecord PeerCertificates.
+// The TLS configuration of the underlying httptest.Server is kept if any.
+func (hts *HTTPTestServer) StartMTLS(clientCAs *x509.CertPool, clientAuth tls.ClientAuthType) {
+	if hts.server.TLS == nil {
+		hts.server.TLS = &tls.Config{}
+	}
+	hts.server.TLS.ClientCAs = clientCAs
+	hts.server.TLS.ClientAuth = clientAuth
+	hts.server.StartTLS()
+}
+
+// Option which makes the test server use the provided certificate when it is started with TLS
+// (StartTLS or StartMTLS) instead of the default httptest certificate.
+//
+// The client returned by Client trusts the leaf of the provided certificate.
+func WithCertificate(cert tls.Certificate) ServerOption {
+	return func(hts *HTTPTestServer) {
+		if hts.server.TLS == nil {
+			hts.server.TLS = &tls.Config{}
+		}
+		hts.server.TLS.Certificates = []tls.Certificate{cert}
+	}
+}
+
+// Option which makes the test server use a generated certificate when it is started with TLS
+// (StartTLS or StartMTLS). A CA is generated along with a leaf certificate valid for the provided
+// host names and IP addresses as well as localhost, 127.0.0.1 and ::1.
+//
+// The CA certificate can be exported with CACertificatePEM or CACertPool to configure the client
+// under test. The option panics if the certificates cannot be generated.
+func WithGeneratedCertificate(hosts ...string) ServerOption {
+	return func(hts *HTTPTestServer) {
+		ca, cert, err := generateCertificates(append([]string{"localhost", "127.0.0.1", "::1"}, hosts...))
+		if err != nil {
+			panic(fmt.Errorf("gosette: failed to generate certificates: %w", err))
+		}
+		WithCertificate(cert)(hts)
+		hts.caCertificate = ca
+	}
+}
+
+// Return the PEM encoded certificate of the CA which has signed the server certificate when the
+// test server uses a generated certificate (see WithGeneratedCertificate). Returns nil otherwise.
+func (hts *HTTPTestServer) CACertificatePEM() []byte {
+	if hts.caCertificate == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: hts.caCertificate.Raw})
+}
+
+// Return a certificate pool which contains the CA which has signed the server certificate when the
+// test server uses a generated certificate (see WithGeneratedCertificate). Returns nil otherwise.
+func (hts *HTTPTestServer) CACertPool() *x509.CertPool {
+	if hts.caCertificate == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(hts.caCertificate)
+	return pool
+}
+
+// Helper function which generates a CA certificate and a leaf certificate signed by this CA which
+// is valid for the provided host names and IP addresses.
+func generateCertificates(hosts []string) (*x509.Certificate, tls.Certificate, error) {
+	ca, caKey, err := generateCA()
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	cert, err := generateLeafCertificate(ca, caKey, hosts)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	return ca, cert, nil
+}
+
+// Helper function which generates a CA certificate and its private key.
+func generateCA() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{Organization: []string{"gosette"}, CommonName: "gosette test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	return ca, caKey, nil
+}
+
+// Helper function which generates a leaf certificate signed by the provided CA which is valid for
+// the provided host names and IP addresses.
+func generateLeafCertificate(ca *x509.Certificate, caKey *ecdsa.PrivateKey, hosts []string) (tls.Certificate, error) {
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate leaf key: %w", err)
+	}
+	now := time.Now()
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{Organization: []string{"gosette"}, CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			leafTemplate.IPAddresses = append(leafTemplate.IPAddresses, ip)
+		} else {
+			leafTemplate.DNSNames = append(leafTemplate.DNSNames, host)
+		}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create leaf certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{leafDER, ca.Raw}, PrivateKey: leafKey}, nil
+}
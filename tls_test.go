@@ -0,0 +1,127 @@
+package gosette
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function which generates a self-signed CA and a client certificate signed by this CA.
+func generateClientCertificate(t *testing.T, commonName string) (*x509.CertPool, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, ca, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+// Test mutual TLS. Test will ensure:
+//   - Clients without a certificate are rejected when client certificates are required
+//   - The certificate presented by the client is recorded
+func TestStartMTLS(t *testing.T) {
+	pool, clientCert := generateClientCertificate(t, "client")
+	srv := NewHTTPTestServer(nil)
+	srv.StartMTLS(pool, tls.RequireAndVerifyClientCert)
+	defer srv.Close()
+
+	// Without client certificate
+	_, err := srv.Client().Get(srv.GetBaseURL())
+	require.Error(t, err)
+
+	// With client certificate
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+	client.Transport = transport
+	resp, err := client.Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	record := srv.PopServerRecord()
+	require.NotNil(t, record)
+	require.Len(t, record.PeerCertificates, 1)
+	require.Equal(t, "client", record.PeerCertificates[0].Subject.CommonName)
+}
+
+// Test the test server can use a generated certificate valid for custom host names. Test will
+// ensure the exported CA can be used by clients to verify the server for these host names only.
+func TestWithGeneratedCertificate(t *testing.T) {
+	srv := NewHTTPTestServer(nil, WithGeneratedCertificate("api.example.com"))
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Default client
+	resp, err := srv.Client().Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Client which trusts the exported CA and uses custom server names
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(srv.CACertificatePEM()))
+	for serverName, valid := range map[string]bool{"api.example.com": true, "localhost": true, "auth.example.com": false} {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: serverName}}}
+		resp, err := client.Get(srv.GetBaseURL())
+		if valid {
+			require.NoError(t, err, serverName)
+			resp.Body.Close()
+		} else {
+			require.Error(t, err, serverName)
+		}
+	}
+	require.NotNil(t, srv.CACertPool())
+
+	// Without generated certificate
+	srv2 := NewHTTPTestServer(nil)
+	require.Nil(t, srv2.CACertificatePEM())
+	require.Nil(t, srv2.CACertPool())
+}
+
+// Test the test server can use a user-provided certificate.
+func TestWithCertificate(t *testing.T) {
+	ca, cert, err := generateCertificates([]string{"custom.example.com", "127.0.0.1"})
+	require.NoError(t, err)
+	srv := NewHTTPTestServer(nil, WithCertificate(cert))
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "custom.example.com"}}}
+	resp, err := client.Get(srv.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,171 @@
+package gosette
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Trace context formats.
+const (
+	// W3C Trace Context (traceparent and tracestate headers).
+	TraceFormatW3C = "w3c"
+	// Zipkin B3, with the single b3 header or the multiple X-B3-* headers.
+	TraceFormatB3 = "b3"
+)
+
+// The trace context propagated by a request.
+type TraceContext struct {
+	// Format the trace context has been propagated with: TraceFormatW3C or TraceFormatB3.
+	Format string
+	// Id of the trace (lower case hexadecimal).
+	TraceID string
+	// Id of the span of the client which has sent the request (lower case hexadecimal).
+	SpanID string
+	// Id of the parent span. Only propagated by B3. Empty otherwise.
+	ParentSpanID string
+	// True if the trace is sampled.
+	Sampled bool
+	// Vendor specific trace state (W3C tracestate header). Empty if none.
+	TraceState string
+}
+
+// Expected formats of trace context values.
+var (
+	traceParentRegexp = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+	b3TraceIDRegexp   = regexp.MustCompile(`^([0-9a-f]{16}|[0-9a-f]{32})$`)
+	b3SpanIDRegexp    = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// Helper function which parses the trace context propagated by the provided request. The W3C
+// format is preferred over the B3 format. Returns nil without error if the request does not
+// propagate a trace context and an error if the propagated trace context is malformed.
+func parseTraceContext(r *http.Request) (*TraceContext, error) {
+	if traceParent := r.Header.Get("Traceparent"); traceParent != "" {
+		return parseTraceParent(traceParent, strings.Join(r.Header.Values("Tracestate"), ","))
+	}
+	if b3 := r.Header.Get("B3"); b3 != "" {
+		return parseB3Single(b3)
+	}
+	if traceID := r.Header.Get("X-B3-Traceid"); traceID != "" {
+		tc := &TraceContext{
+			Format:       TraceFormatB3,
+			TraceID:      strings.ToLower(traceID),
+			SpanID:       strings.ToLower(r.Header.Get("X-B3-Spanid")),
+			ParentSpanID: strings.ToLower(r.Header.Get("X-B3-Parentspanid")),
+			Sampled:      r.Header.Get("X-B3-Sampled") == "1" || r.Header.Get("X-B3-Flags") == "1",
+		}
+		return tc, tc.validateB3()
+	}
+	return nil, nil
+}
+
+// Helper function which parses a W3C traceparent header and its tracestate header.
+func parseTraceParent(traceParent string, traceState string) (*TraceContext, error) {
+	match := traceParentRegexp.FindStringSubmatch(traceParent)
+	if match == nil || match[1] == "ff" || (match[1] == "00" && match[5] != "") {
+		return nil, fmt.Errorf("malformed traceparent header %q", traceParent)
+	}
+	if match[2] == strings.Repeat("0", 32) || match[3] == strings.Repeat("0", 16) {
+		return nil, fmt.Errorf("invalid all-zero id in traceparent header %q", traceParent)
+	}
+	var flags int
+	fmt.Sscanf(match[4], "%x", &flags)
+	return &TraceContext{
+		Format:     TraceFormatW3C,
+		TraceID:    match[2],
+		SpanID:     match[3],
+		Sampled:    flags&1 == 1,
+		TraceState: traceState,
+	}, nil
+}
+
+// Helper function which parses a B3 single header of form
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId} where the last two fields are optional.
+func parseB3Single(b3 string) (*TraceContext, error) {
+	fields := strings.Split(strings.ToLower(b3), "-")
+	if len(fields) < 2 || len(fields) > 4 {
+		return nil, fmt.Errorf("malformed b3 header %q", b3)
+	}
+	tc := &TraceContext{Format: TraceFormatB3, TraceID: fields[0], SpanID: fields[1]}
+	if len(fields) > 2 {
+		tc.Sampled = fields[2] == "1" || fields[2] == "d"
+	}
+	if len(fields) > 3 {
+		tc.ParentSpanID = fields[3]
+	}
+	return tc, tc.validateB3()
+}
+
+// Helper method which checks the ids of a B3 trace context.
+func (tc *TraceContext) validateB3() error {
+	if !b3TraceIDRegexp.MatchString(tc.TraceID) {
+		return fmt.Errorf("malformed B3 trace id %q", tc.TraceID)
+	}
+	if !b3SpanIDRegexp.MatchString(tc.SpanID) {
+		return fmt.Errorf("malformed B3 span id %q", tc.SpanID)
+	}
+	if tc.ParentSpanID != "" && !b3SpanIDRegexp.MatchString(tc.ParentSpanID) {
+		return fmt.Errorf("malformed B3 parent span id %q", tc.ParentSpanID)
+	}
+	return nil
+}
+
+// Build a filter which selects records of requests which propagate the trace with the provided
+// id.
+func ByTraceID(traceID string) RecordFilter {
+	return func(record *ServerRecord) bool {
+		return record.TraceContext != nil && record.TraceContext.TraceID == strings.ToLower(traceID)
+	}
+}
+
+// Check that all the provided records propagate a trace context and that they belong to the same
+// trace. Useful to check that a client propagates its context across retries and redirects.
+//
+// An error which describes the first mismatch is returned if the check fails.
+func AssertSameTrace(records ...*ServerRecord) error {
+	var traceID string
+	for i, record := range records {
+		if record.TraceContext == nil {
+			return fmt.Errorf("expected record #%d to propagate a trace context, got none", i+1)
+		}
+		if i == 0 {
+			traceID = record.TraceContext.TraceID
+		} else if record.TraceContext.TraceID != traceID {
+			return fmt.Errorf("expected record #%d to belong to trace %s, got trace %s", i+1, traceID, record.TraceContext.TraceID)
+		}
+	}
+	return nil
+}
+
+// Check that the provided records propagate distinct span ids: each request (ex: each retry) has
+// been sent from its own client span.
+//
+// An error which describes the first duplicate is returned if the check fails.
+func AssertDistinctSpans(records ...*ServerRecord) error {
+	seen := map[string]int{}
+	for i, record := range records {
+		if record.TraceContext == nil {
+			return fmt.Errorf("expected record #%d to propagate a trace context, got none", i+1)
+		}
+		if j, ok := seen[record.TraceContext.SpanID]; ok {
+			return fmt.Errorf("expected records #%d and #%d to have distinct span ids, got %s twice", j, i+1, record.TraceContext.SpanID)
+		}
+		seen[record.TraceContext.SpanID] = i + 1
+	}
+	return nil
+}
+
+// Check that the recorded request propagates the trace with the provided id.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertTraceID(traceID string) error {
+	if record.TraceContext == nil {
+		return fmt.Errorf("expected trace %s, got no trace context", traceID)
+	}
+	if record.TraceContext.TraceID != strings.ToLower(traceID) {
+		return fmt.Errorf("expected trace %s, got trace %s", traceID, record.TraceContext.TraceID)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package gosette
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test W3C and B3 trace contexts are parsed and malformed ones are reported.
+func TestParseTraceContext(t *testing.T) {
+	cases := map[string]struct {
+		header   http.Header
+		expected *TraceContext
+		invalid  bool
+	}{
+		"none": {header: http.Header{}},
+		"w3c": {
+			header: http.Header{
+				"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+				"Tracestate":  {"congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7"},
+			},
+			expected: &TraceContext{Format: TraceFormatW3C, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, TraceState: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		},
+		"w3c future version": {
+			header:   http.Header{"Traceparent": {"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"}},
+			expected: &TraceContext{Format: TraceFormatW3C, TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+		"w3c malformed":  {header: http.Header{"Traceparent": {"00-abc-def-01"}}, invalid: true},
+		"w3c zero":       {header: http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}}, invalid: true},
+		"w3c version ff": {header: http.Header{"Traceparent": {"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, invalid: true},
+		"b3 single": {
+			header:   http.Header{"B3": {"80F198EE56343BA864FE8B2A57D3EFF7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"}},
+			expected: &TraceContext{Format: TraceFormatB3, TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", ParentSpanID: "05e3ac9a4f6e3b90", Sampled: true},
+		},
+		"b3 single short": {
+			header:   http.Header{"B3": {"64fe8b2a57d3eff7-e457b5a2e4d86bd1"}},
+			expected: &TraceContext{Format: TraceFormatB3, TraceID: "64fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1"},
+		},
+		"b3 single malformed": {header: http.Header{"B3": {"0"}}, invalid: true},
+		"b3 multi": {
+			header: http.Header{
+				"X-B3-Traceid":      {"80f198ee56343ba864fe8b2a57d3eff7"},
+				"X-B3-Spanid":       {"e457b5a2e4d86bd1"},
+				"X-B3-Parentspanid": {"05e3ac9a4f6e3b90"},
+				"X-B3-Sampled":      {"1"},
+			},
+			expected: &TraceContext{Format: TraceFormatB3, TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", ParentSpanID: "05e3ac9a4f6e3b90", Sampled: true},
+		},
+		"b3 multi bad span":   {header: http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"x"}}, invalid: true},
+		"b3 multi bad parent": {header: http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}, "X-B3-Parentspanid": {"x"}}, invalid: true},
+		"b3 multi bad trace":  {header: http.Header{"X-B3-Traceid": {"x"}}, invalid: true},
+	}
+	for name, tc := range cases {
+		r := &http.Request{Header: tc.header}
+		parsed, err := parseTraceContext(r)
+		if tc.invalid {
+			require.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		require.Equal(t, tc.expected, parsed, name)
+	}
+}
+
+// Test trace contexts are recorded and propagation assertions and filters.
+func (suite *HTTPTestServerUnitTestSuite) TestTraceContextAssertions() {
+	spans := []string{"00f067aa0ba902b7", "00f067aa0ba902b8", "00f067aa0ba902b8"}
+	for _, span := range spans {
+		req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL(), nil)
+		require.NoError(suite.T(), err)
+		req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span+"-01")
+		resp, err := suite.hts.Client().Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+	req, err := http.NewRequest(http.MethodGet, suite.hts.GetBaseURL(), nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Traceparent", "malformed")
+	resp, err := suite.hts.Client().Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	records := suite.hts.FindRecords(ByTraceID("4BF92F3577B34DA6A3CE929D0E0E4736"))
+	require.Len(suite.T(), records, 3)
+	require.NoError(suite.T(), AssertSameTrace(records...))
+	require.NoError(suite.T(), AssertDistinctSpans(records[:2]...))
+	require.Error(suite.T(), AssertDistinctSpans(records...))
+	require.NoError(suite.T(), records[0].AssertTraceID("4bf92f3577b34da6a3ce929d0e0e4736"))
+	require.Error(suite.T(), records[0].AssertTraceID("80f198ee56343ba864fe8b2a57d3eff7"))
+	// Records without trace context
+	malformed := suite.hts.FindRecords()[3]
+	require.Nil(suite.T(), malformed.TraceContext)
+	require.Len(suite.T(), malformed.ValidationErrors, 1)
+	require.Error(suite.T(), AssertSameTrace(records[0], malformed))
+	require.Error(suite.T(), AssertDistinctSpans(malformed))
+	require.Error(suite.T(), malformed.AssertTraceID("4bf92f3577b34da6a3ce929d0e0e4736"))
+	other := &ServerRecord{TraceContext: &TraceContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7"}}
+	require.Error(suite.T(), AssertSameTrace(records[0], other))
+}
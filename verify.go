@@ -0,0 +1,182 @@
+package gosette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Verifier used to verify the requests received by the test server.
+//
+// Verifications are performed against the server records currently retained by the test server:
+// records which have been popped or cleared are not taken into account. Verifications return a
+// non-nil error describing the mismatch when they fail so they can be used with any testing
+// framework.
+type Verifier struct {
+	// The test server whose records are verified.
+	hts *HTTPTestServer
+}
+
+// Get a Verifier used to verify the requests received by the test server.
+func (hts *HTTPTestServer) Verify() *Verifier {
+	return &Verifier{hts: hts}
+}
+
+// Select the recorded requests which use the provided method and target the provided path. An
+// empty method or path matches any method or path.
+func (v *Verifier) Requests(method string, path string) *RequestVerification {
+	// Build matcher and description
+	matchers := []RequestMatcher{}
+	description := []string{}
+	if method != "" {
+		matchers = append(matchers, MethodMatcher(method))
+		description = append(description, strings.ToUpper(method))
+	}
+	if path != "" {
+		matchers = append(matchers, PathMatcher(path))
+		description = append(description, path)
+	}
+	if len(description) == 0 {
+		description = append(description, "any request")
+	}
+	return &RequestVerification{
+		hts:         v.hts,
+		matcher:     MatchAll(matchers...),
+		description: strings.Join(description, " "),
+	}
+}
+
+// Select the recorded requests which are matched by the provided request matcher.
+func (v *Verifier) RequestsMatching(matcher RequestMatcher) *RequestVerification {
+	return &RequestVerification{
+		hts:         v.hts,
+		matcher:     matcher,
+		description: "the provided matcher",
+	}
+}
+
+// Verify that no recorded requests target the provided path.
+func (v *Verifier) NoRequests(path string) error {
+	return v.Requests("", path).Never()
+}
+
+// Verify that recorded requests selected by the provided verifications occurred in the provided
+// relative order: a request selected by each verification must have been recorded after a request
+// selected by the previous one. Requests do not have to be consecutive and other requests may
+// have been recorded in between.
+//
+// Example:
+//
+//	v := hts.Verify()
+//	err := v.InOrder(v.Requests("POST", "/login"), v.Requests("GET", "/profile"), v.Requests("POST", "/logout"))
+func (v *Verifier) InOrder(verifications ...*RequestVerification) error {
+	records := v.hts.snapshotServerRecords()
+	next := 0
+	for i, rv := range verifications {
+		found := -1
+		for j := next; j < len(records); j++ {
+			if records[j].matches(rv.matcher) {
+				found = j
+				break
+			}
+		}
+		if found < 0 {
+			if i == 0 {
+				return rv.mismatch(true, "expected requests in order: no request matching %s", rv.description)
+			}
+			// Closest records are only described when no request at all is selected
+			previous := verifications[i-1]
+			return rv.mismatch(rv.count() == 0, "expected requests in order: no request matching %s after request #%d matching %s", rv.description, next, previous.description)
+		}
+		next = found + 1
+	}
+	return nil
+}
+
+// A verification of the recorded requests which are selected by a request matcher.
+type RequestVerification struct {
+	// The test server whose records are verified.
+	hts *HTTPTestServer
+	// Matcher used to select records.
+	matcher RequestMatcher
+	// Human readable description of the selected requests.
+	description string
+}
+
+// Verify exactly n recorded requests are selected.
+func (rv *RequestVerification) Count(n int) error {
+	if count := rv.count(); count != n {
+		return rv.mismatch(count < n, "expected %d request(s) matching %s, got %d", n, rv.description, count)
+	}
+	return nil
+}
+
+// Verify at least n recorded requests are selected.
+func (rv *RequestVerification) AtLeast(n int) error {
+	if count := rv.count(); count < n {
+		return rv.mismatch(true, "expected at least %d request(s) matching %s, got %d", n, rv.description, count)
+	}
+	return nil
+}
+
+// Verify at most n recorded requests are selected.
+func (rv *RequestVerification) AtMost(n int) error {
+	if count := rv.count(); count > n {
+		return fmt.Errorf("expected at most %d request(s) matching %s, got %d", n, rv.description, count)
+	}
+	return nil
+}
+
+// Verify exactly one recorded request is selected.
+func (rv *RequestVerification) Once() error {
+	return rv.Count(1)
+}
+
+// Verify no recorded requests are selected.
+func (rv *RequestVerification) Never() error {
+	return rv.Count(0)
+}
+
+// Helper method which builds the error returned when a verification fails. When too few requests
+// are selected, the error describes which criteria the closest recorded requests match.
+func (rv *RequestVerification) mismatch(tooFew bool, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	if !tooFew {
+		return err
+	}
+	if explanation := explainClosestRecords(rv.hts.snapshotServerRecords(), rv.matcher); explanation != "" {
+		return fmt.Errorf("%w\n%s", err, explanation)
+	}
+	return err
+}
+
+// Helper method which counts the selected records.
+func (rv *RequestVerification) count() int {
+	count := 0
+	for _, record := range rv.hts.snapshotServerRecords() {
+		if record.matches(rv.matcher) {
+			count++
+		}
+	}
+	return count
+}
+
+// Helper method which returns a copy of the records currently retained by the test server.
+func (hts *HTTPTestServer) snapshotServerRecords() []*ServerRecord {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	records := make([]*ServerRecord, len(hts.records))
+	copy(records, hts.records)
+	return records
+}
+
+// Helper method which returns true if the recorded request is matched by the provided matcher.
+// The matcher is provided with a fresh copy of the recorded request body.
+func (record *ServerRecord) matches(matcher RequestMatcher) bool {
+	if record.Request == nil {
+		return false
+	}
+	record.Request.Body = io.NopCloser(bytes.NewReader(record.RequestBody.Bytes()))
+	return matcher.Match(record.Request)
+}
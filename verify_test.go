@@ -0,0 +1,90 @@
+package gosette
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test the verification API. Test will ensure request counts are verified against records and
+// that failed verifications return a descriptive error.
+func (suite *HTTPTestServerUnitTestSuite) TestVerify() {
+	// Get a HTTP client
+	client := suite.hts.Client()
+
+	// Send two POST /orders and one GET /orders
+	for i := 0; i < 2; i++ {
+		_, err := client.Post(suite.hts.GetBaseURL()+"/orders", "application/json", strings.NewReader(`{"id": 1}`))
+		require.NoError(suite.T(), err)
+	}
+	_, err := client.Get(suite.hts.GetBaseURL() + "/orders")
+	require.NoError(suite.T(), err)
+
+	// Verify counts
+	require.NoError(suite.T(), suite.hts.Verify().Requests(http.MethodPost, "/orders").Count(2))
+	require.NoError(suite.T(), suite.hts.Verify().Requests("get", "/orders").Once())
+	require.NoError(suite.T(), suite.hts.Verify().Requests("", "/orders").AtLeast(3))
+	require.NoError(suite.T(), suite.hts.Verify().Requests(http.MethodPost, "").AtMost(2))
+	require.NoError(suite.T(), suite.hts.Verify().Requests("", "").Count(3))
+	require.NoError(suite.T(), suite.hts.Verify().NoRequests("/admin"))
+	require.NoError(suite.T(), suite.hts.Verify().RequestsMatching(BodyContainsMatcher(`"id"`)).Count(2))
+
+	// Failed verifications
+	err = suite.hts.Verify().Requests(http.MethodPost, "/orders").Count(1)
+	require.EqualError(suite.T(), err, "expected 1 request(s) matching POST /orders, got 2")
+	err = suite.hts.Verify().Requests("", "").AtLeast(4)
+	require.EqualError(suite.T(), err, "expected at least 4 request(s) matching any request, got 3")
+	err = suite.hts.Verify().Requests(http.MethodPost, "").AtMost(1)
+	require.EqualError(suite.T(), err, "expected at most 1 request(s) matching POST, got 2")
+	err = suite.hts.Verify().NoRequests("/orders")
+	require.EqualError(suite.T(), err, "expected 0 request(s) matching /orders, got 3")
+	err = suite.hts.Verify().RequestsMatching(PathMatcher("/")).Once()
+	require.EqualError(suite.T(), err, `expected 1 request(s) matching the provided matcher, got 0
+closest recorded requests:
+  POST /orders (0/1 criteria matched):
+    ✗ path: expected "/", got "/orders"
+  POST /orders (0/1 criteria matched):
+    ✗ path: expected "/", got "/orders"
+  GET /orders (0/1 criteria matched):
+    ✗ path: expected "/", got "/orders"`)
+
+	// Records without request are never matched
+	require.False(suite.T(), (&ServerRecord{}).matches(MatchAll()))
+}
+
+// Test ordered verifications. Test will ensure requests are verified in their relative order,
+// other requests being allowed in between, and that failed verifications return a descriptive
+// error.
+func (suite *HTTPTestServerUnitTestSuite) TestVerifyInOrder() {
+	// Send POST /login, GET /health, GET /profile and POST /logout
+	client := suite.hts.Client()
+	for _, path := range []string{"/login", "/health", "/profile", "/logout"} {
+		method := http.MethodPost
+		if path == "/health" || path == "/profile" {
+			method = http.MethodGet
+		}
+		req, err := http.NewRequest(method, suite.hts.GetBaseURL()+path, nil)
+		require.NoError(suite.T(), err)
+		resp, err := client.Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+	}
+
+	// Verify order
+	v := suite.hts.Verify()
+	require.NoError(suite.T(), v.InOrder())
+	require.NoError(suite.T(), v.InOrder(v.Requests(http.MethodPost, "/login"), v.Requests(http.MethodGet, "/profile"), v.Requests(http.MethodPost, "/logout")))
+	require.NoError(suite.T(), v.InOrder(v.Requests(http.MethodPost, ""), v.Requests(http.MethodPost, "")))
+	require.NoError(suite.T(), v.InOrder(v.Requests(http.MethodGet, ""), v.Requests(http.MethodGet, "")))
+
+	// Failed verifications
+	err := v.InOrder(v.Requests(http.MethodGet, "/profile"), v.Requests(http.MethodPost, "/login"))
+	require.EqualError(suite.T(), err, "expected requests in order: no request matching POST /login after request #3 matching GET /profile")
+	err = v.InOrder(v.Requests(http.MethodPost, ""), v.Requests(http.MethodPost, ""), v.Requests(http.MethodPost, ""))
+	require.EqualError(suite.T(), err, "expected requests in order: no request matching POST after request #4 matching POST")
+	err = v.InOrder(v.Requests(http.MethodPost, "/login"), v.Requests(http.MethodDelete, "/logout"))
+	require.True(suite.T(), strings.HasPrefix(err.Error(), "expected requests in order: no request matching DELETE /logout after request #1 matching POST /login\nclosest recorded requests:\n"))
+	err = v.InOrder(v.Requests("", "/admin"))
+	require.True(suite.T(), strings.HasPrefix(err.Error(), "expected requests in order: no request matching /admin\nclosest recorded requests:\n"))
+}
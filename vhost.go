@@ -0,0 +1,92 @@
+package gosette
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// A virtual host of a test server: a set of predefined responses served only for the requests
+// which target a given host name. Virtual hosts allow one test server to emulate several hosts
+// (ex: api.example.com and auth.example.com) for clients which talk to multiple hosts.
+//
+// A request targets a virtual host when the server name it has presented with TLS (SNI) is equal
+// to the virtual host name. When the request has not presented a server name (plain HTTP or TLS
+// without SNI), the Host header (without port) is used instead. Comparisons are case insensitive.
+//
+// A VirtualHost is a RequestMatcher: it can be used to verify or find the requests which have
+// targeted the virtual host.
+type VirtualHost struct {
+	// The test server the predefined responses are registered to.
+	hts *HTTPTestServer
+	// Host name of the virtual host.
+	name string
+}
+
+// Get the virtual host with the provided host name. Use WithGeneratedCertificate to serve a
+// certificate which is valid for the virtual host names over TLS.
+func (hts *HTTPTestServer) VirtualHost(name string) *VirtualHost {
+	return &VirtualHost{hts: hts, name: name}
+}
+
+// Return the host name of the virtual host.
+func (vh *VirtualHost) Name() string {
+	return vh.name
+}
+
+// Match returns true if the provided request targets the virtual host.
+func (vh *VirtualHost) Match(r *http.Request) bool {
+	if r.TLS != nil && r.TLS.ServerName != "" {
+		return strings.EqualFold(r.TLS.ServerName, vh.name)
+	}
+	return strings.EqualFold(requestHostname(r), vh.name)
+}
+
+// Explain describes the server name (SNI) or the host targeted by the provided request.
+func (vh *VirtualHost) Explain(r *http.Request) []CriterionResult {
+	actual := requestHostname(r)
+	if r.TLS != nil && r.TLS.ServerName != "" {
+		actual = r.TLS.ServerName
+	}
+	return []CriterionResult{{
+		Criterion: "virtual host",
+		Expected:  fmt.Sprintf("%q", vh.name),
+		Actual:    fmt.Sprintf("%q", actual),
+		Matched:   vh.Match(r),
+	}}
+}
+
+// Register a predefined response which will be served for each request which targets the virtual
+// host and is matched by the provided matcher. See HTTPTestServer RegisterResponse.
+func (vh *VirtualHost) RegisterResponse(matcher RequestMatcher, resp *PredefinedServerResponse) {
+	vh.hts.RegisterResponse(MatchAll(vh, matcher), resp)
+}
+
+// Start describing the requests which target the virtual host a predefined response must be
+// served for.
+func (vh *VirtualHost) When() *RequestMatcherBuilder {
+	return vh.hts.When().Matching(vh)
+}
+
+// Build a request matcher which matches requests which have presented the provided server name
+// with TLS (SNI). Comparison is case insensitive.
+func SNIMatcher(serverName string) RequestMatcher {
+	return newCriterionMatcher("server name", fmt.Sprintf("%q", serverName), func(r *http.Request) string {
+		if r.TLS == nil {
+			return "no TLS"
+		}
+		return fmt.Sprintf("%q", r.TLS.ServerName)
+	}, func(r *http.Request) bool {
+		return r.TLS != nil && strings.EqualFold(r.TLS.ServerName, serverName)
+	})
+}
+
+// Helper function which returns the Host of the provided request without port.
+func requestHostname(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return strings.Trim(r.Host, "[]")
+	}
+	return host
+}
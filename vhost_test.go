@@ -0,0 +1,100 @@
+package gosette
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Helper function which returns a client which sends all requests to the provided test server,
+// whatever the host they target. The client trusts the test server CA if any.
+func virtualHostClient(hts *HTTPTestServer) *http.Client {
+	addr := hts.GetUnderlyingHTTPTestServer().Listener.Addr().String()
+	dialer := &net.Dialer{}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSClientConfig: &tls.Config{RootCAs: hts.CACertPool()},
+		},
+	}
+}
+
+// Helper function which sends a GET request and returns the response body.
+func getBody(t *testing.T, client *http.Client, target string) string {
+	resp, err := client.Get(target)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// Test SNI-based virtual hosts. Test will ensure:
+//   - Each virtual host serves its own predefined responses
+//   - The server name presented by the client is recorded
+func TestVirtualHostsWithSNI(t *testing.T) {
+	srv := NewHTTPTestServer(nil, WithGeneratedCertificate("api.example.com", "auth.example.com"))
+	srv.StartTLS()
+	defer srv.Close()
+	api := srv.VirtualHost("api.example.com")
+	auth := srv.VirtualHost("auth.example.com")
+	api.When().Get("/whoami").RespondWith().StringBody("api")
+	auth.RegisterResponse(PathMatcher("/whoami"), &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("auth")})
+	require.Equal(t, "api.example.com", api.Name())
+
+	client := virtualHostClient(srv)
+	_, port, err := net.SplitHostPort(srv.GetUnderlyingHTTPTestServer().Listener.Addr().String())
+	require.NoError(t, err)
+	require.Equal(t, "api", getBody(t, client, "https://api.example.com:"+port+"/whoami"))
+	require.Equal(t, "auth", getBody(t, client, "https://auth.example.com:"+port+"/whoami"))
+	require.Equal(t, "", getBody(t, srv.Client(), srv.GetBaseURL()+"/whoami"))
+
+	records := srv.FindRecords(ByMatcher(SNIMatcher("AUTH.example.com")))
+	require.Len(t, records, 1)
+	require.Equal(t, "auth.example.com", records[0].ServerName)
+	require.Len(t, srv.FindRecords(ByMatcher(api)), 1)
+}
+
+// Test virtual hosts with plain HTTP: the Host header is used.
+func TestVirtualHostsWithHost(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	srv.VirtualHost("api.example.com").When().Get("/whoami").RespondWith().StringBody("api")
+	srv.VirtualHost("auth.example.com").When().Get("/whoami").RespondWith().StringBody("auth")
+
+	client := virtualHostClient(srv)
+	require.Equal(t, "api", getBody(t, client, "http://api.example.com/whoami"))
+	require.Equal(t, "auth", getBody(t, client, "http://auth.example.com:8080/whoami"))
+	u, err := url.Parse(srv.GetBaseURL())
+	require.NoError(t, err)
+	require.Equal(t, "", getBody(t, client, "http://"+u.Host+"/whoami"))
+	require.Empty(t, srv.PopServerRecord().ServerName)
+}
+
+// Test predefined responses bound to a host. Test will ensure:
+//   - Host queues are consulted before the other route queues
+//   - The Host header is compared without port and in a case insensitive way
+//   - Host matchers can be used with the builder
+func TestPushPredefinedServerResponseForHost(t *testing.T) {
+	srv := NewHTTPTestServer(nil)
+	srv.Start()
+	defer srv.Close()
+	srv.PushPredefinedServerResponseForHost("API.example.com", &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("api")})
+	srv.PushPredefinedServerResponseForPath("/whoami", &PredefinedServerResponse{Status: http.StatusOK, Body: []byte("path")})
+	srv.When().Host("auth.example.com").RespondWith().StringBody("auth")
+
+	client := virtualHostClient(srv)
+	require.Equal(t, "api", getBody(t, client, "http://api.example.com:8080/whoami"))
+	require.Equal(t, "auth", getBody(t, client, "http://auth.example.com/whoami"))
+	require.Equal(t, "path", getBody(t, client, "http://other.example.com/whoami"))
+	require.Len(t, srv.FindRecords(ByMatcher(HostMatcher("api.example.com"))), 1)
+}
@@ -0,0 +1,238 @@
+package gosette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// An outbound HTTP callback fired by the test server once a predefined response has been served.
+// Useful to test webhook consumers end to end: the client under test calls the test server which
+// later calls the client back.
+//
+// URL, header values and Body are Go templates (see text/template) executed with a TemplateData
+// built from the request which has triggered the webhook. Example:
+//
+//	{"orderId": "{{index .JSON "id"}}", "status": "shipped"}
+type Webhook struct {
+	// URL the callback is sent to.
+	URL string
+	// HTTP method of the callback. Defaults to POST.
+	Method string
+	// Headers of the callback.
+	Headers http.Header
+	// Body template of the callback.
+	Body string
+	// Delay to wait, once the response has been served, before the callback is sent.
+	Delay time.Duration
+}
+
+// Data the webhook templates and the response body templates are executed with.
+type TemplateData struct {
+	// Method of the triggering request.
+	Method string
+	// Path of the triggering request.
+	Path string
+	// Query parameters of the triggering request.
+	Query url.Values
+	// Headers of the triggering request.
+	Header http.Header
+	// Body of the triggering request.
+	Body string
+	// Body of the triggering request decoded from JSON. Nil if the body is not valid JSON.
+	JSON interface{}
+	// Parameters extracted from the path of the triggering request (see PathTemplateMatcher).
+	PathParams map[string]string
+}
+
+// Data the webhook templates are executed with.
+//
+// Deprecated: use TemplateData.
+type WebhookData = TemplateData
+
+// The outcome of a webhook fired by the test server.
+type WebhookDelivery struct {
+	// The fired webhook.
+	Webhook *Webhook
+	// The callback request sent by the test server. Nil if the request could not be built.
+	Request *http.Request
+	// The body of the callback request.
+	RequestBody []byte
+	// Status code of the response to the callback. Zero if no response has been received.
+	Status int
+	// Body of the response to the callback.
+	ResponseBody []byte
+	// Error which has occured while the callback was built or sent. Nil otherwise.
+	Err error
+}
+
+// Set the client used to send webhooks (ex: a client which trusts the certificate of the
+// webhook consumer). By default, a client with a 30 seconds timeout is used.
+func (hts *HTTPTestServer) SetWebhookClient(client *http.Client) {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	hts.webhookClient = client
+}
+
+// Get the deliveries of the webhooks fired so far, in the order they have completed.
+func (hts *HTTPTestServer) WebhookDeliveries() []*WebhookDelivery {
+	hts.mu.Lock()
+	defer hts.mu.Unlock()
+	deliveries := make([]*WebhookDelivery, len(hts.webhookDeliveries))
+	copy(deliveries, hts.webhookDeliveries)
+	return deliveries
+}
+
+// Wait until at least n webhook deliveries have completed or the timeout expires. The first n
+// deliveries are returned. In case the timeout expires, the completed deliveries are returned
+// with an error.
+func (hts *HTTPTestServer) WaitForWebhooks(n int, timeout time.Duration) ([]*WebhookDelivery, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// Return the first n deliveries if available
+		hts.mu.Lock()
+		if len(hts.webhookDeliveries) >= n {
+			deliveries := make([]*WebhookDelivery, n)
+			copy(deliveries, hts.webhookDeliveries)
+			hts.mu.Unlock()
+			return deliveries, nil
+		}
+		webhookDelivered := hts.webhookDelivered
+		hts.mu.Unlock()
+		// Wait for a new delivery or the timeout
+		select {
+		case <-webhookDelivered:
+		case <-timer.C:
+			deliveries := hts.WebhookDeliveries()
+			return deliveries, fmt.Errorf("timed out after %s waiting for %d webhook(s), got %d", timeout, n, len(deliveries))
+		}
+	}
+}
+
+// Helper method which fires the webhooks of the provided predefined response in the background.
+// Webhooks which are still waiting for their delay are cancelled when the test server is closed.
+func (srv *HTTPTestServer) fireWebhooks(response *PredefinedServerResponse, serverRecord *ServerRecord) {
+	if len(response.Webhooks) == 0 {
+		return
+	}
+	data := newTemplateData(serverRecord)
+	srv.mu.Lock()
+	client, closing, clock := srv.webhookClient, srv.closing, srv.clock
+	srv.mu.Unlock()
+	for _, webhook := range response.Webhooks {
+		go func(webhook *Webhook) {
+			// Wait for the delay unless the test server is closed
+			if webhook.Delay > 0 {
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					select {
+					case <-closing:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				err := clock.Sleep(ctx, webhook.Delay)
+				cancel()
+				if err != nil {
+					return
+				}
+			}
+			delivery := webhook.send(client, data)
+			// Add the delivery and wake up waiting goroutines
+			srv.mu.Lock()
+			srv.webhookDeliveries = append(srv.webhookDeliveries, delivery)
+			close(srv.webhookDelivered)
+			srv.webhookDelivered = make(chan struct{})
+			srv.mu.Unlock()
+		}(webhook)
+	}
+}
+
+// Helper method which builds and sends the callback with the provided client and template data.
+func (webhook *Webhook) send(client *http.Client, data *TemplateData) *WebhookDelivery {
+	delivery := &WebhookDelivery{Webhook: webhook}
+	// Execute the templates
+	target, err := executeTemplate(webhook.URL, data)
+	if err != nil {
+		delivery.Err = fmt.Errorf("failed to build webhook URL: %w", err)
+		return delivery
+	}
+	body, err := executeTemplate(webhook.Body, data)
+	if err != nil {
+		delivery.Err = fmt.Errorf("failed to build webhook body: %w", err)
+		return delivery
+	}
+	delivery.RequestBody = []byte(body)
+	method := webhook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		delivery.Err = fmt.Errorf("failed to build webhook request: %w", err)
+		return delivery
+	}
+	for header, values := range webhook.Headers {
+		for _, value := range values {
+			value, err = executeTemplate(value, data)
+			if err != nil {
+				delivery.Err = fmt.Errorf("failed to build webhook header %s: %w", header, err)
+				return delivery
+			}
+			req.Header.Add(header, value)
+		}
+	}
+	delivery.Request = req
+	// Send the callback and read the response
+	resp, err := client.Do(req)
+	if err != nil {
+		delivery.Err = fmt.Errorf("failed to send webhook: %w", err)
+		return delivery
+	}
+	defer resp.Body.Close()
+	delivery.Status = resp.StatusCode
+	delivery.ResponseBody, err = io.ReadAll(resp.Body)
+	if err != nil {
+		delivery.Err = fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	return delivery
+}
+
+// Helper function which builds the data the templates are executed with.
+func newTemplateData(serverRecord *ServerRecord) *TemplateData {
+	r := serverRecord.Request
+	data := &TemplateData{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.Query(),
+		Header:     r.Header.Clone(),
+		Body:       serverRecord.RequestBody.String(),
+		PathParams: serverRecord.PathParams,
+	}
+	var v interface{}
+	if json.Unmarshal(serverRecord.RequestBody.Bytes(), &v) == nil {
+		data.JSON = v
+	}
+	return data
+}
+
+// Helper function which executes the provided template with the provided data.
+func executeTemplate(text string, data *TemplateData) (string, error) {
+	tmpl, err := template.New("gosette").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
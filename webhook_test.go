@@ -0,0 +1,72 @@
+package gosette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test a webhook is fired once the response has been served, with templates executed with the
+// triggering request, and its delivery is recorded.
+func TestWebhook(t *testing.T) {
+	// Webhook consumer
+	received := make(chan string, 1)
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.Method + " " + r.URL.Path + " " + r.Header.Get("X-Event") + " " + string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer consumer.Close()
+	// Test server
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.When().Post("/orders").RespondWith().Status(http.StatusCreated).Webhook(&Webhook{
+		URL:     consumer.URL + "/hooks/{{index .JSON \"id\"}}",
+		Headers: http.Header{"X-Event": {"{{.Method}} {{.Path}}"}},
+		Body:    `{"id":"{{index .JSON "id"}}","status":"shipped"}`,
+		Delay:   50 * time.Millisecond,
+	})
+	start := time.Now()
+	resp, err := hts.Client().Post(hts.GetBaseURL()+"/orders", "application/json", strings.NewReader(`{"id":"42"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	deliveries, err := hts.WaitForWebhooks(1, time.Second)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, `POST /hooks/42 POST /orders {"id":"42","status":"shipped"}`, <-received)
+	require.NoError(t, deliveries[0].Err)
+	require.Equal(t, http.StatusAccepted, deliveries[0].Status)
+	require.Equal(t, `{"id":"42","status":"shipped"}`, string(deliveries[0].RequestBody))
+	// Deliveries are cleared with records
+	hts.ClearServerRecords()
+	require.Empty(t, hts.WebhookDeliveries())
+}
+
+// Test webhook failures are recorded in deliveries and waiting for webhooks times out.
+func TestWebhookErrors(t *testing.T) {
+	hts := NewHTTPTestServer(nil)
+	hts.Start()
+	defer hts.Close()
+	hts.SetWebhookClient(&http.Client{Timeout: time.Second})
+	hts.When().Get("/").RespondWith().
+		Webhook(&Webhook{URL: "{{.Unknown}}"}).
+		Webhook(&Webhook{URL: "http://127.0.0.1:0", Body: "{{"}).
+		Webhook(&Webhook{URL: "http://127.0.0.1:1/", Method: http.MethodPut})
+	resp, err := hts.Client().Get(hts.GetBaseURL())
+	require.NoError(t, err)
+	resp.Body.Close()
+	deliveries, err := hts.WaitForWebhooks(3, time.Second)
+	require.NoError(t, err)
+	for _, delivery := range deliveries {
+		require.Error(t, delivery.Err)
+	}
+	_, err = hts.WaitForWebhooks(4, 10*time.Millisecond)
+	require.Error(t, err)
+}
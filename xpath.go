@@ -0,0 +1,457 @@
+package gosette
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A XML namespace binding: a prefix which can be used in XPath expressions and the namespace URI
+// it is bound to.
+type XMLNamespace struct {
+	// Prefix used in XPath expressions.
+	Prefix string
+	// URI of the namespace.
+	URI string
+}
+
+// Bind the provided prefix to the provided namespace URI in XPath expressions.
+func XMLNS(prefix string, uri string) XMLNamespace {
+	return XMLNamespace{Prefix: prefix, URI: uri}
+}
+
+// Build a request matcher which matches requests whose body is a XML document in which at least
+// one node selected by the provided XPath expression has a string value equal to the expected
+// value.
+//
+// The string value of an element is its text content, the string value of an attribute or a text
+// node is its value. Leading and trailing white spaces are trimmed.
+//
+// Supported XPath syntax: absolute location paths made of child (/) and descendant (//) steps.
+// Steps can select elements (name, prefix:name or *), attributes (@name, @prefix:name or @*) and
+// text nodes (text()). Steps can be filtered with predicates: position ([1], [last()]), attribute
+// or child existence ([@id], [name]) and attribute or child value ([@id='1'], [name="john"]).
+//
+// Unprefixed names match elements and attributes regardless of their namespace. Prefixed names
+// match only nodes in the namespace the prefix is bound to with the provided namespaces: the
+// prefixes declared by the document itself are not used. The matcher does not match requests
+// whose body is not a valid XML document or when the expression is invalid.
+func BodyXPathMatcher(expr string, expected string, namespaces ...XMLNamespace) RequestMatcher {
+	return newCriterionMatcher("XPath "+expr, fmt.Sprintf("%q", expected), func(r *http.Request) string {
+		return explainedValues(requestXPath(r, expr, namespaces))
+	}, func(r *http.Request) bool {
+		values, err := requestXPath(r, expr, namespaces)
+		return err == nil && containsString(values, expected)
+	})
+}
+
+// Build a request matcher which matches requests whose body is a XML document in which the
+// provided XPath expression selects at least one node. See BodyXPathMatcher for the supported
+// syntax.
+func BodyXPathExistsMatcher(expr string, namespaces ...XMLNamespace) RequestMatcher {
+	return newCriterionMatcher("XPath "+expr, "at least one node", func(r *http.Request) string {
+		return explainedValues(requestXPath(r, expr, namespaces))
+	}, func(r *http.Request) bool {
+		values, err := requestXPath(r, expr, namespaces)
+		return err == nil && len(values) > 0
+	})
+}
+
+// Build a filter which selects records whose request body is a XML document in which at least one
+// node selected by the provided XPath expression has a string value equal to the expected value.
+// See BodyXPathMatcher.
+func ByXPath(expr string, expected string, namespaces ...XMLNamespace) RecordFilter {
+	return ByMatcher(BodyXPathMatcher(expr, expected, namespaces...))
+}
+
+// Evaluate the provided XPath expression against the request body of the record and return the
+// string values of the selected nodes. See BodyXPathMatcher for the supported syntax.
+//
+// An error is returned if the expression is invalid or if the request body is not a valid XML
+// document.
+func (record *ServerRecord) XPath(expr string, namespaces ...XMLNamespace) ([]string, error) {
+	return evalXPath(record.RequestBody.Bytes(), expr, namespaces)
+}
+
+// Check that at least one node selected by the provided XPath expression in the request body of
+// the record has a string value equal to the expected value. See BodyXPathMatcher.
+//
+// An error which describes the mismatch is returned if the check fails.
+func (record *ServerRecord) AssertXPath(expr string, expected string, namespaces ...XMLNamespace) error {
+	values, err := record.XPath(expr, namespaces...)
+	if err != nil {
+		return err
+	}
+	if !containsString(values, expected) {
+		return fmt.Errorf("expected %s to be %q, got %q", expr, expected, values)
+	}
+	return nil
+}
+
+// Helper function which reads the body of the provided request and evaluates the provided XPath
+// expression against it.
+func requestXPath(r *http.Request, expr string, namespaces []XMLNamespace) ([]string, error) {
+	if r.Body == nil {
+		return nil, fmt.Errorf("request has no body")
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return evalXPath(body, expr, namespaces)
+}
+
+// Helper function which parses the provided XML document and evaluates the provided XPath
+// expression against it.
+func evalXPath(data []byte, expr string, namespaces []XMLNamespace) ([]string, error) {
+	steps, err := parseXPath(expr, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseXMLDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*xmlNode{doc}
+	for _, step := range steps {
+		nodes = step.apply(nodes)
+	}
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, strings.TrimSpace(node.value()))
+	}
+	return values, nil
+}
+
+// Helper function which returns true if the provided values contain the expected value.
+func containsString(values []string, expected string) bool {
+	for _, value := range values {
+		if value == expected {
+			return true
+		}
+	}
+	return false
+}
+
+/*************************************************************************************************/
+/* XML DOCUMENT                                                                                  */
+/*************************************************************************************************/
+
+// Kinds of XML nodes.
+const (
+	xmlDocumentNode = iota
+	xmlElementNode
+	xmlAttributeNode
+	xmlTextNode
+)
+
+// A node of a parsed XML document.
+type xmlNode struct {
+	// Kind of the node.
+	kind int
+	// Name of the element or attribute.
+	name xml.Name
+	// Value of the attribute or text node.
+	text string
+	// Attributes of the element (namespace declarations excluded).
+	attrs []*xmlNode
+	// Children of the document or element.
+	children []*xmlNode
+}
+
+// Helper function which parses the provided XML document.
+func parseXMLDocument(data []byte) (*xmlNode, error) {
+	doc := &xmlNode{kind: xmlDocumentNode}
+	stack := []*xmlNode{doc}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode XML document: %w", err)
+		}
+		parent := stack[len(stack)-1]
+		switch typed := token.(type) {
+		case xml.StartElement:
+			element := &xmlNode{kind: xmlElementNode, name: typed.Name}
+			for _, attr := range typed.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				element.attrs = append(element.attrs, &xmlNode{kind: xmlAttributeNode, name: attr.Name, text: attr.Value})
+			}
+			parent.children = append(parent.children, element)
+			stack = append(stack, element)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if parent.kind == xmlElementNode {
+				parent.children = append(parent.children, &xmlNode{kind: xmlTextNode, text: string(typed)})
+			}
+		}
+	}
+	if len(doc.children) == 0 {
+		return nil, fmt.Errorf("failed to decode XML document: no root element")
+	}
+	return doc, nil
+}
+
+// Helper method which returns the string value of the node.
+func (node *xmlNode) value() string {
+	if node.kind == xmlAttributeNode || node.kind == xmlTextNode {
+		return node.text
+	}
+	var sb strings.Builder
+	for _, child := range node.children {
+		sb.WriteString(child.value())
+	}
+	return sb.String()
+}
+
+// Helper method which returns the node and all its descendant elements.
+func (node *xmlNode) descendantsOrSelf() []*xmlNode {
+	nodes := []*xmlNode{node}
+	for _, child := range node.children {
+		if child.kind == xmlElementNode {
+			nodes = append(nodes, child.descendantsOrSelf()...)
+		}
+	}
+	return nodes
+}
+
+/*************************************************************************************************/
+/* XPATH                                                                                         */
+/*************************************************************************************************/
+
+// A name test used by XPath steps and predicates. An empty space matches any namespace.
+type xpathName struct {
+	space string
+	local string
+}
+
+// Helper method which returns true if the provided name passes the test.
+func (test xpathName) match(name xml.Name) bool {
+	return (test.local == "*" || test.local == name.Local) && (test.space == "" || test.space == name.Space)
+}
+
+// A predicate used to filter the nodes selected by a XPath step.
+type xpathPredicate struct {
+	// Position of the selected node (1-based). 0 if the predicate does not test the position, -1
+	// to select the last node.
+	position int
+	// Name of the tested attribute or child element.
+	name xpathName
+	// True if an attribute is tested.
+	attribute bool
+	// Expected value of the tested attribute or child. Nil to test existence only.
+	value *string
+}
+
+// A step of a XPath location path.
+type xpathStep struct {
+	// True if the step selects descendants (//) instead of children (/).
+	descendant bool
+	// Kind of the selected nodes: element, attribute or text.
+	kind int
+	// Name test of the selected elements or attributes.
+	name xpathName
+	// Predicates applied to the selected nodes.
+	predicates []xpathPredicate
+}
+
+// Helper function which parses a XPath expression.
+func parseXPath(expr string, namespaces []XMLNamespace) ([]xpathStep, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("invalid XPath %q: expression must be an absolute location path", expr)
+	}
+	steps := []xpathStep{}
+	rest := expr
+	for rest != "" {
+		step := xpathStep{kind: xmlElementNode}
+		// Parse separator
+		if strings.HasPrefix(rest, "//") {
+			step.descendant = true
+			rest = rest[2:]
+		} else if strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+		} else {
+			return nil, fmt.Errorf("invalid XPath %q: unexpected %q", expr, rest[:1])
+		}
+		// Parse node test
+		end := strings.IndexAny(rest, "/[")
+		if end < 0 {
+			end = len(rest)
+		}
+		test := rest[:end]
+		rest = rest[end:]
+		switch {
+		case test == "text()":
+			step.kind = xmlTextNode
+		case strings.HasPrefix(test, "@"):
+			step.kind = xmlAttributeNode
+			test = test[1:]
+		}
+		if step.kind != xmlTextNode {
+			name, err := parseXPathName(test, namespaces)
+			if err != nil {
+				return nil, fmt.Errorf("invalid XPath %q: %w", expr, err)
+			}
+			step.name = name
+		}
+		// Parse predicates
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid XPath %q: missing ]", expr)
+			}
+			predicate, err := parseXPathPredicate(strings.TrimSpace(rest[1:end]), namespaces)
+			if err != nil {
+				return nil, fmt.Errorf("invalid XPath %q: %w", expr, err)
+			}
+			step.predicates = append(step.predicates, predicate)
+			rest = rest[end+1:]
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Helper function which parses a name test (name, prefix:name or *).
+func parseXPathName(test string, namespaces []XMLNamespace) (xpathName, error) {
+	if test == "" {
+		return xpathName{}, fmt.Errorf("empty name test")
+	}
+	prefix, local := "", test
+	if i := strings.Index(test, ":"); i >= 0 {
+		prefix, local = test[:i], test[i+1:]
+	}
+	if local == "" || strings.ContainsAny(local, ":()@='\" ") {
+		return xpathName{}, fmt.Errorf("invalid name test %q", test)
+	}
+	if prefix == "" {
+		return xpathName{local: local}, nil
+	}
+	for _, ns := range namespaces {
+		if ns.Prefix == prefix {
+			return xpathName{space: ns.URI, local: local}, nil
+		}
+	}
+	return xpathName{}, fmt.Errorf("unbound namespace prefix %q", prefix)
+}
+
+// Helper function which parses a predicate (without the brackets).
+func parseXPathPredicate(predicate string, namespaces []XMLNamespace) (xpathPredicate, error) {
+	// Position
+	if predicate == "last()" {
+		return xpathPredicate{position: -1}, nil
+	}
+	if position, err := strconv.Atoi(predicate); err == nil {
+		if position < 1 {
+			return xpathPredicate{}, fmt.Errorf("invalid position %d", position)
+		}
+		return xpathPredicate{position: position}, nil
+	}
+	// Attribute or child test
+	result := xpathPredicate{}
+	test := predicate
+	if i := strings.Index(predicate, "="); i >= 0 {
+		test = strings.TrimSpace(predicate[:i])
+		literal := strings.TrimSpace(predicate[i+1:])
+		if len(literal) < 2 || (literal[0] != '\'' && literal[0] != '"') || literal[len(literal)-1] != literal[0] {
+			return xpathPredicate{}, fmt.Errorf("invalid literal %s", literal)
+		}
+		value := literal[1 : len(literal)-1]
+		result.value = &value
+	}
+	if strings.HasPrefix(test, "@") {
+		result.attribute = true
+		test = test[1:]
+	}
+	name, err := parseXPathName(test, namespaces)
+	if err != nil {
+		return xpathPredicate{}, err
+	}
+	result.name = name
+	return result, nil
+}
+
+// Helper method which applies the step to the provided context nodes and returns the selected
+// nodes in document order, without duplicates.
+func (step xpathStep) apply(nodes []*xmlNode) []*xmlNode {
+	selected := []*xmlNode{}
+	seen := map[*xmlNode]bool{}
+	for _, node := range nodes {
+		parents := []*xmlNode{node}
+		if step.descendant {
+			parents = node.descendantsOrSelf()
+		}
+		for _, parent := range parents {
+			for _, candidate := range step.filter(step.candidates(parent)) {
+				if !seen[candidate] {
+					seen[candidate] = true
+					selected = append(selected, candidate)
+				}
+			}
+		}
+	}
+	return selected
+}
+
+// Helper method which returns the nodes of the provided parent which pass the node test.
+func (step xpathStep) candidates(parent *xmlNode) []*xmlNode {
+	candidates := []*xmlNode{}
+	nodes := parent.children
+	if step.kind == xmlAttributeNode {
+		nodes = parent.attrs
+	}
+	for _, node := range nodes {
+		if node.kind == step.kind && (step.kind == xmlTextNode || step.name.match(node.name)) {
+			candidates = append(candidates, node)
+		}
+	}
+	return candidates
+}
+
+// Helper method which applies the step predicates to the provided nodes.
+func (step xpathStep) filter(nodes []*xmlNode) []*xmlNode {
+	for _, predicate := range step.predicates {
+		filtered := []*xmlNode{}
+		for i, node := range nodes {
+			if predicate.match(node, i+1, len(nodes)) {
+				filtered = append(filtered, node)
+			}
+		}
+		nodes = filtered
+	}
+	return nodes
+}
+
+// Helper method which returns true if the provided node, at the provided position in a node set
+// of the provided size, passes the predicate.
+func (predicate xpathPredicate) match(node *xmlNode, position int, size int) bool {
+	switch {
+	case predicate.position > 0:
+		return position == predicate.position
+	case predicate.position < 0:
+		return position == size
+	}
+	candidates := node.children
+	if predicate.attribute {
+		candidates = node.attrs
+	}
+	for _, candidate := range candidates {
+		if candidate.kind == xmlTextNode || !predicate.name.match(candidate.name) {
+			continue
+		}
+		if predicate.value == nil || strings.TrimSpace(candidate.value()) == *predicate.value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package gosette
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SOAP document used in XPath tests.
+const testXPathDocument = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:m="urn:orders">
+	<soap:Body>
+		<m:CreateOrder id="42">
+			<m:Item sku="a" qty="1">Apple</m:Item>
+			<m:Item sku="b" qty="2">Banana</m:Item>
+			<Note>  first  </Note>
+		</m:CreateOrder>
+	</soap:Body>
+</soap:Envelope>`
+
+// Test the XPath evaluation against a XML document.
+func (suite *HTTPTestServerUnitTestSuite) TestXPath() {
+	record := &ServerRecord{RequestBody: bytes.NewBufferString(testXPathDocument)}
+	soap := XMLNS("s", "http://www.w3.org/2003/05/soap-envelope")
+	orders := XMLNS("o", "urn:orders")
+	testCases := []struct {
+		expr     string
+		expected []string
+	}{
+		{"/Envelope/Body/CreateOrder/@id", []string{"42"}},
+		{"/s:Envelope/s:Body/o:CreateOrder/@id", []string{"42"}},
+		{"//Item", []string{"Apple", "Banana"}},
+		{"//o:Item/@sku", []string{"a", "b"}},
+		{"//Item[2]", []string{"Banana"}},
+		{"//Item[last()]/@qty", []string{"2"}},
+		{"//Item[@sku='a']/text()", []string{"Apple"}},
+		{"//Item[@sku=\"b\"][1]", []string{"Banana"}},
+		{"//CreateOrder[Note='first']/@*", []string{"42"}},
+		{"//CreateOrder[@id]/Note", []string{"first"}},
+		{"//*[@qty]/@sku", []string{"a", "b"}},
+		{"//s:Item", []string{}},
+		{"//Item[@missing]", []string{}},
+		{"//Body//Item[1]", []string{"Apple"}},
+	}
+	for _, tc := range testCases {
+		values, err := record.XPath(tc.expr, soap, orders)
+		require.NoError(suite.T(), err, tc.expr)
+		require.Equal(suite.T(), tc.expected, values, tc.expr)
+	}
+	// Invalid expressions
+	for _, expr := range []string{"Envelope", "/x:Envelope", "//Item[0]", "//Item[1", "//Item[@sku=a]", "/", "//a:"} {
+		_, err := record.XPath(expr, soap, orders)
+		require.Error(suite.T(), err, expr)
+	}
+	// Invalid documents
+	_, err := (&ServerRecord{RequestBody: bytes.NewBufferString("<a>")}).XPath("/a")
+	require.Error(suite.T(), err)
+	_, err = (&ServerRecord{RequestBody: bytes.NewBufferString("")}).XPath("/a")
+	require.Error(suite.T(), err)
+	// Assertions
+	require.NoError(suite.T(), record.AssertXPath("//o:CreateOrder/@id", "42", orders))
+	require.Error(suite.T(), record.AssertXPath("//o:CreateOrder/@id", "43", orders))
+	require.Error(suite.T(), record.AssertXPath("//o:CreateOrder/@id", "42"))
+}
+
+// Test XPath request matchers, record filter and builder method.
+func (suite *HTTPTestServerUnitTestSuite) TestXPathMatchers() {
+	// Matchers
+	req := httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(testXPathDocument))
+	require.True(suite.T(), BodyXPathMatcher("//CreateOrder/@id", "42").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(testXPathDocument))
+	require.False(suite.T(), BodyXPathMatcher("//CreateOrder/@id", "43").Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(testXPathDocument))
+	require.True(suite.T(), BodyXPathExistsMatcher("//o:Item", XMLNS("o", "urn:orders")).Match(req))
+	req = httptest.NewRequest(http.MethodPost, "/soap", strings.NewReader(testXPathDocument))
+	require.False(suite.T(), BodyXPathExistsMatcher("//Item[3]").Match(req))
+	req.Body = nil
+	require.False(suite.T(), BodyXPathExistsMatcher("/").Match(req))
+
+	// Builder and record filter
+	suite.hts.When().Post("/soap").WithXPath("//CreateOrder/@id", "42").
+		RespondWith().Status(http.StatusOK)
+	client := suite.hts.Client()
+	resp, err := client.Post(suite.hts.GetBaseURL()+"/soap", "application/soap+xml", strings.NewReader(testXPathDocument))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	resp, err = client.Post(suite.hts.GetBaseURL()+"/soap", "application/soap+xml", strings.NewReader(`<CreateOrder id="7"/>`))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+	records := suite.hts.FindRecords(ByXPath("/CreateOrder/@id", "7"))
+	require.Len(suite.T(), records, 1)
+	require.Equal(suite.T(), http.StatusNotFound, records[0].Response.Code)
+}